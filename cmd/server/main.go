@@ -12,8 +12,12 @@ import (
 
 	"tipjar/internal/config"
 	"tipjar/internal/database"
+	"tipjar/internal/email"
+	"tipjar/internal/events"
 	"tipjar/internal/handlers"
 	"tipjar/internal/auth"
+	"tipjar/internal/services"
+	"tipjar/internal/storage"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -51,11 +55,48 @@ func main() {
 	e.Use(middleware.CORS())
 
 	// Initialize auth
-	authService := auth.NewService(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.SessionSecret)
+	authRegistry := auth.NewRegistry(cfg)
+
+	// Initialize proof storage
+	store, err := storage.New(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize storage", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize email; nil when SMTP isn't configured
+	mailer, err := email.New(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize email", "error", err)
+		os.Exit(1)
+	}
+
+	// Jar events for live pages; in-process, so a single instance only
+	bus := events.NewMemoryBus()
 
 	// Initialize handlers (handles static files and all routes)
-	h := handlers.New(db, authService, cfg)
+	h := handlers.New(db, authRegistry, store, bus, mailer, cfg)
 	h.RegisterRoutes(e)
+	e.Server.RegisterOnShutdown(bus.Close)
+
+	// Start background jobs
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	sessionService := services.NewSessionService(db, cfg.SessionSecret)
+	go sessionService.StartCleanup(bgCtx, time.Hour)
+
+	disputeService := services.NewDisputeService(db, bus)
+	go disputeService.StartResolver(bgCtx, 5*time.Minute)
+
+	webhookService := services.NewWebhookService(db)
+	go webhookService.StartDispatcher(bgCtx, 10*time.Second)
+
+	if mailer != nil {
+		emailService := services.NewEmailService(db, mailer, cfg.BaseURL, cfg.SessionSecret)
+		go emailService.StartDispatcher(bgCtx, 10*time.Second)
+		go emailService.StartDigests(bgCtx, 15*time.Minute)
+	}
 
 	slog.Info("Server starting", "port", cfg.Port)
 
@@ -73,6 +114,8 @@ func main() {
 
 	slog.Info("Shutting down server...")
 
+	stopBackground()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -7,68 +7,76 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"tipjar/internal/config"
 
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
-type Service struct {
-	config *oauth2.Config
-	sessionSecret string
+// Identity is the account information a provider returns after login
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
 }
 
-type GoogleUser struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
-	VerifiedEmail bool   `json:"verified_email"`
+// Provider is an OAuth2/OIDC login provider
+type Provider interface {
+	// Name is the URL-safe key used in /auth/:provider
+	Name() string
+	DisplayName() string
+	AuthCodeURL(ctx context.Context, state string) (string, error)
+	Exchange(ctx context.Context, code string) (*Identity, error)
 }
 
-func NewService(clientID, clientSecret, sessionSecret string) *Service {
-	config := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  "http://localhost:8080/auth/callback",
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
-	}
-
-	return &Service{
-		config:        config,
-		sessionSecret: sessionSecret,
-	}
+// Registry holds the login providers enabled in config, in display order
+type Registry struct {
+	providers map[string]Provider
+	order     []string
 }
 
-func (s *Service) GetAuthURL(state string) string {
-	return s.config.AuthCodeURL(state, oauth2.AccessTypeOffline)
-}
+// NewRegistry enables every provider that has credentials configured
+func NewRegistry(cfg *config.Config) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+
+	if cfg.GoogleClientID != "" {
+		r.Register(NewGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, callbackURL(cfg, "google")))
+	}
+	if cfg.GitHubClientID != "" {
+		r.Register(NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, callbackURL(cfg, "github")))
+	}
+	if cfg.DiscordClientID != "" {
+		r.Register(NewDiscordProvider(cfg.DiscordClientID, cfg.DiscordClientSecret, callbackURL(cfg, "discord")))
+	}
+	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
+		r.Register(NewOIDCProvider(cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCDisplayName, callbackURL(cfg, "oidc")))
+	}
 
-func (s *Service) ExchangeCode(ctx context.Context, code string) (*oauth2.Token, error) {
-	return s.config.Exchange(ctx, code)
+	return r
 }
 
-func (s *Service) GetUserInfo(ctx context.Context, token *oauth2.Token) (*GoogleUser, error) {
-	client := s.config.Client(ctx, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, err
+func (r *Registry) Register(p Provider) {
+	if _, exists := r.providers[p.Name()]; !exists {
+		r.order = append(r.order, p.Name())
 	}
-	defer resp.Body.Close()
+	r.providers[p.Name()] = p
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get user info: %s", resp.Status)
-	}
+// Get returns the named provider, or nil if it isn't enabled
+func (r *Registry) Get(name string) Provider {
+	return r.providers[name]
+}
 
-	var user GoogleUser
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return nil, err
+func (r *Registry) Providers() []Provider {
+	providers := make([]Provider, len(r.order))
+	for i, name := range r.order {
+		providers[i] = r.providers[name]
 	}
-
-	return &user, nil
+	return providers
 }
 
 func GenerateState() (string, error) {
@@ -79,3 +87,30 @@ func GenerateState() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(b), nil
 }
+
+func callbackURL(cfg *config.Config, provider string) string {
+	return fmt.Sprintf("%s/auth/%s/callback", strings.TrimRight(cfg.BaseURL, "/"), provider)
+}
+
+// fetchJSON makes an authenticated GET request and decodes the JSON response
+func fetchJSON(ctx context.Context, config *oauth2.Config, token *oauth2.Token, url string, v interface{}) error {
+	client := config.Client(ctx, token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID     = "client-id"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "https://tipjar.example/auth/test/callback"
+	testCode         = "auth-code"
+	testAccessToken  = "access-token"
+)
+
+// fakeProvider stands in for a provider's token and API endpoints. Requests
+// keep their original Host header, so handlers are registered under the real
+// provider hostnames, e.g. "oauth2.googleapis.com/token".
+type fakeProvider struct {
+	t      *testing.T
+	mux    *http.ServeMux
+	server *httptest.Server
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	f := &fakeProvider{t: t, mux: http.NewServeMux()}
+	f.server = httptest.NewServer(f.mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// context routes every request made by the oauth2 package to the fake server
+func (f *fakeProvider) context() context.Context {
+	target, _ := url.Parse(f.server.URL)
+	client := &http.Client{Transport: rewriteTransport{target: target}}
+	return context.WithValue(context.Background(), oauth2.HTTPClient, client)
+}
+
+// token answers a token request for testCode with testAccessToken
+func (f *fakeProvider) token(pattern string) {
+	f.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			f.t.Errorf("parse token request: %v", err)
+		}
+		if got := r.PostForm.Get("code"); got != testCode {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		if got := r.PostForm.Get("grant_type"); got != "authorization_code" {
+			f.t.Errorf("grant_type = %q", got)
+		}
+		if got := r.PostForm.Get("redirect_uri"); got != testRedirectURL {
+			f.t.Errorf("redirect_uri = %q", got)
+		}
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if id != testClientID || secret != testClientSecret {
+			f.t.Errorf("client credentials = %q/%q", id, secret)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": testAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+}
+
+// json serves body to requests carrying testAccessToken
+func (f *fakeProvider) json(pattern, body string) {
+	f.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer "+testAccessToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+}
+
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Host = req.URL.Host
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// checkAuthCodeURL checks the login redirect points at wantEndpoint and
+// carries the client, state and scopes
+func checkAuthCodeURL(t *testing.T, got, wantEndpoint string, wantScopes ...string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatalf("parse %q: %v", got, err)
+	}
+	if endpoint := u.Scheme + "://" + u.Host + u.Path; endpoint != wantEndpoint {
+		t.Errorf("endpoint = %s, want %s", endpoint, wantEndpoint)
+	}
+
+	q := u.Query()
+	want := map[string]string{
+		"client_id":     testClientID,
+		"redirect_uri":  testRedirectURL,
+		"response_type": "code",
+		"state":         "state-123",
+		"scope":         strings.Join(wantScopes, " "),
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), value)
+		}
+	}
+	return q
+}
+
+func checkIdentity(t *testing.T, got *Identity, err error, want Identity) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if *got != want {
+		t.Errorf("identity = %+v\nwant       %+v", *got, want)
+	}
+}
+
+func TestGoogleProvider(t *testing.T) {
+	p := NewGoogleProvider(testClientID, testClientSecret, testRedirectURL)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := checkAuthCodeURL(t, authURL, "https://accounts.google.com/o/oauth2/auth",
+		"https://www.googleapis.com/auth/userinfo.email",
+		"https://www.googleapis.com/auth/userinfo.profile")
+	if q.Get("access_type") != "offline" {
+		t.Errorf("access_type = %q, want offline", q.Get("access_type"))
+	}
+
+	tests := []struct {
+		name     string
+		userinfo string
+		want     Identity
+	}{
+		{
+			name:     "verified",
+			userinfo: `{"id":"1234","email":"ada@example.com","name":"Ada","picture":"https://example.com/a.png","verified_email":true}`,
+			want:     Identity{Provider: "google", Subject: "1234", Email: "ada@example.com", EmailVerified: true, Name: "Ada", AvatarURL: "https://example.com/a.png"},
+		},
+		{
+			name:     "unverified",
+			userinfo: `{"id":"1234","email":"ada@example.com","name":"Ada","verified_email":false}`,
+			want:     Identity{Provider: "google", Subject: "1234", Email: "ada@example.com", Name: "Ada"},
+		},
+		{
+			name:     "verification missing",
+			userinfo: `{"id":"1234","email":"ada@example.com","name":"Ada"}`,
+			want:     Identity{Provider: "google", Subject: "1234", Email: "ada@example.com", Name: "Ada"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProvider(t)
+			f.token("oauth2.googleapis.com/token")
+			f.json("www.googleapis.com/oauth2/v2/userinfo", tt.userinfo)
+
+			got, err := p.Exchange(f.context(), testCode)
+			checkIdentity(t, got, err, tt.want)
+		})
+	}
+}
+
+func TestGitHubProvider(t *testing.T) {
+	p := NewGitHubProvider(testClientID, testClientSecret, testRedirectURL)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAuthCodeURL(t, authURL, "https://github.com/login/oauth/authorize", "read:user", "user:email")
+
+	tests := []struct {
+		name   string
+		user   string
+		emails string
+		want   Identity
+	}{
+		{
+			name:   "verified primary",
+			user:   `{"id":42,"login":"ada","name":"Ada Lovelace","avatar_url":"https://example.com/a.png"}`,
+			emails: `[{"email":"old@example.com","primary":false,"verified":true},{"email":"ada@example.com","primary":true,"verified":true}]`,
+			want:   Identity{Provider: "github", Subject: "42", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace", AvatarURL: "https://example.com/a.png"},
+		},
+		{
+			name:   "unverified primary",
+			user:   `{"id":42,"login":"ada","name":"Ada Lovelace"}`,
+			emails: `[{"email":"ada@example.com","primary":true,"verified":false},{"email":"other@example.com","primary":false,"verified":true}]`,
+			want:   Identity{Provider: "github", Subject: "42", Email: "ada@example.com", Name: "Ada Lovelace"},
+		},
+		{
+			name:   "login as name without emails",
+			user:   `{"id":42,"login":"ada","name":""}`,
+			emails: `[]`,
+			want:   Identity{Provider: "github", Subject: "42", Name: "ada"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProvider(t)
+			f.token("github.com/login/oauth/access_token")
+			f.json("api.github.com/user", tt.user)
+			f.json("api.github.com/user/emails", tt.emails)
+
+			got, err := p.Exchange(f.context(), testCode)
+			checkIdentity(t, got, err, tt.want)
+		})
+	}
+
+	t.Run("emails unavailable", func(t *testing.T) {
+		f := newFakeProvider(t)
+		f.token("github.com/login/oauth/access_token")
+		f.json("api.github.com/user", `{"id":42,"login":"ada"}`)
+
+		if _, err := p.Exchange(f.context(), testCode); err == nil {
+			t.Error("expected an error when the emails request fails")
+		}
+	})
+}
+
+func TestDiscordProvider(t *testing.T) {
+	p := NewDiscordProvider(testClientID, testClientSecret, testRedirectURL)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAuthCodeURL(t, authURL, "https://discord.com/oauth2/authorize", "identify", "email")
+
+	tests := []struct {
+		name string
+		user string
+		want Identity
+	}{
+		{
+			name: "verified with avatar",
+			user: `{"id":"80","username":"ada","global_name":"Ada","avatar":"abc123","email":"ada@example.com","verified":true}`,
+			want: Identity{Provider: "discord", Subject: "80", Email: "ada@example.com", EmailVerified: true, Name: "Ada", AvatarURL: "https://cdn.discordapp.com/avatars/80/abc123.png"},
+		},
+		{
+			name: "unverified without display name",
+			user: `{"id":"80","username":"ada","global_name":null,"avatar":null,"email":"ada@example.com","verified":false}`,
+			want: Identity{Provider: "discord", Subject: "80", Email: "ada@example.com", Name: "ada"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProvider(t)
+			f.token("discord.com/api/oauth2/token")
+			f.json("discord.com/api/users/@me", tt.user)
+
+			got, err := p.Exchange(f.context(), testCode)
+			checkIdentity(t, got, err, tt.want)
+		})
+	}
+}
+
+// newFakeIssuer serves OIDC discovery, token and userinfo endpoints from one
+// server, the way most issuers do
+func newFakeIssuer(t *testing.T, userinfo string) *fakeProvider {
+	f := newFakeProvider(t)
+	f.mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			AuthorizationEndpoint: f.server.URL + "/authorize",
+			TokenEndpoint:         f.server.URL + "/token",
+			UserInfoEndpoint:      f.server.URL + "/userinfo",
+		})
+	})
+	f.token("/token")
+	f.json("/userinfo", userinfo)
+	return f
+}
+
+func TestOIDCProvider(t *testing.T) {
+	t.Run("auth code url", func(t *testing.T) {
+		f := newFakeIssuer(t, `{}`)
+		p := NewOIDCProvider(f.server.URL+"/", testClientID, testClientSecret, "", testRedirectURL)
+		if p.DisplayName() != "SSO" {
+			t.Errorf("display name = %q, want SSO", p.DisplayName())
+		}
+
+		authURL, err := p.AuthCodeURL(context.Background(), "state-123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkAuthCodeURL(t, authURL, f.server.URL+"/authorize", "openid", "email", "profile")
+	})
+
+	tests := []struct {
+		name     string
+		userinfo string
+		want     Identity
+	}{
+		{
+			name:     "verified",
+			userinfo: `{"sub":"u-1","email":"ada@example.com","email_verified":true,"name":"Ada","picture":"https://example.com/a.png"}`,
+			want:     Identity{Provider: "oidc", Subject: "u-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada", AvatarURL: "https://example.com/a.png"},
+		},
+		{
+			name:     "unverified",
+			userinfo: `{"sub":"u-1","email":"ada@example.com","email_verified":false,"name":"Ada"}`,
+			want:     Identity{Provider: "oidc", Subject: "u-1", Email: "ada@example.com", Name: "Ada"},
+		},
+		{
+			name:     "preferred username as name",
+			userinfo: `{"sub":"u-1","email":"ada@example.com","preferred_username":"ada"}`,
+			want:     Identity{Provider: "oidc", Subject: "u-1", Email: "ada@example.com", Name: "ada"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t, tt.userinfo)
+			p := NewOIDCProvider(f.server.URL, testClientID, testClientSecret, "Company SSO", testRedirectURL)
+
+			got, err := p.Exchange(context.Background(), testCode)
+			checkIdentity(t, got, err, tt.want)
+		})
+	}
+
+	t.Run("missing sub", func(t *testing.T) {
+		f := newFakeIssuer(t, `{"email":"ada@example.com","email_verified":true}`)
+		p := NewOIDCProvider(f.server.URL, testClientID, testClientSecret, "", testRedirectURL)
+
+		if _, err := p.Exchange(context.Background(), testCode); err == nil {
+			t.Error("expected an error for a userinfo response without sub")
+		}
+	})
+
+	t.Run("bad code", func(t *testing.T) {
+		f := newFakeIssuer(t, `{"sub":"u-1"}`)
+		p := NewOIDCProvider(f.server.URL, testClientID, testClientSecret, "", testRedirectURL)
+
+		if _, err := p.Exchange(context.Background(), "wrong-code"); err == nil {
+			t.Error("expected an error for a rejected code")
+		}
+	})
+
+	t.Run("incomplete discovery", func(t *testing.T) {
+		f := newFakeProvider(t)
+		f.mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"authorization_endpoint":"https://issuer.example/authorize"}`))
+		})
+		p := NewOIDCProvider(f.server.URL, testClientID, testClientSecret, "", testRedirectURL)
+
+		if _, err := p.AuthCodeURL(context.Background(), "state-123"); err == nil {
+			t.Error("expected an error for a discovery document without endpoints")
+		}
+	})
+
+	t.Run("no issuer", func(t *testing.T) {
+		f := newFakeProvider(t)
+		p := NewOIDCProvider(f.server.URL, testClientID, testClientSecret, "", testRedirectURL)
+
+		if _, err := p.AuthCodeURL(context.Background(), "state-123"); err == nil {
+			t.Error("expected an error when discovery returns 404")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+const discordUserURL = "https://discord.com/api/users/@me"
+
+var discordEndpoint = oauth2.Endpoint{
+	AuthURL:  "https://discord.com/oauth2/authorize",
+	TokenURL: "https://discord.com/api/oauth2/token",
+}
+
+type DiscordProvider struct {
+	config *oauth2.Config
+}
+
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Avatar     string `json:"avatar"`
+	Email      string `json:"email"`
+	Verified   bool   `json:"verified"`
+}
+
+func NewDiscordProvider(clientID, clientSecret, redirectURL string) *DiscordProvider {
+	return &DiscordProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"identify", "email"},
+			Endpoint:     discordEndpoint,
+		},
+	}
+}
+
+func (p *DiscordProvider) Name() string        { return "discord" }
+func (p *DiscordProvider) DisplayName() string { return "Discord" }
+
+func (p *DiscordProvider) AuthCodeURL(ctx context.Context, state string) (string, error) {
+	return p.config.AuthCodeURL(state), nil
+}
+
+func (p *DiscordProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user discordUser
+	if err := fetchJSON(ctx, p.config, token, discordUserURL, &user); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:      p.Name(),
+		Subject:       user.ID,
+		Email:         user.Email,
+		EmailVerified: user.Verified,
+		Name:          user.GlobalName,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Username
+	}
+	if user.Avatar != "" {
+		identity.AvatarURL = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", user.ID, user.Avatar)
+	}
+
+	return identity, nil
+}
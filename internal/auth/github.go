@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const (
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+type GitHubProvider struct {
+	config *oauth2.Config
+}
+
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) *GitHubProvider {
+	return &GitHubProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+	}
+}
+
+func (p *GitHubProvider) Name() string        { return "github" }
+func (p *GitHubProvider) DisplayName() string { return "GitHub" }
+
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, state string) (string, error) {
+	return p.config.AuthCodeURL(state), nil
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user githubUser
+	if err := fetchJSON(ctx, p.config, token, githubUserURL, &user); err != nil {
+		return nil, err
+	}
+
+	// The profile email may be hidden, so use the primary address instead
+	var emails []githubEmail
+	if err := fetchJSON(ctx, p.config, token, githubEmailsURL, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:  p.Name(),
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const googleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
+
+type GoogleProvider struct {
+	config *oauth2.Config
+}
+
+type googleUser struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	VerifiedEmail bool   `json:"verified_email"`
+}
+
+func NewGoogleProvider(clientID, clientSecret, redirectURL string) *GoogleProvider {
+	return &GoogleProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes: []string{
+				"https://www.googleapis.com/auth/userinfo.email",
+				"https://www.googleapis.com/auth/userinfo.profile",
+			},
+			Endpoint: google.Endpoint,
+		},
+	}
+}
+
+func (p *GoogleProvider) Name() string        { return "google" }
+func (p *GoogleProvider) DisplayName() string { return "Google" }
+
+func (p *GoogleProvider) AuthCodeURL(ctx context.Context, state string) (string, error) {
+	return p.config.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+}
+
+func (p *GoogleProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user googleUser
+	if err := fetchJSON(ctx, p.config, token, googleUserInfoURL, &user); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:      p.Name(),
+		Subject:       user.ID,
+		Email:         user.Email,
+		EmailVerified: user.VerifiedEmail,
+		Name:          user.Name,
+		AvatarURL:     user.Picture,
+	}, nil
+}
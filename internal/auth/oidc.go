@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider logs in against any OpenID Connect issuer. Endpoints are
+// discovered from the issuer's well-known configuration on first use.
+type OIDCProvider struct {
+	issuerURL   string
+	displayName string
+	config      *oauth2.Config
+
+	mu          sync.Mutex
+	discovered  bool
+	userInfoURL string
+}
+
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcUserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Username      string `json:"preferred_username"`
+	Picture       string `json:"picture"`
+}
+
+func NewOIDCProvider(issuerURL, clientID, clientSecret, displayName, redirectURL string) *OIDCProvider {
+	if displayName == "" {
+		displayName = "SSO"
+	}
+
+	return &OIDCProvider{
+		issuerURL:   strings.TrimRight(issuerURL, "/"),
+		displayName: displayName,
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+	}
+}
+
+func (p *OIDCProvider) Name() string        { return "oidc" }
+func (p *OIDCProvider) DisplayName() string { return p.displayName }
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+	return p.config.AuthCodeURL(state), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var info oidcUserInfo
+	if err := fetchJSON(ctx, p.config, token, p.userInfoURL, &info); err != nil {
+		return nil, err
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("userinfo response is missing sub")
+	}
+
+	identity := &Identity{
+		Provider:      p.Name(),
+		Subject:       info.Subject,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+		AvatarURL:     info.Picture,
+	}
+	if identity.Name == "" {
+		identity.Name = info.Username
+	}
+
+	return identity, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.issuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to discover OIDC configuration: %s", resp.Status)
+	}
+
+	var doc oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserInfoEndpoint == "" {
+		return fmt.Errorf("OIDC configuration is missing required endpoints")
+	}
+
+	p.config.Endpoint = oauth2.Endpoint{
+		AuthURL:  doc.AuthorizationEndpoint,
+		TokenURL: doc.TokenEndpoint,
+	}
+	p.userInfoURL = doc.UserInfoEndpoint
+	p.discovered = true
+
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package authz decides what each role in a jar is allowed to do. Handlers
+// and services ask it rather than checking role names themselves.
+package authz
+
+// Permission is something a member may be allowed to do in a jar
+type Permission string
+
+const (
+	ViewJar           Permission = "view_jar"           // See the jar, its members, balances and history
+	ReportOffense     Permission = "report_offense"     // Report offenses, and pay and dispute your own
+	VoteOnDisputes    Permission = "vote_on_disputes"   // Open disputes and vote on them
+	VerifyPayments    Permission = "verify_payments"    // Approve or reject other people's payments
+	OverrulePayments  Permission = "overrule_payments"  // A verdict on a payment is final
+	PayOnBehalf       Permission = "pay_on_behalf"      // Record payments for someone else's offenses
+	EditOffenseTypes  Permission = "edit_offense_types" // Create, edit and deactivate offense types
+	ManageJar         Permission = "manage_jar"         // Change settings, webhooks and chat channels
+	ManageMembers     Permission = "manage_members"     // Change roles and remove members
+	ArchiveJar        Permission = "archive_jar"        // Archive the jar and restore it
+	DeleteJar         Permission = "delete_jar"
+	TransferOwnership Permission = "transfer_ownership"
+)
+
+const (
+	Owner     = "owner"
+	Admin     = "admin"
+	Moderator = "moderator"
+	Member    = "member"
+	Viewer    = "viewer"
+)
+
+// Role is a role members can hold, with what it's for
+type Role struct {
+	Name        string
+	Description string
+}
+
+// Roles lists every role, most powerful first. Each jar has exactly one
+// owner, who can only be replaced by transferring ownership.
+var Roles = []Role{
+	{Owner, "Everything, including deleting the jar"},
+	{Admin, "Manages members and settings"},
+	{Moderator, "Edits offense types and settles payments"},
+	{Member, "Reports offenses and verifies payments"},
+	{Viewer, "Can look but not take part"},
+}
+
+var permissions = map[string][]Permission{
+	Owner: {
+		ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+		EditOffenseTypes, ManageJar, ManageMembers, ArchiveJar, DeleteJar, TransferOwnership,
+	},
+	Admin: {
+		ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+		EditOffenseTypes, ManageJar, ManageMembers, ArchiveJar,
+	},
+	Moderator: {
+		ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+		EditOffenseTypes,
+	},
+	Member: {
+		ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments,
+	},
+	Viewer: {
+		ViewJar,
+	},
+}
+
+// Can reports whether a role holds a permission. Unknown roles, including
+// "" for people who aren't members, hold none.
+func Can(role string, permission Permission) bool {
+	for _, p := range permissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// archivedPermissions are all that's left of a role's permissions once the
+// jar is archived, which makes it read-only until it's restored
+var archivedPermissions = []Permission{ViewJar, ArchiveJar, DeleteJar}
+
+// AllowedWhenArchived reports whether a permission still applies in an
+// archived jar. Check it as well as Can before changing an archived jar.
+func AllowedWhenArchived(permission Permission) bool {
+	for _, p := range archivedPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRole reports whether role is one of Roles
+func IsRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
+
+// CanAssign reports whether someone with actorRole may change a member from
+// one role to another. Nobody can make or unmake the owner this way;
+// ownership only changes hands by transfer.
+func CanAssign(actorRole, from, to string) bool {
+	if !Can(actorRole, ManageMembers) || !IsRole(to) {
+		return false
+	}
+	return from != Owner && to != Owner
+}
+
+// CanRemove reports whether someone with actorRole may remove a member with
+// memberRole. The owner can't be removed; they have to hand the jar over
+// first.
+func CanRemove(actorRole, memberRole string) bool {
+	return Can(actorRole, ManageMembers) && memberRole != Owner
+}
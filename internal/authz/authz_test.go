@@ -0,0 +1,132 @@
+package authz
+
+import "testing"
+
+var allPermissions = []Permission{
+	ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+	EditOffenseTypes, ManageJar, ManageMembers, ArchiveJar, DeleteJar, TransferOwnership,
+}
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role string
+		want []Permission
+	}{
+		{Owner, allPermissions},
+		{Admin, []Permission{
+			ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+			EditOffenseTypes, ManageJar, ManageMembers, ArchiveJar,
+		}},
+		{Moderator, []Permission{
+			ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments, OverrulePayments, PayOnBehalf,
+			EditOffenseTypes,
+		}},
+		{Member, []Permission{ViewJar, ReportOffense, VoteOnDisputes, VerifyPayments}},
+		{Viewer, []Permission{ViewJar}},
+		{"", nil},
+		{"superuser", nil},
+		{"Owner", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			want := make(map[Permission]bool)
+			for _, p := range tt.want {
+				want[p] = true
+			}
+			for _, p := range allPermissions {
+				if got := Can(tt.role, p); got != want[p] {
+					t.Errorf("Can(%q, %s) = %v, want %v", tt.role, p, got, want[p])
+				}
+			}
+		})
+	}
+}
+
+func TestRolesAreOrderedByPower(t *testing.T) {
+	if len(Roles) != 5 {
+		t.Fatalf("got %d roles, want 5", len(Roles))
+	}
+
+	// Each role can do everything the one below it can
+	for i := 1; i < len(Roles); i++ {
+		stronger, weaker := Roles[i-1].Name, Roles[i].Name
+		if !IsRole(weaker) {
+			t.Errorf("%s isn't a role", weaker)
+		}
+		for _, p := range allPermissions {
+			if Can(weaker, p) && !Can(stronger, p) {
+				t.Errorf("%s can %s but %s can't", weaker, p, stronger)
+			}
+		}
+	}
+}
+
+func TestCanAssign(t *testing.T) {
+	roles := []string{Owner, Admin, Moderator, Member, Viewer}
+
+	for _, actor := range roles {
+		for _, from := range roles {
+			for _, to := range roles {
+				want := (actor == Owner || actor == Admin) && from != Owner && to != Owner
+				if got := CanAssign(actor, from, to); got != want {
+					t.Errorf("CanAssign(%s, %s, %s) = %v, want %v", actor, from, to, got, want)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		actor, from, to string
+	}{
+		{Owner, Member, ""},
+		{Owner, Member, "superuser"},
+		{"", Member, Viewer},
+		{"superuser", Member, Viewer},
+	}
+	for _, tt := range tests {
+		if CanAssign(tt.actor, tt.from, tt.to) {
+			t.Errorf("CanAssign(%q, %q, %q) = true, want false", tt.actor, tt.from, tt.to)
+		}
+	}
+}
+
+func TestCanRemove(t *testing.T) {
+	roles := []string{Owner, Admin, Moderator, Member, Viewer}
+
+	for _, actor := range roles {
+		for _, member := range roles {
+			want := (actor == Owner || actor == Admin) && member != Owner
+			if got := CanRemove(actor, member); got != want {
+				t.Errorf("CanRemove(%s, %s) = %v, want %v", actor, member, got, want)
+			}
+		}
+	}
+
+	if CanRemove("", Member) {
+		t.Error("non-members can remove members")
+	}
+}
+
+func TestAllowedWhenArchived(t *testing.T) {
+	allowed := map[Permission]bool{ViewJar: true, ArchiveJar: true, DeleteJar: true}
+
+	for _, p := range allPermissions {
+		if got := AllowedWhenArchived(p); got != allowed[p] {
+			t.Errorf("AllowedWhenArchived(%s) = %v, want %v", p, got, allowed[p])
+		}
+	}
+}
+
+func TestIsRole(t *testing.T) {
+	for _, role := range Roles {
+		if !IsRole(role.Name) {
+			t.Errorf("IsRole(%q) = false", role.Name)
+		}
+	}
+	for _, role := range []string{"", "superuser", "OWNER", " member"} {
+		if IsRole(role) {
+			t.Errorf("IsRole(%q) = true", role)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Package dbtest connects tests to a scratch Postgres database. Tests that use
+// it are skipped unless TEST_DATABASE_URL points at a database they may write
+// to; the migrations are applied on first use.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+)
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+	userSeq     atomic.Int64
+)
+
+// Open returns a connection to the test database, skipping tb when none is
+// configured. The connection is closed when the test finishes.
+func Open(tb testing.TB) *database.DB {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL not set")
+	}
+
+	migrateOnce.Do(func() {
+		migrateErr = database.RunMigrations(url)
+	})
+	if migrateErr != nil {
+		tb.Fatalf("migrate test database: %v", migrateErr)
+	}
+
+	db, err := database.New(url)
+	if err != nil {
+		tb.Fatalf("connect to test database: %v", err)
+	}
+	tb.Cleanup(db.Close)
+	return db
+}
+
+// User creates a user with a unique email address. Tests share the database,
+// so they should only look at rows they created themselves.
+func User(tb testing.TB, db *database.DB, name string) sqlc.User {
+	tb.Helper()
+
+	email := fmt.Sprintf("%s-%d-%d@example.test", name, time.Now().UnixNano(), userSeq.Add(1))
+	user, err := db.CreateUser(context.Background(), sqlc.CreateUserParams{
+		Email: email,
+		Name:  name,
+	})
+	if err != nil {
+		tb.Fatalf("create user %s: %v", name, err)
+	}
+	return user
+}
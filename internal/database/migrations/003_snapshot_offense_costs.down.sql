@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS offense_type_prices;
+
+ALTER TABLE offenses
+  DROP COLUMN cost_amount,
+  DROP COLUMN cost_unit;
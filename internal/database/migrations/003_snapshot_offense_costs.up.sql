@@ -0,0 +1,28 @@
+-- Freeze each offense's cost at report time so editing an offense type
+-- doesn't change what existing offenses are worth
+ALTER TABLE offenses
+  ADD COLUMN cost_amount DECIMAL(10,2),
+  ADD COLUMN cost_unit VARCHAR(100);
+
+UPDATE offenses o
+SET cost_amount = COALESCE(o.cost_override, ot.cost_amount),
+    cost_unit = ot.cost_unit
+FROM offense_types ot
+WHERE o.offense_type_id = ot.id;
+
+-- Offense type price history
+CREATE TABLE offense_type_prices (
+    id SERIAL PRIMARY KEY,
+    offense_type_id INTEGER NOT NULL REFERENCES offense_types(id) ON DELETE CASCADE,
+    cost_amount DECIMAL(10,2),
+    cost_unit VARCHAR(100),
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    effective_from TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_offense_type_prices_offense_type_id ON offense_type_prices(offense_type_id);
+
+-- Seed the history with each type's current price; earlier changes weren't recorded
+INSERT INTO offense_type_prices (offense_type_id, cost_amount, cost_unit, effective_from)
+SELECT id, cost_amount, cost_unit, created_at
+FROM offense_types;
@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN google_id VARCHAR(255) UNIQUE;
+
+UPDATE users u
+SET google_id = ui.subject
+FROM user_identities ui
+WHERE ui.user_id = u.id AND ui.provider = 'google';
+
+CREATE INDEX idx_users_google_id ON users(google_id);
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,10 @@
+-- Offenses left mid-dispute go back to pending
+UPDATE offenses SET status = 'pending' WHERE status = 'disputed';
+
+DROP TABLE IF EXISTS jar_events;
+DROP TABLE IF EXISTS dispute_votes;
+DROP TABLE IF EXISTS offense_disputes;
+
+ALTER TABLE tip_jars
+  DROP COLUMN dispute_quorum,
+  DROP COLUMN dispute_window_hours;
@@ -0,0 +1,46 @@
+-- Per-jar rules for closing disputes
+ALTER TABLE tip_jars
+  ADD COLUMN dispute_quorum INTEGER NOT NULL DEFAULT 3 CHECK (dispute_quorum > 0),
+  ADD COLUMN dispute_window_hours INTEGER NOT NULL DEFAULT 72 CHECK (dispute_window_hours > 0);
+
+-- An offender's challenge to a pending offense
+CREATE TABLE offense_disputes (
+    id SERIAL PRIMARY KEY,
+    offense_id INTEGER UNIQUE NOT NULL REFERENCES offenses(id) ON DELETE CASCADE,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    opened_by INTEGER NOT NULL REFERENCES users(id),
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'upheld', 'reduced', 'dismissed')),
+    resolved_amount DECIMAL(10,2),
+    deadline TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP
+);
+
+-- One vote per member per dispute; members may change their vote while it is open
+CREATE TABLE dispute_votes (
+    id SERIAL PRIMARY KEY,
+    dispute_id INTEGER NOT NULL REFERENCES offense_disputes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    vote VARCHAR(20) NOT NULL CHECK (vote IN ('uphold', 'reduce', 'dismiss')),
+    reduced_amount DECIMAL(10,2),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(dispute_id, user_id),
+    CHECK (vote <> 'reduce' OR reduced_amount IS NOT NULL)
+);
+
+-- Jar history: things that happened in a jar besides offenses being reported
+CREATE TABLE jar_events (
+    id SERIAL PRIMARY KEY,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    actor_id INTEGER REFERENCES users(id),
+    offense_id INTEGER REFERENCES offenses(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_offense_disputes_jar_id ON offense_disputes(jar_id);
+CREATE INDEX idx_offense_disputes_open_deadline ON offense_disputes(deadline) WHERE status = 'open';
+CREATE INDEX idx_dispute_votes_dispute_id ON dispute_votes(dispute_id);
+CREATE INDEX idx_jar_events_jar_id ON jar_events(jar_id, created_at);
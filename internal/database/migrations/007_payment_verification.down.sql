@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS payment_verifications;
+
+ALTER TABLE payments
+  DROP COLUMN rejected;
+
+-- Payments still being verified are treated as accepted
+UPDATE offenses SET status = 'paid' WHERE status = 'verifying';
+ALTER TABLE offenses DROP CONSTRAINT offenses_status_check;
+ALTER TABLE offenses ADD CONSTRAINT offenses_status_check
+  CHECK (status IN ('pending', 'paid', 'disputed', 'forgiven'));
+
+ALTER TABLE tip_jars
+  DROP COLUMN verification_required,
+  DROP COLUMN verifications_needed;
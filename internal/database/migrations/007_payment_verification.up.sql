@@ -0,0 +1,24 @@
+-- Jars can require other members to confirm a payment before it counts
+ALTER TABLE tip_jars
+  ADD COLUMN verification_required BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN verifications_needed INTEGER NOT NULL DEFAULT 1 CHECK (verifications_needed > 0);
+
+-- Offenses whose payment is waiting on witnesses
+ALTER TABLE offenses DROP CONSTRAINT offenses_status_check;
+ALTER TABLE offenses ADD CONSTRAINT offenses_status_check
+  CHECK (status IN ('pending', 'paid', 'disputed', 'forgiven', 'verifying'));
+
+ALTER TABLE payments
+  ADD COLUMN rejected BOOLEAN NOT NULL DEFAULT false;
+
+-- Each member's verdict on a payment
+CREATE TABLE payment_verifications (
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    approved BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(payment_id, user_id)
+);
+
+CREATE INDEX idx_payment_verifications_payment_id ON payment_verifications(payment_id);
@@ -0,0 +1,9 @@
+-- Batch payments keep only their first offense
+UPDATE payments p
+SET offense_id = (SELECT MIN(pa.offense_id) FROM payment_allocations pa WHERE pa.payment_id = p.id)
+WHERE p.offense_id IS NULL;
+
+DELETE FROM payments WHERE offense_id IS NULL;
+ALTER TABLE payments ALTER COLUMN offense_id SET NOT NULL;
+
+DROP TABLE IF EXISTS payment_allocations;
@@ -0,0 +1,21 @@
+-- A single payment can settle several offenses. Allocations record which
+-- offenses a payment covered and how much of it went to each one.
+CREATE TABLE payment_allocations (
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    offense_id INTEGER NOT NULL REFERENCES offenses(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(payment_id, offense_id)
+);
+
+CREATE INDEX idx_payment_allocations_payment_id ON payment_allocations(payment_id);
+CREATE INDEX idx_payment_allocations_offense_id ON payment_allocations(offense_id);
+
+INSERT INTO payment_allocations (payment_id, offense_id, amount, created_at)
+SELECT id, offense_id, amount, created_at
+FROM payments;
+
+-- payments.offense_id and amount are only filled in for payments covering a
+-- single offense; allocations are the source of truth
+ALTER TABLE payments ALTER COLUMN offense_id DROP NOT NULL;
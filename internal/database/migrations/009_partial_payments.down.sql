@@ -0,0 +1 @@
+DROP VIEW IF EXISTS offense_payment_totals;
@@ -0,0 +1,8 @@
+-- How much has been paid towards each offense, ignoring rejected payments.
+-- An offense's remaining balance is its cost_amount minus paid_amount.
+CREATE VIEW offense_payment_totals AS
+SELECT pa.offense_id, SUM(pa.amount) AS paid_amount
+FROM payment_allocations pa
+INNER JOIN payments p ON pa.payment_id = p.id
+WHERE NOT p.rejected
+GROUP BY pa.offense_id;
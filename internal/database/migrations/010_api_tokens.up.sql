@@ -0,0 +1,14 @@
+-- Personal access tokens for scripts and bots using the API
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(12) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('read', 'report', 'full')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
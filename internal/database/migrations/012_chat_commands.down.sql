@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS chat_channels;
+DROP TABLE IF EXISTS chat_link_requests;
+DROP TABLE IF EXISTS chat_accounts;
@@ -0,0 +1,39 @@
+-- Chat users (Slack, Discord) linked to tip jar users for slash commands.
+-- Slack user IDs are only unique within a workspace; Discord's are global so
+-- workspace_id is left empty for them.
+CREATE TABLE chat_accounts (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL CHECK (provider IN ('slack', 'discord')),
+    workspace_id VARCHAR(64) NOT NULL DEFAULT '',
+    external_user_id VARCHAR(64) NOT NULL,
+    external_name VARCHAR(255) NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(provider, workspace_id, external_user_id)
+);
+
+CREATE INDEX idx_chat_accounts_user_id ON chat_accounts(user_id);
+
+-- One-time links handed out by "/tipjar link", confirmed on the website
+CREATE TABLE chat_link_requests (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL CHECK (provider IN ('slack', 'discord')),
+    workspace_id VARCHAR(64) NOT NULL DEFAULT '',
+    external_user_id VARCHAR(64) NOT NULL,
+    external_name VARCHAR(255) NOT NULL DEFAULT '',
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- The jar each chat channel reports offenses to
+CREATE TABLE chat_channels (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL CHECK (provider IN ('slack', 'discord')),
+    workspace_id VARCHAR(64) NOT NULL DEFAULT '',
+    channel_id VARCHAR(64) NOT NULL,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    connected_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(provider, workspace_id, channel_id)
+);
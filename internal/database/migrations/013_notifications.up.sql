@@ -0,0 +1,24 @@
+-- In-app notifications, e.g. when someone reports an offense against you
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    link VARCHAR(255) NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Kinds of notification a user has switched on or off for a jar. Anything
+-- without a row is on.
+CREATE TABLE notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, jar_id, event_type)
+);
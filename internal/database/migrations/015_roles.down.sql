@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_jar_memberships_owner;
+
+ALTER TABLE jar_memberships DROP CONSTRAINT jar_memberships_role_check;
+
+UPDATE jar_memberships SET role = 'admin' WHERE role = 'owner';
+UPDATE jar_memberships SET role = 'member' WHERE role IN ('moderator', 'viewer');
+
+ALTER TABLE jar_memberships ADD CONSTRAINT jar_memberships_role_check
+    CHECK (role IN ('admin', 'member'));
@@ -0,0 +1,19 @@
+-- Widen roles from admin/member to owner, admin, moderator, member and viewer
+ALTER TABLE jar_memberships DROP CONSTRAINT jar_memberships_role_check;
+ALTER TABLE jar_memberships ADD CONSTRAINT jar_memberships_role_check
+    CHECK (role IN ('owner', 'admin', 'moderator', 'member', 'viewer'));
+
+-- Each jar's owner is its creator if they're still an admin, otherwise its
+-- longest-standing admin
+UPDATE jar_memberships jm
+SET role = 'owner'
+FROM (
+    SELECT DISTINCT ON (m.jar_id) m.id
+    FROM jar_memberships m
+    JOIN tip_jars t ON t.id = m.jar_id
+    WHERE m.role = 'admin'
+    ORDER BY m.jar_id, (m.user_id = t.created_by) DESC, m.joined_at, m.id
+) owners
+WHERE jm.id = owners.id;
+
+CREATE UNIQUE INDEX idx_jar_memberships_owner ON jar_memberships(jar_id) WHERE role = 'owner';
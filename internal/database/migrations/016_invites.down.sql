@@ -0,0 +1,18 @@
+ALTER TABLE tip_jars ADD COLUMN invite_code VARCHAR(50);
+
+-- Keep each jar's oldest invite that still works, or make one up
+UPDATE tip_jars tj
+SET invite_code = COALESCE(
+    (SELECT i.code FROM invites i
+     WHERE i.jar_id = tj.id AND i.revoked_at IS NULL
+     ORDER BY i.created_at, i.id
+     LIMIT 1),
+    UPPER(SUBSTRING(MD5(tj.id::text || RANDOM()::text) FROM 1 FOR 8))
+);
+
+ALTER TABLE tip_jars ALTER COLUMN invite_code SET NOT NULL;
+ALTER TABLE tip_jars ADD CONSTRAINT tip_jars_invite_code_key UNIQUE (invite_code);
+CREATE INDEX idx_tip_jars_invite_code ON tip_jars(invite_code);
+
+DROP TABLE IF EXISTS invite_redemptions;
+DROP TABLE IF EXISTS invites;
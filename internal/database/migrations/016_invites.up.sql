@@ -0,0 +1,34 @@
+-- A jar can have several invites, each optionally limited in time, uses and
+-- the role it grants. Revoked invites are kept so joins stay attributed.
+CREATE TABLE invites (
+    id SERIAL PRIMARY KEY,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    label VARCHAR(100),
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'moderator', 'member', 'viewer')),
+    max_uses INTEGER CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Who joined with which invite
+CREATE TABLE invite_redemptions (
+    id SERIAL PRIMARY KEY,
+    invite_id INTEGER NOT NULL REFERENCES invites(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_invites_jar_id ON invites(jar_id);
+CREATE INDEX idx_invite_redemptions_invite_id ON invite_redemptions(invite_id);
+
+-- Each jar's permanent code becomes its first invite
+INSERT INTO invites (jar_id, code, label, role, created_by, created_at)
+SELECT id, invite_code, 'Original invite', 'member', created_by, created_at
+FROM tip_jars;
+
+DROP INDEX IF EXISTS idx_tip_jars_invite_code;
+ALTER TABLE tip_jars DROP COLUMN invite_code;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS join_requests;
+ALTER TABLE tip_jars DROP COLUMN IF EXISTS join_approval_required;
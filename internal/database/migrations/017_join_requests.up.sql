@@ -0,0 +1,20 @@
+-- Jars can ask admins to approve people before they join
+ALTER TABLE tip_jars ADD COLUMN join_approval_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Someone who used an invite to a jar that needs approval. The invite's use is
+-- taken when they ask, so a request can't outlive the invite's limits.
+CREATE TABLE join_requests (
+    id SERIAL PRIMARY KEY,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invite_id INTEGER NOT NULL REFERENCES invites(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    message TEXT,
+    reviewed_by INTEGER REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One open request per person per jar
+CREATE UNIQUE INDEX idx_join_requests_pending ON join_requests(jar_id, user_id) WHERE status = 'pending';
+CREATE INDEX idx_join_requests_user_id ON join_requests(user_id);
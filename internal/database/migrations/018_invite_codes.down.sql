@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_invites_legacy_code;
+
+UPDATE invites SET code = legacy_code WHERE legacy_code IS NOT NULL;
+
+ALTER TABLE invites DROP COLUMN legacy_code;
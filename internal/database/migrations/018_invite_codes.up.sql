@@ -0,0 +1,40 @@
+-- Invite codes move to Crockford's base 32 with a check symbol (see
+-- internal/invitecode). Every existing invite gets a new code; the old one is
+-- kept in legacy_code so links and codes already handed out keep working.
+ALTER TABLE invites ADD COLUMN legacy_code VARCHAR(50);
+
+CREATE FUNCTION pg_temp.generate_invite_code() RETURNS TEXT AS $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    code TEXT := '';
+    total INTEGER := 0;
+    symbol INTEGER;
+BEGIN
+    FOR i IN 0..6 LOOP
+        symbol := floor(random() * 32)::INTEGER;
+        code := code || substr(alphabet, symbol + 1, 1);
+        total := total + (2 * i + 1) * symbol;
+    END LOOP;
+    RETURN code || substr(alphabet, total % 32 + 1, 1);
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    invite RECORD;
+    new_code TEXT;
+BEGIN
+    FOR invite IN SELECT id FROM invites ORDER BY id LOOP
+        LOOP
+            new_code := pg_temp.generate_invite_code();
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM invites WHERE code = new_code);
+        END LOOP;
+
+        UPDATE invites SET legacy_code = code, code = new_code WHERE id = invite.id;
+    END LOOP;
+END;
+$$;
+
+DROP FUNCTION pg_temp.generate_invite_code();
+
+CREATE INDEX idx_invites_legacy_code ON invites(legacy_code);
@@ -0,0 +1 @@
+ALTER TABLE tip_jars DROP COLUMN IF EXISTS archived_at;
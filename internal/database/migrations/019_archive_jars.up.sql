@@ -0,0 +1,3 @@
+-- Archived jars are read-only and listed apart on the dashboard until an
+-- admin restores them
+ALTER TABLE tip_jars ADD COLUMN archived_at TIMESTAMP;
@@ -0,0 +1,4 @@
+ALTER TABLE offenses DROP COLUMN IF EXISTS reported_period_id;
+ALTER TABLE offenses DROP COLUMN IF EXISTS period_id;
+DROP TABLE IF EXISTS jar_period_balances;
+DROP TABLE IF EXISTS jar_periods;
@@ -0,0 +1,49 @@
+-- Jars run in named periods, e.g. a quarter. Closing one records each
+-- member's final balances and locks its offenses; the next one starts empty
+-- or with the unpaid offenses carried over.
+CREATE TABLE jar_periods (
+    id SERIAL PRIMARY KEY,
+    jar_id INTEGER NOT NULL REFERENCES tip_jars(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMP,
+    closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    carried_over BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Every jar has exactly one open period
+CREATE UNIQUE INDEX idx_jar_periods_open ON jar_periods(jar_id) WHERE closed_at IS NULL;
+CREATE INDEX idx_jar_periods_jar_id ON jar_periods(jar_id);
+
+-- A closed period's final standings, per member and unit
+CREATE TABLE jar_period_balances (
+    period_id INTEGER NOT NULL REFERENCES jar_periods(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    unit VARCHAR(100) NOT NULL,
+    offense_count INTEGER NOT NULL,
+    total_amount DECIMAL(10,2) NOT NULL,
+    paid_amount DECIMAL(10,2) NOT NULL,
+    unpaid_amount DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (period_id, user_id, unit)
+);
+
+INSERT INTO jar_periods (jar_id, name, started_at)
+SELECT id, 'First period', created_at FROM tip_jars;
+
+-- period_id is the period an offense counts towards, which changes when it's
+-- carried over; reported_period_id is where it was reported and never changes
+ALTER TABLE offenses
+    ADD COLUMN period_id INTEGER REFERENCES jar_periods(id) ON DELETE CASCADE,
+    ADD COLUMN reported_period_id INTEGER REFERENCES jar_periods(id) ON DELETE CASCADE;
+
+UPDATE offenses o
+SET period_id = p.id, reported_period_id = p.id
+FROM jar_periods p
+WHERE p.jar_id = o.jar_id;
+
+ALTER TABLE offenses
+    ALTER COLUMN period_id SET NOT NULL,
+    ALTER COLUMN reported_period_id SET NOT NULL;
+
+CREATE INDEX idx_offenses_period_id ON offenses(period_id);
+CREATE INDEX idx_offenses_reported_period_id ON offenses(reported_period_id);
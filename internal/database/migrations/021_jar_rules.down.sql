@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_offenses_jar_offender_created;
+DROP TABLE IF EXISTS jar_rules;
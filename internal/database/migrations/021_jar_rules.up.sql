@@ -0,0 +1,19 @@
+-- Limits a jar puts on reporting offenses. A jar without a row, or with a
+-- column left NULL, doesn't apply that rule.
+CREATE TABLE jar_rules (
+    jar_id INTEGER PRIMARY KEY REFERENCES tip_jars(id) ON DELETE CASCADE,
+    -- Offenses one member can be reported for in any 24 hours
+    max_offenses_per_day INTEGER CHECK (max_offenses_per_day > 0),
+    -- How long before the same reporter can report the same member again
+    report_cooldown_minutes INTEGER CHECK (report_cooldown_minutes > 0),
+    -- Each earlier offense of the same type against the same member within the
+    -- window multiplies the cost once more
+    escalation_window_days INTEGER CHECK (escalation_window_days > 0),
+    escalation_multiplier DECIMAL(4,2) CHECK (escalation_multiplier > 1),
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((escalation_window_days IS NULL) = (escalation_multiplier IS NULL))
+);
+
+-- Rule checks look back over one member's recent offenses
+CREATE INDEX idx_offenses_jar_offender_created ON offenses(jar_id, offender_id, created_at);
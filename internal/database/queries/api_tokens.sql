@@ -0,0 +1,25 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scope)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, token_prefix, token_hash, scope, created_at, last_used_at, revoked_at;
+
+-- name: GetApiTokenByHash :one
+SELECT id, user_id, name, token_prefix, token_hash, scope, created_at, last_used_at, revoked_at
+FROM api_tokens
+WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: TouchApiToken :exec
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: ListApiTokensForUser :many
+SELECT id, user_id, name, token_prefix, token_hash, scope, created_at, last_used_at, revoked_at
+FROM api_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokeApiToken :execrows
+UPDATE api_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
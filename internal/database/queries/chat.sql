@@ -0,0 +1,43 @@
+-- name: GetChatAccount :one
+SELECT id, provider, workspace_id, external_user_id, external_name, user_id, created_at
+FROM chat_accounts
+WHERE provider = $1 AND workspace_id = $2 AND external_user_id = $3;
+
+-- name: UpsertChatAccount :one
+INSERT INTO chat_accounts (provider, workspace_id, external_user_id, external_name, user_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (provider, workspace_id, external_user_id)
+DO UPDATE SET external_name = EXCLUDED.external_name, user_id = EXCLUDED.user_id, created_at = NOW()
+RETURNING id, provider, workspace_id, external_user_id, external_name, user_id, created_at;
+
+-- name: DeleteChatAccount :execrows
+DELETE FROM chat_accounts
+WHERE provider = $1 AND workspace_id = $2 AND external_user_id = $3;
+
+-- name: CreateChatLinkRequest :one
+INSERT INTO chat_link_requests (provider, workspace_id, external_user_id, external_name, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, provider, workspace_id, external_user_id, external_name, token_hash, expires_at, created_at;
+
+-- name: GetChatLinkRequestByHash :one
+SELECT id, provider, workspace_id, external_user_id, external_name, token_hash, expires_at, created_at
+FROM chat_link_requests
+WHERE token_hash = $1 AND expires_at > NOW();
+
+-- name: DeleteChatLinkRequests :exec
+-- Clears a chat user's outstanding link requests, along with any that have
+-- expired for anyone
+DELETE FROM chat_link_requests
+WHERE (provider = $1 AND workspace_id = $2 AND external_user_id = $3) OR expires_at <= NOW();
+
+-- name: GetChatChannel :one
+SELECT id, provider, workspace_id, channel_id, jar_id, connected_by, created_at
+FROM chat_channels
+WHERE provider = $1 AND workspace_id = $2 AND channel_id = $3;
+
+-- name: UpsertChatChannel :one
+INSERT INTO chat_channels (provider, workspace_id, channel_id, jar_id, connected_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (provider, workspace_id, channel_id)
+DO UPDATE SET jar_id = EXCLUDED.jar_id, connected_by = EXCLUDED.connected_by, created_at = NOW()
+RETURNING id, provider, workspace_id, channel_id, jar_id, connected_by, created_at;
@@ -0,0 +1,67 @@
+-- name: CreateDispute :one
+INSERT INTO offense_disputes (offense_id, jar_id, opened_by, reason, deadline)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at;
+
+-- name: GetDispute :one
+SELECT id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at
+FROM offense_disputes
+WHERE id = $1;
+
+-- name: GetDisputeByOffense :one
+SELECT id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at
+FROM offense_disputes
+WHERE offense_id = $1;
+
+-- name: GetDisputeForUpdate :one
+SELECT id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at
+FROM offense_disputes
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListDisputesForJar :many
+SELECT d.id, d.offense_id, d.jar_id, d.opened_by, d.reason, d.status, d.resolved_amount, d.deadline, d.created_at, d.resolved_at,
+       ot.name as offense_type_name,
+       o.offender_id, offender.name as offender_name,
+       o.cost_amount, o.cost_unit
+FROM offense_disputes d
+INNER JOIN offenses o ON d.offense_id = o.id
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+INNER JOIN users offender ON o.offender_id = offender.id
+WHERE d.jar_id = $1
+ORDER BY d.status = 'open' DESC, d.created_at DESC
+LIMIT $2;
+
+-- name: ListExpiredDisputes :many
+SELECT id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at
+FROM offense_disputes
+WHERE status = 'open' AND deadline <= NOW()
+ORDER BY deadline ASC;
+
+-- name: ResolveDispute :one
+UPDATE offense_disputes
+SET status = $2, resolved_amount = $3, resolved_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING id, offense_id, jar_id, opened_by, reason, status, resolved_amount, deadline, created_at, resolved_at;
+
+-- name: UpsertDisputeVote :one
+INSERT INTO dispute_votes (dispute_id, user_id, vote, reduced_amount)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (dispute_id, user_id)
+DO UPDATE SET vote = EXCLUDED.vote, reduced_amount = EXCLUDED.reduced_amount, created_at = NOW()
+RETURNING id, dispute_id, user_id, vote, reduced_amount, created_at;
+
+-- name: ListDisputeVotes :many
+SELECT id, dispute_id, user_id, vote, reduced_amount, created_at
+FROM dispute_votes
+WHERE dispute_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListDisputeVotesForJar :many
+SELECT dv.id, dv.dispute_id, dv.user_id, dv.vote, dv.reduced_amount, dv.created_at,
+       u.name as user_name
+FROM dispute_votes dv
+INNER JOIN offense_disputes d ON dv.dispute_id = d.id
+INNER JOIN users u ON dv.user_id = u.id
+WHERE d.jar_id = $1
+ORDER BY dv.created_at ASC;
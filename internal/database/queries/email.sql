@@ -0,0 +1,74 @@
+-- name: GetEmailSettings :one
+SELECT user_id, mode, last_digest_at, updated_at
+FROM email_settings
+WHERE user_id = $1;
+
+-- name: UpsertEmailMode :exec
+INSERT INTO email_settings (user_id, mode)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET mode = EXCLUDED.mode, updated_at = NOW();
+
+-- name: EnqueueImmediateEmail :exec
+-- Only queues the email for users who want emails as things happen and
+-- haven't switched this kind of notification off for the jar
+INSERT INTO email_outbox (user_id, template, subject, payload)
+SELECT sqlc.arg(user_id)::int, sqlc.arg(template)::varchar, sqlc.arg(subject)::text, sqlc.arg(payload)::text
+WHERE COALESCE((SELECT s.mode FROM email_settings s WHERE s.user_id = sqlc.arg(user_id)::int), 'immediate') = 'immediate'
+  AND NOT EXISTS (
+    SELECT 1 FROM notification_preferences p
+    WHERE p.user_id = sqlc.arg(user_id)::int
+      AND p.jar_id = sqlc.arg(jar_id)::int
+      AND p.event_type = sqlc.arg(event_type)::varchar
+      AND NOT p.enabled
+);
+
+-- name: CreateEmail :exec
+INSERT INTO email_outbox (user_id, template, subject, payload)
+VALUES ($1, $2, $3, $4);
+
+-- name: ClaimDueEmails :many
+-- Pushes next_attempt_at out while an email is being sent so other workers
+-- leave it alone; recording the outcome sets it properly
+UPDATE email_outbox
+SET next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE id IN (
+    SELECT id FROM email_outbox
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, template, subject, payload, status, attempts, next_attempt_at, last_error, created_at, sent_at;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW()
+WHERE id = $1;
+
+-- name: RecordEmailFailure :exec
+UPDATE email_outbox
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4
+WHERE id = $1;
+
+-- name: ExpireStaleEmails :execrows
+-- Gives up on emails that have waited too long to still be worth sending,
+-- e.g. ones queued while email wasn't set up
+UPDATE email_outbox
+SET status = 'failed', last_error = 'expired before it could be sent'
+WHERE status = 'pending' AND created_at < $1;
+
+-- name: ClaimDueDigests :many
+-- Marks each user's digest as sent before it's built so that only one worker
+-- sends it. The intervals are an hour short of a day and a week so digests
+-- don't creep later each time.
+UPDATE email_settings
+SET last_digest_at = NOW()
+WHERE user_id IN (
+    SELECT s.user_id FROM email_settings s
+    WHERE (s.mode = 'daily' AND (s.last_digest_at IS NULL OR s.last_digest_at <= NOW() - INTERVAL '23 hours'))
+       OR (s.mode = 'weekly' AND (s.last_digest_at IS NULL OR s.last_digest_at <= NOW() - INTERVAL '167 hours'))
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING user_id, mode;
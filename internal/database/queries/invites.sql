@@ -0,0 +1,64 @@
+-- name: CreateInvite :one
+INSERT INTO invites (jar_id, code, label, role, max_uses, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code;
+
+-- name: GetInvite :one
+SELECT id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code
+FROM invites
+WHERE id = $1;
+
+-- name: GetInviteByCode :one
+SELECT id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code
+FROM invites
+WHERE code = $1;
+
+-- name: GetInviteByLegacyCode :one
+-- Finds an invite by the code it had before codes moved to Crockford's
+-- alphabet. Those codes were case-sensitive, so an exact match wins over one
+-- that only matches once upper-cased.
+SELECT id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code
+FROM invites
+WHERE legacy_code = $1 OR legacy_code = UPPER($1)
+ORDER BY legacy_code = $1 DESC
+LIMIT 1;
+
+-- name: ListInvitesForJar :many
+SELECT id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code
+FROM invites
+WHERE jar_id = $1
+ORDER BY revoked_at IS NOT NULL, created_at DESC, id DESC;
+
+-- name: RevokeInvite :execrows
+UPDATE invites
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: UseInvite :one
+-- Counts a use only if the invite still works, so two people can't both take
+-- an invite's last use
+UPDATE invites
+SET uses = uses + 1
+WHERE id = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+  AND (max_uses IS NULL OR uses < max_uses)
+RETURNING id, jar_id, code, label, role, max_uses, uses, expires_at, revoked_at, created_by, created_at, legacy_code;
+
+-- name: CreateInviteRedemption :exec
+INSERT INTO invite_redemptions (invite_id, user_id)
+VALUES ($1, $2);
+
+-- name: ListInviteRedemptionsForJar :many
+SELECT ir.invite_id, ir.user_id, u.name as user_name, ir.created_at
+FROM invite_redemptions ir
+INNER JOIN invites i ON ir.invite_id = i.id
+INNER JOIN users u ON ir.user_id = u.id
+WHERE i.jar_id = $1
+ORDER BY ir.created_at;
+
+-- name: ReleaseInviteUse :exec
+-- Gives back a use taken by a join request that was turned down
+UPDATE invites
+SET uses = uses - 1
+WHERE id = $1 AND uses > 0;
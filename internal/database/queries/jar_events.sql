@@ -0,0 +1,13 @@
+-- name: CreateJarEvent :one
+INSERT INTO jar_events (jar_id, actor_id, offense_id, event_type, message)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, jar_id, actor_id, offense_id, event_type, message, created_at;
+
+-- name: ListJarEvents :many
+SELECT je.id, je.jar_id, je.actor_id, je.offense_id, je.event_type, je.message, je.created_at,
+       u.name as actor_name
+FROM jar_events je
+LEFT JOIN users u ON je.actor_id = u.id
+WHERE je.jar_id = $1
+ORDER BY je.created_at DESC, je.id DESC
+LIMIT $2;
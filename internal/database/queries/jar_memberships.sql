@@ -32,8 +32,11 @@ SELECT EXISTS(
     WHERE jar_id = $1 AND user_id = $2
 );
 
--- name: IsUserJarAdmin :one
-SELECT EXISTS(
-    SELECT 1 FROM jar_memberships
-    WHERE jar_id = $1 AND user_id = $2 AND role = 'admin'
-);
\ No newline at end of file
+-- name: LockJarMembership :one
+-- Holds a member's row until the transaction ends, so they can't leave while
+-- they're being reported and reports against them are checked against the
+-- jar's rules one at a time
+SELECT id, jar_id, user_id, role, joined_at
+FROM jar_memberships
+WHERE jar_id = $1 AND user_id = $2
+FOR UPDATE;
@@ -0,0 +1,77 @@
+-- name: GetJarPeriod :one
+SELECT id, jar_id, name, started_at, closed_at, closed_by, carried_over
+FROM jar_periods
+WHERE id = $1;
+
+-- name: GetJarPeriodForShare :one
+-- Locks the period so it can't close until the caller's transaction is done
+SELECT id, jar_id, name, started_at, closed_at, closed_by, carried_over
+FROM jar_periods
+WHERE id = $1
+FOR SHARE;
+
+-- name: GetOpenJarPeriod :one
+SELECT id, jar_id, name, started_at, closed_at, closed_by, carried_over
+FROM jar_periods
+WHERE jar_id = $1 AND closed_at IS NULL;
+
+-- name: GetOpenJarPeriodForShare :one
+SELECT id, jar_id, name, started_at, closed_at, closed_by, carried_over
+FROM jar_periods
+WHERE jar_id = $1 AND closed_at IS NULL
+FOR SHARE;
+
+-- name: GetOpenJarPeriodForUpdate :one
+SELECT id, jar_id, name, started_at, closed_at, closed_by, carried_over
+FROM jar_periods
+WHERE jar_id = $1 AND closed_at IS NULL
+FOR UPDATE;
+
+-- name: ListJarPeriods :many
+SELECT p.id, p.jar_id, p.name, p.started_at, p.closed_at, p.closed_by, p.carried_over,
+       u.name as closed_by_name
+FROM jar_periods p
+LEFT JOIN users u ON p.closed_by = u.id
+WHERE p.jar_id = $1
+ORDER BY p.started_at DESC, p.id DESC;
+
+-- name: CreateJarPeriod :one
+INSERT INTO jar_periods (jar_id, name)
+VALUES ($1, $2)
+RETURNING id, jar_id, name, started_at, closed_at, closed_by, carried_over;
+
+-- name: CloseJarPeriod :one
+UPDATE jar_periods
+SET name = $2, closed_at = NOW(), closed_by = $3, carried_over = $4
+WHERE id = $1 AND closed_at IS NULL
+RETURNING id, jar_id, name, started_at, closed_at, closed_by, carried_over;
+
+-- name: SnapshotJarPeriodBalances :exec
+-- Records each member's final standing per unit. Forgiven offenses don't
+-- count, and only pending ones can still be owed. Only payments made during
+-- the period count as paid in it, so what was paid towards an offense before
+-- it was carried over isn't counted again.
+INSERT INTO jar_period_balances (period_id, user_id, unit, offense_count, total_amount, paid_amount, unpaid_amount)
+SELECT o.period_id, o.offender_id, COALESCE(o.cost_unit, 'items'),
+       COUNT(*),
+       COALESCE(SUM(o.cost_amount), 0),
+       COALESCE(SUM(period_paid.amount), 0),
+       COALESCE(SUM(GREATEST(o.cost_amount - COALESCE(opt.paid_amount, 0), 0)) FILTER (WHERE o.status = 'pending'), 0)
+FROM offenses o
+INNER JOIN jar_periods jp ON jp.id = o.period_id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+LEFT JOIN LATERAL (
+    SELECT SUM(pa.amount) AS amount
+    FROM payment_allocations pa
+    INNER JOIN payments p ON pa.payment_id = p.id
+    WHERE pa.offense_id = o.id AND NOT p.rejected AND p.created_at >= jp.started_at
+) period_paid ON true
+WHERE o.period_id = $1 AND o.status <> 'forgiven'
+GROUP BY o.period_id, o.offender_id, COALESCE(o.cost_unit, 'items');
+
+-- name: ListJarPeriodBalances :many
+SELECT b.user_id, u.name as user_name, u.avatar, b.unit, b.offense_count, b.total_amount, b.paid_amount, b.unpaid_amount
+FROM jar_period_balances b
+INNER JOIN users u ON b.user_id = u.id
+WHERE b.period_id = $1
+ORDER BY u.name, u.id, b.unpaid_amount DESC;
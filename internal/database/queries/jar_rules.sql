@@ -0,0 +1,39 @@
+-- name: GetJarRules :one
+SELECT jar_id, max_offenses_per_day, report_cooldown_minutes, escalation_window_days, escalation_multiplier, updated_by, updated_at
+FROM jar_rules
+WHERE jar_id = $1;
+
+-- name: UpsertJarRules :one
+INSERT INTO jar_rules (jar_id, max_offenses_per_day, report_cooldown_minutes, escalation_window_days, escalation_multiplier, updated_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (jar_id) DO UPDATE
+SET max_offenses_per_day = EXCLUDED.max_offenses_per_day,
+    report_cooldown_minutes = EXCLUDED.report_cooldown_minutes,
+    escalation_window_days = EXCLUDED.escalation_window_days,
+    escalation_multiplier = EXCLUDED.escalation_multiplier,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = NOW()
+RETURNING jar_id, max_offenses_per_day, report_cooldown_minutes, escalation_window_days, escalation_multiplier, updated_by, updated_at;
+
+-- name: CountOffensesAgainstSince :one
+-- Offenses a member was reported for in the last so many minutes, leaving out
+-- forgiven ones
+SELECT COUNT(*)
+FROM offenses
+WHERE jar_id = sqlc.arg(jar_id) AND offender_id = sqlc.arg(offender_id) AND status != 'forgiven'
+  AND created_at > NOW() - make_interval(mins => sqlc.arg(minutes)::int);
+
+-- name: CountRepeatOffensesSince :one
+-- Earlier offenses of one type against a member in the last so many days,
+-- leaving out forgiven ones
+SELECT COUNT(*)
+FROM offenses
+WHERE jar_id = sqlc.arg(jar_id) AND offender_id = sqlc.arg(offender_id) AND offense_type_id = sqlc.arg(offense_type_id) AND status != 'forgiven'
+  AND created_at > NOW() - make_interval(days => sqlc.arg(days)::int);
+
+-- name: GetReportCooldownRemaining :one
+-- Minutes, rounded up, until the reporter's cooldown on a member runs out; 0
+-- when it already has
+SELECT GREATEST(CEIL(EXTRACT(EPOCH FROM MAX(created_at) + make_interval(mins => sqlc.arg(cooldown_minutes)::int) - NOW()) / 60), 0)::int AS minutes_left
+FROM offenses
+WHERE jar_id = sqlc.arg(jar_id) AND reporter_id = sqlc.arg(reporter_id) AND offender_id = sqlc.arg(offender_id);
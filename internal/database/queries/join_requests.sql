@@ -0,0 +1,39 @@
+-- name: CreateJoinRequest :one
+INSERT INTO join_requests (jar_id, user_id, invite_id)
+VALUES ($1, $2, $3)
+RETURNING id, jar_id, user_id, invite_id, status, message, reviewed_by, reviewed_at, created_at;
+
+-- name: GetJoinRequest :one
+SELECT id, jar_id, user_id, invite_id, status, message, reviewed_by, reviewed_at, created_at
+FROM join_requests
+WHERE id = $1;
+
+-- name: GetPendingJoinRequest :one
+SELECT id, jar_id, user_id, invite_id, status, message, reviewed_by, reviewed_at, created_at
+FROM join_requests
+WHERE jar_id = $1 AND user_id = $2 AND status = 'pending';
+
+-- name: ListPendingJoinRequestsForJar :many
+SELECT jr.id, jr.jar_id, jr.user_id, jr.invite_id, jr.created_at,
+       u.name as user_name, u.email as user_email, u.avatar as user_avatar,
+       i.code as invite_code, i.label as invite_label, i.role as invite_role
+FROM join_requests jr
+INNER JOIN users u ON jr.user_id = u.id
+INNER JOIN invites i ON jr.invite_id = i.id
+WHERE jr.jar_id = $1 AND jr.status = 'pending'
+ORDER BY jr.created_at ASC;
+
+-- name: ListPendingJoinRequestsForUser :many
+SELECT jr.id, jr.jar_id, jr.created_at, tj.name as jar_name
+FROM join_requests jr
+INNER JOIN tip_jars tj ON jr.jar_id = tj.id
+WHERE jr.user_id = $1 AND jr.status = 'pending'
+ORDER BY jr.created_at DESC;
+
+-- name: ReviewJoinRequest :one
+-- Only settles a request that's still pending, so two admins can't both act
+-- on it
+UPDATE join_requests
+SET status = $2, message = $3, reviewed_by = $4, reviewed_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, jar_id, user_id, invite_id, status, message, reviewed_by, reviewed_at, created_at;
@@ -0,0 +1,45 @@
+-- name: CreateNotification :exec
+-- Skips users who have switched this kind of notification off for the jar
+INSERT INTO notifications (user_id, jar_id, event_type, message, link)
+SELECT sqlc.arg(user_id)::int, sqlc.arg(jar_id)::int, sqlc.arg(event_type)::varchar, sqlc.arg(message)::text, sqlc.arg(link)::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM notification_preferences p
+    WHERE p.user_id = sqlc.arg(user_id)::int
+      AND p.jar_id = sqlc.arg(jar_id)::int
+      AND p.event_type = sqlc.arg(event_type)::varchar
+      AND NOT p.enabled
+);
+
+-- name: ListNotificationsForUser :many
+SELECT n.id, n.user_id, n.jar_id, t.name AS jar_name, n.event_type, n.message, n.link, n.read_at, n.created_at
+FROM notifications n
+JOIN tip_jars t ON n.jar_id = t.id
+WHERE n.user_id = $1
+ORDER BY n.created_at DESC, n.id DESC
+LIMIT $2;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, jar_id, event_type, message, link, read_at, created_at;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: ListNotificationPreferences :many
+SELECT user_id, jar_id, event_type, enabled
+FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, jar_id, event_type, enabled)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, jar_id, event_type)
+DO UPDATE SET enabled = EXCLUDED.enabled;
@@ -0,0 +1,13 @@
+-- name: CreateOffenseTypePrice :one
+INSERT INTO offense_type_prices (offense_type_id, cost_amount, cost_unit, changed_by)
+VALUES ($1, $2, $3, $4)
+RETURNING id, offense_type_id, cost_amount, cost_unit, changed_by, effective_from;
+
+-- name: ListOffenseTypePricesForJar :many
+SELECT otp.id, otp.offense_type_id, otp.cost_amount, otp.cost_unit, otp.changed_by, otp.effective_from,
+       ot.name as offense_type_name, u.name as changed_by_name
+FROM offense_type_prices otp
+INNER JOIN offense_types ot ON otp.offense_type_id = ot.id
+LEFT JOIN users u ON otp.changed_by = u.id
+WHERE ot.jar_id = $1
+ORDER BY otp.effective_from DESC, otp.id DESC;
@@ -1,87 +1,153 @@
 -- name: GetOffense :one
-SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at
+SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
 FROM offenses
 WHERE id = $1;
 
+-- name: GetOffenseForUpdate :one
+SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
+FROM offenses
+WHERE id = $1
+FOR UPDATE;
+
 -- name: ListOffensesForJar :many
-SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at,
-       ot.name as offense_type_name, ot.cost_amount, ot.cost_unit,
-       reporter.name as reporter_name, offender.name as offender_name
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
+       reporter.name as reporter_name, offender.name as offender_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
 FROM offenses o
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 INNER JOIN users reporter ON o.reporter_id = reporter.id
 INNER JOIN users offender ON o.offender_id = offender.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
 WHERE o.jar_id = $1
 ORDER BY o.created_at DESC
 LIMIT $2 OFFSET $3;
 
 -- name: ListPendingOffensesForUser :many
-SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at,
-       ot.name as offense_type_name, ot.cost_amount, ot.cost_unit,
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
        tj.name as jar_name
 FROM offenses o
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 INNER JOIN tip_jars tj ON o.jar_id = tj.id
-WHERE o.offender_id = $1 AND o.status = 'pending'
+WHERE o.offender_id = $1 AND o.status = 'pending' AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
 ORDER BY o.created_at DESC;
 
+-- name: ListPendingOffensesForUserInJar :many
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
+FROM offenses o
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending' AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+ORDER BY o.created_at ASC;
+
 -- name: CreateOffense :one
-INSERT INTO offenses (jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at;
+-- The offense is reported in, and counts towards, the given period
+INSERT INTO offenses (jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, cost_amount, cost_unit, period_id, reported_period_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id;
 
--- name: UpdateOffenseStatus :one
+-- name: UpdateOffenseStatusInPeriod :one
+-- Callers hold a lock on the period (see openPeriod), so the offense can't be
+-- carried out of it while its status changes
 UPDATE offenses
 SET status = $2, updated_at = NOW()
-WHERE id = $1
-RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at;
+WHERE id = $1 AND period_id = $3
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id;
 
 -- name: GetUserBalanceInJar :one
+-- Offenses awaiting payment verification still owe whatever that payment
+-- leaves unpaid
 SELECT 
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed
 FROM offenses o
-INNER JOIN offense_types ot ON o.offense_type_id = ot.id
-WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending';
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status IN ('pending', 'verifying') AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL);
 
 -- name: GetUserBalancesByUnitInJar :many
+-- Offenses awaiting payment verification count while their payment leaves
+-- something unpaid
 SELECT 
-    COALESCE(ot.cost_unit, 'items') as unit,
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed,
+    COALESCE(o.cost_unit, 'items') as unit,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
     COUNT(*) as offense_count
 FROM offenses o
-INNER JOIN offense_types ot ON o.offense_type_id = ot.id
-WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending'
-GROUP BY ot.cost_unit
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status IN ('pending', 'verifying') AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+    AND (o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0))
+GROUP BY o.cost_unit
 ORDER BY total_owed DESC;
 
 -- name: GetJarBalancesByUnit :many
+-- Offenses awaiting payment verification count while their payment leaves
+-- something unpaid
 SELECT 
     u.id as user_id,
     u.name as user_name,
     u.avatar,
-    COALESCE(ot.cost_unit, 'items') as unit,
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed,
-    COUNT(*) as offense_count
-FROM users u
-INNER JOIN jar_memberships jm ON u.id = jm.user_id
-LEFT JOIN offenses o ON u.id = o.offender_id AND o.jar_id = $1 AND o.status = 'pending'
-LEFT JOIN offense_types ot ON o.offense_type_id = ot.id
+    COALESCE(o.cost_unit, 'items') as unit,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
+    COUNT(o.id) as offense_count
+FROM jar_memberships jm
+INNER JOIN users u ON jm.user_id = u.id
+INNER JOIN offenses o ON o.offender_id = jm.user_id AND o.jar_id = jm.jar_id AND o.status IN ('pending', 'verifying')
+    AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE jm.jar_id = $1
+    AND (o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0))
+GROUP BY jm.joined_at, u.id, u.name, u.avatar, COALESCE(o.cost_unit, 'items')
+ORDER BY jm.joined_at ASC, u.id, total_owed DESC;
+
+-- name: GetJarMemberBalances :many
+-- Offenses awaiting payment verification count while their payment leaves
+-- something unpaid
+SELECT 
+    u.id as user_id,
+    u.name as user_name,
+    u.avatar,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
+    COUNT(o.id) FILTER (WHERE o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0)) as pending_count
+FROM jar_memberships jm
+INNER JOIN users u ON jm.user_id = u.id
+LEFT JOIN offenses o ON o.offender_id = jm.user_id AND o.jar_id = jm.jar_id AND o.status IN ('pending', 'verifying')
+    AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
 WHERE jm.jar_id = $1
-GROUP BY u.id, u.name, u.avatar, ot.cost_unit
-HAVING COUNT(o.id) > 0 OR ot.cost_unit IS NULL
-ORDER BY u.name, total_owed DESC;
\ No newline at end of file
+GROUP BY jm.joined_at, u.id, u.name, u.avatar
+ORDER BY jm.joined_at ASC;
+
+-- name: UpdateOffenseCost :one
+UPDATE offenses
+SET cost_amount = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id;
+
+
+-- name: ListOffensesForPeriod :many
+-- The period's ledger: what was reported in it and what counted towards it,
+-- which includes offenses carried in from earlier periods
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit, o.period_id, o.reported_period_id,
+       ot.name as offense_type_name,
+       reporter.name as reporter_name, offender.name as offender_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
+FROM offenses o
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+INNER JOIN users reporter ON o.reporter_id = reporter.id
+INNER JOIN users offender ON o.offender_id = offender.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.period_id = $1 OR o.reported_period_id = $1
+ORDER BY o.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUnsettledOffensesInPeriod :one
+-- Offenses with an open dispute or a payment awaiting verification
+SELECT COUNT(*) FROM offenses
+WHERE period_id = $1 AND status IN ('disputed', 'verifying');
+
+-- name: CarryOverOffenses :execrows
+-- Moves the offenses still owed in a closing period into the next one
+UPDATE offenses
+SET period_id = sqlc.arg(to_period_id), updated_at = NOW()
+WHERE period_id = sqlc.arg(from_period_id) AND status = 'pending';
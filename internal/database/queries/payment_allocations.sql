@@ -0,0 +1,17 @@
+-- name: CreatePaymentAllocation :one
+INSERT INTO payment_allocations (payment_id, offense_id, amount)
+VALUES ($1, $2, $3)
+RETURNING id, payment_id, offense_id, amount, created_at;
+
+-- name: ListAllocationsForPayment :many
+SELECT pa.id, pa.payment_id, pa.offense_id, pa.amount, pa.created_at,
+       o.jar_id, o.offender_id, o.status, o.cost_unit,
+       ot.name as offense_type_name
+FROM payment_allocations pa
+INNER JOIN offenses o ON pa.offense_id = o.id
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+WHERE pa.payment_id = $1
+ORDER BY pa.id;
+
+-- name: GetOffensePaidAmount :one
+SELECT COALESCE((SELECT paid_amount FROM offense_payment_totals WHERE offense_id = $1), 0)::numeric as paid_amount;
@@ -0,0 +1,13 @@
+-- name: UpsertPaymentVerification :one
+INSERT INTO payment_verifications (payment_id, user_id, approved)
+VALUES ($1, $2, $3)
+ON CONFLICT (payment_id, user_id)
+DO UPDATE SET approved = EXCLUDED.approved, created_at = NOW()
+RETURNING id, payment_id, user_id, approved, created_at;
+
+-- name: CountPaymentVerifications :one
+SELECT
+    COUNT(*) FILTER (WHERE approved) as approvals,
+    COUNT(*) FILTER (WHERE NOT approved) as rejections
+FROM payment_verifications
+WHERE payment_id = $1;
@@ -1,27 +1,40 @@
 -- name: GetPayment :one
-SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
+SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
 FROM payments
 WHERE id = $1;
 
 -- name: ListPaymentsForOffense :many
-SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
-FROM payments
-WHERE offense_id = $1
-ORDER BY created_at DESC;
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
+       pa.amount as allocated_amount
+FROM payments p
+INNER JOIN payment_allocations pa ON pa.payment_id = p.id
+WHERE pa.offense_id = $1
+ORDER BY p.created_at DESC;
 
 -- name: CreatePayment :one
 INSERT INTO payments (offense_id, user_id, amount, proof_type, proof_url)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at;
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected;
+
+-- name: CreateBatchPayment :one
+INSERT INTO payments (user_id, proof_type, proof_url)
+VALUES ($1, $2, $3)
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected;
+
+-- name: GetPaymentForUpdate :one
+SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
+FROM payments
+WHERE id = $1
+FOR UPDATE;
 
 -- name: VerifyPayment :one
 UPDATE payments
 SET verified = true, verified_by = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at;
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected;
 
 -- name: ListPaymentsForUser :many
-SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at,
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
        o.jar_id, tj.name as jar_name, ot.name as offense_type_name
 FROM payments p
 INNER JOIN offenses o ON p.offense_id = o.id
@@ -29,4 +42,40 @@ INNER JOIN tip_jars tj ON o.jar_id = tj.id
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 WHERE p.user_id = $1
 ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+LIMIT $2 OFFSET $3;;
+
+-- name: RejectPayment :one
+UPDATE payments
+SET rejected = true, updated_at = NOW()
+WHERE id = $1
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected;
+
+-- name: ListPaymentsAwaitingVerification :many
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
+       pa.offense_id as allocated_offense_id, pa.amount as allocated_amount,
+       o.jar_id, tj.name as jar_name, ot.name as offense_type_name,
+       o.offender_id, offender.name as offender_name, o.cost_unit,
+       pv.approved as my_verdict, jm.role as my_role
+FROM payments p
+INNER JOIN payment_allocations pa ON pa.payment_id = p.id
+INNER JOIN offenses o ON pa.offense_id = o.id
+INNER JOIN tip_jars tj ON o.jar_id = tj.id
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+INNER JOIN users offender ON o.offender_id = offender.id
+INNER JOIN jar_memberships jm ON jm.jar_id = o.jar_id AND jm.user_id = $1
+LEFT JOIN payment_verifications pv ON pv.payment_id = p.id AND pv.user_id = $1
+WHERE o.status = 'verifying' AND NOT p.verified AND NOT p.rejected
+  AND o.offender_id <> $1 AND p.user_id <> $1
+  AND tj.archived_at IS NULL
+ORDER BY p.created_at ASC, p.id, pa.id;
+
+-- name: DeletePaymentsForJar :many
+-- Returns the proofs of the deleted payments so the files can be removed too
+DELETE FROM payments
+WHERE id IN (
+    SELECT pa.payment_id
+    FROM payment_allocations pa
+    INNER JOIN offenses o ON pa.offense_id = o.id
+    WHERE o.jar_id = $1
+)
+RETURNING proof_url;
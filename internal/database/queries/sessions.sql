@@ -0,0 +1,36 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, token_hash, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at;
+
+-- name: GetSessionByTokenHash :one
+SELECT id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at
+FROM sessions
+WHERE token_hash = $1 AND expires_at > NOW();
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = NOW()
+WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '5 minutes';
+
+-- name: ListSessionsForUser :many
+SELECT id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at
+FROM sessions
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY last_seen_at DESC;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteSessionByTokenHash :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= NOW();
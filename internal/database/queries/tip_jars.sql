@@ -1,20 +1,15 @@
 -- name: GetTipJar :one
-SELECT id, name, description, invite_code, created_by, created_at, updated_at
+SELECT id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
 FROM tip_jars
 WHERE id = $1;
 
--- name: GetTipJarByInviteCode :one
-SELECT id, name, description, invite_code, created_by, created_at, updated_at
-FROM tip_jars
-WHERE invite_code = $1;
-
 -- name: CreateTipJar :one
-INSERT INTO tip_jars (name, description, invite_code, created_by)
-VALUES ($1, $2, $3, $4)
-RETURNING id, name, description, invite_code, created_by, created_at, updated_at;
+INSERT INTO tip_jars (name, description, created_by)
+VALUES ($1, $2, $3)
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
 
 -- name: ListTipJarsForUser :many
-SELECT tj.id, tj.name, tj.description, tj.invite_code, tj.created_by, tj.created_at, tj.updated_at
+SELECT tj.id, tj.name, tj.description, tj.created_by, tj.created_at, tj.updated_at, tj.dispute_quorum, tj.dispute_window_hours, tj.verification_required, tj.verifications_needed, tj.join_approval_required, tj.archived_at
 FROM tip_jars tj
 INNER JOIN jar_memberships jm ON tj.id = jm.jar_id
 WHERE jm.user_id = $1
@@ -24,14 +19,44 @@ ORDER BY tj.created_at DESC;
 UPDATE tip_jars
 SET name = $2, description = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, description, invite_code, created_by, created_at, updated_at;
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
+
+-- name: UpdateTipJarDisputeSettings :one
+UPDATE tip_jars
+SET dispute_quorum = $2, dispute_window_hours = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
+
+-- name: UpdateTipJarVerificationSettings :one
+UPDATE tip_jars
+SET verification_required = $2, verifications_needed = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
+
+-- name: UpdateTipJarJoinApproval :one
+UPDATE tip_jars
+SET join_approval_required = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
+
+-- name: ArchiveTipJar :one
+UPDATE tip_jars
+SET archived_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND archived_at IS NULL
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
+
+-- name: RestoreTipJar :one
+UPDATE tip_jars
+SET archived_at = NULL, updated_at = NOW()
+WHERE id = $1 AND archived_at IS NOT NULL
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at;
 
 -- name: DeleteTipJar :exec
 DELETE FROM tip_jars
 WHERE id = $1;
 
 -- name: ListTipJarsForUserWithMemberCount :many
-SELECT tj.id, tj.name, tj.description, tj.invite_code, tj.created_by, tj.created_at, tj.updated_at,
+SELECT tj.id, tj.name, tj.description, tj.created_by, tj.created_at, tj.updated_at, tj.dispute_quorum, tj.dispute_window_hours, tj.verification_required, tj.verifications_needed, tj.join_approval_required, tj.archived_at,
        member_counts.member_count
 FROM tip_jars tj
 INNER JOIN jar_memberships jm ON tj.id = jm.jar_id
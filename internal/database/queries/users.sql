@@ -1,25 +1,31 @@
--- name: GetUserByGoogleID :one
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
-FROM users 
-WHERE google_id = $1;
-
 -- name: GetUserByID :one
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
+SELECT id, email, name, avatar, created_at, updated_at 
 FROM users 
 WHERE id = $1;
 
+-- name: GetUserByEmail :one
+SELECT id, email, name, avatar, created_at, updated_at 
+FROM users 
+WHERE email = $1;
+
+-- name: GetUserByIdentity :one
+SELECT u.id, u.email, u.name, u.avatar, u.created_at, u.updated_at
+FROM users u
+INNER JOIN user_identities ui ON u.id = ui.user_id
+WHERE ui.provider = $1 AND ui.subject = $2;
+
 -- name: CreateUser :one
-INSERT INTO users (email, name, avatar, google_id)
-VALUES ($1, $2, $3, $4)
-RETURNING id, email, name, avatar, google_id, created_at, updated_at;
+INSERT INTO users (email, name, avatar)
+VALUES ($1, $2, $3)
+RETURNING id, email, name, avatar, created_at, updated_at;
 
 -- name: UpdateUser :one
 UPDATE users 
 SET name = $2, avatar = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, name, avatar, google_id, created_at, updated_at;
+RETURNING id, email, name, avatar, created_at, updated_at;
 
 -- name: ListUsers :many
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
+SELECT id, email, name, avatar, created_at, updated_at 
 FROM users
-ORDER BY created_at DESC;
\ No newline at end of file
+ORDER BY created_at DESC;
@@ -0,0 +1,79 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (jar_id, url, secret, event_types, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, jar_id, url, secret, event_types, is_active, created_by, created_at, updated_at;
+
+-- name: GetWebhook :one
+SELECT id, jar_id, url, secret, event_types, is_active, created_by, created_at, updated_at
+FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooksForJar :many
+SELECT id, jar_id, url, secret, event_types, is_active, created_by, created_at, updated_at
+FROM webhooks
+WHERE jar_id = $1
+ORDER BY created_at;
+
+-- name: SetWebhookActive :exec
+UPDATE webhooks
+SET is_active = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT w.id, sqlc.arg(event_type)::varchar, sqlc.arg(payload)::text
+FROM webhooks w
+WHERE w.jar_id = sqlc.arg(jar_id) AND w.is_active AND sqlc.arg(event_type) = ANY(w.event_types);
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+VALUES ($1, $2, $3)
+RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at;
+
+-- name: GetWebhookDelivery :one
+SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at
+FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Pushes next_attempt_at out while a delivery is in flight so other workers
+-- leave it alone; recording the outcome sets it properly. Deliveries to paused
+-- webhooks wait until they're resumed
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE id IN (
+    SELECT d.id FROM webhook_deliveries d
+    JOIN webhooks w ON w.id = d.webhook_id
+    WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.is_active
+    ORDER BY d.next_attempt_at
+    LIMIT $1
+    FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_status_code = $2, last_error = NULL, delivered_at = NOW()
+WHERE id = $1;
+
+-- name: ReleaseWebhookDelivery :exec
+-- Hands a claimed delivery back without counting an attempt
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW()
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_status_code = $4, last_error = $5
+WHERE id = $1;
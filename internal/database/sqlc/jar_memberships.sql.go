@@ -75,25 +75,6 @@ func (q *Queries) GetJarMembership(ctx context.Context, arg GetJarMembershipPara
 	return i, err
 }
 
-const isUserJarAdmin = `-- name: IsUserJarAdmin :one
-SELECT EXISTS(
-    SELECT 1 FROM jar_memberships
-    WHERE jar_id = $1 AND user_id = $2 AND role = 'admin'
-)
-`
-
-type IsUserJarAdminParams struct {
-	JarID  int32 `db:"jar_id" json:"jar_id"`
-	UserID int32 `db:"user_id" json:"user_id"`
-}
-
-func (q *Queries) IsUserJarAdmin(ctx context.Context, arg IsUserJarAdminParams) (bool, error) {
-	row := q.db.QueryRow(ctx, isUserJarAdmin, arg.JarID, arg.UserID)
-	var exists bool
-	err := row.Scan(&exists)
-	return exists, err
-}
-
 const isUserJarMember = `-- name: IsUserJarMember :one
 SELECT EXISTS(
     SELECT 1 FROM jar_memberships
@@ -162,6 +143,34 @@ func (q *Queries) ListJarMembers(ctx context.Context, jarID int32) ([]ListJarMem
 	return items, nil
 }
 
+const lockJarMembership = `-- name: LockJarMembership :one
+SELECT id, jar_id, user_id, role, joined_at
+FROM jar_memberships
+WHERE jar_id = $1 AND user_id = $2
+FOR UPDATE
+`
+
+type LockJarMembershipParams struct {
+	JarID  int32 `db:"jar_id" json:"jar_id"`
+	UserID int32 `db:"user_id" json:"user_id"`
+}
+
+// Holds a member's row until the transaction ends, so they can't leave while
+// they're being reported and reports against them are checked against the
+// jar's rules one at a time
+func (q *Queries) LockJarMembership(ctx context.Context, arg LockJarMembershipParams) (JarMembership, error) {
+	row := q.db.QueryRow(ctx, lockJarMembership, arg.JarID, arg.UserID)
+	var i JarMembership
+	err := row.Scan(
+		&i.ID,
+		&i.JarID,
+		&i.UserID,
+		&i.Role,
+		&i.JoinedAt,
+	)
+	return i, err
+}
+
 const updateMemberRole = `-- name: UpdateMemberRole :one
 UPDATE jar_memberships
 SET role = $3
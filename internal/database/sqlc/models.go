@@ -8,6 +8,111 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiToken struct {
+	ID          int32            `db:"id" json:"id"`
+	UserID      int32            `db:"user_id" json:"user_id"`
+	Name        string           `db:"name" json:"name"`
+	TokenPrefix string           `db:"token_prefix" json:"token_prefix"`
+	TokenHash   string           `db:"token_hash" json:"token_hash"`
+	Scope       string           `db:"scope" json:"scope"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
+	LastUsedAt  pgtype.Timestamp `db:"last_used_at" json:"last_used_at"`
+	RevokedAt   pgtype.Timestamp `db:"revoked_at" json:"revoked_at"`
+}
+
+type ChatAccount struct {
+	ID             int32            `db:"id" json:"id"`
+	Provider       string           `db:"provider" json:"provider"`
+	WorkspaceID    string           `db:"workspace_id" json:"workspace_id"`
+	ExternalUserID string           `db:"external_user_id" json:"external_user_id"`
+	ExternalName   string           `db:"external_name" json:"external_name"`
+	UserID         int32            `db:"user_id" json:"user_id"`
+	CreatedAt      pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type ChatChannel struct {
+	ID          int32            `db:"id" json:"id"`
+	Provider    string           `db:"provider" json:"provider"`
+	WorkspaceID string           `db:"workspace_id" json:"workspace_id"`
+	ChannelID   string           `db:"channel_id" json:"channel_id"`
+	JarID       int32            `db:"jar_id" json:"jar_id"`
+	ConnectedBy int32            `db:"connected_by" json:"connected_by"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type ChatLinkRequest struct {
+	ID             int32            `db:"id" json:"id"`
+	Provider       string           `db:"provider" json:"provider"`
+	WorkspaceID    string           `db:"workspace_id" json:"workspace_id"`
+	ExternalUserID string           `db:"external_user_id" json:"external_user_id"`
+	ExternalName   string           `db:"external_name" json:"external_name"`
+	TokenHash      string           `db:"token_hash" json:"token_hash"`
+	ExpiresAt      pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	CreatedAt      pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type DisputeVote struct {
+	ID            int32            `db:"id" json:"id"`
+	DisputeID     int32            `db:"dispute_id" json:"dispute_id"`
+	UserID        int32            `db:"user_id" json:"user_id"`
+	Vote          string           `db:"vote" json:"vote"`
+	ReducedAmount pgtype.Numeric   `db:"reduced_amount" json:"reduced_amount"`
+	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type EmailOutbox struct {
+	ID            int32            `db:"id" json:"id"`
+	UserID        int32            `db:"user_id" json:"user_id"`
+	Template      string           `db:"template" json:"template"`
+	Subject       string           `db:"subject" json:"subject"`
+	Payload       string           `db:"payload" json:"payload"`
+	Status        string           `db:"status" json:"status"`
+	Attempts      int32            `db:"attempts" json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     pgtype.Text      `db:"last_error" json:"last_error"`
+	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
+	SentAt        pgtype.Timestamp `db:"sent_at" json:"sent_at"`
+}
+
+type EmailSetting struct {
+	UserID       int32            `db:"user_id" json:"user_id"`
+	Mode         string           `db:"mode" json:"mode"`
+	LastDigestAt pgtype.Timestamp `db:"last_digest_at" json:"last_digest_at"`
+	UpdatedAt    pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+}
+
+type Invite struct {
+	ID         int32            `db:"id" json:"id"`
+	JarID      int32            `db:"jar_id" json:"jar_id"`
+	Code       string           `db:"code" json:"code"`
+	Label      pgtype.Text      `db:"label" json:"label"`
+	Role       string           `db:"role" json:"role"`
+	MaxUses    pgtype.Int4      `db:"max_uses" json:"max_uses"`
+	Uses       int32            `db:"uses" json:"uses"`
+	ExpiresAt  pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	RevokedAt  pgtype.Timestamp `db:"revoked_at" json:"revoked_at"`
+	CreatedBy  int32            `db:"created_by" json:"created_by"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
+	LegacyCode pgtype.Text      `db:"legacy_code" json:"legacy_code"`
+}
+
+type InviteRedemption struct {
+	ID        int32            `db:"id" json:"id"`
+	InviteID  int32            `db:"invite_id" json:"invite_id"`
+	UserID    int32            `db:"user_id" json:"user_id"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type JarEvent struct {
+	ID        int32            `db:"id" json:"id"`
+	JarID     int32            `db:"jar_id" json:"jar_id"`
+	ActorID   pgtype.Int4      `db:"actor_id" json:"actor_id"`
+	OffenseID pgtype.Int4      `db:"offense_id" json:"offense_id"`
+	EventType string           `db:"event_type" json:"event_type"`
+	Message   string           `db:"message" json:"message"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
 type JarMembership struct {
 	ID       int32            `db:"id" json:"id"`
 	JarID    int32            `db:"jar_id" json:"jar_id"`
@@ -16,17 +121,94 @@ type JarMembership struct {
 	JoinedAt pgtype.Timestamp `db:"joined_at" json:"joined_at"`
 }
 
+type JarPeriod struct {
+	ID          int32            `db:"id" json:"id"`
+	JarID       int32            `db:"jar_id" json:"jar_id"`
+	Name        string           `db:"name" json:"name"`
+	StartedAt   pgtype.Timestamp `db:"started_at" json:"started_at"`
+	ClosedAt    pgtype.Timestamp `db:"closed_at" json:"closed_at"`
+	ClosedBy    pgtype.Int4      `db:"closed_by" json:"closed_by"`
+	CarriedOver bool             `db:"carried_over" json:"carried_over"`
+}
+
+type JarPeriodBalance struct {
+	PeriodID     int32          `db:"period_id" json:"period_id"`
+	UserID       int32          `db:"user_id" json:"user_id"`
+	Unit         string         `db:"unit" json:"unit"`
+	OffenseCount int32          `db:"offense_count" json:"offense_count"`
+	TotalAmount  pgtype.Numeric `db:"total_amount" json:"total_amount"`
+	PaidAmount   pgtype.Numeric `db:"paid_amount" json:"paid_amount"`
+	UnpaidAmount pgtype.Numeric `db:"unpaid_amount" json:"unpaid_amount"`
+}
+
+type JarRule struct {
+	JarID                 int32            `db:"jar_id" json:"jar_id"`
+	MaxOffensesPerDay     pgtype.Int4      `db:"max_offenses_per_day" json:"max_offenses_per_day"`
+	ReportCooldownMinutes pgtype.Int4      `db:"report_cooldown_minutes" json:"report_cooldown_minutes"`
+	EscalationWindowDays  pgtype.Int4      `db:"escalation_window_days" json:"escalation_window_days"`
+	EscalationMultiplier  pgtype.Numeric   `db:"escalation_multiplier" json:"escalation_multiplier"`
+	UpdatedBy             pgtype.Int4      `db:"updated_by" json:"updated_by"`
+	UpdatedAt             pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+}
+
+type JoinRequest struct {
+	ID         int32            `db:"id" json:"id"`
+	JarID      int32            `db:"jar_id" json:"jar_id"`
+	UserID     int32            `db:"user_id" json:"user_id"`
+	InviteID   int32            `db:"invite_id" json:"invite_id"`
+	Status     string           `db:"status" json:"status"`
+	Message    pgtype.Text      `db:"message" json:"message"`
+	ReviewedBy pgtype.Int4      `db:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt pgtype.Timestamp `db:"reviewed_at" json:"reviewed_at"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type Notification struct {
+	ID        int32            `db:"id" json:"id"`
+	UserID    int32            `db:"user_id" json:"user_id"`
+	JarID     int32            `db:"jar_id" json:"jar_id"`
+	EventType string           `db:"event_type" json:"event_type"`
+	Message   string           `db:"message" json:"message"`
+	Link      string           `db:"link" json:"link"`
+	ReadAt    pgtype.Timestamp `db:"read_at" json:"read_at"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type NotificationPreference struct {
+	UserID    int32  `db:"user_id" json:"user_id"`
+	JarID     int32  `db:"jar_id" json:"jar_id"`
+	EventType string `db:"event_type" json:"event_type"`
+	Enabled   bool   `db:"enabled" json:"enabled"`
+}
+
 type Offense struct {
-	ID            int32            `db:"id" json:"id"`
-	JarID         int32            `db:"jar_id" json:"jar_id"`
-	OffenseTypeID int32            `db:"offense_type_id" json:"offense_type_id"`
-	ReporterID    int32            `db:"reporter_id" json:"reporter_id"`
-	OffenderID    int32            `db:"offender_id" json:"offender_id"`
-	Notes         pgtype.Text      `db:"notes" json:"notes"`
-	CostOverride  pgtype.Numeric   `db:"cost_override" json:"cost_override"`
-	Status        string           `db:"status" json:"status"`
-	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt     pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	ID               int32            `db:"id" json:"id"`
+	JarID            int32            `db:"jar_id" json:"jar_id"`
+	OffenseTypeID    int32            `db:"offense_type_id" json:"offense_type_id"`
+	ReporterID       int32            `db:"reporter_id" json:"reporter_id"`
+	OffenderID       int32            `db:"offender_id" json:"offender_id"`
+	Notes            pgtype.Text      `db:"notes" json:"notes"`
+	CostOverride     pgtype.Numeric   `db:"cost_override" json:"cost_override"`
+	Status           string           `db:"status" json:"status"`
+	CreatedAt        pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt        pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	CostAmount       pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
+	CostUnit         pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	PeriodID         int32            `db:"period_id" json:"period_id"`
+	ReportedPeriodID int32            `db:"reported_period_id" json:"reported_period_id"`
+}
+
+type OffenseDispute struct {
+	ID             int32            `db:"id" json:"id"`
+	OffenseID      int32            `db:"offense_id" json:"offense_id"`
+	JarID          int32            `db:"jar_id" json:"jar_id"`
+	OpenedBy       int32            `db:"opened_by" json:"opened_by"`
+	Reason         string           `db:"reason" json:"reason"`
+	Status         string           `db:"status" json:"status"`
+	ResolvedAmount pgtype.Numeric   `db:"resolved_amount" json:"resolved_amount"`
+	Deadline       pgtype.Timestamp `db:"deadline" json:"deadline"`
+	CreatedAt      pgtype.Timestamp `db:"created_at" json:"created_at"`
+	ResolvedAt     pgtype.Timestamp `db:"resolved_at" json:"resolved_at"`
 }
 
 type OffenseType struct {
@@ -41,9 +223,18 @@ type OffenseType struct {
 	CostUnit    pgtype.Text      `db:"cost_unit" json:"cost_unit"`
 }
 
+type OffenseTypePrice struct {
+	ID            int32            `db:"id" json:"id"`
+	OffenseTypeID int32            `db:"offense_type_id" json:"offense_type_id"`
+	CostAmount    pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
+	CostUnit      pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	ChangedBy     pgtype.Int4      `db:"changed_by" json:"changed_by"`
+	EffectiveFrom pgtype.Timestamp `db:"effective_from" json:"effective_from"`
+}
+
 type Payment struct {
 	ID         int32            `db:"id" json:"id"`
-	OffenseID  int32            `db:"offense_id" json:"offense_id"`
+	OffenseID  pgtype.Int4      `db:"offense_id" json:"offense_id"`
 	UserID     int32            `db:"user_id" json:"user_id"`
 	Amount     pgtype.Numeric   `db:"amount" json:"amount"`
 	ProofType  pgtype.Text      `db:"proof_type" json:"proof_type"`
@@ -52,16 +243,49 @@ type Payment struct {
 	VerifiedBy pgtype.Int4      `db:"verified_by" json:"verified_by"`
 	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt  pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Rejected   bool             `db:"rejected" json:"rejected"`
+}
+
+type PaymentAllocation struct {
+	ID        int32            `db:"id" json:"id"`
+	PaymentID int32            `db:"payment_id" json:"payment_id"`
+	OffenseID int32            `db:"offense_id" json:"offense_id"`
+	Amount    pgtype.Numeric   `db:"amount" json:"amount"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type PaymentVerification struct {
+	ID        int32            `db:"id" json:"id"`
+	PaymentID int32            `db:"payment_id" json:"payment_id"`
+	UserID    int32            `db:"user_id" json:"user_id"`
+	Approved  bool             `db:"approved" json:"approved"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type Session struct {
+	ID         int32            `db:"id" json:"id"`
+	UserID     int32            `db:"user_id" json:"user_id"`
+	TokenHash  string           `db:"token_hash" json:"token_hash"`
+	UserAgent  pgtype.Text      `db:"user_agent" json:"user_agent"`
+	IpAddress  pgtype.Text      `db:"ip_address" json:"ip_address"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
+	LastSeenAt pgtype.Timestamp `db:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  pgtype.Timestamp `db:"expires_at" json:"expires_at"`
 }
 
 type TipJar struct {
-	ID          int32            `db:"id" json:"id"`
-	Name        string           `db:"name" json:"name"`
-	Description pgtype.Text      `db:"description" json:"description"`
-	InviteCode  string           `db:"invite_code" json:"invite_code"`
-	CreatedBy   int32            `db:"created_by" json:"created_by"`
-	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	ID                   int32            `db:"id" json:"id"`
+	Name                 string           `db:"name" json:"name"`
+	Description          pgtype.Text      `db:"description" json:"description"`
+	CreatedBy            int32            `db:"created_by" json:"created_by"`
+	CreatedAt            pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt            pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	DisputeQuorum        int32            `db:"dispute_quorum" json:"dispute_quorum"`
+	DisputeWindowHours   int32            `db:"dispute_window_hours" json:"dispute_window_hours"`
+	VerificationRequired bool             `db:"verification_required" json:"verification_required"`
+	VerificationsNeeded  int32            `db:"verifications_needed" json:"verifications_needed"`
+	JoinApprovalRequired bool             `db:"join_approval_required" json:"join_approval_required"`
+	ArchivedAt           pgtype.Timestamp `db:"archived_at" json:"archived_at"`
 }
 
 type User struct {
@@ -69,7 +293,42 @@ type User struct {
 	Email     string           `db:"email" json:"email"`
 	Name      string           `db:"name" json:"name"`
 	Avatar    pgtype.Text      `db:"avatar" json:"avatar"`
-	GoogleID  string           `db:"google_id" json:"google_id"`
 	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt pgtype.Timestamp `db:"updated_at" json:"updated_at"`
 }
+
+type UserIdentity struct {
+	ID          int32            `db:"id" json:"id"`
+	UserID      int32            `db:"user_id" json:"user_id"`
+	Provider    string           `db:"provider" json:"provider"`
+	Subject     string           `db:"subject" json:"subject"`
+	Email       pgtype.Text      `db:"email" json:"email"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
+	LastLoginAt pgtype.Timestamp `db:"last_login_at" json:"last_login_at"`
+}
+
+type Webhook struct {
+	ID         int32            `db:"id" json:"id"`
+	JarID      int32            `db:"jar_id" json:"jar_id"`
+	Url        string           `db:"url" json:"url"`
+	Secret     string           `db:"secret" json:"secret"`
+	EventTypes []string         `db:"event_types" json:"event_types"`
+	IsActive   bool             `db:"is_active" json:"is_active"`
+	CreatedBy  int32            `db:"created_by" json:"created_by"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int32            `db:"id" json:"id"`
+	WebhookID      int32            `db:"webhook_id" json:"webhook_id"`
+	EventType      string           `db:"event_type" json:"event_type"`
+	Payload        string           `db:"payload" json:"payload"`
+	Status         string           `db:"status" json:"status"`
+	Attempts       int32            `db:"attempts" json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode pgtype.Int4      `db:"last_status_code" json:"last_status_code"`
+	LastError      pgtype.Text      `db:"last_error" json:"last_error"`
+	CreatedAt      pgtype.Timestamp `db:"created_at" json:"created_at"`
+	DeliveredAt    pgtype.Timestamp `db:"delivered_at" json:"delivered_at"`
+}
@@ -11,10 +11,43 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const carryOverOffenses = `-- name: CarryOverOffenses :execrows
+UPDATE offenses
+SET period_id = $1, updated_at = NOW()
+WHERE period_id = $2 AND status = 'pending'
+`
+
+type CarryOverOffensesParams struct {
+	ToPeriodID   int32 `db:"to_period_id" json:"to_period_id"`
+	FromPeriodID int32 `db:"from_period_id" json:"from_period_id"`
+}
+
+// Moves the offenses still owed in a closing period into the next one
+func (q *Queries) CarryOverOffenses(ctx context.Context, arg CarryOverOffensesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, carryOverOffenses, arg.ToPeriodID, arg.FromPeriodID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const countUnsettledOffensesInPeriod = `-- name: CountUnsettledOffensesInPeriod :one
+SELECT COUNT(*) FROM offenses
+WHERE period_id = $1 AND status IN ('disputed', 'verifying')
+`
+
+// Offenses with an open dispute or a payment awaiting verification
+func (q *Queries) CountUnsettledOffensesInPeriod(ctx context.Context, periodID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnsettledOffensesInPeriod, periodID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createOffense = `-- name: CreateOffense :one
-INSERT INTO offenses (jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at
+INSERT INTO offenses (jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, cost_amount, cost_unit, period_id, reported_period_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
 `
 
 type CreateOffenseParams struct {
@@ -24,8 +57,12 @@ type CreateOffenseParams struct {
 	OffenderID    int32          `db:"offender_id" json:"offender_id"`
 	Notes         pgtype.Text    `db:"notes" json:"notes"`
 	CostOverride  pgtype.Numeric `db:"cost_override" json:"cost_override"`
+	CostAmount    pgtype.Numeric `db:"cost_amount" json:"cost_amount"`
+	CostUnit      pgtype.Text    `db:"cost_unit" json:"cost_unit"`
+	PeriodID      int32          `db:"period_id" json:"period_id"`
 }
 
+// The offense is reported in, and counts towards, the given period
 func (q *Queries) CreateOffense(ctx context.Context, arg CreateOffenseParams) (Offense, error) {
 	row := q.db.QueryRow(ctx, createOffense,
 		arg.JarID,
@@ -34,6 +71,9 @@ func (q *Queries) CreateOffense(ctx context.Context, arg CreateOffenseParams) (O
 		arg.OffenderID,
 		arg.Notes,
 		arg.CostOverride,
+		arg.CostAmount,
+		arg.CostUnit,
+		arg.PeriodID,
 	)
 	var i Offense
 	err := row.Scan(
@@ -47,6 +87,10 @@ func (q *Queries) CreateOffense(ctx context.Context, arg CreateOffenseParams) (O
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostAmount,
+		&i.CostUnit,
+		&i.PeriodID,
+		&i.ReportedPeriodID,
 	)
 	return i, err
 }
@@ -56,33 +100,31 @@ SELECT
     u.id as user_id,
     u.name as user_name,
     u.avatar,
-    COALESCE(ot.cost_unit, 'items') as unit,
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed,
-    COUNT(*) as offense_count
-FROM users u
-INNER JOIN jar_memberships jm ON u.id = jm.user_id
-LEFT JOIN offenses o ON u.id = o.offender_id AND o.jar_id = $1 AND o.status = 'pending'
-LEFT JOIN offense_types ot ON o.offense_type_id = ot.id
+    COALESCE(o.cost_unit, 'items') as unit,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
+    COUNT(o.id) as offense_count
+FROM jar_memberships jm
+INNER JOIN users u ON jm.user_id = u.id
+INNER JOIN offenses o ON o.offender_id = jm.user_id AND o.jar_id = jm.jar_id AND o.status IN ('pending', 'verifying')
+    AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
 WHERE jm.jar_id = $1
-GROUP BY u.id, u.name, u.avatar, ot.cost_unit
-HAVING COUNT(o.id) > 0 OR ot.cost_unit IS NULL
-ORDER BY u.name, total_owed DESC
+    AND (o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0))
+GROUP BY jm.joined_at, u.id, u.name, u.avatar, COALESCE(o.cost_unit, 'items')
+ORDER BY jm.joined_at ASC, u.id, total_owed DESC
 `
 
 type GetJarBalancesByUnitRow struct {
-	UserID       int32       `db:"user_id" json:"user_id"`
-	UserName     string      `db:"user_name" json:"user_name"`
-	Avatar       pgtype.Text `db:"avatar" json:"avatar"`
-	Unit         string      `db:"unit" json:"unit"`
-	TotalOwed    interface{} `db:"total_owed" json:"total_owed"`
-	OffenseCount int64       `db:"offense_count" json:"offense_count"`
+	UserID       int32          `db:"user_id" json:"user_id"`
+	UserName     string         `db:"user_name" json:"user_name"`
+	Avatar       pgtype.Text    `db:"avatar" json:"avatar"`
+	Unit         string         `db:"unit" json:"unit"`
+	TotalOwed    pgtype.Numeric `db:"total_owed" json:"total_owed"`
+	OffenseCount int64          `db:"offense_count" json:"offense_count"`
 }
 
+// Offenses awaiting payment verification count while their payment leaves
+// something unpaid
 func (q *Queries) GetJarBalancesByUnit(ctx context.Context, jarID int32) ([]GetJarBalancesByUnitRow, error) {
 	rows, err := q.db.Query(ctx, getJarBalancesByUnit, jarID)
 	if err != nil {
@@ -110,8 +152,61 @@ func (q *Queries) GetJarBalancesByUnit(ctx context.Context, jarID int32) ([]GetJ
 	return items, nil
 }
 
+const getJarMemberBalances = `-- name: GetJarMemberBalances :many
+SELECT 
+    u.id as user_id,
+    u.name as user_name,
+    u.avatar,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
+    COUNT(o.id) FILTER (WHERE o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0)) as pending_count
+FROM jar_memberships jm
+INNER JOIN users u ON jm.user_id = u.id
+LEFT JOIN offenses o ON o.offender_id = jm.user_id AND o.jar_id = jm.jar_id AND o.status IN ('pending', 'verifying')
+    AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE jm.jar_id = $1
+GROUP BY jm.joined_at, u.id, u.name, u.avatar
+ORDER BY jm.joined_at ASC
+`
+
+type GetJarMemberBalancesRow struct {
+	UserID       int32          `db:"user_id" json:"user_id"`
+	UserName     string         `db:"user_name" json:"user_name"`
+	Avatar       pgtype.Text    `db:"avatar" json:"avatar"`
+	TotalOwed    pgtype.Numeric `db:"total_owed" json:"total_owed"`
+	PendingCount int64          `db:"pending_count" json:"pending_count"`
+}
+
+// Offenses awaiting payment verification count while their payment leaves
+// something unpaid
+func (q *Queries) GetJarMemberBalances(ctx context.Context, jarID int32) ([]GetJarMemberBalancesRow, error) {
+	rows, err := q.db.Query(ctx, getJarMemberBalances, jarID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetJarMemberBalancesRow
+	for rows.Next() {
+		var i GetJarMemberBalancesRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserName,
+			&i.Avatar,
+			&i.TotalOwed,
+			&i.PendingCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getOffense = `-- name: GetOffense :one
-SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at
+SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
 FROM offenses
 WHERE id = $1
 `
@@ -130,21 +225,49 @@ func (q *Queries) GetOffense(ctx context.Context, id int32) (Offense, error) {
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostAmount,
+		&i.CostUnit,
+		&i.PeriodID,
+		&i.ReportedPeriodID,
+	)
+	return i, err
+}
+
+const getOffenseForUpdate = `-- name: GetOffenseForUpdate :one
+SELECT id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
+FROM offenses
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetOffenseForUpdate(ctx context.Context, id int32) (Offense, error) {
+	row := q.db.QueryRow(ctx, getOffenseForUpdate, id)
+	var i Offense
+	err := row.Scan(
+		&i.ID,
+		&i.JarID,
+		&i.OffenseTypeID,
+		&i.ReporterID,
+		&i.OffenderID,
+		&i.Notes,
+		&i.CostOverride,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CostAmount,
+		&i.CostUnit,
+		&i.PeriodID,
+		&i.ReportedPeriodID,
 	)
 	return i, err
 }
 
 const getUserBalanceInJar = `-- name: GetUserBalanceInJar :one
 SELECT 
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed
 FROM offenses o
-INNER JOIN offense_types ot ON o.offense_type_id = ot.id
-WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending'
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status IN ('pending', 'verifying') AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
 `
 
 type GetUserBalanceInJarParams struct {
@@ -152,27 +275,25 @@ type GetUserBalanceInJarParams struct {
 	OffenderID int32 `db:"offender_id" json:"offender_id"`
 }
 
-func (q *Queries) GetUserBalanceInJar(ctx context.Context, arg GetUserBalanceInJarParams) (interface{}, error) {
+// Offenses awaiting payment verification still owe whatever that payment
+// leaves unpaid
+func (q *Queries) GetUserBalanceInJar(ctx context.Context, arg GetUserBalanceInJarParams) (pgtype.Numeric, error) {
 	row := q.db.QueryRow(ctx, getUserBalanceInJar, arg.JarID, arg.OffenderID)
-	var total_owed interface{}
+	var total_owed pgtype.Numeric
 	err := row.Scan(&total_owed)
 	return total_owed, err
 }
 
 const getUserBalancesByUnitInJar = `-- name: GetUserBalancesByUnitInJar :many
 SELECT 
-    COALESCE(ot.cost_unit, 'items') as unit,
-    COALESCE(SUM(
-        CASE 
-            WHEN o.cost_override IS NOT NULL THEN o.cost_override
-            ELSE ot.cost_amount
-        END
-    ), 0) as total_owed,
+    COALESCE(o.cost_unit, 'items') as unit,
+    COALESCE(SUM(o.cost_amount - COALESCE(opt.paid_amount, 0)), 0)::numeric as total_owed,
     COUNT(*) as offense_count
 FROM offenses o
-INNER JOIN offense_types ot ON o.offense_type_id = ot.id
-WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending'
-GROUP BY ot.cost_unit
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status IN ('pending', 'verifying') AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+    AND (o.status = 'pending' OR o.cost_amount > COALESCE(opt.paid_amount, 0))
+GROUP BY o.cost_unit
 ORDER BY total_owed DESC
 `
 
@@ -182,11 +303,13 @@ type GetUserBalancesByUnitInJarParams struct {
 }
 
 type GetUserBalancesByUnitInJarRow struct {
-	Unit         string      `db:"unit" json:"unit"`
-	TotalOwed    interface{} `db:"total_owed" json:"total_owed"`
-	OffenseCount int64       `db:"offense_count" json:"offense_count"`
+	Unit         string         `db:"unit" json:"unit"`
+	TotalOwed    pgtype.Numeric `db:"total_owed" json:"total_owed"`
+	OffenseCount int64          `db:"offense_count" json:"offense_count"`
 }
 
+// Offenses awaiting payment verification count while their payment leaves
+// something unpaid
 func (q *Queries) GetUserBalancesByUnitInJar(ctx context.Context, arg GetUserBalancesByUnitInJarParams) ([]GetUserBalancesByUnitInJarRow, error) {
 	rows, err := q.db.Query(ctx, getUserBalancesByUnitInJar, arg.JarID, arg.OffenderID)
 	if err != nil {
@@ -208,13 +331,15 @@ func (q *Queries) GetUserBalancesByUnitInJar(ctx context.Context, arg GetUserBal
 }
 
 const listOffensesForJar = `-- name: ListOffensesForJar :many
-SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at,
-       ot.name as offense_type_name, ot.cost_amount, ot.cost_unit,
-       reporter.name as reporter_name, offender.name as offender_name
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
+       reporter.name as reporter_name, offender.name as offender_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
 FROM offenses o
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 INNER JOIN users reporter ON o.reporter_id = reporter.id
 INNER JOIN users offender ON o.offender_id = offender.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
 WHERE o.jar_id = $1
 ORDER BY o.created_at DESC
 LIMIT $2 OFFSET $3
@@ -237,11 +362,12 @@ type ListOffensesForJarRow struct {
 	Status          string           `db:"status" json:"status"`
 	CreatedAt       pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt       pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
 	CostAmount      pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
 	CostUnit        pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
 	ReporterName    string           `db:"reporter_name" json:"reporter_name"`
 	OffenderName    string           `db:"offender_name" json:"offender_name"`
+	PaidAmount      pgtype.Numeric   `db:"paid_amount" json:"paid_amount"`
 }
 
 func (q *Queries) ListOffensesForJar(ctx context.Context, arg ListOffensesForJarParams) ([]ListOffensesForJarRow, error) {
@@ -264,11 +390,95 @@ func (q *Queries) ListOffensesForJar(ctx context.Context, arg ListOffensesForJar
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CostAmount,
+			&i.CostUnit,
 			&i.OffenseTypeName,
+			&i.ReporterName,
+			&i.OffenderName,
+			&i.PaidAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOffensesForPeriod = `-- name: ListOffensesForPeriod :many
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit, o.period_id, o.reported_period_id,
+       ot.name as offense_type_name,
+       reporter.name as reporter_name, offender.name as offender_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
+FROM offenses o
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+INNER JOIN users reporter ON o.reporter_id = reporter.id
+INNER JOIN users offender ON o.offender_id = offender.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.period_id = $1 OR o.reported_period_id = $1
+ORDER BY o.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListOffensesForPeriodParams struct {
+	PeriodID int32 `db:"period_id" json:"period_id"`
+	Limit    int32 `db:"limit" json:"limit"`
+	Offset   int32 `db:"offset" json:"offset"`
+}
+
+type ListOffensesForPeriodRow struct {
+	ID               int32            `db:"id" json:"id"`
+	JarID            int32            `db:"jar_id" json:"jar_id"`
+	OffenseTypeID    int32            `db:"offense_type_id" json:"offense_type_id"`
+	ReporterID       int32            `db:"reporter_id" json:"reporter_id"`
+	OffenderID       int32            `db:"offender_id" json:"offender_id"`
+	Notes            pgtype.Text      `db:"notes" json:"notes"`
+	CostOverride     pgtype.Numeric   `db:"cost_override" json:"cost_override"`
+	Status           string           `db:"status" json:"status"`
+	CreatedAt        pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt        pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	CostAmount       pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
+	CostUnit         pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	PeriodID         int32            `db:"period_id" json:"period_id"`
+	ReportedPeriodID int32            `db:"reported_period_id" json:"reported_period_id"`
+	OffenseTypeName  string           `db:"offense_type_name" json:"offense_type_name"`
+	ReporterName     string           `db:"reporter_name" json:"reporter_name"`
+	OffenderName     string           `db:"offender_name" json:"offender_name"`
+	PaidAmount       pgtype.Numeric   `db:"paid_amount" json:"paid_amount"`
+}
+
+// The period's ledger: what was reported in it and what counted towards it,
+// which includes offenses carried in from earlier periods
+func (q *Queries) ListOffensesForPeriod(ctx context.Context, arg ListOffensesForPeriodParams) ([]ListOffensesForPeriodRow, error) {
+	rows, err := q.db.Query(ctx, listOffensesForPeriod, arg.PeriodID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOffensesForPeriodRow
+	for rows.Next() {
+		var i ListOffensesForPeriodRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.JarID,
+			&i.OffenseTypeID,
+			&i.ReporterID,
+			&i.OffenderID,
+			&i.Notes,
+			&i.CostOverride,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 			&i.CostAmount,
 			&i.CostUnit,
+			&i.PeriodID,
+			&i.ReportedPeriodID,
+			&i.OffenseTypeName,
 			&i.ReporterName,
 			&i.OffenderName,
+			&i.PaidAmount,
 		); err != nil {
 			return nil, err
 		}
@@ -281,13 +491,13 @@ func (q *Queries) ListOffensesForJar(ctx context.Context, arg ListOffensesForJar
 }
 
 const listPendingOffensesForUser = `-- name: ListPendingOffensesForUser :many
-SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at,
-       ot.name as offense_type_name, ot.cost_amount, ot.cost_unit,
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
        tj.name as jar_name
 FROM offenses o
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 INNER JOIN tip_jars tj ON o.jar_id = tj.id
-WHERE o.offender_id = $1 AND o.status = 'pending'
+WHERE o.offender_id = $1 AND o.status = 'pending' AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
 ORDER BY o.created_at DESC
 `
 
@@ -302,9 +512,9 @@ type ListPendingOffensesForUserRow struct {
 	Status          string           `db:"status" json:"status"`
 	CreatedAt       pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt       pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
 	CostAmount      pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
 	CostUnit        pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
 	JarName         string           `db:"jar_name" json:"jar_name"`
 }
 
@@ -328,9 +538,9 @@ func (q *Queries) ListPendingOffensesForUser(ctx context.Context, offenderID int
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.OffenseTypeName,
 			&i.CostAmount,
 			&i.CostUnit,
+			&i.OffenseTypeName,
 			&i.JarName,
 		); err != nil {
 			return nil, err
@@ -343,20 +553,125 @@ func (q *Queries) ListPendingOffensesForUser(ctx context.Context, offenderID int
 	return items, nil
 }
 
-const updateOffenseStatus = `-- name: UpdateOffenseStatus :one
+const listPendingOffensesForUserInJar = `-- name: ListPendingOffensesForUserInJar :many
+SELECT o.id, o.jar_id, o.offense_type_id, o.reporter_id, o.offender_id, o.notes, o.cost_override, o.status, o.created_at, o.updated_at, o.cost_amount, o.cost_unit,
+       ot.name as offense_type_name,
+       COALESCE(opt.paid_amount, 0)::numeric as paid_amount
+FROM offenses o
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+LEFT JOIN offense_payment_totals opt ON opt.offense_id = o.id
+WHERE o.jar_id = $1 AND o.offender_id = $2 AND o.status = 'pending' AND o.period_id IN (SELECT id FROM jar_periods WHERE closed_at IS NULL)
+ORDER BY o.created_at ASC
+`
+
+type ListPendingOffensesForUserInJarParams struct {
+	JarID      int32 `db:"jar_id" json:"jar_id"`
+	OffenderID int32 `db:"offender_id" json:"offender_id"`
+}
+
+type ListPendingOffensesForUserInJarRow struct {
+	ID              int32            `db:"id" json:"id"`
+	JarID           int32            `db:"jar_id" json:"jar_id"`
+	OffenseTypeID   int32            `db:"offense_type_id" json:"offense_type_id"`
+	ReporterID      int32            `db:"reporter_id" json:"reporter_id"`
+	OffenderID      int32            `db:"offender_id" json:"offender_id"`
+	Notes           pgtype.Text      `db:"notes" json:"notes"`
+	CostOverride    pgtype.Numeric   `db:"cost_override" json:"cost_override"`
+	Status          string           `db:"status" json:"status"`
+	CreatedAt       pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	CostAmount      pgtype.Numeric   `db:"cost_amount" json:"cost_amount"`
+	CostUnit        pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
+	PaidAmount      pgtype.Numeric   `db:"paid_amount" json:"paid_amount"`
+}
+
+func (q *Queries) ListPendingOffensesForUserInJar(ctx context.Context, arg ListPendingOffensesForUserInJarParams) ([]ListPendingOffensesForUserInJarRow, error) {
+	rows, err := q.db.Query(ctx, listPendingOffensesForUserInJar, arg.JarID, arg.OffenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPendingOffensesForUserInJarRow
+	for rows.Next() {
+		var i ListPendingOffensesForUserInJarRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.JarID,
+			&i.OffenseTypeID,
+			&i.ReporterID,
+			&i.OffenderID,
+			&i.Notes,
+			&i.CostOverride,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CostAmount,
+			&i.CostUnit,
+			&i.OffenseTypeName,
+			&i.PaidAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateOffenseCost = `-- name: UpdateOffenseCost :one
 UPDATE offenses
-SET status = $2, updated_at = NOW()
+SET cost_amount = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
+`
+
+type UpdateOffenseCostParams struct {
+	ID         int32          `db:"id" json:"id"`
+	CostAmount pgtype.Numeric `db:"cost_amount" json:"cost_amount"`
+}
+
+func (q *Queries) UpdateOffenseCost(ctx context.Context, arg UpdateOffenseCostParams) (Offense, error) {
+	row := q.db.QueryRow(ctx, updateOffenseCost, arg.ID, arg.CostAmount)
+	var i Offense
+	err := row.Scan(
+		&i.ID,
+		&i.JarID,
+		&i.OffenseTypeID,
+		&i.ReporterID,
+		&i.OffenderID,
+		&i.Notes,
+		&i.CostOverride,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CostAmount,
+		&i.CostUnit,
+		&i.PeriodID,
+		&i.ReportedPeriodID,
+	)
+	return i, err
+}
+
+const updateOffenseStatusInPeriod = `-- name: UpdateOffenseStatusInPeriod :one
+UPDATE offenses
+SET status = $2, updated_at = NOW()
+WHERE id = $1 AND period_id = $3
+RETURNING id, jar_id, offense_type_id, reporter_id, offender_id, notes, cost_override, status, created_at, updated_at, cost_amount, cost_unit, period_id, reported_period_id
 `
 
-type UpdateOffenseStatusParams struct {
-	ID     int32  `db:"id" json:"id"`
-	Status string `db:"status" json:"status"`
+type UpdateOffenseStatusInPeriodParams struct {
+	ID       int32  `db:"id" json:"id"`
+	Status   string `db:"status" json:"status"`
+	PeriodID int32  `db:"period_id" json:"period_id"`
 }
 
-func (q *Queries) UpdateOffenseStatus(ctx context.Context, arg UpdateOffenseStatusParams) (Offense, error) {
-	row := q.db.QueryRow(ctx, updateOffenseStatus, arg.ID, arg.Status)
+// Callers hold a lock on the period (see openPeriod), so the offense can't be
+// carried out of it while its status changes
+func (q *Queries) UpdateOffenseStatusInPeriod(ctx context.Context, arg UpdateOffenseStatusInPeriodParams) (Offense, error) {
+	row := q.db.QueryRow(ctx, updateOffenseStatusInPeriod, arg.ID, arg.Status, arg.PeriodID)
 	var i Offense
 	err := row.Scan(
 		&i.ID,
@@ -369,6 +684,10 @@ func (q *Queries) UpdateOffenseStatus(ctx context.Context, arg UpdateOffenseStat
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostAmount,
+		&i.CostUnit,
+		&i.PeriodID,
+		&i.ReportedPeriodID,
 	)
 	return i, err
 }
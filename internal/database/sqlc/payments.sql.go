@@ -11,14 +11,45 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createBatchPayment = `-- name: CreateBatchPayment :one
+INSERT INTO payments (user_id, proof_type, proof_url)
+VALUES ($1, $2, $3)
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
+`
+
+type CreateBatchPaymentParams struct {
+	UserID    int32       `db:"user_id" json:"user_id"`
+	ProofType pgtype.Text `db:"proof_type" json:"proof_type"`
+	ProofUrl  pgtype.Text `db:"proof_url" json:"proof_url"`
+}
+
+func (q *Queries) CreateBatchPayment(ctx context.Context, arg CreateBatchPaymentParams) (Payment, error) {
+	row := q.db.QueryRow(ctx, createBatchPayment, arg.UserID, arg.ProofType, arg.ProofUrl)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OffenseID,
+		&i.UserID,
+		&i.Amount,
+		&i.ProofType,
+		&i.ProofUrl,
+		&i.Verified,
+		&i.VerifiedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rejected,
+	)
+	return i, err
+}
+
 const createPayment = `-- name: CreatePayment :one
 INSERT INTO payments (offense_id, user_id, amount, proof_type, proof_url)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
 `
 
 type CreatePaymentParams struct {
-	OffenseID int32          `db:"offense_id" json:"offense_id"`
+	OffenseID pgtype.Int4    `db:"offense_id" json:"offense_id"`
 	UserID    int32          `db:"user_id" json:"user_id"`
 	Amount    pgtype.Numeric `db:"amount" json:"amount"`
 	ProofType pgtype.Text    `db:"proof_type" json:"proof_type"`
@@ -45,12 +76,45 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (P
 		&i.VerifiedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rejected,
 	)
 	return i, err
 }
 
+const deletePaymentsForJar = `-- name: DeletePaymentsForJar :many
+DELETE FROM payments
+WHERE id IN (
+    SELECT pa.payment_id
+    FROM payment_allocations pa
+    INNER JOIN offenses o ON pa.offense_id = o.id
+    WHERE o.jar_id = $1
+)
+RETURNING proof_url
+`
+
+// Returns the proofs of the deleted payments so the files can be removed too
+func (q *Queries) DeletePaymentsForJar(ctx context.Context, jarID int32) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, deletePaymentsForJar, jarID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var proof_url pgtype.Text
+		if err := rows.Scan(&proof_url); err != nil {
+			return nil, err
+		}
+		items = append(items, proof_url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPayment = `-- name: GetPayment :one
-SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
+SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
 FROM payments
 WHERE id = $1
 `
@@ -69,26 +133,156 @@ func (q *Queries) GetPayment(ctx context.Context, id int32) (Payment, error) {
 		&i.VerifiedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rejected,
 	)
 	return i, err
 }
 
-const listPaymentsForOffense = `-- name: ListPaymentsForOffense :many
-SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
+const getPaymentForUpdate = `-- name: GetPaymentForUpdate :one
+SELECT id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
 FROM payments
-WHERE offense_id = $1
-ORDER BY created_at DESC
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetPaymentForUpdate(ctx context.Context, id int32) (Payment, error) {
+	row := q.db.QueryRow(ctx, getPaymentForUpdate, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OffenseID,
+		&i.UserID,
+		&i.Amount,
+		&i.ProofType,
+		&i.ProofUrl,
+		&i.Verified,
+		&i.VerifiedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rejected,
+	)
+	return i, err
+}
+
+const listPaymentsAwaitingVerification = `-- name: ListPaymentsAwaitingVerification :many
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
+       pa.offense_id as allocated_offense_id, pa.amount as allocated_amount,
+       o.jar_id, tj.name as jar_name, ot.name as offense_type_name,
+       o.offender_id, offender.name as offender_name, o.cost_unit,
+       pv.approved as my_verdict, jm.role as my_role
+FROM payments p
+INNER JOIN payment_allocations pa ON pa.payment_id = p.id
+INNER JOIN offenses o ON pa.offense_id = o.id
+INNER JOIN tip_jars tj ON o.jar_id = tj.id
+INNER JOIN offense_types ot ON o.offense_type_id = ot.id
+INNER JOIN users offender ON o.offender_id = offender.id
+INNER JOIN jar_memberships jm ON jm.jar_id = o.jar_id AND jm.user_id = $1
+LEFT JOIN payment_verifications pv ON pv.payment_id = p.id AND pv.user_id = $1
+WHERE o.status = 'verifying' AND NOT p.verified AND NOT p.rejected
+  AND o.offender_id <> $1 AND p.user_id <> $1
+  AND tj.archived_at IS NULL
+ORDER BY p.created_at ASC, p.id, pa.id
+`
+
+type ListPaymentsAwaitingVerificationRow struct {
+	ID                 int32            `db:"id" json:"id"`
+	OffenseID          pgtype.Int4      `db:"offense_id" json:"offense_id"`
+	UserID             int32            `db:"user_id" json:"user_id"`
+	Amount             pgtype.Numeric   `db:"amount" json:"amount"`
+	ProofType          pgtype.Text      `db:"proof_type" json:"proof_type"`
+	ProofUrl           pgtype.Text      `db:"proof_url" json:"proof_url"`
+	Verified           bool             `db:"verified" json:"verified"`
+	VerifiedBy         pgtype.Int4      `db:"verified_by" json:"verified_by"`
+	CreatedAt          pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Rejected           bool             `db:"rejected" json:"rejected"`
+	AllocatedOffenseID int32            `db:"allocated_offense_id" json:"allocated_offense_id"`
+	AllocatedAmount    pgtype.Numeric   `db:"allocated_amount" json:"allocated_amount"`
+	JarID              int32            `db:"jar_id" json:"jar_id"`
+	JarName            string           `db:"jar_name" json:"jar_name"`
+	OffenseTypeName    string           `db:"offense_type_name" json:"offense_type_name"`
+	OffenderID         int32            `db:"offender_id" json:"offender_id"`
+	OffenderName       string           `db:"offender_name" json:"offender_name"`
+	CostUnit           pgtype.Text      `db:"cost_unit" json:"cost_unit"`
+	MyVerdict          pgtype.Bool      `db:"my_verdict" json:"my_verdict"`
+	MyRole             string           `db:"my_role" json:"my_role"`
+}
+
+func (q *Queries) ListPaymentsAwaitingVerification(ctx context.Context, userID int32) ([]ListPaymentsAwaitingVerificationRow, error) {
+	rows, err := q.db.Query(ctx, listPaymentsAwaitingVerification, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPaymentsAwaitingVerificationRow
+	for rows.Next() {
+		var i ListPaymentsAwaitingVerificationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OffenseID,
+			&i.UserID,
+			&i.Amount,
+			&i.ProofType,
+			&i.ProofUrl,
+			&i.Verified,
+			&i.VerifiedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rejected,
+			&i.AllocatedOffenseID,
+			&i.AllocatedAmount,
+			&i.JarID,
+			&i.JarName,
+			&i.OffenseTypeName,
+			&i.OffenderID,
+			&i.OffenderName,
+			&i.CostUnit,
+			&i.MyVerdict,
+			&i.MyRole,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentsForOffense = `-- name: ListPaymentsForOffense :many
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
+       pa.amount as allocated_amount
+FROM payments p
+INNER JOIN payment_allocations pa ON pa.payment_id = p.id
+WHERE pa.offense_id = $1
+ORDER BY p.created_at DESC
 `
 
-func (q *Queries) ListPaymentsForOffense(ctx context.Context, offenseID int32) ([]Payment, error) {
+type ListPaymentsForOffenseRow struct {
+	ID              int32            `db:"id" json:"id"`
+	OffenseID       pgtype.Int4      `db:"offense_id" json:"offense_id"`
+	UserID          int32            `db:"user_id" json:"user_id"`
+	Amount          pgtype.Numeric   `db:"amount" json:"amount"`
+	ProofType       pgtype.Text      `db:"proof_type" json:"proof_type"`
+	ProofUrl        pgtype.Text      `db:"proof_url" json:"proof_url"`
+	Verified        bool             `db:"verified" json:"verified"`
+	VerifiedBy      pgtype.Int4      `db:"verified_by" json:"verified_by"`
+	CreatedAt       pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Rejected        bool             `db:"rejected" json:"rejected"`
+	AllocatedAmount pgtype.Numeric   `db:"allocated_amount" json:"allocated_amount"`
+}
+
+func (q *Queries) ListPaymentsForOffense(ctx context.Context, offenseID int32) ([]ListPaymentsForOffenseRow, error) {
 	rows, err := q.db.Query(ctx, listPaymentsForOffense, offenseID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Payment
+	var items []ListPaymentsForOffenseRow
 	for rows.Next() {
-		var i Payment
+		var i ListPaymentsForOffenseRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.OffenseID,
@@ -100,6 +294,8 @@ func (q *Queries) ListPaymentsForOffense(ctx context.Context, offenseID int32) (
 			&i.VerifiedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rejected,
+			&i.AllocatedAmount,
 		); err != nil {
 			return nil, err
 		}
@@ -112,7 +308,7 @@ func (q *Queries) ListPaymentsForOffense(ctx context.Context, offenseID int32) (
 }
 
 const listPaymentsForUser = `-- name: ListPaymentsForUser :many
-SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at,
+SELECT p.id, p.offense_id, p.user_id, p.amount, p.proof_type, p.proof_url, p.verified, p.verified_by, p.created_at, p.updated_at, p.rejected,
        o.jar_id, tj.name as jar_name, ot.name as offense_type_name
 FROM payments p
 INNER JOIN offenses o ON p.offense_id = o.id
@@ -120,7 +316,7 @@ INNER JOIN tip_jars tj ON o.jar_id = tj.id
 INNER JOIN offense_types ot ON o.offense_type_id = ot.id
 WHERE p.user_id = $1
 ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3
+LIMIT $2 OFFSET $3;
 `
 
 type ListPaymentsForUserParams struct {
@@ -131,7 +327,7 @@ type ListPaymentsForUserParams struct {
 
 type ListPaymentsForUserRow struct {
 	ID              int32            `db:"id" json:"id"`
-	OffenseID       int32            `db:"offense_id" json:"offense_id"`
+	OffenseID       pgtype.Int4      `db:"offense_id" json:"offense_id"`
 	UserID          int32            `db:"user_id" json:"user_id"`
 	Amount          pgtype.Numeric   `db:"amount" json:"amount"`
 	ProofType       pgtype.Text      `db:"proof_type" json:"proof_type"`
@@ -140,6 +336,7 @@ type ListPaymentsForUserRow struct {
 	VerifiedBy      pgtype.Int4      `db:"verified_by" json:"verified_by"`
 	CreatedAt       pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt       pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Rejected        bool             `db:"rejected" json:"rejected"`
 	JarID           int32            `db:"jar_id" json:"jar_id"`
 	JarName         string           `db:"jar_name" json:"jar_name"`
 	OffenseTypeName string           `db:"offense_type_name" json:"offense_type_name"`
@@ -165,6 +362,7 @@ func (q *Queries) ListPaymentsForUser(ctx context.Context, arg ListPaymentsForUs
 			&i.VerifiedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rejected,
 			&i.JarID,
 			&i.JarName,
 			&i.OffenseTypeName,
@@ -179,11 +377,37 @@ func (q *Queries) ListPaymentsForUser(ctx context.Context, arg ListPaymentsForUs
 	return items, nil
 }
 
+const rejectPayment = `-- name: RejectPayment :one
+UPDATE payments
+SET rejected = true, updated_at = NOW()
+WHERE id = $1
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
+`
+
+func (q *Queries) RejectPayment(ctx context.Context, id int32) (Payment, error) {
+	row := q.db.QueryRow(ctx, rejectPayment, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OffenseID,
+		&i.UserID,
+		&i.Amount,
+		&i.ProofType,
+		&i.ProofUrl,
+		&i.Verified,
+		&i.VerifiedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Rejected,
+	)
+	return i, err
+}
+
 const verifyPayment = `-- name: VerifyPayment :one
 UPDATE payments
 SET verified = true, verified_by = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at
+RETURNING id, offense_id, user_id, amount, proof_type, proof_url, verified, verified_by, created_at, updated_at, rejected
 `
 
 type VerifyPaymentParams struct {
@@ -205,6 +429,7 @@ func (q *Queries) VerifyPayment(ctx context.Context, arg VerifyPaymentParams) (P
 		&i.VerifiedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rejected,
 	)
 	return i, err
 }
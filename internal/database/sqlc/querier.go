@@ -6,46 +6,171 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	ArchiveTipJar(ctx context.Context, id int32) (TipJar, error)
+	CarryOverOffenses(ctx context.Context, arg CarryOverOffensesParams) (int64, error)
+	ClaimDueDigests(ctx context.Context, limit int32) ([]ClaimDueDigestsRow, error)
+	ClaimDueEmails(ctx context.Context, limit int32) ([]EmailOutbox, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error)
+	CloseJarPeriod(ctx context.Context, arg CloseJarPeriodParams) (JarPeriod, error)
+	CountOffensesAgainstSince(ctx context.Context, arg CountOffensesAgainstSinceParams) (int64, error)
+	CountPaymentVerifications(ctx context.Context, paymentID int32) (CountPaymentVerificationsRow, error)
+	CountRepeatOffensesSince(ctx context.Context, arg CountRepeatOffensesSinceParams) (int64, error)
+	CountUnreadNotifications(ctx context.Context, userID int32) (int64, error)
+	CountUnsettledOffensesInPeriod(ctx context.Context, periodID int32) (int64, error)
+	CountUserIdentities(ctx context.Context, userID int32) (int64, error)
+	CreateApiToken(ctx context.Context, arg CreateApiTokenParams) (ApiToken, error)
+	CreateBatchPayment(ctx context.Context, arg CreateBatchPaymentParams) (Payment, error)
+	CreateChatLinkRequest(ctx context.Context, arg CreateChatLinkRequestParams) (ChatLinkRequest, error)
+	CreateDispute(ctx context.Context, arg CreateDisputeParams) (OffenseDispute, error)
+	CreateEmail(ctx context.Context, arg CreateEmailParams) error
+	CreateInvite(ctx context.Context, arg CreateInviteParams) (Invite, error)
+	CreateInviteRedemption(ctx context.Context, arg CreateInviteRedemptionParams) error
+	CreateJarEvent(ctx context.Context, arg CreateJarEventParams) (JarEvent, error)
 	CreateJarMembership(ctx context.Context, arg CreateJarMembershipParams) (JarMembership, error)
+	CreateJarPeriod(ctx context.Context, arg CreateJarPeriodParams) (JarPeriod, error)
+	CreateJoinRequest(ctx context.Context, arg CreateJoinRequestParams) (JoinRequest, error)
+	CreateNotification(ctx context.Context, arg CreateNotificationParams) error
 	CreateOffense(ctx context.Context, arg CreateOffenseParams) (Offense, error)
 	CreateOffenseType(ctx context.Context, arg CreateOffenseTypeParams) (CreateOffenseTypeRow, error)
+	CreateOffenseTypePrice(ctx context.Context, arg CreateOffenseTypePriceParams) (OffenseTypePrice, error)
 	CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error)
+	CreatePaymentAllocation(ctx context.Context, arg CreatePaymentAllocationParams) (PaymentAllocation, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTipJar(ctx context.Context, arg CreateTipJarParams) (TipJar, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	DeleteChatAccount(ctx context.Context, arg DeleteChatAccountParams) (int64, error)
+	DeleteChatLinkRequests(ctx context.Context, arg DeleteChatLinkRequestsParams) error
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
 	DeleteJarMembership(ctx context.Context, arg DeleteJarMembershipParams) error
+	DeletePaymentsForJar(ctx context.Context, jarID int32) ([]pgtype.Text, error)
+	DeleteSession(ctx context.Context, arg DeleteSessionParams) error
+	DeleteSessionByTokenHash(ctx context.Context, tokenHash string) error
+	DeleteSessionsForUser(ctx context.Context, userID int32) error
 	DeleteTipJar(ctx context.Context, id int32) error
+	DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) error
+	DeleteWebhook(ctx context.Context, id int32) error
+	EnqueueImmediateEmail(ctx context.Context, arg EnqueueImmediateEmailParams) error
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error)
+	ExpireStaleEmails(ctx context.Context, createdAt pgtype.Timestamp) (int64, error)
+	GetApiTokenByHash(ctx context.Context, tokenHash string) (ApiToken, error)
+	GetChatAccount(ctx context.Context, arg GetChatAccountParams) (ChatAccount, error)
+	GetChatChannel(ctx context.Context, arg GetChatChannelParams) (ChatChannel, error)
+	GetChatLinkRequestByHash(ctx context.Context, tokenHash string) (ChatLinkRequest, error)
+	GetDispute(ctx context.Context, id int32) (OffenseDispute, error)
+	GetDisputeByOffense(ctx context.Context, offenseID int32) (OffenseDispute, error)
+	GetDisputeForUpdate(ctx context.Context, id int32) (OffenseDispute, error)
+	GetEmailSettings(ctx context.Context, userID int32) (EmailSetting, error)
+	GetInvite(ctx context.Context, id int32) (Invite, error)
+	GetInviteByCode(ctx context.Context, code string) (Invite, error)
+	GetInviteByLegacyCode(ctx context.Context, legacyCode pgtype.Text) (Invite, error)
 	GetJarBalancesByUnit(ctx context.Context, jarID int32) ([]GetJarBalancesByUnitRow, error)
+	GetJarMemberBalances(ctx context.Context, jarID int32) ([]GetJarMemberBalancesRow, error)
 	GetJarMembership(ctx context.Context, arg GetJarMembershipParams) (JarMembership, error)
+	GetJarPeriod(ctx context.Context, id int32) (JarPeriod, error)
+	GetJarPeriodForShare(ctx context.Context, id int32) (JarPeriod, error)
+	GetJarRules(ctx context.Context, jarID int32) (JarRule, error)
+	GetJoinRequest(ctx context.Context, id int32) (JoinRequest, error)
 	GetOffense(ctx context.Context, id int32) (Offense, error)
+	GetOffenseForUpdate(ctx context.Context, id int32) (Offense, error)
+	GetOffensePaidAmount(ctx context.Context, offenseID int32) (pgtype.Numeric, error)
 	GetOffenseType(ctx context.Context, id int32) (GetOffenseTypeRow, error)
+	GetOpenJarPeriod(ctx context.Context, jarID int32) (JarPeriod, error)
+	GetOpenJarPeriodForShare(ctx context.Context, jarID int32) (JarPeriod, error)
+	GetOpenJarPeriodForUpdate(ctx context.Context, jarID int32) (JarPeriod, error)
 	GetPayment(ctx context.Context, id int32) (Payment, error)
+	GetPaymentForUpdate(ctx context.Context, id int32) (Payment, error)
+	GetPendingJoinRequest(ctx context.Context, arg GetPendingJoinRequestParams) (JoinRequest, error)
+	GetReportCooldownRemaining(ctx context.Context, arg GetReportCooldownRemainingParams) (int32, error)
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (Session, error)
 	GetTipJar(ctx context.Context, id int32) (TipJar, error)
-	GetTipJarByInviteCode(ctx context.Context, inviteCode string) (TipJar, error)
-	GetUserBalanceInJar(ctx context.Context, arg GetUserBalanceInJarParams) (interface{}, error)
+	GetUserBalanceInJar(ctx context.Context, arg GetUserBalanceInJarParams) (pgtype.Numeric, error)
 	GetUserBalancesByUnitInJar(ctx context.Context, arg GetUserBalancesByUnitInJarParams) ([]GetUserBalancesByUnitInJarRow, error)
-	GetUserByGoogleID(ctx context.Context, googleID string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id int32) (User, error)
-	IsUserJarAdmin(ctx context.Context, arg IsUserJarAdminParams) (bool, error)
+	GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	GetWebhook(ctx context.Context, id int32) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id int32) (WebhookDelivery, error)
 	IsUserJarMember(ctx context.Context, arg IsUserJarMemberParams) (bool, error)
 	ListAllOffenseTypesForJar(ctx context.Context, jarID int32) ([]ListAllOffenseTypesForJarRow, error)
+	ListAllocationsForPayment(ctx context.Context, paymentID int32) ([]ListAllocationsForPaymentRow, error)
+	ListApiTokensForUser(ctx context.Context, userID int32) ([]ApiToken, error)
+	ListDisputeVotes(ctx context.Context, disputeID int32) ([]DisputeVote, error)
+	ListDisputeVotesForJar(ctx context.Context, jarID int32) ([]ListDisputeVotesForJarRow, error)
+	ListDisputesForJar(ctx context.Context, arg ListDisputesForJarParams) ([]ListDisputesForJarRow, error)
+	ListExpiredDisputes(ctx context.Context) ([]OffenseDispute, error)
+	ListInviteRedemptionsForJar(ctx context.Context, jarID int32) ([]ListInviteRedemptionsForJarRow, error)
+	ListInvitesForJar(ctx context.Context, jarID int32) ([]Invite, error)
+	ListJarEvents(ctx context.Context, arg ListJarEventsParams) ([]ListJarEventsRow, error)
 	ListJarMembers(ctx context.Context, jarID int32) ([]ListJarMembersRow, error)
+	ListJarPeriodBalances(ctx context.Context, periodID int32) ([]ListJarPeriodBalancesRow, error)
+	ListJarPeriods(ctx context.Context, jarID int32) ([]ListJarPeriodsRow, error)
+	ListNotificationPreferences(ctx context.Context, userID int32) ([]NotificationPreference, error)
+	ListNotificationsForUser(ctx context.Context, arg ListNotificationsForUserParams) ([]ListNotificationsForUserRow, error)
+	ListOffenseTypePricesForJar(ctx context.Context, jarID int32) ([]ListOffenseTypePricesForJarRow, error)
 	ListOffenseTypesForJar(ctx context.Context, jarID int32) ([]ListOffenseTypesForJarRow, error)
 	ListOffensesForJar(ctx context.Context, arg ListOffensesForJarParams) ([]ListOffensesForJarRow, error)
-	ListPaymentsForOffense(ctx context.Context, offenseID int32) ([]Payment, error)
+	ListOffensesForPeriod(ctx context.Context, arg ListOffensesForPeriodParams) ([]ListOffensesForPeriodRow, error)
+	ListPaymentsAwaitingVerification(ctx context.Context, userID int32) ([]ListPaymentsAwaitingVerificationRow, error)
+	ListPaymentsForOffense(ctx context.Context, offenseID int32) ([]ListPaymentsForOffenseRow, error)
 	ListPaymentsForUser(ctx context.Context, arg ListPaymentsForUserParams) ([]ListPaymentsForUserRow, error)
+	ListPendingJoinRequestsForJar(ctx context.Context, jarID int32) ([]ListPendingJoinRequestsForJarRow, error)
+	ListPendingJoinRequestsForUser(ctx context.Context, userID int32) ([]ListPendingJoinRequestsForUserRow, error)
 	ListPendingOffensesForUser(ctx context.Context, offenderID int32) ([]ListPendingOffensesForUserRow, error)
+	ListPendingOffensesForUserInJar(ctx context.Context, arg ListPendingOffensesForUserInJarParams) ([]ListPendingOffensesForUserInJarRow, error)
+	ListSessionsForUser(ctx context.Context, userID int32) ([]Session, error)
 	ListTipJarsForUser(ctx context.Context, userID int32) ([]TipJar, error)
 	ListTipJarsForUserWithMemberCount(ctx context.Context, userID int32) ([]ListTipJarsForUserWithMemberCountRow, error)
+	ListUserIdentities(ctx context.Context, userID int32) ([]UserIdentity, error)
 	ListUsers(ctx context.Context) ([]User, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooksForJar(ctx context.Context, jarID int32) ([]Webhook, error)
+	LockJarMembership(ctx context.Context, arg LockJarMembershipParams) (JarMembership, error)
+	MarkAllNotificationsRead(ctx context.Context, userID int32) error
+	MarkEmailSent(ctx context.Context, id int32) error
+	MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) (Notification, error)
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error
+	RecordEmailFailure(ctx context.Context, arg RecordEmailFailureParams) error
+	RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) error
+	RejectPayment(ctx context.Context, id int32) (Payment, error)
+	ReleaseInviteUse(ctx context.Context, id int32) error
+	ReleaseWebhookDelivery(ctx context.Context, id int32) error
+	ResolveDispute(ctx context.Context, arg ResolveDisputeParams) (OffenseDispute, error)
+	RestoreTipJar(ctx context.Context, id int32) (TipJar, error)
+	ReviewJoinRequest(ctx context.Context, arg ReviewJoinRequestParams) (JoinRequest, error)
+	RevokeApiToken(ctx context.Context, arg RevokeApiTokenParams) (int64, error)
+	RevokeInvite(ctx context.Context, id int32) (int64, error)
 	SetOffenseTypeActiveStatus(ctx context.Context, arg SetOffenseTypeActiveStatusParams) (SetOffenseTypeActiveStatusRow, error)
+	SetWebhookActive(ctx context.Context, arg SetWebhookActiveParams) error
+	SnapshotJarPeriodBalances(ctx context.Context, periodID int32) error
+	TouchApiToken(ctx context.Context, id int32) error
+	TouchSession(ctx context.Context, id int32) error
+	TouchUserIdentity(ctx context.Context, arg TouchUserIdentityParams) error
 	UpdateMemberRole(ctx context.Context, arg UpdateMemberRoleParams) (JarMembership, error)
-	UpdateOffenseStatus(ctx context.Context, arg UpdateOffenseStatusParams) (Offense, error)
+	UpdateOffenseCost(ctx context.Context, arg UpdateOffenseCostParams) (Offense, error)
+	UpdateOffenseStatusInPeriod(ctx context.Context, arg UpdateOffenseStatusInPeriodParams) (Offense, error)
 	UpdateOffenseType(ctx context.Context, arg UpdateOffenseTypeParams) (UpdateOffenseTypeRow, error)
 	UpdateTipJar(ctx context.Context, arg UpdateTipJarParams) (TipJar, error)
+	UpdateTipJarDisputeSettings(ctx context.Context, arg UpdateTipJarDisputeSettingsParams) (TipJar, error)
+	UpdateTipJarJoinApproval(ctx context.Context, arg UpdateTipJarJoinApprovalParams) (TipJar, error)
+	UpdateTipJarVerificationSettings(ctx context.Context, arg UpdateTipJarVerificationSettingsParams) (TipJar, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpsertChatAccount(ctx context.Context, arg UpsertChatAccountParams) (ChatAccount, error)
+	UpsertChatChannel(ctx context.Context, arg UpsertChatChannelParams) (ChatChannel, error)
+	UpsertDisputeVote(ctx context.Context, arg UpsertDisputeVoteParams) (DisputeVote, error)
+	UpsertEmailMode(ctx context.Context, arg UpsertEmailModeParams) error
+	UpsertJarRules(ctx context.Context, arg UpsertJarRulesParams) (JarRule, error)
+	UpsertNotificationPreference(ctx context.Context, arg UpsertNotificationPreferenceParams) error
+	UpsertPaymentVerification(ctx context.Context, arg UpsertPaymentVerificationParams) (PaymentVerification, error)
+	UseInvite(ctx context.Context, id int32) (Invite, error)
 	VerifyPayment(ctx context.Context, arg VerifyPaymentParams) (Payment, error)
 }
 
@@ -11,35 +11,61 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const archiveTipJar = `-- name: ArchiveTipJar :one
+UPDATE tip_jars
+SET archived_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND archived_at IS NULL
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
+`
+
+func (q *Queries) ArchiveTipJar(ctx context.Context, id int32) (TipJar, error) {
+	row := q.db.QueryRow(ctx, archiveTipJar, id)
+	var i TipJar
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
 const createTipJar = `-- name: CreateTipJar :one
-INSERT INTO tip_jars (name, description, invite_code, created_by)
-VALUES ($1, $2, $3, $4)
-RETURNING id, name, description, invite_code, created_by, created_at, updated_at
+INSERT INTO tip_jars (name, description, created_by)
+VALUES ($1, $2, $3)
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
 `
 
 type CreateTipJarParams struct {
 	Name        string      `db:"name" json:"name"`
 	Description pgtype.Text `db:"description" json:"description"`
-	InviteCode  string      `db:"invite_code" json:"invite_code"`
 	CreatedBy   int32       `db:"created_by" json:"created_by"`
 }
 
 func (q *Queries) CreateTipJar(ctx context.Context, arg CreateTipJarParams) (TipJar, error) {
-	row := q.db.QueryRow(ctx, createTipJar,
-		arg.Name,
-		arg.Description,
-		arg.InviteCode,
-		arg.CreatedBy,
-	)
+	row := q.db.QueryRow(ctx, createTipJar, arg.Name, arg.Description, arg.CreatedBy)
 	var i TipJar
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Description,
-		&i.InviteCode,
 		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
 	)
 	return i, err
 }
@@ -55,7 +81,7 @@ func (q *Queries) DeleteTipJar(ctx context.Context, id int32) error {
 }
 
 const getTipJar = `-- name: GetTipJar :one
-SELECT id, name, description, invite_code, created_by, created_at, updated_at
+SELECT id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
 FROM tip_jars
 WHERE id = $1
 `
@@ -67,37 +93,21 @@ func (q *Queries) GetTipJar(ctx context.Context, id int32) (TipJar, error) {
 		&i.ID,
 		&i.Name,
 		&i.Description,
-		&i.InviteCode,
-		&i.CreatedBy,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const getTipJarByInviteCode = `-- name: GetTipJarByInviteCode :one
-SELECT id, name, description, invite_code, created_by, created_at, updated_at
-FROM tip_jars
-WHERE invite_code = $1
-`
-
-func (q *Queries) GetTipJarByInviteCode(ctx context.Context, inviteCode string) (TipJar, error) {
-	row := q.db.QueryRow(ctx, getTipJarByInviteCode, inviteCode)
-	var i TipJar
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.InviteCode,
 		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
 	)
 	return i, err
 }
 
 const listTipJarsForUser = `-- name: ListTipJarsForUser :many
-SELECT tj.id, tj.name, tj.description, tj.invite_code, tj.created_by, tj.created_at, tj.updated_at
+SELECT tj.id, tj.name, tj.description, tj.created_by, tj.created_at, tj.updated_at, tj.dispute_quorum, tj.dispute_window_hours, tj.verification_required, tj.verifications_needed, tj.join_approval_required, tj.archived_at
 FROM tip_jars tj
 INNER JOIN jar_memberships jm ON tj.id = jm.jar_id
 WHERE jm.user_id = $1
@@ -117,10 +127,15 @@ func (q *Queries) ListTipJarsForUser(ctx context.Context, userID int32) ([]TipJa
 			&i.ID,
 			&i.Name,
 			&i.Description,
-			&i.InviteCode,
 			&i.CreatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DisputeQuorum,
+			&i.DisputeWindowHours,
+			&i.VerificationRequired,
+			&i.VerificationsNeeded,
+			&i.JoinApprovalRequired,
+			&i.ArchivedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -133,7 +148,7 @@ func (q *Queries) ListTipJarsForUser(ctx context.Context, userID int32) ([]TipJa
 }
 
 const listTipJarsForUserWithMemberCount = `-- name: ListTipJarsForUserWithMemberCount :many
-SELECT tj.id, tj.name, tj.description, tj.invite_code, tj.created_by, tj.created_at, tj.updated_at,
+SELECT tj.id, tj.name, tj.description, tj.created_by, tj.created_at, tj.updated_at, tj.dispute_quorum, tj.dispute_window_hours, tj.verification_required, tj.verifications_needed, tj.join_approval_required, tj.archived_at,
        member_counts.member_count
 FROM tip_jars tj
 INNER JOIN jar_memberships jm ON tj.id = jm.jar_id
@@ -147,14 +162,19 @@ ORDER BY tj.created_at DESC
 `
 
 type ListTipJarsForUserWithMemberCountRow struct {
-	ID          int32            `db:"id" json:"id"`
-	Name        string           `db:"name" json:"name"`
-	Description pgtype.Text      `db:"description" json:"description"`
-	InviteCode  string           `db:"invite_code" json:"invite_code"`
-	CreatedBy   int32            `db:"created_by" json:"created_by"`
-	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	MemberCount int64            `db:"member_count" json:"member_count"`
+	ID                   int32            `db:"id" json:"id"`
+	Name                 string           `db:"name" json:"name"`
+	Description          pgtype.Text      `db:"description" json:"description"`
+	CreatedBy            int32            `db:"created_by" json:"created_by"`
+	CreatedAt            pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt            pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	DisputeQuorum        int32            `db:"dispute_quorum" json:"dispute_quorum"`
+	DisputeWindowHours   int32            `db:"dispute_window_hours" json:"dispute_window_hours"`
+	VerificationRequired bool             `db:"verification_required" json:"verification_required"`
+	VerificationsNeeded  int32            `db:"verifications_needed" json:"verifications_needed"`
+	JoinApprovalRequired bool             `db:"join_approval_required" json:"join_approval_required"`
+	ArchivedAt           pgtype.Timestamp `db:"archived_at" json:"archived_at"`
+	MemberCount          int64            `db:"member_count" json:"member_count"`
 }
 
 func (q *Queries) ListTipJarsForUserWithMemberCount(ctx context.Context, userID int32) ([]ListTipJarsForUserWithMemberCountRow, error) {
@@ -170,10 +190,15 @@ func (q *Queries) ListTipJarsForUserWithMemberCount(ctx context.Context, userID
 			&i.ID,
 			&i.Name,
 			&i.Description,
-			&i.InviteCode,
 			&i.CreatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DisputeQuorum,
+			&i.DisputeWindowHours,
+			&i.VerificationRequired,
+			&i.VerificationsNeeded,
+			&i.JoinApprovalRequired,
+			&i.ArchivedAt,
 			&i.MemberCount,
 		); err != nil {
 			return nil, err
@@ -186,11 +211,38 @@ func (q *Queries) ListTipJarsForUserWithMemberCount(ctx context.Context, userID
 	return items, nil
 }
 
+const restoreTipJar = `-- name: RestoreTipJar :one
+UPDATE tip_jars
+SET archived_at = NULL, updated_at = NOW()
+WHERE id = $1 AND archived_at IS NOT NULL
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
+`
+
+func (q *Queries) RestoreTipJar(ctx context.Context, id int32) (TipJar, error) {
+	row := q.db.QueryRow(ctx, restoreTipJar, id)
+	var i TipJar
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
 const updateTipJar = `-- name: UpdateTipJar :one
 UPDATE tip_jars
 SET name = $2, description = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, description, invite_code, created_by, created_at, updated_at
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
 `
 
 type UpdateTipJarParams struct {
@@ -206,10 +258,113 @@ func (q *Queries) UpdateTipJar(ctx context.Context, arg UpdateTipJarParams) (Tip
 		&i.ID,
 		&i.Name,
 		&i.Description,
-		&i.InviteCode,
 		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const updateTipJarDisputeSettings = `-- name: UpdateTipJarDisputeSettings :one
+UPDATE tip_jars
+SET dispute_quorum = $2, dispute_window_hours = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
+`
+
+type UpdateTipJarDisputeSettingsParams struct {
+	ID                 int32 `db:"id" json:"id"`
+	DisputeQuorum      int32 `db:"dispute_quorum" json:"dispute_quorum"`
+	DisputeWindowHours int32 `db:"dispute_window_hours" json:"dispute_window_hours"`
+}
+
+func (q *Queries) UpdateTipJarDisputeSettings(ctx context.Context, arg UpdateTipJarDisputeSettingsParams) (TipJar, error) {
+	row := q.db.QueryRow(ctx, updateTipJarDisputeSettings, arg.ID, arg.DisputeQuorum, arg.DisputeWindowHours)
+	var i TipJar
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const updateTipJarJoinApproval = `-- name: UpdateTipJarJoinApproval :one
+UPDATE tip_jars
+SET join_approval_required = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
+`
+
+type UpdateTipJarJoinApprovalParams struct {
+	ID                   int32 `db:"id" json:"id"`
+	JoinApprovalRequired bool  `db:"join_approval_required" json:"join_approval_required"`
+}
+
+func (q *Queries) UpdateTipJarJoinApproval(ctx context.Context, arg UpdateTipJarJoinApprovalParams) (TipJar, error) {
+	row := q.db.QueryRow(ctx, updateTipJarJoinApproval, arg.ID, arg.JoinApprovalRequired)
+	var i TipJar
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const updateTipJarVerificationSettings = `-- name: UpdateTipJarVerificationSettings :one
+UPDATE tip_jars
+SET verification_required = $2, verifications_needed = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, created_by, created_at, updated_at, dispute_quorum, dispute_window_hours, verification_required, verifications_needed, join_approval_required, archived_at
+`
+
+type UpdateTipJarVerificationSettingsParams struct {
+	ID                   int32 `db:"id" json:"id"`
+	VerificationRequired bool  `db:"verification_required" json:"verification_required"`
+	VerificationsNeeded  int32 `db:"verifications_needed" json:"verifications_needed"`
+}
+
+func (q *Queries) UpdateTipJarVerificationSettings(ctx context.Context, arg UpdateTipJarVerificationSettingsParams) (TipJar, error) {
+	row := q.db.QueryRow(ctx, updateTipJarVerificationSettings, arg.ID, arg.VerificationRequired, arg.VerificationsNeeded)
+	var i TipJar
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisputeQuorum,
+		&i.DisputeWindowHours,
+		&i.VerificationRequired,
+		&i.VerificationsNeeded,
+		&i.JoinApprovalRequired,
+		&i.ArchivedAt,
 	)
 	return i, err
 }
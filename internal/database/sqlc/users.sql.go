@@ -12,53 +12,45 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, name, avatar, google_id)
-VALUES ($1, $2, $3, $4)
-RETURNING id, email, name, avatar, google_id, created_at, updated_at
+INSERT INTO users (email, name, avatar)
+VALUES ($1, $2, $3)
+RETURNING id, email, name, avatar, created_at, updated_at
 `
 
 type CreateUserParams struct {
-	Email    string      `db:"email" json:"email"`
-	Name     string      `db:"name" json:"name"`
-	Avatar   pgtype.Text `db:"avatar" json:"avatar"`
-	GoogleID string      `db:"google_id" json:"google_id"`
+	Email  string      `db:"email" json:"email"`
+	Name   string      `db:"name" json:"name"`
+	Avatar pgtype.Text `db:"avatar" json:"avatar"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser,
-		arg.Email,
-		arg.Name,
-		arg.Avatar,
-		arg.GoogleID,
-	)
+	row := q.db.QueryRow(ctx, createUser, arg.Email, arg.Name, arg.Avatar)
 	var i User
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.Name,
 		&i.Avatar,
-		&i.GoogleID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getUserByGoogleID = `-- name: GetUserByGoogleID :one
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, name, avatar, created_at, updated_at 
 FROM users 
-WHERE google_id = $1
+WHERE email = $1
 `
 
-func (q *Queries) GetUserByGoogleID(ctx context.Context, googleID string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByGoogleID, googleID)
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
 	var i User
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.Name,
 		&i.Avatar,
-		&i.GoogleID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -66,7 +58,7 @@ func (q *Queries) GetUserByGoogleID(ctx context.Context, googleID string) (User,
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
+SELECT id, email, name, avatar, created_at, updated_at 
 FROM users 
 WHERE id = $1
 `
@@ -79,7 +71,32 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 		&i.Email,
 		&i.Name,
 		&i.Avatar,
-		&i.GoogleID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByIdentity = `-- name: GetUserByIdentity :one
+SELECT u.id, u.email, u.name, u.avatar, u.created_at, u.updated_at
+FROM users u
+INNER JOIN user_identities ui ON u.id = ui.user_id
+WHERE ui.provider = $1 AND ui.subject = $2
+`
+
+type GetUserByIdentityParams struct {
+	Provider string `db:"provider" json:"provider"`
+	Subject  string `db:"subject" json:"subject"`
+}
+
+func (q *Queries) GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByIdentity, arg.Provider, arg.Subject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Avatar,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -87,7 +104,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, email, name, avatar, google_id, created_at, updated_at 
+SELECT id, email, name, avatar, created_at, updated_at 
 FROM users
 ORDER BY created_at DESC
 `
@@ -106,7 +123,6 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 			&i.Email,
 			&i.Name,
 			&i.Avatar,
-			&i.GoogleID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -124,7 +140,7 @@ const updateUser = `-- name: UpdateUser :one
 UPDATE users 
 SET name = $2, avatar = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, name, avatar, google_id, created_at, updated_at
+RETURNING id, email, name, avatar, created_at, updated_at
 `
 
 type UpdateUserParams struct {
@@ -141,7 +157,6 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.Name,
 		&i.Avatar,
-		&i.GoogleID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -0,0 +1,124 @@
+// Package email renders notification emails and sends them over SMTP
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"tipjar/internal/config"
+)
+
+// Message is one rendered email to one person
+type Message struct {
+	To             string
+	Subject        string
+	HTML           string
+	Text           string
+	UnsubscribeURL string // Sent as List-Unsubscribe, so mail clients can offer one-click unsubscribe
+}
+
+// Mailer sends messages through an SMTP server. Without a username it sends
+// without authenticating, as local catchers like MailHog expect.
+type Mailer struct {
+	addr string
+	host string
+	from mail.Address
+	auth smtp.Auth
+}
+
+// New returns the mailer configured by SMTP_HOST, or nil if email isn't set up
+func New(cfg *config.Config) (*Mailer, error) {
+	if cfg.SMTPHost == "" {
+		return nil, nil
+	}
+	return NewMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+}
+
+func NewMailer(host string, port int, username, password, from string) (*Mailer, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+
+	m := &Mailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: *address,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *Mailer) Send(msg Message) error {
+	body, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from.Address, []string{msg.To}, body)
+}
+
+// build writes the message as multipart/alternative, plain text first so
+// clients that can show HTML prefer it
+func (m *Mailer) build(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := []struct{ name, value string }{
+		{"From", m.from.String()},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", messageID(), m.host)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+	if msg.UnsubscribeURL != "" {
+		headers = append(headers,
+			struct{ name, value string }{"List-Unsubscribe", "<" + msg.UnsubscribeURL + ">"},
+			struct{ name, value string }{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		)
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header.name, header.value)
+	}
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,129 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"tipjar/internal/email/emailtest"
+)
+
+func TestMailerSend(t *testing.T) {
+	server := emailtest.NewServer(t)
+	mailer, err := NewMailer(server.Host, server.Port, "", "", "Tip Jar <tipjar@example.test>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(Message{
+		To:             "alice@example.test",
+		Subject:        "Bob reported you for Late – again",
+		HTML:           "<p>Hi Alice</p>",
+		Text:           "Hi Alice",
+		UnsubscribeURL: "https://tipjar.example.test/email/unsubscribe?user=1&token=abc",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server got %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+
+	if msg.From != "tipjar@example.test" || len(msg.To) != 1 || msg.To[0] != "alice@example.test" {
+		t.Errorf("envelope from %q to %v", msg.From, msg.To)
+	}
+	if got := msg.Header.Get("From"); got != `"Tip Jar" <tipjar@example.test>` {
+		t.Errorf("From = %q", got)
+	}
+	if msg.Subject != "Bob reported you for Late – again" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	if msg.Text != "Hi Alice" || msg.HTML != "<p>Hi Alice</p>" {
+		t.Errorf("text = %q, html = %q", msg.Text, msg.HTML)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<https://tipjar.example.test/email/unsubscribe?user=1&token=abc>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := msg.Header.Get("List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click" {
+		t.Errorf("List-Unsubscribe-Post = %q", got)
+	}
+
+	// Messages without an unsubscribe link don't offer one
+	if err := mailer.Send(Message{To: "alice@example.test", Subject: "Hi", Text: "Hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if msg := server.Messages()[1]; msg.Header.Get("List-Unsubscribe") != "" {
+		t.Errorf("List-Unsubscribe = %q, want none", msg.Header.Get("List-Unsubscribe"))
+	}
+}
+
+func TestRender(t *testing.T) {
+	page := Page{
+		BaseURL:        "https://tipjar.example.test",
+		SettingsURL:    "https://tipjar.example.test/notifications/preferences",
+		UnsubscribeURL: "https://tipjar.example.test/email/unsubscribe?user=1&token=abc",
+	}
+
+	tests := []struct {
+		template string
+		data     any
+		want     []string
+	}{
+		{"offense_reported", OffenseReported{
+			RecipientName: "Alice",
+			ReporterName:  "Bob",
+			OffenseType:   "Late",
+			JarID:         7,
+			JarName:       "Standup",
+			Amount:        "$5.00",
+			Notes:         "Ten minutes",
+		}, []string{"Hi Alice", "Bob", "Late", "$5.00", "Ten minutes", "/jars/7"}},
+		{"digest", Digest{
+			RecipientName: "Alice",
+			Period:        "daily",
+			Jars: []DigestJar{{
+				ID:      7,
+				Name:    "Standup",
+				YouOwe:  "$5.00",
+				Members: []DigestMember{{Name: "Alice", Owed: "$5.00", Offenses: 1}, {Name: "Bob", Owed: "$2.00", Offenses: 2}},
+			}},
+		}, []string{"Hi Alice", "Standup", "/jars/7", "You owe $5.00", "Bob", "$2.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			page := page
+			page.Data = tt.data
+
+			html, text, err := Render(tt.template, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("text is missing %q:\n%s", want, text)
+				}
+				if !strings.Contains(html, want) {
+					t.Errorf("HTML is missing %q:\n%s", want, html)
+				}
+			}
+			if !strings.Contains(text, page.UnsubscribeURL) {
+				t.Errorf("text has no unsubscribe link:\n%s", text)
+			}
+			if !strings.Contains(html, `href="https://tipjar.example.test/email/unsubscribe?user=1&amp;token=abc"`) {
+				t.Errorf("HTML has no unsubscribe link:\n%s", html)
+			}
+		})
+	}
+
+	// Data from reports is escaped in the HTML
+	html, _, err := Render("offense_reported", Page{Data: OffenseReported{OffenseType: "<script>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("HTML isn't escaped")
+	}
+}
@@ -0,0 +1,203 @@
+// Package emailtest runs an SMTP server inside the test process that keeps
+// every message it's sent, so tests can check emails without a mail catcher.
+// It speaks just enough SMTP for net/smtp: no TLS and no authentication.
+package emailtest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message is one email the server accepted
+type Message struct {
+	From    string
+	To      []string
+	Header  mail.Header
+	Subject string // Decoded from the header
+	Text    string
+	HTML    string
+}
+
+type Server struct {
+	Host string
+	Port int
+
+	tb       testing.TB
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a server on a free local port. It's stopped when the test
+// finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("listen for SMTP: %v", err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+
+	s := &Server{Host: addr.IP.String(), Port: addr.Port, tb: tb, listener: listener}
+	go s.serve()
+	tb.Cleanup(func() { listener.Close() })
+	return s
+}
+
+// Messages returns everything received so far, oldest first
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// To returns the messages received for one address. Tests share the
+// database, so a dispatcher may send other tests' queued emails too.
+func (s *Server) To(address string) []Message {
+	var messages []Message
+	for _, msg := range s.Messages() {
+		for _, to := range msg.To {
+			if to == address {
+				messages = append(messages, msg)
+				break
+			}
+		}
+	}
+	return messages
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	var from string
+	var to []string
+	reply := func(line string) bool {
+		return tp.PrintfLine("%s", line) == nil
+	}
+
+	if !reply("220 emailtest ready") {
+		return
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 emailtest")
+		case "MAIL":
+			from = addressArg(arg)
+			to = nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, addressArg(arg))
+			reply("250 OK")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg, err := parse(data)
+			if err != nil {
+				s.tb.Errorf("emailtest: unreadable message: %v", err)
+				reply("554 Unreadable message")
+				continue
+			}
+			msg.From, msg.To = from, to
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// addressArg reads the address out of "FROM:<a@b.c> BODY=8BITMIME"
+func addressArg(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	address, _, _ := strings.Cut(rest, ">")
+	return address
+}
+
+// parse reads the headers and the plain text and HTML parts of a
+// multipart/alternative message
+func parse(data []byte) (Message, error) {
+	parsed, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{Header: parsed.Header}
+	msg.Subject, err = new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		return Message{}, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		return Message{}, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(parsed.Body)
+		msg.Text = string(body)
+		return msg, err
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return msg, nil
+		}
+		if err != nil {
+			return Message{}, err
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return Message{}, err
+		}
+
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			msg.Text = string(body)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			msg.HTML = string(body)
+		}
+	}
+}
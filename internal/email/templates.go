@@ -0,0 +1,69 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/*.txt"))
+)
+
+// Page is what every template is rendered with. Data is one of the types
+// below, matching the template.
+type Page struct {
+	BaseURL        string
+	SettingsURL    string
+	UnsubscribeURL string
+	Data           any
+}
+
+// OffenseReported is the data for the "offense_reported" template
+type OffenseReported struct {
+	RecipientName string `json:"recipient_name"`
+	ReporterName  string `json:"reporter_name"`
+	OffenseType   string `json:"offense_type"`
+	JarID         int    `json:"jar_id"`
+	JarName       string `json:"jar_name"`
+	Amount        string `json:"amount"`
+	Notes         string `json:"notes,omitempty"`
+}
+
+// Digest is the data for the "digest" template
+type Digest struct {
+	RecipientName string      `json:"recipient_name"`
+	Period        string      `json:"period"` // "daily" or "weekly"
+	Jars          []DigestJar `json:"jars"`
+}
+
+// DigestJar is what's outstanding in one jar
+type DigestJar struct {
+	ID      int            `json:"id"`
+	Name    string         `json:"name"`
+	YouOwe  string         `json:"you_owe,omitempty"`
+	Members []DigestMember `json:"members"`
+}
+
+type DigestMember struct {
+	Name     string `json:"name"`
+	Owed     string `json:"owed"`
+	Offenses int    `json:"offenses"`
+}
+
+// Render renders a template's HTML and plain text versions
+func Render(name string, page Page) (html, text string, err error) {
+	var htmlBuf, textBuf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html", page); err != nil {
+		return "", "", err
+	}
+	if err := textTemplates.ExecuteTemplate(&textBuf, name+".txt", page); err != nil {
+		return "", "", err
+	}
+	return htmlBuf.String(), textBuf.String(), nil
+}
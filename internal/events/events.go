@@ -0,0 +1,113 @@
+// Package events carries changes to a jar to the people looking at it.
+// Services publish an Event after the change commits, and each open jar page
+// holds a subscription for its jar.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many events a slow subscriber can fall behind
+// before newer ones are dropped for it
+const subscriberBuffer = 16
+
+// Event is something that happened in a jar. Types match the webhook event
+// types, e.g. "offense.reported", and Data is the same payload webhooks get.
+type Event struct {
+	Type      string    `json:"type"`
+	JarID     int       `json:"jar_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Bus fans events out to subscribers. MemoryBus only reaches subscribers in
+// the same process; running several instances needs an implementation
+// backed by something shared, such as Postgres LISTEN/NOTIFY.
+type Bus interface {
+	// Publish sends the event to the jar's subscribers. It doesn't block on
+	// slow subscribers and doesn't fail: events are hints to refresh, and
+	// losing one only delays an update.
+	Publish(ctx context.Context, event Event)
+
+	// Subscribe returns a channel of the jar's events and a function that
+	// ends the subscription and closes the channel.
+	Subscribe(jarID int) (<-chan Event, func())
+}
+
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan Event]struct{}
+	closed      bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subscribers: make(map[int]map[chan Event]struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.JarID] {
+		select {
+		case ch <- event:
+		default:
+			// The subscriber is behind; it'll catch up on the next event
+		}
+	}
+}
+
+func (b *MemoryBus) Subscribe(jarID int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[jarID] == nil {
+		b.subscribers[jarID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[jarID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			// Close already closed the channel if it's no longer subscribed
+			if _, ok := b.subscribers[jarID][ch]; !ok {
+				return
+			}
+			delete(b.subscribers[jarID], ch)
+			if len(b.subscribers[jarID]) == 0 {
+				delete(b.subscribers, jarID)
+			}
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Close ends every subscription, so long-lived streams finish and the server
+// can shut down
+func (b *MemoryBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	b.subscribers = make(map[int]map[chan Event]struct{})
+	b.closed = true
+}
@@ -0,0 +1,1305 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/models"
+	"tipjar/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultOffensePageSize = 50
+	maxOffensePageSize     = 200
+)
+
+// apiErrorCodes names the error statuses the API returns
+var apiErrorCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusConflict:              "conflict",
+	http.StatusGone:                  "gone",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "invalid",
+	http.StatusInternalServerError:   "internal",
+}
+
+func (h *Handlers) registerAPIRoutes(api *echo.Group) {
+	api.GET("/user", h.handleGetUser)
+	api.GET("/jars", h.handleAPIListJars)
+	api.POST("/jars", h.handleAPICreateJar)
+	api.GET("/jars/lookup", h.handleLookupJar)
+	api.POST("/jars/join", h.handleAPIJoinJar)
+	api.GET("/jars/:id", h.handleAPIGetJar)
+	api.PATCH("/jars/:id", h.handleAPIUpdateJar)
+	api.DELETE("/jars/:id", h.handleAPIDeleteJar)
+	api.POST("/jars/:id/archive", h.handleAPIArchiveJar)
+	api.POST("/jars/:id/restore", h.handleAPIRestoreJar)
+	api.GET("/jars/:id/members", h.handleAPIListMembers)
+	api.PATCH("/jars/:id/members/:user_id", h.handleAPIUpdateMember)
+	api.DELETE("/jars/:id/members/:user_id", h.handleAPIRemoveMember)
+	api.POST("/jars/:id/transfer", h.handleAPITransferOwnership)
+	api.GET("/jars/:id/invites", h.handleAPIListInvites)
+	api.POST("/jars/:id/invites", h.handleAPICreateInvite)
+	api.DELETE("/jars/:id/invites/:invite_id", h.handleAPIRevokeInvite)
+	api.POST("/jars/:id/invites/:invite_id/rotate", h.handleAPIRotateInvite)
+	api.GET("/jars/:id/join-requests", h.handleAPIListJoinRequests)
+	api.POST("/jars/:id/join-requests/:request_id/approve", h.handleAPIApproveJoinRequest)
+	api.POST("/jars/:id/join-requests/:request_id/reject", h.handleAPIRejectJoinRequest)
+	api.GET("/jars/:id/rules", h.handleAPIGetJarRules)
+	api.PUT("/jars/:id/rules", h.handleAPIUpdateJarRules)
+	api.GET("/jars/:id/periods", h.handleAPIListPeriods)
+	api.POST("/jars/:id/periods/close", h.handleAPIClosePeriod)
+	api.GET("/jars/:id/periods/:period_id", h.handleAPIGetPeriod)
+	api.GET("/jars/:id/periods/:period_id/offenses", h.handleAPIListPeriodOffenses)
+	api.GET("/jars/:id/offense-types", h.handleAPIListOffenseTypes)
+	api.POST("/jars/:id/offense-types", h.handleAPICreateOffenseType)
+	api.PATCH("/offense-types/:id", h.handleAPIUpdateOffenseType)
+	api.GET("/jars/:id/offenses", h.handleAPIListOffenses)
+	api.POST("/jars/:id/offenses", h.handleAPICreateOffense)
+	api.GET("/offenses/:id", h.handleAPIGetOffense)
+	api.GET("/offenses/:id/payments", h.handleAPIListOffensePayments)
+	api.POST("/payments", h.handleAPICreatePayment)
+	api.GET("/payments/:id", h.handleAPIGetPayment)
+	api.POST("/payments/:id/verify", h.handleAPIVerifyPayment)
+	api.POST("/payments/:id/reject", h.handleAPIRejectPayment)
+}
+
+// reportScopeRoutes are the writes a report-scoped token may make besides reads
+var reportScopeRoutes = map[string]bool{
+	http.MethodPost + " /api/v1/jars/:id/offenses": true,
+}
+
+// requireAPIAuth accepts a personal access token in the Authorization header
+// or the session cookie, answering with 401 rather than a login redirect
+func (h *Handlers) requireAPIAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if header == "" {
+			user := h.getCurrentUser(c)
+			if user == nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return echo.NewHTTPError(http.StatusUnauthorized, "Sign in or send a personal access token")
+			}
+
+			c.Set("user", user)
+			return next(c)
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return echo.NewHTTPError(http.StatusUnauthorized, "Authorization must be a Bearer token")
+		}
+
+		apiToken, err := h.apiTokenService.Authenticate(c.Request().Context(), strings.TrimSpace(token))
+		if err == services.ErrInvalidAPIToken {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or revoked token")
+		}
+		if err != nil {
+			c.Logger().Error("Failed to authenticate API token", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to authenticate")
+		}
+
+		if !tokenScopeAllows(apiToken.Scope, c.Request().Method, c.Path()) {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("This token's %s scope doesn't allow this request", apiToken.Scope))
+		}
+
+		user, err := h.userService.GetUserByID(c.Request().Context(), apiToken.UserID)
+		if err != nil || user == nil {
+			c.Logger().Error("Failed to load token owner", "error", err, "token_id", apiToken.ID)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or revoked token")
+		}
+
+		c.Set("user", user)
+		c.Set("api_token", apiToken)
+		return next(c)
+	}
+}
+
+// tokenScopeAllows reports whether a token with scope may call the route
+func tokenScopeAllows(scope, method, route string) bool {
+	switch scope {
+	case "full":
+		return true
+	case "report":
+		if reportScopeRoutes[method+" "+route] {
+			return true
+		}
+	}
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// apiErrors renders errors from API handlers as {"error": {"code", "message"}}
+// instead of Echo's default body
+func (h *Handlers) apiErrors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err == nil || c.Response().Committed {
+			return err
+		}
+
+		status := http.StatusInternalServerError
+		message := http.StatusText(status)
+		if he, ok := err.(*echo.HTTPError); ok {
+			status = he.Code
+			message = fmt.Sprint(he.Message)
+		} else {
+			c.Logger().Error("Unhandled API error", "error", err)
+		}
+
+		code, ok := apiErrorCodes[status]
+		if !ok {
+			code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+		}
+
+		return c.JSON(status, APIError{Error: APIErrorBody{Code: code, Message: message}})
+	}
+}
+
+func (h *Handlers) handleGetUser(c echo.Context) error {
+	user := h.getCurrentUser(c)
+	return c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+func (h *Handlers) handleAPIListJars(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jars, err := h.tipJarService.ListTipJarsForUserWithMemberCount(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to load user's tip jars", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load tip jars")
+	}
+
+	data := make([]JarResponse, len(jars))
+	for i, jar := range jars {
+		data[i] = toJarResponse(jar.TipJar)
+		data[i].MemberCount = &jar.MemberCount
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[JarResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPICreateJar(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	var req CreateJarRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Jar name is required")
+	}
+
+	jar, err := h.tipJarService.CreateTipJar(c.Request().Context(), name, strings.TrimSpace(req.Description), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to create tip jar", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create tip jar")
+	}
+
+	return c.JSON(http.StatusCreated, toJarResponse(jar))
+}
+
+func (h *Handlers) handleAPIJoinJar(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	var req JoinJarRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	inviteCode := strings.TrimSpace(req.InviteCode)
+	if inviteCode == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invite code is required")
+	}
+
+	jar, request, err := h.inviteService.Join(c.Request().Context(), inviteCode, user.ID)
+	if err != nil {
+		if err == services.ErrAlreadyMember {
+			return echo.NewHTTPError(http.StatusConflict, "You are already a member of this jar")
+		}
+		if err == services.ErrAlreadyAsked {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return inviteError(c, err, "Failed to join jar")
+	}
+
+	// Jars that need approval answer with the request waiting for an admin
+	if request != nil {
+		return c.JSON(http.StatusAccepted, toJoinRequestResponse(request))
+	}
+
+	return c.JSON(http.StatusOK, toJarResponse(jar))
+}
+
+func (h *Handlers) handleAPIGetJar(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toJarResponse(jar))
+}
+
+func (h *Handlers) handleAPIUpdateJar(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageJar)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateJarRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	name := jar.Name
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+	}
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Jar name is required")
+	}
+
+	var description string
+	if jar.Description != nil {
+		description = *jar.Description
+	}
+	if req.Description != nil {
+		description = strings.TrimSpace(*req.Description)
+	}
+
+	quorum, window := jar.DisputeQuorum, jar.DisputeWindowHours
+	if req.DisputeQuorum != nil {
+		quorum = *req.DisputeQuorum
+	}
+	if req.DisputeWindowHours != nil {
+		window = *req.DisputeWindowHours
+	}
+	if quorum < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Dispute quorum must be at least 1")
+	}
+	if window < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Dispute window must be at least 1 hour")
+	}
+
+	required, needed := jar.VerificationRequired, jar.VerificationsNeeded
+	if req.VerificationRequired != nil {
+		required = *req.VerificationRequired
+	}
+	if req.VerificationsNeeded != nil {
+		needed = *req.VerificationsNeeded
+	}
+	if needed < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Verifications needed must be at least 1")
+	}
+
+	approval := jar.JoinApprovalRequired
+	if req.JoinApprovalRequired != nil {
+		approval = *req.JoinApprovalRequired
+	}
+
+	ctx := c.Request().Context()
+	if err := h.tipJarService.UpdateTipJar(ctx, jar.ID, name, description); err != nil {
+		c.Logger().Error("Failed to update jar", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
+	if err := h.tipJarService.UpdateDisputeSettings(ctx, jar.ID, quorum, window); err != nil {
+		c.Logger().Error("Failed to update dispute settings", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
+	if err := h.tipJarService.UpdateVerificationSettings(ctx, jar.ID, required, needed); err != nil {
+		c.Logger().Error("Failed to update verification settings", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
+	if err := h.tipJarService.UpdateJoinApproval(ctx, jar.ID, approval); err != nil {
+		c.Logger().Error("Failed to update join approval", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
+
+	jar, err = h.tipJarService.GetTipJar(ctx, jar.ID)
+	if err != nil || jar == nil {
+		c.Logger().Error("Failed to get jar", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	return c.JSON(http.StatusOK, toJarResponse(jar))
+}
+
+func (h *Handlers) handleAPIDeleteJar(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.DeleteJar)
+	if err != nil {
+		return err
+	}
+
+	// Deleting can't be undone, so the jar's name has to be given as well
+	if err := h.deleteJar(c, jar.ID, c.QueryParam("confirm_name")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handlers) handleAPIArchiveJar(c echo.Context) error {
+	return h.apiSetJarArchived(c, true)
+}
+
+func (h *Handlers) handleAPIRestoreJar(c echo.Context) error {
+	return h.apiSetJarArchived(c, false)
+}
+
+func (h *Handlers) apiSetJarArchived(c echo.Context, archived bool) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ArchiveJar)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if archived {
+		err = h.tipJarService.ArchiveTipJar(ctx, jar.ID, user.ID)
+	} else {
+		err = h.tipJarService.RestoreTipJar(ctx, jar.ID, user.ID)
+	}
+	if err != nil {
+		return jarError(c, err, "Failed to update jar")
+	}
+
+	jar, err = h.tipJarService.GetTipJar(ctx, jar.ID)
+	if err != nil || jar == nil {
+		c.Logger().Error("Failed to get jar", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	return c.JSON(http.StatusOK, toJarResponse(jar))
+}
+
+func (h *Handlers) handleAPIListMembers(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	members, err := h.tipJarService.GetJarMembers(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar members", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load members")
+	}
+
+	data := make([]MemberResponse, len(members))
+	for i, member := range members {
+		data[i] = toMemberResponse(member)
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[MemberResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPIUpdateMember(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req UpdateMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	err = h.tipJarService.UpdateMemberRole(c.Request().Context(), jar.ID, userID, req.Role, h.getCurrentUser(c).ID)
+	if err != nil {
+		return apiMemberError(c, err, "Failed to update member")
+	}
+
+	return h.apiMember(c, jar.ID, userID)
+}
+
+// handleAPIRemoveMember lets those who manage members remove them, and anyone
+// leave a jar
+func (h *Handlers) handleAPIRemoveMember(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	permission := authz.ManageMembers
+	if userID == user.ID {
+		permission = authz.ViewJar
+	}
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), permission)
+	if err != nil {
+		return err
+	}
+
+	if err := h.tipJarService.RemoveMember(c.Request().Context(), jar.ID, userID, user.ID); err != nil {
+		return apiMemberError(c, err, "Failed to remove member")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handlers) handleAPITransferOwnership(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.TransferOwnership)
+	if err != nil {
+		return err
+	}
+
+	var req TransferOwnershipRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.tipJarService.TransferOwnership(c.Request().Context(), jar.ID, user.ID, req.UserID); err != nil {
+		return apiMemberError(c, err, "Failed to transfer ownership")
+	}
+
+	return h.apiMember(c, jar.ID, req.UserID)
+}
+
+func (h *Handlers) handleAPIListInvites(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	invites, err := h.inviteService.ListInvites(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list invites", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load invites")
+	}
+
+	data := make([]InviteResponse, len(invites))
+	for i := range invites {
+		data[i] = toInviteResponse(&invites[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[InviteResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPICreateInvite(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	var req CreateInviteRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	invite, err := h.inviteService.CreateInvite(c.Request().Context(), jar.ID, user.ID, services.InviteOptions{
+		Label:     strings.TrimSpace(req.Label),
+		Role:      req.Role,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return inviteError(c, err, "Failed to create invite")
+	}
+
+	return c.JSON(http.StatusCreated, toInviteResponse(invite))
+}
+
+func (h *Handlers) handleAPIRevokeInvite(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	invite, err := h.jarInvite(c, jar)
+	if err != nil {
+		return err
+	}
+
+	if err := h.inviteService.RevokeInvite(c.Request().Context(), invite.ID); err != nil {
+		c.Logger().Error("Failed to revoke invite", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke invite")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handlers) handleAPIRotateInvite(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	invite, err := h.jarInvite(c, jar)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := h.inviteService.RotateInvite(c.Request().Context(), invite.ID, user.ID)
+	if err != nil {
+		if err == services.ErrInviteRevoked {
+			return echo.NewHTTPError(http.StatusConflict, "Revoked invites can't be rotated")
+		}
+		return inviteError(c, err, "Failed to rotate invite")
+	}
+
+	return c.JSON(http.StatusCreated, toInviteResponse(rotated))
+}
+
+func (h *Handlers) handleAPIListJoinRequests(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	requests, err := h.joinService.ListPendingForJar(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list join requests", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load join requests")
+	}
+
+	data := make([]JoinRequestResponse, len(requests))
+	for i := range requests {
+		data[i] = toJoinRequestResponse(&requests[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[JoinRequestResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPIApproveJoinRequest(c echo.Context) error {
+	return h.handleAPIReviewJoinRequest(c, true)
+}
+
+func (h *Handlers) handleAPIRejectJoinRequest(c echo.Context) error {
+	return h.handleAPIReviewJoinRequest(c, false)
+}
+
+func (h *Handlers) handleAPIReviewJoinRequest(c echo.Context, approve bool) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageMembers)
+	if err != nil {
+		return err
+	}
+
+	request, err := h.jarJoinRequest(c, jar)
+	if err != nil {
+		return err
+	}
+
+	var req ReviewJoinRequestRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	message := strings.TrimSpace(req.Message)
+	if approve {
+		err = h.joinService.Approve(ctx, request.ID, user.ID, message)
+	} else {
+		err = h.joinService.Reject(ctx, request.ID, user.ID, message)
+	}
+	if err != nil {
+		return joinRequestError(c, err, "Failed to answer join request")
+	}
+
+	request, err = h.joinService.GetJoinRequest(ctx, request.ID)
+	if err != nil || request == nil {
+		c.Logger().Error("Failed to get join request", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load join request")
+	}
+
+	return c.JSON(http.StatusOK, toJoinRequestResponse(request))
+}
+
+func (h *Handlers) handleAPIGetJarRules(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	rules, err := h.ruleService.GetRules(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar rules", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar rules")
+	}
+
+	return c.JSON(http.StatusOK, toJarRulesResponse(rules))
+}
+
+// handleAPIUpdateJarRules replaces the jar's rules; fields left out are off
+func (h *Handlers) handleAPIUpdateJarRules(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageJar)
+	if err != nil {
+		return err
+	}
+
+	var req JarRulesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	rules, err := h.ruleService.UpdateRules(c.Request().Context(), jar.ID, user.ID,
+		req.MaxOffensesPerDay, req.ReportCooldownMinutes, req.EscalationWindowDays, req.EscalationMultiplier)
+	if err != nil {
+		return offenseRuleError(c, err, "Failed to update jar rules")
+	}
+
+	return c.JSON(http.StatusOK, toJarRulesResponse(rules))
+}
+
+func (h *Handlers) handleAPIListPeriods(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	periods, err := h.periodService.ListPeriods(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list periods", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load periods")
+	}
+
+	data := make([]PeriodResponse, len(periods))
+	for i := range periods {
+		data[i] = toPeriodResponse(&periods[i], nil)
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[PeriodResponse]{Data: data})
+}
+
+// handleAPIGetPeriod includes the final standings once the period is closed
+func (h *Handlers) handleAPIGetPeriod(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	period, err := h.jarPeriod(c, jar)
+	if err != nil {
+		return err
+	}
+
+	standings, err := h.periodService.GetStandings(c.Request().Context(), period.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get period standings", "error", err, "period_id", period.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load period")
+	}
+
+	return c.JSON(http.StatusOK, toPeriodResponse(period, standings))
+}
+
+// handleAPIListPeriodOffenses pages through a period's ledger with ?limit= and
+// ?offset=, including offenses carried into or out of it
+func (h *Handlers) handleAPIListPeriodOffenses(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	period, err := h.jarPeriod(c, jar)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := offensePage(c)
+	if err != nil {
+		return err
+	}
+
+	activities, err := h.periodService.GetLedger(c.Request().Context(), period.ID, limit, offset)
+	if err != nil {
+		c.Logger().Error("Failed to get period ledger", "error", err, "period_id", period.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offenses")
+	}
+
+	data := make([]LedgerEntryResponse, len(activities))
+	for i := range activities {
+		data[i] = toLedgerEntryResponse(&activities[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[LedgerEntryResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPIClosePeriod(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ManageJar)
+	if err != nil {
+		return err
+	}
+
+	var req ClosePeriodRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	next, err := h.periodService.ClosePeriod(c.Request().Context(), jar.ID, user.ID, req.Name, req.NextName, req.CarryOver)
+	if err != nil {
+		return periodError(c, err, "Failed to close period")
+	}
+
+	return c.JSON(http.StatusCreated, toPeriodResponse(next, nil))
+}
+
+// jarPeriod loads the :period_id period, making sure it belongs to jar
+func (h *Handlers) jarPeriod(c echo.Context, jar *models.TipJar) (*models.JarPeriod, error) {
+	periodID, err := strconv.Atoi(c.Param("period_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid period ID")
+	}
+
+	period, err := h.periodService.GetPeriod(c.Request().Context(), periodID)
+	if err != nil {
+		c.Logger().Error("Failed to get period", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load period")
+	}
+
+	if period == nil || period.JarID != jar.ID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Period not found")
+	}
+
+	return period, nil
+}
+
+func (h *Handlers) handleAPIListOffenseTypes(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	offenseTypes, err := h.offenseService.GetAllOffenseTypesForJar(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense types", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense types")
+	}
+
+	data := make([]OffenseTypeResponse, len(offenseTypes))
+	for i := range offenseTypes {
+		data[i] = toOffenseTypeResponse(&offenseTypes[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[OffenseTypeResponse]{Data: data})
+}
+
+func (h *Handlers) handleAPICreateOffenseType(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.EditOffenseTypes)
+	if err != nil {
+		return err
+	}
+
+	var req OffenseTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	var name, description string
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		description = strings.TrimSpace(*req.Description)
+	}
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Offense name is required")
+	}
+	if req.CostAmount != nil && req.CostAmount.IsNegative() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cost amount cannot be negative")
+	}
+
+	offenseType, err := h.offenseService.CreateOffenseType(
+		c.Request().Context(),
+		jar.ID,
+		name,
+		description,
+		req.CostAmount,
+		trimmedOrNil(req.CostUnit),
+		user.ID,
+	)
+	if err != nil {
+		c.Logger().Error("Failed to create offense type", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create offense type")
+	}
+
+	return c.JSON(http.StatusCreated, toOffenseTypeResponse(offenseType))
+}
+
+// handleAPIUpdateOffenseType changes the fields present in the body. Prices
+// only change when cost_amount or cost_unit is sent.
+func (h *Handlers) handleAPIUpdateOffenseType(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	offenseTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
+	}
+
+	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense type", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense type")
+	}
+	if offenseType == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense type not found")
+	}
+
+	if _, err := h.apiJarForMember(c, strconv.Itoa(offenseType.JarID), authz.EditOffenseTypes); err != nil {
+		return err
+	}
+
+	var req OffenseTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Name != nil || req.Description != nil || req.CostAmount != nil || req.CostUnit != nil {
+		name := offenseType.Name
+		if req.Name != nil {
+			name = strings.TrimSpace(*req.Name)
+		}
+		if name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Offense name is required")
+		}
+
+		var description string
+		if offenseType.Description != nil {
+			description = *offenseType.Description
+		}
+		if req.Description != nil {
+			description = strings.TrimSpace(*req.Description)
+		}
+
+		costAmount := offenseType.CostAmount
+		if req.CostAmount != nil {
+			if req.CostAmount.IsNegative() {
+				return echo.NewHTTPError(http.StatusBadRequest, "Cost amount cannot be negative")
+			}
+			costAmount = req.CostAmount
+		}
+
+		costUnit := offenseType.CostUnit
+		if req.CostUnit != nil {
+			costUnit = trimmedOrNil(req.CostUnit)
+		}
+
+		offenseType, err = h.offenseService.UpdateOffenseType(
+			c.Request().Context(),
+			offenseTypeID,
+			name,
+			description,
+			costAmount,
+			costUnit,
+			user.ID,
+		)
+		if err != nil {
+			c.Logger().Error("Failed to update offense type", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update offense type")
+		}
+	}
+
+	if req.IsActive != nil && *req.IsActive != offenseType.IsActive {
+		err = h.offenseService.SetOffenseTypeActiveStatus(c.Request().Context(), offenseTypeID, *req.IsActive)
+		if err != nil {
+			c.Logger().Error("Failed to change offense type status", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update offense type")
+		}
+		offenseType.IsActive = *req.IsActive
+	}
+
+	return c.JSON(http.StatusOK, toOffenseTypeResponse(offenseType))
+}
+
+// handleAPIListOffenses pages through a jar's offenses with ?limit= and ?offset=
+func (h *Handlers) handleAPIListOffenses(c echo.Context) error {
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ViewJar)
+	if err != nil {
+		return err
+	}
+
+	limit, offset, err := offensePage(c)
+	if err != nil {
+		return err
+	}
+
+	offenses, err := h.offenseService.ListOffensesForJar(c.Request().Context(), jar.ID, limit, offset)
+	if err != nil {
+		c.Logger().Error("Failed to list offenses", "error", err, "jar_id", jar.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offenses")
+	}
+
+	data := make([]OffenseResponse, len(offenses))
+	for i := range offenses {
+		data[i] = toOffenseResponse(&offenses[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[OffenseResponse]{Data: data})
+}
+
+// offensePage reads ?limit= and ?offset= for listing offenses
+func offensePage(c echo.Context) (limit, offset int, err error) {
+	limit = defaultOffensePageSize
+	if s := c.QueryParam("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxOffensePageSize {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", maxOffensePageSize))
+		}
+	}
+
+	if s := c.QueryParam("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Offset cannot be negative")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func (h *Handlers) handleAPICreateOffense(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.apiJarForMember(c, c.Param("id"), authz.ReportOffense)
+	if err != nil {
+		return err
+	}
+
+	var req CreateOffenseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	ctx := c.Request().Context()
+
+	offenseType, err := h.offenseService.GetOffenseType(ctx, req.OffenseTypeID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense type", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify offense type")
+	}
+	if offenseType == nil || offenseType.JarID != jar.ID || !offenseType.IsActive {
+		return echo.NewHTTPError(http.StatusBadRequest, "Offense type is not available in this jar")
+	}
+
+	isOffenderMember, err := h.tipJarService.IsUserJarMember(ctx, jar.ID, req.OffenderID)
+	if err != nil {
+		c.Logger().Error("Failed to check offender membership", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify offender")
+	}
+	if !isOffenderMember {
+		return echo.NewHTTPError(http.StatusBadRequest, "Offender is not a member of this jar")
+	}
+
+	if req.CostOverride != nil && req.CostOverride.IsNegative() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cost override cannot be negative")
+	}
+
+	offense, err := h.offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, user.ID, req.OffenderID, strings.TrimSpace(req.Notes), req.CostOverride)
+	if err != nil {
+		return offenseRuleError(c, err, "Failed to report offense")
+	}
+
+	detail, err := h.offenseService.GetOffenseDetail(ctx, offense.ID)
+	if err != nil || detail == nil {
+		c.Logger().Error("Failed to get offense detail", "error", err, "offense_id", offense.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+	}
+
+	return c.JSON(http.StatusCreated, toOffenseResponse(detail))
+}
+
+func (h *Handlers) handleAPIGetOffense(c echo.Context) error {
+	offense, err := h.apiOffenseForMember(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toOffenseResponse(offense))
+}
+
+func (h *Handlers) handleAPIListOffensePayments(c echo.Context) error {
+	offense, err := h.apiOffenseForMember(c)
+	if err != nil {
+		return err
+	}
+
+	payments, err := h.offenseService.ListPaymentsForOffense(c.Request().Context(), offense.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list payments", "error", err, "offense_id", offense.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payments")
+	}
+
+	data := make([]PaymentResponse, len(payments))
+	for i := range payments {
+		data[i] = toPaymentResponse(&payments[i])
+	}
+
+	return c.JSON(http.StatusOK, ListResponse[PaymentResponse]{Data: data})
+}
+
+// handleAPICreatePayment records a payment without proof; proofs are uploaded
+// through the web form. Members who can pay on someone's behalf may pay a
+// single offense for them, but several offenses can only be paid by their
+// offender.
+func (h *Handlers) handleAPICreatePayment(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	var req CreatePaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if len(req.OffenseIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Select at least one offense to pay")
+	}
+
+	ctx := c.Request().Context()
+
+	for _, offenseID := range req.OffenseIDs {
+		offense, err := h.offenseService.GetOffenseDetail(ctx, offenseID)
+		if err != nil {
+			c.Logger().Error("Failed to get offense detail", "error", err, "offense_id", offenseID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+		}
+		if offense == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+		}
+
+		if offense.OffenderID != user.ID && len(req.OffenseIDs) > 1 {
+			return echo.NewHTTPError(http.StatusForbidden, "You can only pay your own offenses")
+		}
+
+		if _, err := h.authorize(c, user, offense.JarID, payPermission(offense, user)); err != nil {
+			return err
+		}
+	}
+
+	if req.Amount != nil && !req.Amount.IsPositive() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Payment amount must be greater than zero")
+	}
+
+	payment, err := h.offenseService.PayOffenses(ctx, user.ID, req.OffenseIDs, req.Amount, nil, nil)
+	if err == services.ErrOffenseNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+	if err == services.ErrOffenseSettled {
+		return echo.NewHTTPError(http.StatusConflict, "One of these offenses has already been settled")
+	}
+	if err == services.ErrPeriodClosed {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err == services.ErrMixedOffenses || err == services.ErrPartialBatch ||
+		err == services.ErrOverpayment || err == services.ErrInvalidPaymentAmount {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create payment", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record payment")
+	}
+
+	return c.JSON(http.StatusCreated, toPaymentResponse(payment))
+}
+
+func (h *Handlers) handleAPIGetPayment(c echo.Context) error {
+	payment, err := h.apiPaymentForMember(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toPaymentResponse(payment))
+}
+
+func (h *Handlers) handleAPIVerifyPayment(c echo.Context) error {
+	return h.handleAPIPaymentVerdict(c, true)
+}
+
+func (h *Handlers) handleAPIRejectPayment(c echo.Context) error {
+	return h.handleAPIPaymentVerdict(c, false)
+}
+
+func (h *Handlers) handleAPIPaymentVerdict(c echo.Context, approved bool) error {
+	user := h.getCurrentUser(c)
+
+	paymentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID")
+	}
+
+	err = h.verifyService.RecordVerdict(c.Request().Context(), paymentID, user.ID, approved)
+	if err == services.ErrPaymentNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+	if err == services.ErrNotJarMember || err == services.ErrOwnPayment || err == services.ErrNotAllowed {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err == services.ErrPaymentSettled {
+		return echo.NewHTTPError(http.StatusConflict, "This payment has already been settled")
+	}
+	if err == services.ErrJarArchived {
+		return echo.NewHTTPError(http.StatusConflict, "This jar is archived. Restore it to make changes.")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to record payment verdict", "error", err, "payment_id", paymentID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record verification")
+	}
+
+	payment, err := h.offenseService.GetPayment(c.Request().Context(), paymentID)
+	if err != nil || payment == nil {
+		c.Logger().Error("Failed to get payment", "error", err, "payment_id", paymentID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+
+	return c.JSON(http.StatusOK, toPaymentResponse(payment))
+}
+
+// apiJarForMember loads the jar in idStr and checks the current user's role in
+// it grants permission
+func (h *Handlers) apiJarForMember(c echo.Context, idStr string, permission authz.Permission) (*models.TipJar, error) {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err, "jar_id", jarID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	if jar == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	}
+
+	role, err := h.authorize(c, user, jarID, permission)
+	if err != nil {
+		// Don't reveal jars the user can't see
+		if he, ok := err.(*echo.HTTPError); ok && he.Code == http.StatusForbidden && role == "" {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+		}
+		return nil, err
+	}
+
+	return jar, nil
+}
+
+func (h *Handlers) apiOffenseForMember(c echo.Context) (*models.OffenseDetail, error) {
+	offenseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid offense ID")
+	}
+
+	offense, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense detail", "error", err, "offense_id", offenseID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+	}
+	if offense == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+
+	if _, err := h.apiJarForMember(c, strconv.Itoa(offense.JarID), authz.ViewJar); err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+
+	return offense, nil
+}
+
+func (h *Handlers) apiPaymentForMember(c echo.Context) (*models.Payment, error) {
+	paymentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID")
+	}
+
+	payment, err := h.offenseService.GetPayment(c.Request().Context(), paymentID)
+	if err != nil {
+		c.Logger().Error("Failed to get payment", "error", err, "payment_id", paymentID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+	if payment == nil || len(payment.Allocations) == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+
+	// Every offense a payment covers is in the same jar
+	offense, err := h.offenseService.GetOffenseDetail(c.Request().Context(), payment.Allocations[0].OffenseID)
+	if err != nil || offense == nil {
+		c.Logger().Error("Failed to get offense", "error", err, "payment_id", paymentID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+
+	if _, err := h.apiJarForMember(c, strconv.Itoa(offense.JarID), authz.ViewJar); err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+
+	return payment, nil
+}
+
+func (h *Handlers) apiMember(c echo.Context, jarID, userID int) error {
+	members, err := h.tipJarService.GetJarMembers(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar members", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load members")
+	}
+
+	for _, member := range members {
+		if member.UserID == userID {
+			return c.JSON(http.StatusOK, toMemberResponse(member))
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusNotFound, "Member not found")
+}
+
+func apiMemberError(c echo.Context, err error, message string) error {
+	if err == services.ErrMemberNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Member not found")
+	}
+	if err == services.ErrInvalidRole {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err == services.ErrOwnerRole {
+		return echo.NewHTTPError(http.StatusConflict, "The jar's owner can't step down or leave; transfer ownership first")
+	}
+	if err == services.ErrNotAllowed {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+func proofPath(paymentID int) string {
+	return fmt.Sprintf("/payments/%d/proof", paymentID)
+}
+
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
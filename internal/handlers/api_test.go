@@ -0,0 +1,625 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tipjar/internal/auth"
+	"tipjar/internal/config"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/events"
+	"tipjar/internal/money"
+	"tipjar/internal/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// apiRoutes returns every "METHOD /path" registered under /api/v1
+func apiRoutes(e *echo.Echo) []string {
+	var routes []string
+	for _, r := range e.Routes() {
+		if strings.HasPrefix(r.Path, "/api/v1/") && !strings.HasSuffix(r.Path, "*") {
+			routes = append(routes, r.Method+" "+r.Path)
+		}
+	}
+	return routes
+}
+
+func TestTokenScopeAllows(t *testing.T) {
+	e := echo.New()
+	(&Handlers{}).RegisterRoutes(e)
+
+	routes := apiRoutes(e)
+	if len(routes) == 0 {
+		t.Fatal("no API routes registered")
+	}
+
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		read := method == http.MethodGet
+		report := read || route == "POST /api/v1/jars/:id/offenses"
+
+		tests := []struct {
+			scope string
+			want  bool
+		}{
+			{"read", read},
+			{"report", report},
+			{"full", true},
+			{"", read},
+			{"admin", read},
+		}
+		for _, tt := range tests {
+			if got := tokenScopeAllows(tt.scope, method, path); got != tt.want {
+				t.Errorf("%q token on %s = %v, want %v", tt.scope, route, got, tt.want)
+			}
+		}
+	}
+
+	if !tokenScopeAllows("read", http.MethodHead, "/api/v1/jars") {
+		t.Error("read tokens should be allowed HEAD requests")
+	}
+}
+
+// TestAPIUnauthenticated checks requests without valid credentials get a JSON
+// 401 before any handler or database is reached
+func TestAPIUnauthenticated(t *testing.T) {
+	e := echo.New()
+	(&Handlers{}).RegisterRoutes(e)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+	}{
+		{"no credentials", "/api/v1/user", ""},
+		{"basic auth", "/api/v1/jars", "Basic dXNlcjpwYXNz"},
+		{"no scheme", "/api/v1/jars", "tj_abcdef"},
+		{"unknown route", "/api/v1/nothing-here", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			expectAPIError(t, rec, http.StatusUnauthorized, "unauthorized")
+			if got := rec.Header().Get(echo.HeaderWWWAuthenticate); got != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", got)
+			}
+		})
+	}
+}
+
+// expectAPIError checks the response is {"error": {"code", "message"}} with
+// the given status and code
+func expectAPIError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Errorf("status = %d, want %d (body %s)", rec.Code, status, rec.Body)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, echo.MIMEApplicationJSON) {
+		t.Errorf("content type = %q, want JSON", ct)
+	}
+
+	var body map[string]map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body %q: %v", rec.Body, err)
+	}
+	if len(body) != 1 || len(body["error"]) != 2 {
+		t.Errorf("error body = %s, want only error.code and error.message", rec.Body)
+	}
+	if body["error"]["code"] != code {
+		t.Errorf("error code = %v, want %s", body["error"]["code"], code)
+	}
+	if msg, _ := body["error"]["message"].(string); msg == "" {
+		t.Error("error message is empty")
+	}
+}
+
+// apiServer runs the full router against the test database and remembers
+// which API routes have answered successfully
+type apiServer struct {
+	t       *testing.T
+	e       *echo.Echo
+	h       *Handlers
+	covered map[string]bool
+}
+
+func newAPIServer(t *testing.T) *apiServer {
+	db := dbtest.Open(t)
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		BaseURL:        "http://tipjar.test",
+		SessionSecret:  "test-secret",
+		MaxUploadBytes: 1 << 20,
+	}
+
+	s := &apiServer{
+		t:       t,
+		e:       echo.New(),
+		h:       New(db, auth.NewRegistry(cfg), store, events.NewMemoryBus(), nil, cfg),
+		covered: make(map[string]bool),
+	}
+	s.e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if err == nil && c.Response().Status < 300 {
+				s.covered[c.Request().Method+" "+c.Path()] = true
+			}
+			return err
+		}
+	})
+	s.h.RegisterRoutes(s.e)
+	return s
+}
+
+// client signs up a user and gives them a token with scope
+func (s *apiServer) client(name, scope string) *apiClient {
+	s.t.Helper()
+
+	user := dbtest.User(s.t, s.h.db, name)
+	token, _, err := s.h.apiTokenService.CreateToken(context.Background(), int(user.ID), "test", scope)
+	if err != nil {
+		s.t.Fatalf("create token: %v", err)
+	}
+	return &apiClient{s: s, userID: int(user.ID), token: token}
+}
+
+// withScope returns a client for the same user using a new token with scope
+func (c *apiClient) withScope(scope string) *apiClient {
+	c.s.t.Helper()
+
+	token, _, err := c.s.h.apiTokenService.CreateToken(context.Background(), c.userID, scope, scope)
+	if err != nil {
+		c.s.t.Fatalf("create token: %v", err)
+	}
+	return &apiClient{s: c.s, userID: c.userID, token: token}
+}
+
+type apiClient struct {
+	s      *apiServer
+	userID int
+	token  string
+}
+
+func (c *apiClient) do(method, path string, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if c.token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+c.token)
+	}
+	rec := httptest.NewRecorder()
+	c.s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// ok makes a request that has to answer with status, decoding the body into out
+func (c *apiClient) ok(method, path string, body any, status int, out any) {
+	c.s.t.Helper()
+
+	rec := c.do(method, path, body)
+	if rec.Code != status {
+		c.s.t.Fatalf("%s %s = %d, want %d: %s", method, path, rec.Code, status, rec.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			c.s.t.Fatalf("%s %s: decode %s: %v", method, path, rec.Body, err)
+		}
+	}
+}
+
+// fails makes a request that has to fail with status and the matching code
+func (c *apiClient) fails(method, path string, body any, status int) {
+	c.s.t.Helper()
+
+	rec := c.do(method, path, body)
+	code, ok := apiErrorCodes[status]
+	if !ok {
+		c.s.t.Fatalf("no API error code for %d", status)
+	}
+	if rec.Code != status {
+		c.s.t.Errorf("%s %s = %d, want %d: %s", method, path, rec.Code, status, rec.Body)
+		return
+	}
+	expectAPIError(c.s.t, rec, status, code)
+}
+
+func TestAPIRoutes(t *testing.T) {
+	s := newAPIServer(t)
+
+	owner := s.client("owner", "full")
+	admin := s.client("admin", "full")
+	member := s.client("member", "full")
+	viewer := s.client("viewer", "full")
+	outsider := s.client("outsider", "full")
+	approved := s.client("approved", "full")
+	rejected := s.client("rejected", "full")
+
+	var me UserResponse
+	owner.ok("GET", "/api/v1/user", nil, 200, &me)
+	if me.ID != owner.userID {
+		t.Fatalf("GET /user = %+v, want user %d", me, owner.userID)
+	}
+
+	// Jars
+	var jar JarResponse
+	owner.ok("POST", "/api/v1/jars", CreateJarRequest{Name: "Office", Description: "Swear jar"}, 201, &jar)
+	jarPath := fmt.Sprintf("/api/v1/jars/%d", jar.ID)
+	owner.fails("POST", "/api/v1/jars", CreateJarRequest{Name: "  "}, 400)
+
+	var jars ListResponse[JarResponse]
+	owner.ok("GET", "/api/v1/jars", nil, 200, &jars)
+	if len(jars.Data) != 1 || jars.Data[0].ID != jar.ID ||
+		jars.Data[0].MemberCount == nil || *jars.Data[0].MemberCount != 1 {
+		t.Errorf("GET /jars = %+v, want the new jar with its owner", jars.Data)
+	}
+	outsider.ok("GET", "/api/v1/jars", nil, 200, &jars)
+	if len(jars.Data) != 0 {
+		t.Errorf("outsider sees %d jars, want none", len(jars.Data))
+	}
+
+	owner.ok("GET", jarPath, nil, 200, &jar)
+	name := "Office jar"
+	owner.ok("PATCH", jarPath, UpdateJarRequest{Name: &name}, 200, &jar)
+	if jar.Name != name {
+		t.Errorf("PATCH name = %q, want %q", jar.Name, name)
+	}
+
+	// Invites and joining
+	var invites ListResponse[InviteResponse]
+	owner.ok("GET", jarPath+"/invites", nil, 200, &invites)
+	if len(invites.Data) != 1 {
+		t.Errorf("got %d invites, want the original one", len(invites.Data))
+	}
+
+	inviteFor := func(role string) InviteResponse {
+		var invite InviteResponse
+		owner.ok("POST", jarPath+"/invites", CreateInviteRequest{Label: role, Role: role}, 201, &invite)
+		return invite
+	}
+	memberInvite, viewerInvite, adminInvite := inviteFor("member"), inviteFor("viewer"), inviteFor("admin")
+
+	var lookup struct {
+		Invite InviteResponse `json:"invite"`
+	}
+	outsider.ok("GET", "/api/v1/jars/lookup?invite_code="+url.QueryEscape(memberInvite.Code), nil, 200, &lookup)
+	if lookup.Invite.ID != memberInvite.ID {
+		t.Errorf("lookup found invite %d, want %d", lookup.Invite.ID, memberInvite.ID)
+	}
+	outsider.fails("GET", "/api/v1/jars/lookup", nil, 400)
+
+	member.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: memberInvite.Code}, 200, nil)
+	viewer.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: viewerInvite.Code}, 200, nil)
+	admin.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: adminInvite.Code}, 200, nil)
+	member.fails("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: memberInvite.Code}, 409)
+
+	var members ListResponse[MemberResponse]
+	viewer.ok("GET", jarPath+"/members", nil, 200, &members)
+	if len(members.Data) != 4 {
+		t.Errorf("got %d members, want 4", len(members.Data))
+	}
+
+	// Join requests
+	approval := true
+	owner.ok("PATCH", jarPath, UpdateJarRequest{JoinApprovalRequired: &approval}, 200, nil)
+
+	var pending JoinRequestResponse
+	approved.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: memberInvite.Code}, 202, &pending)
+	var other JoinRequestResponse
+	rejected.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: memberInvite.Code}, 202, &other)
+
+	var requests ListResponse[JoinRequestResponse]
+	admin.ok("GET", jarPath+"/join-requests", nil, 200, &requests)
+	if len(requests.Data) != 2 {
+		t.Errorf("got %d join requests, want 2", len(requests.Data))
+	}
+	member.fails("GET", jarPath+"/join-requests", nil, 403)
+
+	var answered JoinRequestResponse
+	admin.ok("POST", fmt.Sprintf("%s/join-requests/%d/approve", jarPath, pending.ID), ReviewJoinRequestRequest{Message: "Welcome"}, 200, &answered)
+	if answered.Status != "approved" {
+		t.Errorf("approved request has status %q", answered.Status)
+	}
+	admin.ok("POST", fmt.Sprintf("%s/join-requests/%d/reject", jarPath, other.ID), nil, 200, &answered)
+	if answered.Status != "rejected" {
+		t.Errorf("rejected request has status %q", answered.Status)
+	}
+	admin.fails("POST", fmt.Sprintf("%s/join-requests/%d/approve", jarPath, 999999), nil, 404)
+
+	approval = false
+	owner.ok("PATCH", jarPath, UpdateJarRequest{JoinApprovalRequired: &approval}, 200, nil)
+
+	var rotated InviteResponse
+	owner.ok("POST", fmt.Sprintf("%s/invites/%d/rotate", jarPath, memberInvite.ID), nil, 201, &rotated)
+	if rotated.Code == memberInvite.Code {
+		t.Error("rotating kept the invite code")
+	}
+	owner.ok("DELETE", fmt.Sprintf("%s/invites/%d", jarPath, adminInvite.ID), nil, 204, nil)
+	owner.fails("POST", fmt.Sprintf("%s/invites/%d/rotate", jarPath, adminInvite.ID), nil, 409)
+	member.fails("POST", jarPath+"/invites", CreateInviteRequest{}, 403)
+
+	// Members
+	var changed MemberResponse
+	owner.ok("PATCH", fmt.Sprintf("%s/members/%d", jarPath, approved.userID), UpdateMemberRequest{Role: "moderator"}, 200, &changed)
+	if changed.Role != "moderator" {
+		t.Errorf("role = %q, want moderator", changed.Role)
+	}
+	owner.fails("PATCH", fmt.Sprintf("%s/members/%d", jarPath, approved.userID), UpdateMemberRequest{Role: "emperor"}, 400)
+	member.fails("PATCH", fmt.Sprintf("%s/members/%d", jarPath, approved.userID), UpdateMemberRequest{Role: "member"}, 403)
+	owner.ok("DELETE", fmt.Sprintf("%s/members/%d", jarPath, approved.userID), nil, 204, nil)
+	owner.fails("DELETE", fmt.Sprintf("%s/members/%d", jarPath, outsider.userID), nil, 404)
+
+	// Offense types
+	var offenseType OffenseTypeResponse
+	cost := money.FromInt(10)
+	unit := "dollars"
+	typeName := "Swearing"
+	admin.ok("POST", jarPath+"/offense-types", OffenseTypeRequest{Name: &typeName, CostAmount: &cost, CostUnit: &unit}, 201, &offenseType)
+	member.fails("POST", jarPath+"/offense-types", OffenseTypeRequest{Name: &typeName}, 403)
+
+	var types ListResponse[OffenseTypeResponse]
+	viewer.ok("GET", jarPath+"/offense-types", nil, 200, &types)
+	if len(types.Data) == 0 {
+		t.Error("no offense types listed")
+	}
+
+	description := "Any four-letter word"
+	typePath := fmt.Sprintf("/api/v1/offense-types/%d", offenseType.ID)
+	admin.ok("PATCH", typePath, OffenseTypeRequest{Description: &description}, 200, &offenseType)
+	if offenseType.Description == nil || *offenseType.Description != description {
+		t.Errorf("description = %v, want %q", offenseType.Description, description)
+	}
+	outsider.fails("PATCH", typePath, OffenseTypeRequest{Description: &description}, 404)
+	member.fails("PATCH", "/api/v1/offense-types/999999", OffenseTypeRequest{}, 404)
+
+	// Offenses
+	report := CreateOffenseRequest{OffenseTypeID: offenseType.ID, OffenderID: member.userID, Notes: "In the standup"}
+	var offense OffenseResponse
+	owner.ok("POST", jarPath+"/offenses", report, 201, &offense)
+	if offense.Amount.Cmp(cost) != 0 || offense.Status != "pending" {
+		t.Errorf("offense = %+v, want %s pending", offense, cost)
+	}
+
+	var ownerOffense OffenseResponse
+	member.withScope("report").ok("POST", jarPath+"/offenses",
+		CreateOffenseRequest{OffenseTypeID: offenseType.ID, OffenderID: owner.userID}, 201, &ownerOffense)
+	viewer.fails("POST", jarPath+"/offenses", report, 403)
+	outsider.fails("POST", jarPath+"/offenses", report, 404)
+	owner.fails("POST", jarPath+"/offenses", CreateOffenseRequest{OffenseTypeID: offenseType.ID, OffenderID: outsider.userID}, 400)
+
+	var offenses ListResponse[OffenseResponse]
+	viewer.ok("GET", jarPath+"/offenses?limit=10", nil, 200, &offenses)
+	if len(offenses.Data) != 2 {
+		t.Errorf("got %d offenses, want 2", len(offenses.Data))
+	}
+	viewer.fails("GET", jarPath+"/offenses?limit=0", nil, 400)
+
+	offensePath := fmt.Sprintf("/api/v1/offenses/%d", offense.ID)
+	viewer.ok("GET", offensePath, nil, 200, &offense)
+	outsider.fails("GET", offensePath, nil, 404)
+	viewer.fails("GET", "/api/v1/offenses/999999", nil, 404)
+
+	// Rules
+	var rules JarRulesResponse
+	viewer.ok("GET", jarPath+"/rules", nil, 200, &rules)
+	if rules.MaxOffensesPerDay != nil {
+		t.Errorf("new jar has a daily limit of %d", *rules.MaxOffensesPerDay)
+	}
+	limit := 1
+	member.fails("PUT", jarPath+"/rules", JarRulesRequest{MaxOffensesPerDay: &limit}, 403)
+	owner.ok("PUT", jarPath+"/rules", JarRulesRequest{MaxOffensesPerDay: &limit}, 200, &rules)
+	owner.fails("POST", jarPath+"/offenses", report, 422)
+	zero := 0
+	owner.fails("PUT", jarPath+"/rules", JarRulesRequest{MaxOffensesPerDay: &zero}, 400)
+	owner.ok("PUT", jarPath+"/rules", JarRulesRequest{}, 200, &rules)
+
+	// Payments
+	verify := true
+	owner.ok("PATCH", jarPath, UpdateJarRequest{VerificationRequired: &verify}, 200, nil)
+
+	var payment PaymentResponse
+	part := money.FromInt(4)
+	member.ok("POST", "/api/v1/payments", CreatePaymentRequest{OffenseIDs: []int{offense.ID}, Amount: &part}, 201, &payment)
+	paymentPath := fmt.Sprintf("/api/v1/payments/%d", payment.ID)
+	viewer.ok("GET", paymentPath, nil, 200, &payment)
+	outsider.fails("GET", paymentPath, nil, 404)
+	member.fails("POST", paymentPath+"/verify", nil, 403)
+	viewer.fails("POST", paymentPath+"/verify", nil, 403)
+	admin.ok("POST", paymentPath+"/verify", nil, 200, &payment)
+	if !payment.Verified {
+		t.Error("payment isn't verified")
+	}
+	admin.fails("POST", paymentPath+"/reject", nil, 409)
+
+	var payments ListResponse[PaymentResponse]
+	viewer.ok("GET", offensePath+"/payments", nil, 200, &payments)
+	if len(payments.Data) != 1 {
+		t.Errorf("got %d payments, want 1", len(payments.Data))
+	}
+
+	member.ok("POST", "/api/v1/payments", CreatePaymentRequest{OffenseIDs: []int{offense.ID}, Amount: &part}, 201, &payment)
+	owner.ok("POST", fmt.Sprintf("/api/v1/payments/%d/reject", payment.ID), nil, 200, &payment)
+	if !payment.Rejected {
+		t.Error("payment isn't rejected")
+	}
+	member.fails("POST", "/api/v1/payments", CreatePaymentRequest{OffenseIDs: []int{ownerOffense.ID}}, 403)
+	member.fails("POST", "/api/v1/payments", CreatePaymentRequest{}, 400)
+	owner.ok("POST", "/api/v1/payments", CreatePaymentRequest{OffenseIDs: []int{ownerOffense.ID}}, 201, &payment)
+	admin.ok("POST", fmt.Sprintf("/api/v1/payments/%d/verify", payment.ID), nil, 200, nil)
+
+	// Periods
+	var periods ListResponse[PeriodResponse]
+	viewer.ok("GET", jarPath+"/periods", nil, 200, &periods)
+	if len(periods.Data) != 1 || periods.Data[0].ClosedAt != nil {
+		t.Fatalf("periods = %+v, want one open period", periods.Data)
+	}
+	first := periods.Data[0]
+
+	member.fails("POST", jarPath+"/periods/close", ClosePeriodRequest{}, 403)
+	var next PeriodResponse
+	owner.ok("POST", jarPath+"/periods/close", ClosePeriodRequest{Name: "Week 1", NextName: "Week 2", CarryOver: true}, 201, &next)
+	if next.Name != "Week 2" || next.ClosedAt != nil {
+		t.Errorf("next period = %+v", next)
+	}
+
+	var closed PeriodResponse
+	periodPath := fmt.Sprintf("%s/periods/%d", jarPath, first.ID)
+	viewer.ok("GET", periodPath, nil, 200, &closed)
+	if closed.Name != "Week 1" || closed.ClosedAt == nil || len(closed.Standings) == 0 {
+		t.Errorf("closed period = %+v, want it renamed with standings", closed)
+	}
+	var ledger ListResponse[LedgerEntryResponse]
+	viewer.ok("GET", periodPath+"/offenses", nil, 200, &ledger)
+	if len(ledger.Data) != 2 {
+		t.Errorf("got %d ledger entries, want 2", len(ledger.Data))
+	}
+	viewer.fails("GET", jarPath+"/periods/999999", nil, 404)
+	outsider.fails("GET", periodPath, nil, 404)
+
+	// Archiving
+	member.fails("POST", jarPath+"/archive", nil, 403)
+	admin.ok("POST", jarPath+"/archive", nil, 200, &jar)
+	if jar.ArchivedAt == nil {
+		t.Error("jar isn't archived")
+	}
+	owner.fails("POST", jarPath+"/offenses", report, 409)
+	admin.fails("POST", jarPath+"/archive", nil, 409)
+	admin.ok("POST", jarPath+"/restore", nil, 200, &jar)
+	if jar.ArchivedAt != nil {
+		t.Error("jar is still archived")
+	}
+
+	// Leaving, handing over and deleting
+	viewer.ok("DELETE", fmt.Sprintf("%s/members/%d", jarPath, viewer.userID), nil, 204, nil)
+	viewer.fails("GET", jarPath, nil, 404)
+
+	admin.fails("POST", jarPath+"/transfer", TransferOwnershipRequest{UserID: admin.userID}, 403)
+	owner.ok("POST", jarPath+"/transfer", TransferOwnershipRequest{UserID: admin.userID}, 200, &changed)
+	if changed.Role != "owner" {
+		t.Errorf("new owner has role %q", changed.Role)
+	}
+
+	owner.fails("DELETE", jarPath+"?confirm_name="+url.QueryEscape(name), nil, 403)
+	admin.fails("DELETE", jarPath+"?confirm_name=wrong", nil, 400)
+	admin.ok("DELETE", jarPath+"?confirm_name="+url.QueryEscape(name), nil, 204, nil)
+	admin.fails("GET", jarPath, nil, 404)
+
+	for _, route := range apiRoutes(s.e) {
+		if !s.covered[route] {
+			t.Errorf("%s was never called successfully", route)
+		}
+	}
+}
+
+func TestAPIAuthErrors(t *testing.T) {
+	s := newAPIServer(t)
+
+	owner := s.client("owner", "full")
+	member := s.client("member", "full")
+	outsider := s.client("outsider", "full")
+
+	var jar JarResponse
+	owner.ok("POST", "/api/v1/jars", CreateJarRequest{Name: "Scopes"}, 201, &jar)
+	jarPath := fmt.Sprintf("/api/v1/jars/%d", jar.ID)
+
+	var invites ListResponse[InviteResponse]
+	owner.ok("GET", jarPath+"/invites", nil, 200, &invites)
+	member.ok("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: invites.Data[0].Code}, 200, nil)
+
+	var offenseType OffenseTypeResponse
+	typeName := "Late"
+	owner.ok("POST", jarPath+"/offense-types", OffenseTypeRequest{Name: &typeName}, 201, &offenseType)
+	report := CreateOffenseRequest{OffenseTypeID: offenseType.ID, OffenderID: owner.userID}
+	name := "Renamed"
+
+	t.Run("401", func(t *testing.T) {
+		anonymous := &apiClient{s: s}
+		anonymous.fails("GET", jarPath, nil, 401)
+
+		bogus := &apiClient{s: s, token: "tj_not-a-real-token"}
+		rec := bogus.do("GET", jarPath, nil)
+		expectAPIError(t, rec, 401, "unauthorized")
+		if got := rec.Header().Get(echo.HeaderWWWAuthenticate); !strings.Contains(got, "invalid_token") {
+			t.Errorf("WWW-Authenticate = %q, want invalid_token", got)
+		}
+
+		token, created, err := s.h.apiTokenService.CreateToken(context.Background(), member.userID, "revoked", "full")
+		if err != nil {
+			t.Fatal(err)
+		}
+		revoked := &apiClient{s: s, userID: member.userID, token: token}
+		revoked.ok("GET", jarPath, nil, 200, nil)
+		if err := s.h.apiTokenService.RevokeToken(context.Background(), member.userID, created.ID); err != nil {
+			t.Fatal(err)
+		}
+		revoked.fails("GET", jarPath, nil, 401)
+	})
+
+	t.Run("403", func(t *testing.T) {
+		member.fails("PATCH", jarPath, UpdateJarRequest{Name: &name}, 403)
+		member.fails("POST", jarPath+"/archive", nil, 403)
+		member.fails("DELETE", jarPath+"?confirm_name=Scopes", nil, 403)
+	})
+
+	t.Run("404", func(t *testing.T) {
+		outsider.fails("GET", jarPath, nil, 404)
+		outsider.fails("GET", jarPath+"/members", nil, 404)
+		outsider.fails("PATCH", jarPath, UpdateJarRequest{Name: &name}, 404)
+		owner.fails("GET", "/api/v1/jars/999999", nil, 404)
+		owner.fails("GET", "/api/v1/payments/999999", nil, 404)
+	})
+
+	t.Run("400", func(t *testing.T) {
+		owner.fails("GET", "/api/v1/jars/abc", nil, 400)
+		owner.fails("GET", "/api/v1/offenses/abc", nil, 400)
+	})
+
+	t.Run("token scopes", func(t *testing.T) {
+		read := owner.withScope("read")
+		read.ok("GET", jarPath, nil, 200, nil)
+		read.ok("GET", jarPath+"/offenses", nil, 200, nil)
+		read.fails("POST", jarPath+"/offenses", report, 403)
+		read.fails("PATCH", jarPath, UpdateJarRequest{Name: &name}, 403)
+		read.fails("POST", "/api/v1/jars", CreateJarRequest{Name: "Nope"}, 403)
+
+		reporter := member.withScope("report")
+		reporter.ok("GET", jarPath+"/members", nil, 200, nil)
+		reporter.ok("POST", jarPath+"/offenses", report, 201, nil)
+		reporter.fails("POST", "/api/v1/payments", CreatePaymentRequest{OffenseIDs: []int{1}}, 403)
+		reporter.fails("POST", "/api/v1/jars/join", JoinJarRequest{InviteCode: "x"}, 403)
+
+		full := owner.withScope("full")
+		full.ok("PATCH", jarPath, UpdateJarRequest{Name: &name}, 200, nil)
+
+		// A scope never grants more than the user's role does
+		member.withScope("full").fails("PATCH", jarPath, UpdateJarRequest{Name: &name}, 403)
+	})
+}
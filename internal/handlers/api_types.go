@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"time"
+
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+)
+
+// Response and request bodies for the /api/v1 endpoints. These are kept apart
+// from the models so the API doesn't change shape when the models do.
+
+type APIError struct {
+	Error APIErrorBody `json:"error"`
+}
+
+type APIErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ListResponse[T any] struct {
+	Data []T `json:"data"`
+}
+
+type UserResponse struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Avatar    *string   `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type JarResponse struct {
+	ID                   int        `json:"id"`
+	Name                 string     `json:"name"`
+	Description          *string    `json:"description"`
+	CreatedBy            int        `json:"created_by"`
+	MemberCount          *int       `json:"member_count,omitempty"`
+	DisputeQuorum        int        `json:"dispute_quorum"`
+	DisputeWindowHours   int        `json:"dispute_window_hours"`
+	VerificationRequired bool       `json:"verification_required"`
+	VerificationsNeeded  int        `json:"verifications_needed"`
+	JoinApprovalRequired bool       `json:"join_approval_required"`
+	ArchivedAt           *time.Time `json:"archived_at"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+type MemberResponse struct {
+	UserID   int       `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Avatar   *string   `json:"avatar"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type InviteResponse struct {
+	ID          int                  `json:"id"`
+	JarID       int                  `json:"jar_id"`
+	Code        string               `json:"code"`
+	Label       *string              `json:"label"`
+	Role        string               `json:"role"`
+	MaxUses     *int                 `json:"max_uses"`
+	Uses        int                  `json:"uses"`
+	ExpiresAt   *time.Time           `json:"expires_at"`
+	RevokedAt   *time.Time           `json:"revoked_at"`
+	Status      string               `json:"status"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Redemptions []RedemptionResponse `json:"redemptions,omitempty"`
+}
+
+type RedemptionResponse struct {
+	UserID    int       `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JoinRequestResponse leaves out the requester's details when they're the one
+// asking
+type JoinRequestResponse struct {
+	ID         int        `json:"id"`
+	JarID      int        `json:"jar_id"`
+	UserID     int        `json:"user_id"`
+	UserName   string     `json:"user_name,omitempty"`
+	UserEmail  string     `json:"user_email,omitempty"`
+	InviteID   int        `json:"invite_id"`
+	Role       string     `json:"role,omitempty"`
+	Status     string     `json:"status"`
+	Message    *string    `json:"message"`
+	ReviewedBy *int       `json:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type OffenseTypeResponse struct {
+	ID          int           `json:"id"`
+	JarID       int           `json:"jar_id"`
+	Name        string        `json:"name"`
+	Description *string       `json:"description"`
+	CostAmount  *money.Amount `json:"cost_amount"`
+	CostUnit    *string       `json:"cost_unit"`
+	IsActive    bool          `json:"is_active"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+type OffenseResponse struct {
+	ID              int          `json:"id"`
+	JarID           int          `json:"jar_id"`
+	OffenseTypeName string       `json:"offense_type_name"`
+	ReporterID      int          `json:"reporter_id"`
+	ReporterName    string       `json:"reporter_name"`
+	OffenderID      int          `json:"offender_id"`
+	OffenderName    string       `json:"offender_name"`
+	Notes           *string      `json:"notes"`
+	Amount          money.Amount `json:"amount"`
+	Paid            money.Amount `json:"paid"`
+	Remaining       money.Amount `json:"remaining"`
+	Unit            string       `json:"unit"`
+	Status          string       `json:"status"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+type JarRulesResponse struct {
+	JarID                 int           `json:"jar_id"`
+	MaxOffensesPerDay     *int          `json:"max_offenses_per_day"`
+	ReportCooldownMinutes *int          `json:"report_cooldown_minutes"`
+	EscalationWindowDays  *int          `json:"escalation_window_days"`
+	EscalationMultiplier  *money.Amount `json:"escalation_multiplier"`
+	UpdatedAt             *time.Time    `json:"updated_at"`
+}
+
+// PeriodResponse carries standings only when a single closed period is
+// fetched
+type PeriodResponse struct {
+	ID          int                      `json:"id"`
+	JarID       int                      `json:"jar_id"`
+	Name        string                   `json:"name"`
+	StartedAt   time.Time                `json:"started_at"`
+	ClosedAt    *time.Time               `json:"closed_at"`
+	ClosedBy    *int                     `json:"closed_by"`
+	CarriedOver bool                     `json:"carried_over"`
+	Standings   []PeriodStandingResponse `json:"standings,omitempty"`
+}
+
+type PeriodStandingResponse struct {
+	UserID       int          `json:"user_id"`
+	Name         string       `json:"name"`
+	Unit         string       `json:"unit"`
+	OffenseCount int          `json:"offense_count"`
+	Total        money.Amount `json:"total"`
+	Paid         money.Amount `json:"paid"`
+	Unpaid       money.Amount `json:"unpaid"`
+}
+
+// LedgerEntryResponse is an offense as listed in a period's ledger. The two
+// period IDs differ when it was carried over.
+type LedgerEntryResponse struct {
+	ID               int           `json:"id"`
+	OffenseTypeName  string        `json:"offense_type_name"`
+	ReporterID       int           `json:"reporter_id"`
+	ReporterName     string        `json:"reporter_name"`
+	OffenderID       int           `json:"offender_id"`
+	OffenderName     string        `json:"offender_name"`
+	Notes            *string       `json:"notes"`
+	Amount           *money.Amount `json:"amount"`
+	Unit             *string       `json:"unit"`
+	Status           string        `json:"status"`
+	PeriodID         int           `json:"period_id"`
+	ReportedPeriodID int           `json:"reported_period_id"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
+
+type PaymentResponse struct {
+	ID          int                  `json:"id"`
+	UserID      int                  `json:"user_id"`
+	Amount      *money.Amount        `json:"amount"`
+	ProofType   *string              `json:"proof_type"`
+	ProofURL    *string              `json:"proof_url"` // Where members can fetch the proof, not the storage key
+	Verified    bool                 `json:"verified"`
+	Rejected    bool                 `json:"rejected"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Allocations []AllocationResponse `json:"allocations,omitempty"`
+}
+
+type AllocationResponse struct {
+	OffenseID       int           `json:"offense_id"`
+	OffenseTypeName string        `json:"offense_type_name"`
+	Amount          *money.Amount `json:"amount"`
+	Unit            *string       `json:"unit"`
+}
+
+type CreateJarRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UpdateJarRequest only changes the fields that are present
+type UpdateJarRequest struct {
+	Name                 *string `json:"name"`
+	Description          *string `json:"description"`
+	DisputeQuorum        *int    `json:"dispute_quorum"`
+	DisputeWindowHours   *int    `json:"dispute_window_hours"`
+	VerificationRequired *bool   `json:"verification_required"`
+	VerificationsNeeded  *int    `json:"verifications_needed"`
+	JoinApprovalRequired *bool   `json:"join_approval_required"`
+}
+
+type JoinJarRequest struct {
+	InviteCode string `json:"invite_code"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// CreateInviteRequest leaves out max_uses or expires_at for an invite that
+// doesn't run out that way. Role defaults to member.
+type CreateInviteRequest struct {
+	Label     string     `json:"label"`
+	Role      string     `json:"role"`
+	MaxUses   *int       `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ReviewJoinRequestRequest struct {
+	Message string `json:"message"`
+}
+
+type OffenseTypeRequest struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	CostAmount  *money.Amount `json:"cost_amount"`
+	CostUnit    *string       `json:"cost_unit"`
+	IsActive    *bool         `json:"is_active"`
+}
+
+type CreateOffenseRequest struct {
+	OffenseTypeID int           `json:"offense_type_id"`
+	OffenderID    int           `json:"offender_id"`
+	Notes         string        `json:"notes"`
+	CostOverride  *money.Amount `json:"cost_override"`
+}
+
+// CreatePaymentRequest pays off the listed offenses. Amount can only be given
+// for a single offense and defaults to whatever is left.
+type CreatePaymentRequest struct {
+	OffenseIDs []int         `json:"offense_ids"`
+	Amount     *money.Amount `json:"amount"`
+}
+
+// JarRulesRequest replaces all of a jar's rules; null or missing turns a rule
+// off
+type JarRulesRequest struct {
+	MaxOffensesPerDay     *int          `json:"max_offenses_per_day"`
+	ReportCooldownMinutes *int          `json:"report_cooldown_minutes"`
+	EscalationWindowDays  *int          `json:"escalation_window_days"`
+	EscalationMultiplier  *money.Amount `json:"escalation_multiplier"`
+}
+
+// ClosePeriodRequest renames the closing period to Name unless it's empty
+type ClosePeriodRequest struct {
+	Name      string `json:"name"`
+	NextName  string `json:"next_name"`
+	CarryOver bool   `json:"carry_over"`
+}
+
+func toUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Avatar:    user.Avatar,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func toJarResponse(jar *models.TipJar) JarResponse {
+	return JarResponse{
+		ID:                   jar.ID,
+		Name:                 jar.Name,
+		Description:          jar.Description,
+		CreatedBy:            jar.CreatedBy,
+		DisputeQuorum:        jar.DisputeQuorum,
+		DisputeWindowHours:   jar.DisputeWindowHours,
+		VerificationRequired: jar.VerificationRequired,
+		VerificationsNeeded:  jar.VerificationsNeeded,
+		JoinApprovalRequired: jar.JoinApprovalRequired,
+		ArchivedAt:           jar.ArchivedAt,
+		CreatedAt:            jar.CreatedAt,
+		UpdatedAt:            jar.UpdatedAt,
+	}
+}
+
+func toMemberResponse(member models.JarMemberInfo) MemberResponse {
+	var avatar *string
+	if member.Avatar != "" {
+		avatar = &member.Avatar
+	}
+
+	return MemberResponse{
+		UserID:   member.UserID,
+		Name:     member.Name,
+		Email:    member.Email,
+		Avatar:   avatar,
+		Role:     member.Role,
+		JoinedAt: member.JoinedAt,
+	}
+}
+
+func toInviteResponse(invite *models.Invite) InviteResponse {
+	var redemptions []RedemptionResponse
+	for _, redemption := range invite.Redemptions {
+		redemptions = append(redemptions, RedemptionResponse{
+			UserID:    redemption.UserID,
+			UserName:  redemption.UserName,
+			CreatedAt: redemption.CreatedAt,
+		})
+	}
+
+	return InviteResponse{
+		ID:          invite.ID,
+		JarID:       invite.JarID,
+		Code:        invite.Code,
+		Label:       invite.Label,
+		Role:        invite.Role,
+		MaxUses:     invite.MaxUses,
+		Uses:        invite.Uses,
+		ExpiresAt:   invite.ExpiresAt,
+		RevokedAt:   invite.RevokedAt,
+		Status:      invite.Status,
+		CreatedAt:   invite.CreatedAt,
+		Redemptions: redemptions,
+	}
+}
+
+func toJoinRequestResponse(request *models.JoinRequest) JoinRequestResponse {
+	return JoinRequestResponse{
+		ID:         request.ID,
+		JarID:      request.JarID,
+		UserID:     request.UserID,
+		UserName:   request.UserName,
+		UserEmail:  request.UserEmail,
+		InviteID:   request.InviteID,
+		Role:       request.Role,
+		Status:     request.Status,
+		Message:    request.Message,
+		ReviewedBy: request.ReviewedBy,
+		ReviewedAt: request.ReviewedAt,
+		CreatedAt:  request.CreatedAt,
+	}
+}
+
+func toOffenseTypeResponse(offenseType *models.OffenseType) OffenseTypeResponse {
+	return OffenseTypeResponse{
+		ID:          offenseType.ID,
+		JarID:       offenseType.JarID,
+		Name:        offenseType.Name,
+		Description: offenseType.Description,
+		CostAmount:  offenseType.CostAmount,
+		CostUnit:    offenseType.CostUnit,
+		IsActive:    offenseType.IsActive,
+		CreatedAt:   offenseType.CreatedAt,
+		UpdatedAt:   offenseType.UpdatedAt,
+	}
+}
+
+func toOffenseResponse(offense *models.OffenseDetail) OffenseResponse {
+	return OffenseResponse{
+		ID:              offense.ID,
+		JarID:           offense.JarID,
+		OffenseTypeName: offense.OffenseTypeName,
+		ReporterID:      offense.ReporterID,
+		ReporterName:    offense.ReporterName,
+		OffenderID:      offense.OffenderID,
+		OffenderName:    offense.OffenderName,
+		Notes:           offense.Notes,
+		Amount:          offense.Amount,
+		Paid:            offense.Paid,
+		Remaining:       offense.Remaining,
+		Unit:            offense.Unit,
+		Status:          offense.Status,
+		CreatedAt:       offense.CreatedAt,
+	}
+}
+
+func toPaymentResponse(payment *models.Payment) PaymentResponse {
+	var proofURL *string
+	if payment.ProofURL != nil {
+		url := proofPath(payment.ID)
+		proofURL = &url
+	}
+
+	allocations := make([]AllocationResponse, len(payment.Allocations))
+	for i, allocation := range payment.Allocations {
+		allocations[i] = AllocationResponse{
+			OffenseID:       allocation.OffenseID,
+			OffenseTypeName: allocation.OffenseTypeName,
+			Amount:          allocation.Amount,
+			Unit:            allocation.Unit,
+		}
+	}
+
+	return PaymentResponse{
+		ID:          payment.ID,
+		UserID:      payment.UserID,
+		Amount:      payment.Amount,
+		ProofType:   payment.ProofType,
+		ProofURL:    proofURL,
+		Verified:    payment.Verified,
+		Rejected:    payment.Rejected,
+		CreatedAt:   payment.CreatedAt,
+		Allocations: allocations,
+	}
+}
+
+func toJarRulesResponse(rules *models.JarRules) JarRulesResponse {
+	return JarRulesResponse{
+		JarID:                 rules.JarID,
+		MaxOffensesPerDay:     rules.MaxOffensesPerDay,
+		ReportCooldownMinutes: rules.ReportCooldownMinutes,
+		EscalationWindowDays:  rules.EscalationWindowDays,
+		EscalationMultiplier:  rules.EscalationMultiplier,
+		UpdatedAt:             rules.UpdatedAt,
+	}
+}
+
+func toPeriodResponse(period *models.JarPeriod, standings []models.PeriodStandingSummary) PeriodResponse {
+	response := PeriodResponse{
+		ID:          period.ID,
+		JarID:       period.JarID,
+		Name:        period.Name,
+		StartedAt:   period.StartedAt,
+		ClosedAt:    period.ClosedAt,
+		ClosedBy:    period.ClosedBy,
+		CarriedOver: period.CarriedOver,
+	}
+	for _, summary := range standings {
+		for _, standing := range summary.Standings {
+			response.Standings = append(response.Standings, PeriodStandingResponse{
+				UserID:       standing.UserID,
+				Name:         standing.Name,
+				Unit:         standing.Unit,
+				OffenseCount: standing.OffenseCount,
+				Total:        standing.Total,
+				Paid:         standing.Paid,
+				Unpaid:       standing.Unpaid,
+			})
+		}
+	}
+	return response
+}
+
+func toLedgerEntryResponse(activity *models.JarActivity) LedgerEntryResponse {
+	return LedgerEntryResponse{
+		ID:               activity.ID,
+		OffenseTypeName:  activity.OffenseTypeName,
+		ReporterID:       activity.ReporterID,
+		ReporterName:     activity.ReporterName,
+		OffenderID:       activity.OffenderID,
+		OffenderName:     activity.OffenderName,
+		Notes:            activity.Notes,
+		Amount:           activity.CostAmount,
+		Unit:             activity.CostUnit,
+		Status:           activity.Status,
+		PeriodID:         activity.PeriodID,
+		ReportedPeriodID: activity.ReportedPeriodID,
+		CreatedAt:        activity.CreatedAt,
+	}
+}
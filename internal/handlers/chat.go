@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tipjar/internal/services"
+	"tipjar/internal/templates"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Slash commands from Slack and Discord. Both send the command to a public
+// endpoint, so each request's signature is checked against the app's key
+// before anything else is done with it. Replies are ephemeral: only the
+// person who ran the command sees them.
+
+// slackMaxSkew is how old a Slack request can be before it's treated as a
+// replay
+const slackMaxSkew = 5 * time.Minute
+
+// maxChatBody caps what's read from a chat provider before the signature has
+// been checked
+const maxChatBody = 64 << 10
+
+const chatCommandFailed = "Something went wrong. Please try again."
+
+// discordInteraction is the part of a Discord interaction payload that
+// commands need
+type discordInteraction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"` // Set instead of Member in DMs
+	Data struct {
+		Name    string          `json:"name"`
+		Options []discordOption `json:"options"`
+	} `json:"data"`
+}
+
+type discordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type discordOption struct {
+	Name    string          `json:"name"`
+	Type    int             `json:"type"`
+	Value   json.RawMessage `json:"value"`
+	Options []discordOption `json:"options"`
+}
+
+const (
+	discordPing               = 1
+	discordApplicationCommand = 2
+
+	discordPong                     = 1
+	discordChannelMessageWithSource = 4
+	discordEphemeral                = 1 << 6
+)
+
+func (h *Handlers) handleSlackCommand(c echo.Context) error {
+	if h.cfg.SlackSigningSecret == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "Slack commands are not enabled")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxChatBody))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request")
+	}
+
+	if !verifySlackSignature(h.cfg.SlackSigningSecret, c.Request().Header, body, time.Now()) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid request signature")
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid command")
+	}
+
+	cmd := parseSlackCommand(form.Get("text"))
+	cmd.Provider = "slack"
+	cmd.WorkspaceID = form.Get("team_id")
+	cmd.ChannelID = form.Get("channel_id")
+	cmd.UserID = form.Get("user_id")
+	cmd.UserName = form.Get("user_name")
+
+	reply, err := h.chatService.Run(c.Request().Context(), cmd)
+	if err != nil {
+		c.Logger().Error("Failed to run Slack command", "error", err)
+		reply = chatCommandFailed
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"response_type": "ephemeral",
+		"text":          reply,
+	})
+}
+
+func (h *Handlers) handleDiscordInteraction(c echo.Context) error {
+	if h.cfg.DiscordPublicKey == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "Discord commands are not enabled")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxChatBody))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request")
+	}
+
+	// Discord checks that unsigned requests are refused before it will use
+	// the endpoint
+	if !verifyDiscordSignature(h.cfg.DiscordPublicKey, c.Request().Header, body) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid request signature")
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid interaction")
+	}
+
+	switch interaction.Type {
+	case discordPing:
+		return c.JSON(http.StatusOK, map[string]int{"type": discordPong})
+	case discordApplicationCommand:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported interaction")
+	}
+
+	user := interaction.User
+	if interaction.Member != nil {
+		user = &interaction.Member.User
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Interaction has no user")
+	}
+
+	cmd := parseDiscordCommand(interaction.Data.Options)
+	cmd.Provider = "discord"
+	cmd.ChannelID = interaction.ChannelID
+	cmd.UserID = user.ID
+	cmd.UserName = user.Username
+
+	reply, err := h.chatService.Run(c.Request().Context(), cmd)
+	if err != nil {
+		c.Logger().Error("Failed to run Discord command", "error", err)
+		reply = chatCommandFailed
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"type": discordChannelMessageWithSource,
+		"data": map[string]any{
+			"content": reply,
+			"flags":   discordEphemeral,
+		},
+	})
+}
+
+func (h *Handlers) handleChatLinkForm(c echo.Context) error {
+	user := h.getCurrentUser(c)
+	token := c.Param("token")
+
+	request, err := h.chatService.GetLinkRequest(c.Request().Context(), token)
+	if err != nil {
+		c.Logger().Error("Failed to get chat link request", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load link")
+	}
+
+	if request == nil {
+		return echo.NewHTTPError(http.StatusNotFound, services.ErrChatLinkNotFound.Error())
+	}
+
+	return h.renderTemplate(c, templates.ChatLink(user, request, token))
+}
+
+func (h *Handlers) handleChatLink(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	account, err := h.chatService.CompleteLink(c.Request().Context(), c.Param("token"), user.ID)
+	if err != nil {
+		if err == services.ErrChatLinkNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		c.Logger().Error("Failed to link chat account", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to link account")
+	}
+
+	return h.renderTemplate(c, templates.ChatLinked(user, account))
+}
+
+// parseSlackCommand reads the text after "/tipjar", e.g.
+// `report <@U123|alice> "Late to standup" again`. Slack only sends mentions
+// as <@ID|name> when the command has "Escape channels, users, and links"
+// turned on.
+func parseSlackCommand(text string) services.ChatCommand {
+	action, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	cmd := services.ChatCommand{Action: strings.ToLower(action)}
+	rest = strings.TrimSpace(rest)
+
+	if mention, after, ok := strings.Cut(rest, ">"); ok && strings.HasPrefix(mention, "<@") {
+		cmd.Target, _, _ = strings.Cut(strings.TrimPrefix(mention, "<@"), "|")
+		rest = strings.TrimSpace(after)
+	}
+
+	cmd.Text = rest
+	return cmd
+}
+
+// parseDiscordCommand reads the subcommand of "/tipjar" and its options. The
+// command is registered with subcommands report (user, offense, notes),
+// balance (user), connect (code), link and unlink.
+func parseDiscordCommand(options []discordOption) services.ChatCommand {
+	var cmd services.ChatCommand
+	if len(options) == 0 {
+		return cmd
+	}
+
+	subcommand := options[0]
+	cmd.Action = subcommand.Name
+
+	for _, option := range subcommand.Options {
+		var value string
+		if err := json.Unmarshal(option.Value, &value); err != nil {
+			continue
+		}
+
+		switch option.Name {
+		case "user":
+			cmd.Target = value
+		case "offense", "code":
+			cmd.Text = value
+		case "notes":
+			cmd.Notes = value
+		}
+	}
+
+	return cmd
+}
+
+// verifySlackSignature checks X-Slack-Signature, which is "v0=" followed by
+// the hex HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(sent, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// verifyDiscordSignature checks X-Signature-Ed25519, an Ed25519 signature of
+// the timestamp followed by the body, against the application's public key
+func verifyDiscordSignature(publicKey string, header http.Header, body []byte) bool {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+
+	message := append([]byte(header.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(ed25519.PublicKey(key), message, signature)
+}
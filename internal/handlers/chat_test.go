@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tipjar/internal/config"
+	"tipjar/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const testSlackSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// newChatServer registers the routes with Slack and Discord commands enabled.
+// The commands sent here only get usage back, so no database is needed.
+func newChatServer(discordKey ed25519.PublicKey) *echo.Echo {
+	e := echo.New()
+	h := &Handlers{
+		cfg: &config.Config{
+			SlackSigningSecret: testSlackSecret,
+			DiscordPublicKey:   hex.EncodeToString(discordKey),
+		},
+		chatService: services.NewChatService(nil, nil, nil, nil, ""),
+	}
+	h.RegisterRoutes(e)
+	return e
+}
+
+func signSlack(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSlackCommandSignature(t *testing.T) {
+	e := newChatServer(make(ed25519.PublicKey, ed25519.PublicKeySize))
+
+	body := "team_id=T1&channel_id=C1&user_id=U1&user_name=alice&text=help"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-slackMaxSkew-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(slackMaxSkew+time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		signature string
+		want      int
+	}{
+		{"valid", body, now, signSlack(testSlackSecret, now, body), http.StatusOK},
+		{"tampered body", body + "&x=1", now, signSlack(testSlackSecret, now, body), http.StatusUnauthorized},
+		{"wrong secret", body, now, signSlack("other", now, body), http.StatusUnauthorized},
+		{"signature without prefix", body, now, strings.TrimPrefix(signSlack(testSlackSecret, now, body), "v0="), http.StatusUnauthorized},
+		{"missing signature", body, now, "", http.StatusUnauthorized},
+		{"missing timestamp", body, "", signSlack(testSlackSecret, "", body), http.StatusUnauthorized},
+		{"signed with another timestamp", body, now, signSlack(testSlackSecret, stale, body), http.StatusUnauthorized},
+		{"stale timestamp", body, stale, signSlack(testSlackSecret, stale, body), http.StatusUnauthorized},
+		{"future timestamp", body, future, signSlack(testSlackSecret, future, body), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/chat/slack", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			if tt.timestamp != "" {
+				req.Header.Set("X-Slack-Request-Timestamp", tt.timestamp)
+			}
+			if tt.signature != "" {
+				req.Header.Set("X-Slack-Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var reply map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply["response_type"] != "ephemeral" || !strings.HasPrefix(reply["text"], "Usage:") {
+				t.Errorf("reply = %v, want ephemeral usage", reply)
+			}
+		})
+	}
+}
+
+func TestDiscordInteractionSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newChatServer(publicKey)
+
+	sign := func(key ed25519.PrivateKey, timestamp, body string) string {
+		return hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body)))
+	}
+
+	ping := `{"type":1}`
+	command := `{"type":2,"channel_id":"C1","user":{"id":"U1","username":"alice"},"data":{"name":"tipjar"}}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		signature string
+		want      int
+		wantType  int
+	}{
+		{"valid ping", ping, now, sign(privateKey, now, ping), http.StatusOK, discordPong},
+		{"valid command", command, now, sign(privateKey, now, command), http.StatusOK, discordChannelMessageWithSource},
+		{"tampered body", command, now, sign(privateKey, now, ping), http.StatusUnauthorized, 0},
+		{"tampered timestamp", ping, now + "0", sign(privateKey, now, ping), http.StatusUnauthorized, 0},
+		{"wrong key", ping, now, sign(otherKey, now, ping), http.StatusUnauthorized, 0},
+		{"signature not hex", ping, now, "not-a-signature", http.StatusUnauthorized, 0},
+		{"truncated signature", ping, now, sign(privateKey, now, ping)[:64], http.StatusUnauthorized, 0},
+		{"missing signature", ping, now, "", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/chat/discord", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("X-Signature-Timestamp", tt.timestamp)
+			if tt.signature != "" {
+				req.Header.Set("X-Signature-Ed25519", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var reply struct {
+				Type int `json:"type"`
+				Data struct {
+					Content string `json:"content"`
+					Flags   int    `json:"flags"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.Type != tt.wantType {
+				t.Errorf("type = %d, want %d", reply.Type, tt.wantType)
+			}
+			if tt.wantType == discordChannelMessageWithSource &&
+				(reply.Data.Flags != discordEphemeral || !strings.HasPrefix(reply.Data.Content, "Usage:")) {
+				t.Errorf("reply = %+v, want ephemeral usage", reply.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tipjar/internal/authz"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sseKeepAlive is how often an idle event stream gets a comment, so proxies
+// don't close it
+const sseKeepAlive = 25 * time.Second
+
+// handleJarEvents streams the jar's events to its members as Server-Sent
+// Events. The jar page listens and refreshes its feed and balances.
+func (h *Handlers) handleJarEvents(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.ViewJar); err != nil {
+		return err
+	}
+
+	stream, unsubscribe := h.events.Subscribe(jarID)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event, ok := <-stream:
+			if !ok {
+				return nil
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				c.Logger().Error("Failed to encode jar event", "error", err)
+				continue
+			}
+
+			// Unnamed, so one onmessage handler sees every type; the type is
+			// in the data
+			if _, err := fmt.Fprintf(res, "data: %s\n\n", data); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
@@ -1,18 +1,26 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"tipjar"
 	"tipjar/internal/auth"
+	"tipjar/internal/authz"
 	"tipjar/internal/config"
 	"tipjar/internal/database"
+	"tipjar/internal/email"
+	"tipjar/internal/events"
+	"tipjar/internal/invitecode"
 	"tipjar/internal/models"
+	"tipjar/internal/money"
 	"tipjar/internal/services"
+	"tipjar/internal/storage"
 	"tipjar/internal/templates"
 
 	"github.com/a-h/templ"
@@ -20,24 +28,54 @@ import (
 )
 
 type Handlers struct {
-	db             *database.DB
-	auth           *auth.Service
-	cfg            *config.Config
-	userService    *services.UserService
-	tipJarService  *services.TipJarService
-	offenseService *services.OffenseService
-	sessionService *services.SessionService
+	db              *database.DB
+	auth            *auth.Registry
+	cfg             *config.Config
+	events          events.Bus
+	userService     *services.UserService
+	tipJarService   *services.TipJarService
+	offenseService  *services.OffenseService
+	sessionService  *services.SessionService
+	disputeService  *services.DisputeService
+	verifyService   *services.VerificationService
+	proofService    *services.ProofService
+	apiTokenService *services.APITokenService
+	webhookService  *services.WebhookService
+	chatService     *services.ChatService
+	notifyService   *services.NotificationService
+	emailService    *services.EmailService
+	inviteService   *services.InviteService
+	joinService     *services.JoinRequestService
+	periodService   *services.PeriodService
+	ruleService     *services.RuleService
 }
 
-func New(db *database.DB, authService *auth.Service, cfg *config.Config) *Handlers {
+func New(db *database.DB, authRegistry *auth.Registry, store storage.Storage, bus events.Bus, mailer *email.Mailer, cfg *config.Config) *Handlers {
+	tipJarService := services.NewTipJarService(db, bus)
+	offenseService := services.NewOffenseService(db, bus)
+	inviteService := services.NewInviteService(db, bus)
+
 	return &Handlers{
-		db:             db,
-		auth:           authService,
-		cfg:            cfg,
-		userService:    services.NewUserService(db),
-		tipJarService:  services.NewTipJarService(db),
-		offenseService: services.NewOffenseService(db),
-		sessionService: services.NewSessionService(cfg.SessionSecret),
+		db:              db,
+		auth:            authRegistry,
+		cfg:             cfg,
+		events:          bus,
+		userService:     services.NewUserService(db),
+		tipJarService:   tipJarService,
+		offenseService:  offenseService,
+		sessionService:  services.NewSessionService(db, cfg.SessionSecret),
+		disputeService:  services.NewDisputeService(db, bus),
+		verifyService:   services.NewVerificationService(db, bus),
+		proofService:    services.NewProofService(store, cfg.MaxUploadBytes),
+		apiTokenService: services.NewAPITokenService(db, cfg.SessionSecret),
+		webhookService:  services.NewWebhookService(db),
+		chatService:     services.NewChatService(db, offenseService, tipJarService, inviteService, cfg.BaseURL),
+		notifyService:   services.NewNotificationService(db),
+		emailService:    services.NewEmailService(db, mailer, cfg.BaseURL, cfg.SessionSecret),
+		inviteService:   inviteService,
+		joinService:     services.NewJoinRequestService(db, bus),
+		periodService:   services.NewPeriodService(db, bus),
+		ruleService:     services.NewRuleService(db),
 	}
 }
 
@@ -48,10 +86,22 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) {
 	// Public routes
 	e.GET("/", h.handleHome)
 	e.GET("/login", h.handleLogin)
-	e.GET("/auth/google", h.handleGoogleAuth)
-	e.GET("/auth/callback", h.handleAuthCallback)
+	e.GET("/auth/:provider", h.handleOAuthStart)
+	e.GET("/auth/:provider/callback", h.handleOAuthCallback)
 	e.POST("/logout", h.handleLogout)
 
+	// Invite links work signed out; the code is kept through logging in
+	e.GET("/join/:code", h.handleJoinLink)
+
+	// Slash commands, authenticated by the chat provider's request signature
+	e.POST("/chat/slack", h.handleSlackCommand)
+	e.POST("/chat/discord", h.handleDiscordInteraction)
+
+	// Unsubscribe links in emails, authenticated by their signature. Mail
+	// clients offering one-click unsubscribe POST to the same URL.
+	e.GET("/email/unsubscribe", h.handleUnsubscribe)
+	e.POST("/email/unsubscribe", h.handleUnsubscribe)
+
 	// Protected routes
 	protected := e.Group("")
 	protected.Use(h.requireAuth)
@@ -62,25 +112,72 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) {
 	protected.GET("/jars/join", h.handleJoinJarForm)
 	protected.POST("/jars/join", h.handleJoinJar)
 	protected.GET("/jars/:id", h.handleViewJar)
+	protected.GET("/jars/:id/events", h.handleJarEvents)
 	protected.GET("/jars/:id/report", h.handleReportOffenseForm)
 	protected.POST("/jars/:id/report", h.handleReportOffense)
+	protected.GET("/jars/:id/pay", h.handlePayBatch)
+	protected.POST("/jars/:id/pay", h.handlePayBatch)
 	protected.POST("/offenses/:id/pay", h.handlePayOffense)
 	protected.GET("/jars/:id/settings", h.handleJarSettings)
 	protected.POST("/jars/:id/settings", h.handleUpdateJarSettings)
+	protected.POST("/jars/:id/leave", h.handleLeaveJar)
+	protected.POST("/jars/:id/archive", h.handleArchiveJar)
+	protected.POST("/jars/:id/restore", h.handleRestoreJar)
+	protected.POST("/jars/:id/delete", h.handleDeleteJar)
+	protected.POST("/jars/:id/periods/close", h.handleClosePeriod)
+	protected.POST("/jars/:id/rules", h.handleUpdateJarRules)
+	protected.POST("/jars/:id/members/:user_id/remove", h.handleRemoveMember)
+	protected.POST("/jars/:id/members/:user_id/role", h.handleUpdateMemberRole)
+	protected.POST("/jars/:id/transfer", h.handleTransferOwnership)
+	protected.POST("/jars/:id/invites", h.handleCreateInvite)
+	protected.POST("/jars/:id/invites/:invite_id/revoke", h.handleRevokeInvite)
+	protected.POST("/jars/:id/invites/:invite_id/rotate", h.handleRotateInvite)
+	protected.GET("/jars/:id/invites/:invite_id/qr.png", h.handleInviteQRCodePNG)
+	protected.GET("/jars/:id/invites/:invite_id/qr.svg", h.handleInviteQRCodeSVG)
+	protected.POST("/jars/:id/join-requests/:request_id/approve", h.handleApproveJoinRequest)
+	protected.POST("/jars/:id/join-requests/:request_id/reject", h.handleRejectJoinRequest)
 	protected.POST("/jars/:id/offense-types", h.handleCreateOffenseType)
 	protected.POST("/jars/:id/offense-types/:offense_type_id/deactivate", h.handleDeactivateOffenseType)
 	protected.POST("/jars/:id/offense-types/:offense_type_id/reactivate", h.handleReactivateOffenseType)
 	protected.GET("/jars/:id/offense-types/:offense_type_id/edit", h.handleEditOffenseTypeForm)
 	protected.POST("/jars/:id/offense-types/:offense_type_id", h.handleUpdateOffenseType)
+	protected.GET("/jars/:id/webhooks", h.handleListWebhooks)
+	protected.POST("/jars/:id/webhooks", h.handleCreateWebhook)
+	protected.GET("/jars/:id/webhooks/:webhook_id", h.handleListWebhookDeliveries)
+	protected.POST("/jars/:id/webhooks/:webhook_id/toggle", h.handleToggleWebhook)
+	protected.POST("/jars/:id/webhooks/:webhook_id/delete", h.handleDeleteWebhook)
+	protected.POST("/jars/:id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", h.handleRedeliverWebhook)
 	protected.GET("/offenses/:id/pay", h.handlePayOffense)
 	protected.POST("/offenses/:id/pay", h.handlePayOffense)
+	protected.POST("/offenses/:id/dispute", h.handleOpenDispute)
+	protected.POST("/disputes/:id/vote", h.handleDisputeVote)
+	protected.GET("/verifications", h.handleListVerifications)
+	protected.POST("/payments/:id/verify", h.handleVerifyPayment)
+	protected.POST("/payments/:id/reject", h.handleRejectPayment)
+	protected.GET("/payments/:id/proof", h.handleViewProof)
+	protected.GET("/account/identities", h.handleListIdentities)
+	protected.POST("/account/identities/:id/unlink", h.handleUnlinkIdentity)
+	protected.GET("/sessions", h.handleListSessions)
+	protected.POST("/sessions/revoke-all", h.handleRevokeAllSessions)
+	protected.POST("/sessions/:id/revoke", h.handleRevokeSession)
+	protected.GET("/account/tokens", h.handleListAPITokens)
+	protected.POST("/account/tokens", h.handleCreateAPIToken)
+	protected.POST("/account/tokens/:id/revoke", h.handleRevokeAPIToken)
+	protected.GET("/chat/link/:token", h.handleChatLinkForm)
+	protected.POST("/chat/link/:token", h.handleChatLink)
+	protected.GET("/notifications", h.handleListNotifications)
+	protected.GET("/notifications/unread", h.handleUnreadNotifications)
+	protected.POST("/notifications/read-all", h.handleMarkAllNotificationsRead)
+	protected.GET("/notifications/preferences", h.handleNotificationPreferences)
+	protected.POST("/notifications/preferences", h.handleUpdateNotificationPreferences)
+	protected.POST("/notifications/email", h.handleUpdateEmailMode)
+	protected.POST("/notifications/:id/read", h.handleMarkNotificationRead)
 
 	// API routes
 	api := e.Group("/api/v1")
-	api.Use(h.requireAuth)
-	api.GET("/user", h.handleGetUser)
-	api.GET("/jars", h.handleAPIListJars)
-	api.GET("/jars/lookup", h.handleLookupJar)
+	api.Use(h.apiErrors)
+	api.Use(h.requireAPIAuth)
+	h.registerAPIRoutes(api)
 }
 
 func (h *Handlers) setupStaticFiles(e *echo.Echo) {
@@ -93,8 +190,7 @@ func (h *Handlers) setupStaticFiles(e *echo.Echo) {
 	// Setup static file handler
 	e.GET("/static/*", echo.WrapHandler(http.StripPrefix("/static/", http.FileServer(http.FS(staticFS)))))
 
-	// Serve uploads directory from filesystem (not embedded)
-	e.Static("/uploads", h.cfg.UploadsDir)
+	// Uploaded proofs are not served statically; see handleViewProof
 }
 
 func (h *Handlers) handleHome(c echo.Context) error {
@@ -112,15 +208,26 @@ func (h *Handlers) handleLogin(c echo.Context) error {
 	}
 
 	// Render login template
-	return h.renderTemplate(c, templates.Login())
+	return h.renderTemplate(c, templates.Login(h.auth.Providers()))
 }
 
-func (h *Handlers) handleGoogleAuth(c echo.Context) error {
+func (h *Handlers) handleOAuthStart(c echo.Context) error {
+	provider := h.auth.Get(c.Param("provider"))
+	if provider == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown login provider")
+	}
+
 	state, err := auth.GenerateState()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate state")
 	}
 
+	authURL, err := provider.AuthCodeURL(c.Request().Context(), state)
+	if err != nil {
+		c.Logger().Error("Failed to build OAuth URL", "error", err, "provider", provider.Name())
+		return echo.NewHTTPError(http.StatusBadGateway, "Login provider is unavailable")
+	}
+
 	// Store state in session cookie for CSRF protection
 	cookie := &http.Cookie{
 		Name:     "oauth_state",
@@ -133,11 +240,15 @@ func (h *Handlers) handleGoogleAuth(c echo.Context) error {
 	}
 	c.SetCookie(cookie)
 
-	authURL := h.auth.GetAuthURL(state)
 	return c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
-func (h *Handlers) handleAuthCallback(c echo.Context) error {
+func (h *Handlers) handleOAuthCallback(c echo.Context) error {
+	provider := h.auth.Get(c.Param("provider"))
+	if provider == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown login provider")
+	}
+
 	// Verify state parameter for CSRF protection
 	stateCookie, err := c.Cookie("oauth_state")
 	if err != nil {
@@ -158,7 +269,7 @@ func (h *Handlers) handleAuthCallback(c echo.Context) error {
 	}
 	c.SetCookie(cookie)
 
-	// Exchange authorization code for token
+	// Exchange authorization code for the provider's account details
 	code := c.QueryParam("code")
 	if code == "" {
 		// Check if there was an OAuth error
@@ -169,49 +280,92 @@ func (h *Handlers) handleAuthCallback(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Missing authorization code")
 	}
 
-	token, err := h.auth.ExchangeCode(c.Request().Context(), code)
-	if err != nil {
-		c.Logger().Error("Failed to exchange OAuth code", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to exchange code for token")
-	}
-
-	// Get user info from Google
-	googleUser, err := h.auth.GetUserInfo(c.Request().Context(), token)
+	identity, err := provider.Exchange(c.Request().Context(), code)
 	if err != nil {
-		c.Logger().Error("Failed to get user info from Google", "error", err)
+		c.Logger().Error("Failed to exchange OAuth code", "error", err, "provider", provider.Name())
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user info")
 	}
 
-	c.Logger().Info("OAuth user info received", "email", googleUser.Email, "name", googleUser.Name, "google_id", googleUser.ID)
+	c.Logger().Info("OAuth user info received", "provider", identity.Provider, "email", identity.Email, "subject", identity.Subject)
 
-	// Check if user exists in our database
-	user, err := h.userService.GetUserByGoogleID(c.Request().Context(), googleUser.ID)
+	// Check if this account is already linked to a user
+	user, err := h.userService.GetUserByIdentity(c.Request().Context(), identity.Provider, identity.Subject)
 	if err != nil {
-		c.Logger().Error("Database error when looking up user", "error", err, "google_id", googleUser.ID)
+		c.Logger().Error("Database error when looking up user", "error", err, "provider", identity.Provider)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
 
-	// Create new user if they don't exist
-	if user == nil {
-		c.Logger().Info("Creating new user", "email", googleUser.Email, "name", googleUser.Name, "google_id", googleUser.ID)
-		user, err = h.userService.CreateUser(
-			c.Request().Context(),
-			googleUser.Email,
-			googleUser.Name,
-			googleUser.Picture,
-			googleUser.ID,
-		)
-		if err != nil {
-			c.Logger().Error("Failed to create new user", "error", err, "email", googleUser.Email)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user")
+	// Signed-in users are linking another provider to their account
+	if currentUser := h.getCurrentUser(c); currentUser != nil {
+		if user != nil && user.ID != currentUser.ID {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("This %s account is already linked to another user", provider.DisplayName()))
 		}
-		c.Logger().Info("Successfully created new user", "user_id", user.ID, "email", user.Email)
-	} else {
+
+		if user == nil {
+			err = h.userService.LinkIdentity(c.Request().Context(), currentUser.ID, identity.Provider, identity.Subject, identity.Email)
+			if err != nil {
+				c.Logger().Error("Failed to link identity", "error", err, "user_id", currentUser.ID)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to link account")
+			}
+			c.Logger().Info("Linked login provider", "user_id", currentUser.ID, "provider", identity.Provider)
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/account/identities")
+	}
+
+	if user != nil {
 		c.Logger().Info("Existing user found", "user_id", user.ID, "email", user.Email)
+		if err := h.userService.RecordIdentityLogin(c.Request().Context(), identity.Provider, identity.Subject, identity.Email); err != nil {
+			c.Logger().Error("Failed to record identity login", "error", err, "user_id", user.ID)
+		}
+	} else {
+		if identity.Email == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Your %s account has no email address", provider.DisplayName()))
+		}
+
+		user, err = h.userService.GetUserByEmail(c.Request().Context(), identity.Email)
+		if err != nil {
+			c.Logger().Error("Database error when looking up user", "error", err, "email", identity.Email)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+		}
+
+		if user != nil {
+			// Only trust a matching email if the provider has verified it
+			if !identity.EmailVerified {
+				return echo.NewHTTPError(http.StatusConflict, "An account with this email already exists. Sign in with your original provider and link this one from your account settings.")
+			}
+
+			err = h.userService.LinkIdentity(c.Request().Context(), user.ID, identity.Provider, identity.Subject, identity.Email)
+			if err != nil {
+				c.Logger().Error("Failed to link identity", "error", err, "user_id", user.ID)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to link account")
+			}
+			c.Logger().Info("Linked login provider by verified email", "user_id", user.ID, "provider", identity.Provider)
+		} else {
+			c.Logger().Info("Creating new user", "email", identity.Email, "name", identity.Name, "provider", identity.Provider)
+			user, err = h.userService.CreateUserWithIdentity(
+				c.Request().Context(),
+				identity.Email,
+				identity.Name,
+				identity.AvatarURL,
+				identity.Provider,
+				identity.Subject,
+			)
+			if err != nil {
+				c.Logger().Error("Failed to create new user", "error", err, "email", identity.Email)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create user")
+			}
+			c.Logger().Info("Successfully created new user", "user_id", user.ID, "email", user.Email)
+		}
 	}
 
 	// Create session
-	sessionToken, err := h.sessionService.CreateSession(user.ID, user.Email, user.Name)
+	sessionToken, err := h.sessionService.CreateSession(
+		c.Request().Context(),
+		user.ID,
+		c.Request().UserAgent(),
+		c.RealIP(),
+	)
 	if err != nil {
 		c.Logger().Error("Failed to create session", "error", err, "user_id", user.ID)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create session")
@@ -222,11 +376,30 @@ func (h *Handlers) handleAuthCallback(c echo.Context) error {
 
 	c.Logger().Info("User successfully authenticated", "user_id", user.ID, "email", user.Email)
 
+	// Send people who logged in from an invite link back to it
+	if joinCookie, err := c.Cookie("join_code"); err == nil {
+		c.SetCookie(&http.Cookie{
+			Name:   "join_code",
+			Value:  "",
+			Path:   "/",
+			MaxAge: -1,
+		})
+		if code := invitecode.Normalize(joinCookie.Value); invitecode.Valid(code) {
+			return c.Redirect(http.StatusTemporaryRedirect, "/join/"+invitecode.Format(code))
+		}
+	}
+
 	// Redirect to dashboard
 	return c.Redirect(http.StatusTemporaryRedirect, "/dashboard")
 }
 
 func (h *Handlers) handleLogout(c echo.Context) error {
+	if cookie, err := c.Cookie("session"); err == nil {
+		if err := h.sessionService.DeleteSession(c.Request().Context(), cookie.Value); err != nil {
+			c.Logger().Error("Failed to delete session", "error", err)
+		}
+	}
+
 	h.sessionService.ClearSessionCookie(c.Response().Writer)
 	return c.Redirect(http.StatusTemporaryRedirect, "/login")
 }
@@ -241,7 +414,23 @@ func (h *Handlers) handleDashboard(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load tip jars")
 	}
 
-	return h.renderTemplate(c, templates.Dashboard(user, jars))
+	pending, err := h.joinService.ListPendingForUser(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to load join requests", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load tip jars")
+	}
+
+	// Archived jars are listed apart, below the ones still in use
+	var active, archived []*models.DashboardJar
+	for _, jar := range jars {
+		if jar.ArchivedAt != nil {
+			archived = append(archived, jar)
+		} else {
+			active = append(active, jar)
+		}
+	}
+
+	return h.renderTemplate(c, templates.Dashboard(user, active, archived, pending))
 }
 
 func (h *Handlers) handleCreateOffenseType(c echo.Context) error {
@@ -253,9 +442,8 @@ func (h *Handlers) handleCreateOffenseType(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
-	if err != nil || !isAdmin {
-		return echo.NewHTTPError(http.StatusForbidden, "Only admins can create offense types")
+	if _, err := h.authorize(c, user, jarID, authz.EditOffenseTypes); err != nil {
+		return err
 	}
 
 	name := strings.TrimSpace(c.FormValue("name"))
@@ -267,13 +455,13 @@ func (h *Handlers) handleCreateOffenseType(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Offense name is required")
 	}
 
-	var costAmount *float64
+	var costAmount *money.Amount
 	if costAmountStr != "" {
-		amount, err := strconv.ParseFloat(costAmountStr, 64)
+		amount, err := money.Parse(costAmountStr)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cost amount")
 		}
-		if amount < 0 {
+		if amount.IsNegative() {
 			return echo.NewHTTPError(http.StatusBadRequest, "Cost amount cannot be negative")
 		}
 		costAmount = &amount
@@ -291,6 +479,7 @@ func (h *Handlers) handleCreateOffenseType(c echo.Context) error {
 		description,
 		costAmount,
 		costUnitPtr,
+		user.ID,
 	)
 	if err != nil {
 		c.Logger().Error("Failed to create offense type", "error", err)
@@ -317,9 +506,8 @@ func (h *Handlers) handleUpdateOffenseType(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
 	}
 
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
-	if err != nil || !isAdmin {
-		return echo.NewHTTPError(http.StatusForbidden, "Only admins can update offense types")
+	if _, err := h.authorize(c, user, jarID, authz.EditOffenseTypes); err != nil {
+		return err
 	}
 
 	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
@@ -341,13 +529,13 @@ func (h *Handlers) handleUpdateOffenseType(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Offense name is required")
 	}
 
-	var costAmount *float64
+	var costAmount *money.Amount
 	if costAmountStr != "" {
-		amount, err := strconv.ParseFloat(costAmountStr, 64)
+		amount, err := money.Parse(costAmountStr)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cost amount")
 		}
-		if amount < 0 {
+		if amount.IsNegative() {
 			return echo.NewHTTPError(http.StatusBadRequest, "Cost amount cannot be negative")
 		}
 		costAmount = &amount
@@ -365,6 +553,7 @@ func (h *Handlers) handleUpdateOffenseType(c echo.Context) error {
 		description,
 		costAmount,
 		costUnitPtr,
+		user.ID,
 	)
 	if err != nil {
 		c.Logger().Error("Failed to update offense type", "error", err)
@@ -395,17 +584,17 @@ func (h *Handlers) handleCreateJar(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invite code is required")
 	}
 
-	if len(inviteCode) != 8 {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invite code must be 8 characters")
+	if !invitecode.Valid(invitecode.Normalize(inviteCode)) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invite code isn't valid. Please generate a new one.")
 	}
 
-	existingJar, err := h.tipJarService.GetTipJarByInviteCode(c.Request().Context(), inviteCode)
+	existingInvite, err := h.inviteService.GetInviteByCode(c.Request().Context(), inviteCode)
 	if err != nil {
 		c.Logger().Error("Failed to check invite code", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to validate invite code")
 	}
 
-	if existingJar != nil {
+	if existingInvite != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invite code already exists. Please generate a new one.")
 	}
 
@@ -426,49 +615,60 @@ func (h *Handlers) handleCreateJar(c echo.Context) error {
 
 func (h *Handlers) handleJoinJarForm(c echo.Context) error {
 	user := h.getCurrentUser(c)
-	return h.renderTemplate(c, templates.JoinJar(user))
+	return h.renderTemplate(c, templates.JoinJar(user, ""))
+}
+
+// handleJoinLink opens the join form with the code from an invite link filled
+// in. Signed-out visitors log in first and are brought back here.
+func (h *Handlers) handleJoinLink(c echo.Context) error {
+	code := invitecode.Normalize(c.Param("code"))
+	if !invitecode.Valid(code) {
+		return echo.NewHTTPError(http.StatusNotFound, "This invite link isn't valid. Please check it for typos.")
+	}
+
+	user := h.getCurrentUser(c)
+	if user == nil {
+		c.SetCookie(&http.Cookie{
+			Name:     "join_code",
+			Value:    code,
+			Path:     "/",
+			MaxAge:   3600,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return c.Redirect(http.StatusTemporaryRedirect, "/login")
+	}
+
+	return h.renderTemplate(c, templates.JoinJar(user, invitecode.Format(code)))
 }
 
 func (h *Handlers) handleJoinJar(c echo.Context) error {
 	user := h.getCurrentUser(c)
 
-	inviteCode := strings.TrimSpace(strings.ToUpper(c.FormValue("invite_code")))
+	inviteCode := strings.TrimSpace(c.FormValue("invite_code"))
 
 	if inviteCode == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invite code is required")
 	}
 
-	if len(inviteCode) != 8 {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invite code format")
-	}
-
-	// Check if jar exists
-	jar, err := h.tipJarService.GetTipJarByInviteCode(c.Request().Context(), inviteCode)
-	if err != nil {
-		c.Logger().Error("Failed to lookup jar by invite code", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to lookup jar")
-	}
-
-	if jar == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Jar not found. Please check your invite code.")
-	}
-
-	// Check if user is already a member
-	isMember, err := h.tipJarService.IsUserJarMember(c.Request().Context(), jar.ID, user.ID)
+	// Join the jar, if the invite still works
+	jar, request, err := h.inviteService.Join(c.Request().Context(), inviteCode, user.ID)
 	if err != nil {
-		c.Logger().Error("Failed to check jar membership", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
+		if err == services.ErrAlreadyMember || err == services.ErrAlreadyAsked {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return inviteError(c, err, "Failed to join jar")
 	}
 
-	if isMember {
-		return echo.NewHTTPError(http.StatusBadRequest, "You are already a member of this jar")
-	}
+	if request != nil {
+		c.Logger().Info("User asked to join jar", "user_id", user.ID, "jar_id", jar.ID, "request_id", request.ID)
 
-	// Join the jar
-	err = h.tipJarService.JoinTipJar(c.Request().Context(), jar.ID, user.ID)
-	if err != nil {
-		c.Logger().Error("Failed to join jar", "error", err, "jar_id", jar.ID, "user_id", user.ID)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to join jar")
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"success":  true,
+			"pending":  true,
+			"jar_id":   jar.ID,
+			"jar_name": jar.Name,
+		})
 	}
 
 	c.Logger().Info("User successfully joined jar", "user_id", user.ID, "jar_id", jar.ID, "jar_name", jar.Name)
@@ -482,29 +682,21 @@ func (h *Handlers) handleJoinJar(c echo.Context) error {
 
 // Add this new handler for the lookup API
 func (h *Handlers) handleLookupJar(c echo.Context) error {
-	inviteCode := strings.TrimSpace(strings.ToUpper(c.QueryParam("invite_code")))
+	inviteCode := strings.TrimSpace(c.QueryParam("invite_code"))
 
 	if inviteCode == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invite code is required")
 	}
 
-	if len(inviteCode) != 8 {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invite code format")
-	}
-
-	// Look up jar by invite code
-	jar, err := h.tipJarService.GetTipJarByInviteCode(c.Request().Context(), inviteCode)
+	// Look up the invite and the jar it's for
+	invite, jar, err := h.inviteService.LookupInvite(c.Request().Context(), inviteCode)
 	if err != nil {
-		c.Logger().Error("Failed to lookup jar by invite code", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to lookup jar")
-	}
-
-	if jar == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+		return inviteError(c, err, "Failed to lookup jar")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"jar": jar,
+		"jar":    jar,
+		"invite": toInviteResponse(invite),
 	})
 }
 
@@ -534,48 +726,100 @@ func (h *Handlers) handleViewJar(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
 	}
 
-	// Check if user is a member of this jar
-	isMember, err := h.tipJarService.IsUserJarMember(c.Request().Context(), jarID, user.ID)
+	role, err := h.authorize(c, user, jarID, authz.ViewJar)
 	if err != nil {
-		c.Logger().Error("Failed to check jar membership", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
+		return err
 	}
 
-	if !isMember {
-		return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this jar")
+	// Get jar members
+	members, err := h.tipJarService.GetJarMembers(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar members", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load members")
 	}
 
-	// Check if user is admin
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
+	periods, err := h.periodService.ListPeriods(c.Request().Context(), jarID)
 	if err != nil {
-		c.Logger().Error("Failed to check admin status", "error", err)
-		isAdmin = false // Default to false on error
+		c.Logger().Error("Failed to get periods", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load periods")
 	}
 
-	// Get jar members
-	members, err := h.tipJarService.GetJarMembers(c.Request().Context(), jarID)
+	period, err := viewedPeriod(c, periods)
 	if err != nil {
-		c.Logger().Error("Failed to get jar members", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load members")
+		return err
 	}
 
-	// Get recent activity (last 10 activities)
-	activities, err := h.tipJarService.GetJarActivity(c.Request().Context(), jarID, 10)
+	// The open period shows its recent activity, a closed one its whole ledger
+	ledgerSize := 10
+	if period.ClosedAt != nil {
+		ledgerSize = closedPeriodLedgerSize
+	}
+	activities, err := h.periodService.GetLedger(c.Request().Context(), period.ID, ledgerSize, 0)
 	if err != nil {
 		c.Logger().Error("Failed to get jar activities", "error", err)
 		// Don't fail the whole page, just log the error
 		activities = []models.JarActivity{}
 	}
 
-	// Get member balances by unit - USE NEW METHOD
-	balances, err := h.tipJarService.GetMemberBalancesByUnit(c.Request().Context(), jarID)
+	// Balances are live while the period is open and frozen once it closes
+	var balances []models.MemberBalanceSummary
+	var standings []models.PeriodStandingSummary
+	if period.ClosedAt == nil {
+		balances, err = h.tipJarService.GetMemberBalancesByUnit(c.Request().Context(), jarID)
+		if err != nil {
+			c.Logger().Error("Failed to get member balances", "error", err)
+			// Don't fail the whole page, just log the error
+			balances = []models.MemberBalanceSummary{}
+		}
+	} else {
+		standings, err = h.periodService.GetStandings(c.Request().Context(), period.ID)
+		if err != nil {
+			c.Logger().Error("Failed to get period standings", "error", err, "period_id", period.ID)
+			standings = []models.PeriodStandingSummary{}
+		}
+	}
+
+	// Get disputes with their votes
+	disputes, err := h.disputeService.GetDisputesForJar(c.Request().Context(), jarID, 20)
 	if err != nil {
-		c.Logger().Error("Failed to get member balances", "error", err)
-		// Don't fail the whole page, just log the error
-		balances = []models.MemberBalanceSummary{}
+		c.Logger().Error("Failed to get disputes", "error", err)
+		disputes = []models.Dispute{}
+	}
+
+	// Get jar history
+	history, err := h.tipJarService.GetJarHistory(c.Request().Context(), jarID, 50)
+	if err != nil {
+		c.Logger().Error("Failed to get jar history", "error", err)
+		history = []models.JarEvent{}
+	}
+
+	return h.renderTemplate(c, templates.ViewJar(user, jar, members, activities, balances, disputes, history, role, periods, period, standings))
+}
+
+// closedPeriodLedgerSize caps how many offenses a closed period's page lists
+const closedPeriodLedgerSize = 500
+
+// viewedPeriod picks the period named by ?period=, or the open one, which
+// leads the list
+func viewedPeriod(c echo.Context, periods []models.JarPeriod) (*models.JarPeriod, error) {
+	param := c.QueryParam("period")
+	if param == "" {
+		if len(periods) == 0 || periods[0].ClosedAt != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Jar has no open period")
+		}
+		return &periods[0], nil
 	}
 
-	return h.renderTemplate(c, templates.ViewJar(user, jar, members, activities, balances, isAdmin))
+	periodID, err := strconv.Atoi(param)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid period ID")
+	}
+	for i := range periods {
+		if periods[i].ID == periodID {
+			return &periods[i], nil
+		}
+	}
+	return nil, echo.NewHTTPError(http.StatusNotFound, "Period not found")
 }
 
 func (h *Handlers) handleReportOffenseForm(c echo.Context) error {
@@ -599,15 +843,8 @@ func (h *Handlers) handleReportOffenseForm(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
 	}
 
-	// Check if user is a member
-	isMember, err := h.tipJarService.IsUserJarMember(c.Request().Context(), jarID, user.ID)
-	if err != nil {
-		c.Logger().Error("Failed to check jar membership", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
-	}
-
-	if !isMember {
-		return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this jar")
+	if _, err := h.authorize(c, user, jarID, authz.ReportOffense); err != nil {
+		return err
 	}
 
 	// Get jar members
@@ -637,15 +874,8 @@ func (h *Handlers) handleReportOffense(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	// Check if user is a member
-	isMember, err := h.tipJarService.IsUserJarMember(c.Request().Context(), jarID, user.ID)
-	if err != nil {
-		c.Logger().Error("Failed to check jar membership", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
-	}
-
-	if !isMember {
-		return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this jar")
+	if _, err := h.authorize(c, user, jarID, authz.ReportOffense); err != nil {
+		return err
 	}
 
 	// Parse form values
@@ -684,13 +914,13 @@ func (h *Handlers) handleReportOffense(c echo.Context) error {
 	}
 
 	// Parse cost override if provided
-	var costOverride *float64
+	var costOverride *money.Amount
 	if costOverrideStr != "" {
-		cost, err := strconv.ParseFloat(costOverrideStr, 64)
+		cost, err := money.Parse(costOverrideStr)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cost override")
 		}
-		if cost < 0 {
+		if cost.IsNegative() {
 			return echo.NewHTTPError(http.StatusBadRequest, "Cost override cannot be negative")
 		}
 		costOverride = &cost
@@ -707,8 +937,7 @@ func (h *Handlers) handleReportOffense(c echo.Context) error {
 		costOverride,
 	)
 	if err != nil {
-		c.Logger().Error("Failed to create offense", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to report offense")
+		return offenseRuleError(c, err, "Failed to report offense")
 	}
 
 	c.Logger().Info("Offense reported successfully",
@@ -724,15 +953,6 @@ func (h *Handlers) handleReportOffense(c echo.Context) error {
 	})
 }
 
-func (h *Handlers) handleGetUser(c echo.Context) error {
-	user := h.getCurrentUser(c)
-	return c.JSON(http.StatusOK, user)
-}
-
-func (h *Handlers) handleAPIListJars(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]string{"message": "API list jars - not implemented yet"})
-}
-
 func (h *Handlers) requireAuth(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user := h.getCurrentUser(c)
@@ -759,17 +979,18 @@ func (h *Handlers) getCurrentUser(c echo.Context) *models.User {
 	}
 
 	// Validate session
-	sessionData, err := h.sessionService.ValidateSession(cookie.Value)
+	session, err := h.sessionService.ValidateSession(c.Request().Context(), cookie.Value)
 	if err != nil {
 		return nil
 	}
 
 	// Get user from database
-	user, err := h.userService.GetUserByID(c.Request().Context(), sessionData.UserID)
+	user, err := h.userService.GetUserByID(c.Request().Context(), session.UserID)
 	if err != nil {
 		return nil
 	}
 
+	c.Set("session", session)
 	return user
 }
 
@@ -777,6 +998,48 @@ func (h *Handlers) renderTemplate(c echo.Context, component templ.Component) err
 	return component.Render(c.Request().Context(), c.Response().Writer)
 }
 
+// authorize checks that the user's role in the jar grants permission and
+// returns the role. Every handler acting on a jar goes through here; what
+// each role may do is decided by the authz package.
+func (h *Handlers) authorize(c echo.Context, user *models.User, jarID int, permission authz.Permission) (string, error) {
+	role, err := h.tipJarService.GetMemberRole(c.Request().Context(), jarID, user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to check jar membership", "error", err, "jar_id", jarID)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
+	}
+
+	if role == "" {
+		return "", echo.NewHTTPError(http.StatusForbidden, "You are not a member of this jar")
+	}
+
+	if !authz.Can(role, permission) {
+		return role, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Your role in this jar (%s) doesn't allow that", role))
+	}
+
+	// Archived jars are read-only
+	if !authz.AllowedWhenArchived(permission) {
+		jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+		if err != nil {
+			c.Logger().Error("Failed to get jar", "error", err, "jar_id", jarID)
+			return role, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+		}
+		if jar != nil && jar.ArchivedAt != nil {
+			return role, echo.NewHTTPError(http.StatusConflict, "This jar is archived. Restore it to make changes.")
+		}
+	}
+
+	return role, nil
+}
+
+// payPermission is what the user needs to pay the offense: their own only
+// needs ReportOffense, anyone else's PayOnBehalf
+func payPermission(offense *models.OffenseDetail, user *models.User) authz.Permission {
+	if offense.OffenderID == user.ID {
+		return authz.ReportOffense
+	}
+	return authz.PayOnBehalf
+}
+
 func (h *Handlers) handleJarSettings(c echo.Context) error {
 	user := h.getCurrentUser(c)
 
@@ -798,22 +1061,9 @@ func (h *Handlers) handleJarSettings(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
 	}
 
-	// Check if user is a member
-	isMember, err := h.tipJarService.IsUserJarMember(c.Request().Context(), jarID, user.ID)
-	if err != nil {
-		c.Logger().Error("Failed to check jar membership", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check membership")
-	}
-
-	if !isMember {
-		return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this jar")
-	}
-
-	// Check if user is admin
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
+	role, err := h.authorize(c, user, jarID, authz.ViewJar)
 	if err != nil {
-		c.Logger().Error("Failed to check admin status", "error", err)
-		isAdmin = false
+		return err
 	}
 
 	// Get jar members
@@ -830,7 +1080,43 @@ func (h *Handlers) handleJarSettings(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense types")
 	}
 
-	return h.renderTemplate(c, templates.JarSettings(user, jar, members, offenseTypes, isAdmin))
+	// Get offense type price history
+	priceHistory, err := h.offenseService.GetPriceHistoryForJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get price history", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load price history")
+	}
+
+	// Only those who can manage members see the invites and join requests
+	var invites []models.Invite
+	var joinRequests []models.JoinRequest
+	if authz.Can(role, authz.ManageMembers) {
+		invites, err = h.inviteService.ListInvites(c.Request().Context(), jarID)
+		if err != nil {
+			c.Logger().Error("Failed to list invites", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load invites")
+		}
+
+		joinRequests, err = h.joinService.ListPendingForJar(c.Request().Context(), jarID)
+		if err != nil {
+			c.Logger().Error("Failed to list join requests", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load join requests")
+		}
+	}
+
+	periods, err := h.periodService.ListPeriods(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get periods", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load periods")
+	}
+
+	rules, err := h.ruleService.GetRules(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar rules", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar rules")
+	}
+
+	return h.renderTemplate(c, templates.JarSettings(user, jar, members, offenseTypes, priceHistory, invites, joinRequests, periods, rules, role))
 }
 
 func (h *Handlers) handleUpdateJarSettings(c echo.Context) error {
@@ -843,10 +1129,8 @@ func (h *Handlers) handleUpdateJarSettings(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	// Check if user is admin
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
-	if err != nil || !isAdmin {
-		return echo.NewHTTPError(http.StatusForbidden, "Only admins can update jar settings")
+	if _, err := h.authorize(c, user, jarID, authz.ManageJar); err != nil {
+		return err
 	}
 
 	name := strings.TrimSpace(c.FormValue("name"))
@@ -856,121 +1140,1131 @@ func (h *Handlers) handleUpdateJarSettings(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Jar name is required")
 	}
 
+	disputeQuorum, err := strconv.Atoi(strings.TrimSpace(c.FormValue("dispute_quorum")))
+	if err != nil || disputeQuorum < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Dispute quorum must be at least 1")
+	}
+
+	disputeWindowHours, err := strconv.Atoi(strings.TrimSpace(c.FormValue("dispute_window_hours")))
+	if err != nil || disputeWindowHours < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Dispute window must be at least 1 hour")
+	}
+
+	verificationRequired := c.FormValue("verification_required") == "on"
+
+	verificationsNeeded, err := strconv.Atoi(strings.TrimSpace(c.FormValue("verifications_needed")))
+	if err != nil || verificationsNeeded < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Verifications needed must be at least 1")
+	}
+
+	joinApprovalRequired := c.FormValue("join_approval_required") == "on"
+
 	err = h.tipJarService.UpdateTipJar(c.Request().Context(), jarID, name, description)
 	if err != nil {
 		c.Logger().Error("Failed to update jar", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
 	}
 
-	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings", jarID))
-}
+	err = h.tipJarService.UpdateDisputeSettings(c.Request().Context(), jarID, disputeQuorum, disputeWindowHours)
+	if err != nil {
+		c.Logger().Error("Failed to update dispute settings", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
 
+	err = h.tipJarService.UpdateVerificationSettings(c.Request().Context(), jarID, verificationRequired, verificationsNeeded)
+	if err != nil {
+		c.Logger().Error("Failed to update verification settings", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
 
-func (h *Handlers) handleDeactivateOffenseType(c echo.Context) error {
-	return h.handleSetOffenseTypeActiveStatus(c, false)
-}
-func (h *Handlers) handleReactivateOffenseType(c echo.Context) error {
-	return h.handleSetOffenseTypeActiveStatus(c, true)
+	err = h.tipJarService.UpdateJoinApproval(c.Request().Context(), jarID, joinApprovalRequired)
+	if err != nil {
+		c.Logger().Error("Failed to update join approval", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update jar")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings", jarID))
 }
 
-func (h *Handlers) handleSetOffenseTypeActiveStatus(c echo.Context, isActive bool) error {
+// handleUpdateJarRules saves the jar's reporting rules; a blank field turns
+// that rule off
+func (h *Handlers) handleUpdateJarRules(c echo.Context) error {
 	user := h.getCurrentUser(c)
 
-	// Parse IDs
-	jarIDStr := c.Param("id")
-	jarID, err := strconv.Atoi(jarIDStr)
+	jarID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	offenseTypeIDStr := c.Param("offense_type_id")
-	offenseTypeID, err := strconv.Atoi(offenseTypeIDStr)
+	if _, err := h.authorize(c, user, jarID, authz.ManageJar); err != nil {
+		return err
+	}
+
+	maxPerDay, err := optionalIntForm(c, "max_offenses_per_day")
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid daily offense limit")
 	}
 
-	// Check if user is admin
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
-	if err != nil || !isAdmin {
-		action := "deactivate"
-		if isActive {
-			action = "reactivate"
-		}
-		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Only admins can %s offense types", action))
+	cooldownMinutes, err := optionalIntForm(c, "report_cooldown_minutes")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid report cooldown")
 	}
 
-	// Verify the offense type belongs to this jar
-	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
+	windowDays, err := optionalIntForm(c, "escalation_window_days")
 	if err != nil {
-		c.Logger().Error("Failed to get offense type", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify offense type")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid escalation window")
 	}
 
-	if offenseType == nil || offenseType.JarID != jarID {
-		return echo.NewHTTPError(http.StatusNotFound, "Offense type not found")
+	var multiplier *money.Amount
+	if s := strings.TrimSpace(c.FormValue("escalation_multiplier")); s != "" {
+		m, err := money.Parse(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid escalation multiplier")
+		}
+		multiplier = &m
 	}
 
-	err = h.offenseService.SetOffenseTypeActiveStatus(c.Request().Context(), offenseTypeID, isActive)
+	_, err = h.ruleService.UpdateRules(c.Request().Context(), jarID, user.ID, maxPerDay, cooldownMinutes, windowDays, multiplier)
 	if err != nil {
-		action := "deactivate"
-		if isActive {
-			action = "reactivate"
-		}
-		c.Logger().Error(fmt.Sprintf("Failed to %s offense type", action), "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to %s offense type", action))
+		return offenseRuleError(c, err, "Failed to update jar rules")
 	}
 
-	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings", jarID))
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#rules", jarID))
 }
 
-func (h *Handlers) handleEditOffenseTypeForm(c echo.Context) error {
+// optionalIntForm reads a whole number form field, or nil if it's blank
+func optionalIntForm(c echo.Context, name string) (*int, error) {
+	s := strings.TrimSpace(c.FormValue(name))
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// offenseRuleError turns an offense the jar's rules turned away, or rules
+// that can't be saved, into a response
+func offenseRuleError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrOffenseLimitReached) || errors.Is(err, services.ErrReportCooldown) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	if err == services.ErrInvalidJarRules {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err == services.ErrMemberNotFound {
+		return echo.NewHTTPError(http.StatusBadRequest, "Offender is not a member of this jar")
+	}
+	if err == services.ErrOffenseTypeNotInJar {
+		return echo.NewHTTPError(http.StatusBadRequest, "Offense type is not available in this jar")
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+func (h *Handlers) handleLeaveJar(c echo.Context) error {
 	user := h.getCurrentUser(c)
 
-	// Parse IDs
 	jarIDStr := c.Param("id")
 	jarID, err := strconv.Atoi(jarIDStr)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	offenseTypeIDStr := c.Param("offense_type_id")
-	offenseTypeID, err := strconv.Atoi(offenseTypeIDStr)
+	err = h.tipJarService.RemoveMember(c.Request().Context(), jarID, user.ID, user.ID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
+		return memberError(c, err, "Failed to leave jar")
 	}
 
-	// Check if user is admin
-	isAdmin, err := h.tipJarService.IsUserJarAdmin(c.Request().Context(), jarID, user.ID)
-	if err != nil || !isAdmin {
-		return echo.NewHTTPError(http.StatusForbidden, "Only admins can edit offense types")
-	}
+	c.Logger().Info("User left jar", "jar_id", jarID, "user_id", user.ID)
 
-	// Get jar details
-	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	return c.Redirect(http.StatusSeeOther, "/jars")
+}
+
+func (h *Handlers) handleArchiveJar(c echo.Context) error {
+	return h.setJarArchived(c, true)
+}
+
+func (h *Handlers) handleRestoreJar(c echo.Context) error {
+	return h.setJarArchived(c, false)
+}
+
+func (h *Handlers) setJarArchived(c echo.Context, archived bool) error {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.Logger().Error("Failed to get jar", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	if jar == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	if _, err := h.authorize(c, user, jarID, authz.ArchiveJar); err != nil {
+		return err
 	}
 
-	// Get offense type
-	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
+	if archived {
+		err = h.tipJarService.ArchiveTipJar(c.Request().Context(), jarID, user.ID)
+	} else {
+		err = h.tipJarService.RestoreTipJar(c.Request().Context(), jarID, user.ID)
+	}
 	if err != nil {
-		c.Logger().Error("Failed to get offense type", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense type")
+		return jarError(c, err, "Failed to update jar")
 	}
 
-	if offenseType == nil || offenseType.JarID != jarID {
-		return echo.NewHTTPError(http.StatusNotFound, "Offense type not found")
-	}
+	c.Logger().Info("Jar archive state changed", "jar_id", jarID, "archived", archived, "user_id", user.ID)
 
-	return h.renderTemplate(c, templates.EditOffenseType(user, jar, offenseType))
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#archive", jarID))
 }
 
-func (h *Handlers) handlePayOffense(c echo.Context) error {
+func (h *Handlers) handleDeleteJar(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.DeleteJar); err != nil {
+		return err
+	}
+
+	if err := h.deleteJar(c, jarID, c.FormValue("confirm_name")); err != nil {
+		return err
+	}
+
+	c.Logger().Info("Jar deleted", "jar_id", jarID, "user_id", user.ID)
+
+	return c.Redirect(http.StatusSeeOther, "/dashboard")
+}
+
+// deleteJar deletes the jar and then the payment proofs stored for it. A
+// proof that can't be removed is only logged, since the jar is already gone.
+func (h *Handlers) deleteJar(c echo.Context, jarID int, confirmName string) error {
+	proofs, err := h.tipJarService.DeleteTipJar(c.Request().Context(), jarID, confirmName)
+	if err != nil {
+		return jarError(c, err, "Failed to delete jar")
+	}
+
+	for _, key := range proofs {
+		if err := h.proofService.Delete(c.Request().Context(), key); err != nil {
+			c.Logger().Error("Failed to delete proof of deleted jar", "error", err, "jar_id", jarID, "key", key)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handlers) handleClosePeriod(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.ManageJar); err != nil {
+		return err
+	}
+
+	next, err := h.periodService.ClosePeriod(c.Request().Context(), jarID, user.ID,
+		c.FormValue("name"), c.FormValue("next_name"), c.FormValue("carry_over") == "on")
+	if err != nil {
+		return periodError(c, err, "Failed to close period")
+	}
+
+	c.Logger().Info("Period closed", "jar_id", jarID, "next_period_id", next.ID, "user_id", user.ID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#periods", jarID))
+}
+
+// periodError turns a failed period close into a response
+func periodError(c echo.Context, err error, message string) error {
+	if err == services.ErrPeriodUnsettled {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err == services.ErrInvalidPeriodName {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// jarError turns a failed archive, restore or delete into a response
+func jarError(c echo.Context, err error, message string) error {
+	if err == services.ErrJarArchived || err == services.ErrJarNotArchived {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err == services.ErrJarNameMismatch {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+func (h *Handlers) handleRemoveMember(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, memberID, err := h.adminMemberParams(c, user)
+	if err != nil {
+		return err
+	}
+
+	err = h.tipJarService.RemoveMember(c.Request().Context(), jarID, memberID, user.ID)
+	if err != nil {
+		return memberError(c, err, "Failed to remove member")
+	}
+
+	c.Logger().Info("Member removed from jar", "jar_id", jarID, "user_id", memberID, "removed_by", user.ID)
+
+	if memberID == user.ID {
+		return c.Redirect(http.StatusSeeOther, "/jars")
+	}
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#members", jarID))
+}
+
+func (h *Handlers) handleUpdateMemberRole(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, memberID, err := h.adminMemberParams(c, user)
+	if err != nil {
+		return err
+	}
+
+	err = h.tipJarService.UpdateMemberRole(c.Request().Context(), jarID, memberID, c.FormValue("role"), user.ID)
+	if err != nil {
+		return memberError(c, err, "Failed to change role")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#members", jarID))
+}
+
+func (h *Handlers) handleTransferOwnership(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	newOwnerID, err := strconv.Atoi(c.FormValue("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.TransferOwnership); err != nil {
+		return err
+	}
+
+	err = h.tipJarService.TransferOwnership(c.Request().Context(), jarID, user.ID, newOwnerID)
+	if err != nil {
+		return memberError(c, err, "Failed to transfer ownership")
+	}
+
+	c.Logger().Info("Jar ownership transferred", "jar_id", jarID, "from_user_id", user.ID, "to_user_id", newOwnerID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#members", jarID))
+}
+
+// adminMemberParams parses the jar and member IDs of a member management
+// route, checking that the current user can manage the jar's members
+func (h *Handlers) adminMemberParams(c echo.Context, user *models.User) (int, int, error) {
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.ManageMembers); err != nil {
+		return 0, 0, err
+	}
+
+	return jarID, memberID, nil
+}
+
+func memberError(c echo.Context, err error, message string) error {
+	if err == services.ErrMemberNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	if err == services.ErrInvalidRole {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err == services.ErrOwnerRole {
+		return echo.NewHTTPError(http.StatusConflict, "You own this jar. Transfer ownership to someone else first.")
+	}
+	if err == services.ErrNotAllowed {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// inviteError turns an invite that can't be used into a response. Codes that
+// once worked answer 410 Gone so people know to ask for a new one.
+func inviteError(c echo.Context, err error, message string) error {
+	if err == services.ErrInviteNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Jar not found. Please check your invite code.")
+	}
+	if err == services.ErrInviteCodeTypo {
+		return echo.NewHTTPError(http.StatusNotFound, "That isn't a valid invite code. Please check it for typos.")
+	}
+	if err == services.ErrInviteRevoked || err == services.ErrInviteExpired || err == services.ErrInviteUsedUp {
+		return echo.NewHTTPError(http.StatusGone, err.Error()+". Ask for a new one.")
+	}
+	if err == services.ErrJarArchived {
+		return echo.NewHTTPError(http.StatusGone, "This jar has been archived, so nobody can join it.")
+	}
+	if err == services.ErrInvalidRole || err == services.ErrInvalidInvite {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// inviteJar loads the jar from the route for someone managing who can join it
+func (h *Handlers) inviteJar(c echo.Context, user *models.User) (*models.TipJar, error) {
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err, "jar_id", jarID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	if jar == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.ManageMembers); err != nil {
+		return nil, err
+	}
+
+	return jar, nil
+}
+
+// jarInvite loads the invite from the route and checks it belongs to the jar
+func (h *Handlers) jarInvite(c echo.Context, jar *models.TipJar) (*models.Invite, error) {
+	inviteID, err := strconv.Atoi(c.Param("invite_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid invite ID")
+	}
+
+	invite, err := h.inviteService.GetInvite(c.Request().Context(), inviteID)
+	if err != nil {
+		c.Logger().Error("Failed to get invite", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load invite")
+	}
+
+	if invite == nil || invite.JarID != jar.ID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Invite not found")
+	}
+
+	return invite, nil
+}
+
+func (h *Handlers) handleCreateInvite(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.inviteJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	opts := services.InviteOptions{
+		Label: strings.TrimSpace(c.FormValue("label")),
+		Role:  c.FormValue("role"),
+	}
+
+	if maxUses := strings.TrimSpace(c.FormValue("max_uses")); maxUses != "" {
+		n, err := strconv.Atoi(maxUses)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Max uses must be a number")
+		}
+		opts.MaxUses = &n
+	}
+
+	if hours := c.FormValue("expires_in_hours"); hours != "" {
+		n, err := strconv.Atoi(hours)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid expiry")
+		}
+		expiresAt := time.Now().Add(time.Duration(n) * time.Hour)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	invite, err := h.inviteService.CreateInvite(c.Request().Context(), jar.ID, user.ID, opts)
+	if err != nil {
+		return inviteError(c, err, "Failed to create invite")
+	}
+
+	c.Logger().Info("Invite created", "jar_id", jar.ID, "invite_id", invite.ID, "created_by", user.ID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#invites", jar.ID))
+}
+
+func (h *Handlers) handleRevokeInvite(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.inviteJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	invite, err := h.jarInvite(c, jar)
+	if err != nil {
+		return err
+	}
+
+	if err := h.inviteService.RevokeInvite(c.Request().Context(), invite.ID); err != nil {
+		c.Logger().Error("Failed to revoke invite", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke invite")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#invites", jar.ID))
+}
+
+func (h *Handlers) handleRotateInvite(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.inviteJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	invite, err := h.jarInvite(c, jar)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.inviteService.RotateInvite(c.Request().Context(), invite.ID, user.ID); err != nil {
+		if err == services.ErrInviteRevoked {
+			return echo.NewHTTPError(http.StatusConflict, "Revoked invites can't be rotated")
+		}
+		return inviteError(c, err, "Failed to rotate invite")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#invites", jar.ID))
+}
+
+// jarJoinRequest loads the join request from the route and checks it belongs
+// to the jar
+func (h *Handlers) jarJoinRequest(c echo.Context, jar *models.TipJar) (*models.JoinRequest, error) {
+	requestID, err := strconv.Atoi(c.Param("request_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request ID")
+	}
+
+	request, err := h.joinService.GetJoinRequest(c.Request().Context(), requestID)
+	if err != nil {
+		c.Logger().Error("Failed to get join request", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load join request")
+	}
+
+	if request == nil || request.JarID != jar.ID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Join request not found")
+	}
+
+	return request, nil
+}
+
+func joinRequestError(c echo.Context, err error, message string) error {
+	if err == services.ErrJoinRequestNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Join request not found")
+	}
+	if err == services.ErrJoinRequestReviewed {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	c.Logger().Error(message, "error", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+func (h *Handlers) handleApproveJoinRequest(c echo.Context) error {
+	return h.handleReviewJoinRequest(c, true)
+}
+
+func (h *Handlers) handleRejectJoinRequest(c echo.Context) error {
+	return h.handleReviewJoinRequest(c, false)
+}
+
+func (h *Handlers) handleReviewJoinRequest(c echo.Context, approve bool) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.inviteJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	request, err := h.jarJoinRequest(c, jar)
+	if err != nil {
+		return err
+	}
+
+	message := strings.TrimSpace(c.FormValue("message"))
+	if approve {
+		err = h.joinService.Approve(c.Request().Context(), request.ID, user.ID, message)
+	} else {
+		err = h.joinService.Reject(c.Request().Context(), request.ID, user.ID, message)
+	}
+	if err != nil {
+		return joinRequestError(c, err, "Failed to answer join request")
+	}
+
+	c.Logger().Info("Join request answered", "jar_id", jar.ID, "request_id", request.ID, "approved", approve, "reviewed_by", user.ID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings#members", jar.ID))
+}
+
+func (h *Handlers) handleDeactivateOffenseType(c echo.Context) error {
+	return h.handleSetOffenseTypeActiveStatus(c, false)
+}
+func (h *Handlers) handleReactivateOffenseType(c echo.Context) error {
+	return h.handleSetOffenseTypeActiveStatus(c, true)
+}
+
+func (h *Handlers) handleSetOffenseTypeActiveStatus(c echo.Context, isActive bool) error {
+	user := h.getCurrentUser(c)
+
+	// Parse IDs
+	jarIDStr := c.Param("id")
+	jarID, err := strconv.Atoi(jarIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	offenseTypeIDStr := c.Param("offense_type_id")
+	offenseTypeID, err := strconv.Atoi(offenseTypeIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.EditOffenseTypes); err != nil {
+		return err
+	}
+
+	// Verify the offense type belongs to this jar
+	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense type", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify offense type")
+	}
+
+	if offenseType == nil || offenseType.JarID != jarID {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense type not found")
+	}
+
+	err = h.offenseService.SetOffenseTypeActiveStatus(c.Request().Context(), offenseTypeID, isActive)
+	if err != nil {
+		action := "deactivate"
+		if isActive {
+			action = "reactivate"
+		}
+		c.Logger().Error(fmt.Sprintf("Failed to %s offense type", action), "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to %s offense type", action))
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/settings", jarID))
+}
+
+func (h *Handlers) handleEditOffenseTypeForm(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	// Parse IDs
+	jarIDStr := c.Param("id")
+	jarID, err := strconv.Atoi(jarIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	offenseTypeIDStr := c.Param("offense_type_id")
+	offenseTypeID, err := strconv.Atoi(offenseTypeIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense type ID")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.EditOffenseTypes); err != nil {
+		return err
+	}
+
+	// Get jar details
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	if jar == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	}
+
+	// Get offense type
+	offenseType, err := h.offenseService.GetOffenseType(c.Request().Context(), offenseTypeID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense type", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense type")
+	}
+
+	if offenseType == nil || offenseType.JarID != jarID {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense type not found")
+	}
+
+	return h.renderTemplate(c, templates.EditOffenseType(user, jar, offenseType))
+}
+
+func (h *Handlers) handlePayOffense(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	// Parse offense ID
+	offenseIDStr := c.Param("id")
+	offenseID, err := strconv.Atoi(offenseIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense ID")
+	}
+
+	// For GET request, show the form
+	if c.Request().Method == http.MethodGet {
+		// Get offense details
+		offenseDetail, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+		if err != nil {
+			c.Logger().Error("Failed to get offense detail", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+		}
+
+		if offenseDetail == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+		}
+
+		// Get the jar for the page
+		jar, err := h.tipJarService.GetTipJar(c.Request().Context(), offenseDetail.JarID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+		}
+
+		if _, err := h.authorize(c, user, offenseDetail.JarID, payPermission(offenseDetail, user)); err != nil {
+			return err
+		}
+
+		return h.renderTemplate(c, templates.PayOffense(user, jar, offenseDetail))
+	}
+
+	// For POST request, process the payment
+	return h.handleSubmitPayment(c, offenseID)
+}
+func (h *Handlers) handleSubmitPayment(c echo.Context, offenseID int) error {
+	user := h.getCurrentUser(c)
+
+	// Get offense details to verify
+	offenseDetail, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+	}
+
+	if offenseDetail == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+
+	isOffender := offenseDetail.OffenderID == user.ID
+	role, err := h.authorize(c, user, offenseDetail.JarID, payPermission(offenseDetail, user))
+	if err != nil {
+		return err
+	}
+
+	// Verify offense is still pending
+	if offenseDetail.Status != "pending" {
+		return echo.NewHTTPError(http.StatusBadRequest, "This offense has already been settled")
+	}
+
+	// Parse form data
+	notes := strings.TrimSpace(c.FormValue("notes"))
+
+	// A blank amount pays off whatever is left
+	var amount *money.Amount
+	if amountStr := strings.TrimSpace(c.FormValue("amount")); amountStr != "" {
+		parsed, err := money.Parse(amountStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment amount")
+		}
+		if !parsed.IsPositive() {
+			return echo.NewHTTPError(http.StatusBadRequest, "Payment amount must be greater than zero")
+		}
+		if parsed.Cmp(offenseDetail.Remaining) > 0 {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("Only %s %s is left to pay", offenseDetail.Remaining.Display(), offenseDetail.Unit))
+		}
+		amount = &parsed
+	}
+
+	// Add note if marked as paid on the offender's behalf
+	if !isOffender {
+		behalfNote := fmt.Sprintf("[Marked as paid by %s: %s]", role, user.Name)
+		if notes != "" {
+			notes = behalfNote + "\n\n" + notes
+		} else {
+			notes = behalfNote
+		}
+	}
+
+	proofURL, proofType, err := h.uploadProof(c, offenseDetail.JarID)
+	if err != nil {
+		return err
+	}
+
+	// Record the payment with the offender as payer
+	payment, err := h.offenseService.PayOffenses(c.Request().Context(), user.ID, []int{offenseID}, amount, proofURL, proofType)
+	if err != nil {
+		h.discardProof(c, proofURL)
+		if err == services.ErrOffenseSettled {
+			return echo.NewHTTPError(http.StatusBadRequest, "This offense has already been settled")
+		}
+		if err == services.ErrPeriodClosed {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if err == services.ErrOverpayment || err == services.ErrInvalidPaymentAmount {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error("Failed to create payment", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record payment")
+	}
+
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), offenseDetail.JarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err, "jar_id", offenseDetail.JarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+
+	c.Logger().Info("Payment recorded successfully",
+		"payment_id", payment.ID,
+		"offense_id", offenseID,
+		"offender_id", offenseDetail.OffenderID,
+		"amount", payment.Amount,
+		"remaining_before", offenseDetail.Remaining,
+		"unit", offenseDetail.Unit,
+		"marked_by_user_id", user.ID,
+		"role", role,
+		"notes", notes,
+		"awaiting_verification", jar.VerificationRequired)
+
+	// Redirect back to jar view
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success":               true,
+		"payment_id":            payment.ID,
+		"awaiting_verification": jar.VerificationRequired,
+		"redirect":              fmt.Sprintf("/jars/%d", offenseDetail.JarID),
+	})
+}
+
+// handlePayBatch lets a member settle several of their pending offenses in a
+// jar with a single payment
+func (h *Handlers) handlePayBatch(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	// Parse jar ID
+	jarIDStr := c.Param("id")
+	jarID, err := strconv.Atoi(jarIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
+	}
+
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+	}
+	if jar == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	}
+
+	if _, err := h.authorize(c, user, jarID, authz.ReportOffense); err != nil {
+		return err
+	}
+
+	// For GET request, show the form
+	if c.Request().Method == http.MethodGet {
+		offenses, err := h.offenseService.GetPendingOffensesForUserInJar(c.Request().Context(), jarID, user.ID)
+		if err != nil {
+			c.Logger().Error("Failed to get pending offenses", "error", err, "jar_id", jarID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offenses")
+		}
+
+		return h.renderTemplate(c, templates.PayBatch(user, jar, offenses))
+	}
+
+	params, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form data")
+	}
+
+	var offenseIDs []int
+	for _, idStr := range params["offense_ids"] {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense ID")
+		}
+		offenseIDs = append(offenseIDs, id)
+	}
+
+	if len(offenseIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Select at least one offense to pay")
+	}
+
+	// Only the member's own pending offenses in this jar can be batched
+	pending, err := h.offenseService.GetPendingOffensesForUserInJar(c.Request().Context(), jarID, user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get pending offenses", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offenses")
+	}
+
+	owed := make(map[int]bool, len(pending))
+	for _, offense := range pending {
+		owed[offense.ID] = true
+	}
+	for _, id := range offenseIDs {
+		if !owed[id] {
+			return echo.NewHTTPError(http.StatusBadRequest, "You can only pay your own pending offenses in this jar")
+		}
+	}
+
+	proofURL, proofType, err := h.uploadProof(c, jarID)
+	if err != nil {
+		return err
+	}
+
+	payment, err := h.offenseService.PayOffenses(c.Request().Context(), user.ID, offenseIDs, nil, proofURL, proofType)
+	if err != nil {
+		h.discardProof(c, proofURL)
+	}
+	if err == services.ErrOffenseNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+	if err == services.ErrOffenseSettled {
+		return echo.NewHTTPError(http.StatusBadRequest, "One of these offenses has already been settled")
+	}
+	if err == services.ErrPeriodClosed {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err == services.ErrMixedOffenses {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create batch payment", "error", err, "jar_id", jarID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record payment")
+	}
+
+	c.Logger().Info("Batch payment recorded",
+		"payment_id", payment.ID,
+		"jar_id", jarID,
+		"user_id", user.ID,
+		"offense_count", len(payment.Allocations),
+		"awaiting_verification", jar.VerificationRequired)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success":               true,
+		"payment_id":            payment.ID,
+		"awaiting_verification": jar.VerificationRequired,
+		"redirect":              fmt.Sprintf("/jars/%d", jarID),
+	})
+}
+
+// uploadProof stores the optional proof_file upload for a payment in the jar,
+// returning its storage key and proof type. Errors are ready to return.
+func (h *Handlers) uploadProof(c echo.Context, jarID int) (*string, *string, error) {
+	file, err := c.FormFile("proof_file")
+	if err != nil || file == nil {
+		return nil, nil, nil
+	}
+
+	tooLarge := fmt.Sprintf("Proof files can be at most %d MB", h.proofService.MaxBytes()>>20)
+	if file.Size > h.proofService.MaxBytes() {
+		return nil, nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, tooLarge)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to read uploaded file")
+	}
+	defer src.Close()
+
+	key, kind, err := h.proofService.Upload(c.Request().Context(), jarID, src, file.Size)
+	if err == services.ErrProofTooLarge {
+		return nil, nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, tooLarge)
+	}
+	if err == services.ErrUnsupportedProofType {
+		return nil, nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, "Proof must be an image (PNG, JPG, GIF, WebP), a PDF receipt or a video (MP4, WebM)")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to store proof", "error", err)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to upload proof")
+	}
+
+	return &key, &kind, nil
+}
+
+// discardProof removes an uploaded proof whose payment could not be recorded
+func (h *Handlers) discardProof(c echo.Context, proofURL *string) {
+	if proofURL == nil {
+		return
+	}
+	if err := h.proofService.Delete(c.Request().Context(), *proofURL); err != nil {
+		c.Logger().Error("Failed to delete orphaned proof", "error", err, "key", *proofURL)
+	}
+}
+
+func (h *Handlers) handleListSessions(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	sessions, err := h.sessionService.ListSessions(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list sessions", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load sessions")
+	}
+
+	currentSessionID := 0
+	if session, ok := c.Get("session").(*models.Session); ok {
+		currentSessionID = session.ID
+	}
+
+	return h.renderTemplate(c, templates.Sessions(user, sessions, currentSessionID))
+}
+
+func (h *Handlers) handleRevokeSession(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	sessionIDStr := c.Param("id")
+	sessionID, err := strconv.Atoi(sessionIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid session ID")
+	}
+
+	if err := h.sessionService.RevokeSession(c.Request().Context(), user.ID, sessionID); err != nil {
+		c.Logger().Error("Failed to revoke session", "error", err, "session_id", sessionID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	// Revoking the session in use is the same as logging out
+	if session, ok := c.Get("session").(*models.Session); ok && session.ID == sessionID {
+		h.sessionService.ClearSessionCookie(c.Response().Writer)
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/sessions")
+}
+
+func (h *Handlers) handleRevokeAllSessions(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	if err := h.sessionService.RevokeAllSessions(c.Request().Context(), user.ID); err != nil {
+		c.Logger().Error("Failed to revoke sessions", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to log out everywhere")
+	}
+
+	c.Logger().Info("User logged out everywhere", "user_id", user.ID)
+
+	h.sessionService.ClearSessionCookie(c.Response().Writer)
+	return c.Redirect(http.StatusSeeOther, "/login")
+}
+
+func (h *Handlers) handleListAPITokens(c echo.Context) error {
+	return h.renderAPITokens(c, "")
+}
+
+// handleCreateAPIToken issues a token and shows it once on the tokens page
+func (h *Handlers) handleCreateAPIToken(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	scope := c.FormValue("scope")
+
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Token name is required")
+	}
+
+	token, apiToken, err := h.apiTokenService.CreateToken(c.Request().Context(), user.ID, name, scope)
+	if err == services.ErrInvalidScope {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to create API token", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create token")
+	}
+
+	c.Logger().Info("API token created", "token_id", apiToken.ID, "user_id", user.ID, "scope", scope)
+
+	return h.renderAPITokens(c, token)
+}
+
+func (h *Handlers) handleRevokeAPIToken(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	tokenIDStr := c.Param("id")
+	tokenID, err := strconv.Atoi(tokenIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid token ID")
+	}
+
+	err = h.apiTokenService.RevokeToken(c.Request().Context(), user.ID, tokenID)
+	if err == services.ErrAPITokenNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Token not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to revoke API token", "error", err, "token_id", tokenID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke token")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/account/tokens")
+}
+
+func (h *Handlers) renderAPITokens(c echo.Context, newToken string) error {
+	user := h.getCurrentUser(c)
+
+	tokens, err := h.apiTokenService.ListTokens(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list API tokens", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load tokens")
+	}
+
+	return h.renderTemplate(c, templates.APITokens(user, tokens, newToken))
+}
+
+func (h *Handlers) handleListIdentities(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	identities, err := h.userService.ListIdentities(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list identities", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load linked accounts")
+	}
+
+	return h.renderTemplate(c, templates.LinkedAccounts(user, identities, h.auth.Providers()))
+}
+
+func (h *Handlers) handleUnlinkIdentity(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	identityIDStr := c.Param("id")
+	identityID, err := strconv.Atoi(identityIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid identity ID")
+	}
+
+	err = h.userService.UnlinkIdentity(c.Request().Context(), user.ID, identityID)
+	if err == services.ErrLastIdentity {
+		return echo.NewHTTPError(http.StatusBadRequest, "You can't unlink your only sign-in method")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to unlink identity", "error", err, "identity_id", identityID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unlink account")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/account/identities")
+}
+
+func (h *Handlers) handleOpenDispute(c echo.Context) error {
 	user := h.getCurrentUser(c)
 
 	// Parse offense ID
@@ -980,122 +2274,389 @@ func (h *Handlers) handlePayOffense(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offense ID")
 	}
 
-	// For GET request, show the form
-	if c.Request().Method == http.MethodGet {
-		// Get offense details
-		offenseDetail, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
-		if err != nil {
-			c.Logger().Error("Failed to get offense detail", "error", err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
-		}
+	reason := strings.TrimSpace(c.FormValue("reason"))
+	if reason == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A reason is required to dispute an offense")
+	}
 
-		if offenseDetail == nil {
-			return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
-		}
+	offenseDetail, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+	if err != nil {
+		c.Logger().Error("Failed to get offense detail", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
+	}
 
-		// Get jar to verify user is a member
-		jar, err := h.tipJarService.GetTipJar(c.Request().Context(), offenseDetail.JarID)
+	if offenseDetail == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	}
+
+	if _, err := h.authorize(c, user, offenseDetail.JarID, authz.VoteOnDisputes); err != nil {
+		return err
+	}
+
+	_, err = h.disputeService.OpenDispute(c.Request().Context(), offenseID, user.ID, reason)
+	if err == services.ErrNotOffender {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the offender can dispute this offense")
+	}
+	if err == services.ErrOffenseNotPending || err == services.ErrAlreadyDisputed {
+		return echo.NewHTTPError(http.StatusBadRequest, "This offense can no longer be disputed")
+	}
+	if err == services.ErrPeriodClosed {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to open dispute", "error", err, "offense_id", offenseID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open dispute")
+	}
+
+	c.Logger().Info("Dispute opened", "offense_id", offenseID, "user_id", user.ID)
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d", offenseDetail.JarID))
+}
+
+func (h *Handlers) handleDisputeVote(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	// Parse dispute ID
+	disputeIDStr := c.Param("id")
+	disputeID, err := strconv.Atoi(disputeIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid dispute ID")
+	}
+
+	dispute, err := h.disputeService.GetDispute(c.Request().Context(), disputeID)
+	if err != nil {
+		c.Logger().Error("Failed to get dispute", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load dispute")
+	}
+
+	if dispute == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Dispute not found")
+	}
+
+	if _, err := h.authorize(c, user, dispute.JarID, authz.VoteOnDisputes); err != nil {
+		return err
+	}
+
+	vote := strings.TrimSpace(c.FormValue("vote"))
+
+	var reducedAmount *money.Amount
+	if vote == "reduce" {
+		amount, err := money.Parse(c.FormValue("reduced_amount"))
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid reduced amount")
 		}
+		reducedAmount = &amount
+	}
+
+	err = h.disputeService.CastVote(c.Request().Context(), disputeID, user.ID, vote, reducedAmount)
+	if err == services.ErrInvalidVote || err == services.ErrInvalidReduction || err == services.ErrDisputeClosed {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err == services.ErrCannotVote {
+		return echo.NewHTTPError(http.StatusForbidden, "You can't vote on your own dispute")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to record vote", "error", err, "dispute_id", disputeID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record vote")
+	}
 
-		// Verify user is either the offender or a jar admin
-		isOffender := offenseDetail.OffenderID == user.ID
-		isAdmin, _ := h.tipJarService.IsUserJarAdmin(c.Request().Context(), offenseDetail.JarID, user.ID)
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d", dispute.JarID))
+}
 
-		if !isOffender && !isAdmin {
-			return echo.NewHTTPError(http.StatusForbidden, "Only the offender or jar admins can mark this offense as paid")
-		}
+func (h *Handlers) handleListVerifications(c echo.Context) error {
+	user := h.getCurrentUser(c)
 
-		return h.renderTemplate(c, templates.PayOffense(user, jar, offenseDetail))
+	pending, err := h.verifyService.ListAwaitingVerification(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list payments awaiting verification", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load verifications")
 	}
 
-	// For POST request, process the payment
-	return h.handleSubmitPayment(c, offenseID)
+	return h.renderTemplate(c, templates.Verifications(user, pending))
 }
-func (h *Handlers) handleSubmitPayment(c echo.Context, offenseID int) error {
+
+func (h *Handlers) handleVerifyPayment(c echo.Context) error {
+	return h.handlePaymentVerdict(c, true)
+}
+
+func (h *Handlers) handleRejectPayment(c echo.Context) error {
+	return h.handlePaymentVerdict(c, false)
+}
+
+func (h *Handlers) handlePaymentVerdict(c echo.Context, approved bool) error {
 	user := h.getCurrentUser(c)
 
-	// Get offense details to verify
-	offenseDetail, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+	// Parse payment ID
+	paymentIDStr := c.Param("id")
+	paymentID, err := strconv.Atoi(paymentIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID")
+	}
+
+	err = h.verifyService.RecordVerdict(c.Request().Context(), paymentID, user.ID, approved)
+	if err == services.ErrPaymentNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	}
+	if err == services.ErrNotJarMember || err == services.ErrOwnPayment || err == services.ErrNotAllowed {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err == services.ErrPaymentSettled {
+		return echo.NewHTTPError(http.StatusBadRequest, "This payment has already been settled")
+	}
+	if err == services.ErrJarArchived {
+		return echo.NewHTTPError(http.StatusConflict, "This jar is archived. Restore it to make changes.")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to record payment verdict", "error", err, "payment_id", paymentID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record verification")
+	}
+
+	c.Logger().Info("Payment verdict recorded", "payment_id", paymentID, "user_id", user.ID, "approved", approved)
+
+	return c.Redirect(http.StatusSeeOther, "/verifications")
+}
+
+// handleViewProof streams a payment's proof file to members of the offense's jar
+func (h *Handlers) handleViewProof(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	// Parse payment ID
+	paymentIDStr := c.Param("id")
+	paymentID, err := strconv.Atoi(paymentIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID")
+	}
+
+	payment, err := h.offenseService.GetPayment(c.Request().Context(), paymentID)
 	if err != nil {
+		c.Logger().Error("Failed to get payment", "error", err, "payment_id", paymentID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment")
+	}
+	if payment == nil || payment.ProofURL == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Proof not found")
+	}
+
+	if len(payment.Allocations) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Proof not found")
+	}
+
+	// Every offense a payment covers is in the same jar
+	offenseID := payment.Allocations[0].OffenseID
+	offense, err := h.offenseService.GetOffenseDetail(c.Request().Context(), offenseID)
+	if err != nil || offense == nil {
+		c.Logger().Error("Failed to get offense", "error", err, "offense_id", offenseID)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load offense")
 	}
 
-	if offenseDetail == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Offense not found")
+	if _, err := h.authorize(c, user, offense.JarID, authz.ViewJar); err != nil {
+		return err
 	}
 
-	// Verify user is either the offender or a jar admin
-	isOffender := offenseDetail.OffenderID == user.ID
-	isAdmin, _ := h.tipJarService.IsUserJarAdmin(c.Request().Context(), offenseDetail.JarID, user.ID)
+	proof, contentType, err := h.proofService.Open(c.Request().Context(), *payment.ProofURL)
+	if err == storage.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Proof not found")
+	}
+	if err != nil {
+		c.Logger().Error("Failed to open proof", "error", err, "payment_id", paymentID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load proof")
+	}
+	defer proof.Close()
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=3600")
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	c.Response().Header().Set("Content-Disposition", "inline")
+	return c.Stream(http.StatusOK, contentType, proof)
+}
 
-	if !isOffender && !isAdmin {
-		return echo.NewHTTPError(http.StatusForbidden, "Only the offender or jar admins can mark this offense as paid")
+// webhookJar loads the jar from the route for a webhook page. Only members who
+// can manage the jar see webhooks, since their secrets are shown in full.
+func (h *Handlers) webhookJar(c echo.Context, user *models.User) (*models.TipJar, error) {
+	jarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid jar ID")
 	}
 
-	// Verify offense is still pending
-	if offenseDetail.Status != "pending" {
-		return echo.NewHTTPError(http.StatusBadRequest, "This offense has already been settled")
+	jar, err := h.tipJarService.GetTipJar(c.Request().Context(), jarID)
+	if err != nil {
+		c.Logger().Error("Failed to get jar", "error", err, "jar_id", jarID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load jar")
 	}
 
-	// Parse form data - just notes now
-	notes := strings.TrimSpace(c.FormValue("notes"))
+	if jar == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Jar not found")
+	}
 
-	// Add note if marked as paid by admin (not the offender)
-	if isAdmin && !isOffender {
-		adminNote := fmt.Sprintf("[Marked as paid by admin: %s]", user.Name)
-		if notes != "" {
-			notes = adminNote + "\n\n" + notes
-		} else {
-			notes = adminNote
-		}
+	if _, err := h.authorize(c, user, jarID, authz.ManageJar); err != nil {
+		return nil, err
 	}
 
-	// Handle file upload if provided
-	var proofURL *string
-	file, err := c.FormFile("proof_file")
-	if err == nil && file != nil {
-		// TODO: Implement file upload handling
-		// For now, we'll skip this and just record the payment
-		c.Logger().Info("File upload received but not yet implemented", "filename", file.Filename)
+	return jar, nil
+}
+
+// jarWebhook loads the webhook from the route and checks it belongs to the jar
+func (h *Handlers) jarWebhook(c echo.Context, jar *models.TipJar) (*models.Webhook, error) {
+	webhookID, err := strconv.Atoi(c.Param("webhook_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	webhook, err := h.webhookService.GetWebhook(c.Request().Context(), webhookID)
+	if err != nil {
+		c.Logger().Error("Failed to get webhook", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to load webhook")
+	}
+
+	if webhook == nil || webhook.JarID != jar.ID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Webhook not found")
+	}
+
+	return webhook, nil
+}
+
+func (h *Handlers) handleListWebhooks(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(c.Request().Context(), jar.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list webhooks", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load webhooks")
+	}
+
+	return h.renderTemplate(c, templates.Webhooks(user, jar, webhooks, services.WebhookEventTypes))
+}
+
+func (h *Handlers) handleCreateWebhook(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
+	if err != nil {
+		return err
 	}
 
-	// Create payment record - always with the full amount owed
-	// Use offender's ID as the payer
-	payment, err := h.offenseService.CreatePayment(
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form")
+	}
+
+	_, err = h.webhookService.CreateWebhook(
 		c.Request().Context(),
-		offenseID,
-		offenseDetail.OffenderID,
-		&offenseDetail.Amount, // Always the full amount
-		proofURL,
-		notes,
+		jar.ID,
+		strings.TrimSpace(form.Get("url")),
+		form["event_types"],
+		user.ID,
 	)
 	if err != nil {
-		c.Logger().Error("Failed to create payment", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record payment")
+		if err == services.ErrInvalidWebhookURL || err == services.ErrNoWebhookEvents || err == services.ErrUnknownEventType {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error("Failed to create webhook", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create webhook")
 	}
 
-	// Update offense status to paid
-	err = h.offenseService.UpdateOffenseStatus(c.Request().Context(), offenseID, "paid")
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/webhooks", jar.ID))
+}
+
+func (h *Handlers) handleToggleWebhook(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
 	if err != nil {
-		c.Logger().Error("Failed to update offense status", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to mark offense as paid")
+		return err
 	}
 
-	c.Logger().Info("Payment recorded successfully",
-		"payment_id", payment.ID,
-		"offense_id", offenseID,
-		"offender_id", offenseDetail.OffenderID,
-		"amount", offenseDetail.Amount,
-		"unit", offenseDetail.Unit,
-		"marked_by_user_id", user.ID,
-		"is_admin", isAdmin)
+	webhook, err := h.jarWebhook(c, jar)
+	if err != nil {
+		return err
+	}
 
-	// Redirect back to jar view
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success":    true,
-		"payment_id": payment.ID,
-		"redirect":   fmt.Sprintf("/jars/%d", offenseDetail.JarID),
-	})
-}
\ No newline at end of file
+	if err := h.webhookService.SetWebhookActive(c.Request().Context(), webhook.ID, !webhook.IsActive); err != nil {
+		c.Logger().Error("Failed to update webhook", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update webhook")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/webhooks", jar.ID))
+}
+
+func (h *Handlers) handleDeleteWebhook(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.jarWebhook(c, jar)
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request().Context(), webhook.ID); err != nil {
+		c.Logger().Error("Failed to delete webhook", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete webhook")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/webhooks", jar.ID))
+}
+
+func (h *Handlers) handleListWebhookDeliveries(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.jarWebhook(c, jar)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request().Context(), webhook.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list webhook deliveries", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load deliveries")
+	}
+
+	return h.renderTemplate(c, templates.WebhookDeliveries(user, jar, webhook, deliveries))
+}
+
+func (h *Handlers) handleRedeliverWebhook(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.webhookJar(c, user)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.jarWebhook(c, jar)
+	if err != nil {
+		return err
+	}
+
+	deliveryID, err := strconv.Atoi(c.Param("delivery_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid delivery ID")
+	}
+
+	delivery, err := h.webhookService.GetDelivery(c.Request().Context(), deliveryID)
+	if err != nil {
+		c.Logger().Error("Failed to get webhook delivery", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load delivery")
+	}
+
+	if delivery == nil || delivery.WebhookID != webhook.ID {
+		return echo.NewHTTPError(http.StatusNotFound, "Delivery not found")
+	}
+
+	if _, err := h.webhookService.Redeliver(c.Request().Context(), delivery.ID); err != nil {
+		c.Logger().Error("Failed to redeliver webhook", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to redeliver")
+	}
+
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/jars/%d/webhooks/%d", jar.ID, webhook.ID))
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"tipjar/internal/models"
+	"tipjar/internal/services"
+	"tipjar/internal/templates"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handlers) handleListNotifications(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	notifications, err := h.notifyService.ListNotifications(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list notifications", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load notifications")
+	}
+
+	return h.renderTemplate(c, templates.Notifications(user, notifications))
+}
+
+// handleUnreadNotifications renders the unread badge for the navigation bar,
+// which polls it
+func (h *Handlers) handleUnreadNotifications(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	count, err := h.notifyService.CountUnread(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to count notifications", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count notifications")
+	}
+
+	return h.renderTemplate(c, templates.NotificationBadge(count))
+}
+
+// handleMarkNotificationRead marks a notification read and follows its link
+func (h *Handlers) handleMarkNotificationRead(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	notificationIDStr := c.Param("id")
+	notificationID, err := strconv.Atoi(notificationIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid notification ID")
+	}
+
+	link, err := h.notifyService.MarkRead(c.Request().Context(), user.ID, notificationID)
+	if err != nil {
+		c.Logger().Error("Failed to mark notification read", "error", err, "notification_id", notificationID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update notification")
+	}
+
+	if link == "" {
+		return c.Redirect(http.StatusSeeOther, "/notifications")
+	}
+	return c.Redirect(http.StatusSeeOther, link)
+}
+
+func (h *Handlers) handleMarkAllNotificationsRead(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	if err := h.notifyService.MarkAllRead(c.Request().Context(), user.ID); err != nil {
+		c.Logger().Error("Failed to mark notifications read", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update notifications")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/notifications")
+}
+
+func (h *Handlers) handleNotificationPreferences(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jars, err := h.tipJarService.ListTipJarsForUser(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list jars", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load preferences")
+	}
+
+	preferences, err := h.notifyService.GetPreferences(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get notification preferences", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load preferences")
+	}
+
+	emailMode, err := h.emailService.GetEmailMode(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to get email setting", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load preferences")
+	}
+
+	return h.renderTemplate(c, templates.NotificationPreferences(
+		user,
+		jars,
+		preferences,
+		services.NotificationTypes,
+		emailMode,
+		h.emailService.Enabled(),
+	))
+}
+
+// handleUpdateNotificationPreferences saves the preferences form. Each
+// checkbox is named "enabled" with the value "<jar id>:<type>", so anything
+// left unchecked is switched off.
+func (h *Handlers) handleUpdateNotificationPreferences(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	jars, err := h.tipJarService.ListTipJarsForUser(c.Request().Context(), user.ID)
+	if err != nil {
+		c.Logger().Error("Failed to list jars", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save preferences")
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form")
+	}
+
+	checked := make(map[string]bool)
+	for _, value := range form["enabled"] {
+		checked[value] = true
+	}
+
+	var preferences []models.NotificationPreference
+	for _, jar := range jars {
+		for _, notificationType := range services.NotificationTypes {
+			preferences = append(preferences, models.NotificationPreference{
+				JarID:     jar.ID,
+				EventType: notificationType.Name,
+				Enabled:   checked[fmt.Sprintf("%d:%s", jar.ID, notificationType.Name)],
+			})
+		}
+	}
+
+	if err := h.notifyService.UpdatePreferences(c.Request().Context(), user.ID, preferences); err != nil {
+		c.Logger().Error("Failed to update notification preferences", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save preferences")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/notifications/preferences")
+}
+
+func (h *Handlers) handleUpdateEmailMode(c echo.Context) error {
+	user := h.getCurrentUser(c)
+
+	err := h.emailService.SetEmailMode(c.Request().Context(), user.ID, c.FormValue("mode"))
+	if err == services.ErrInvalidEmailMode {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to update email setting", "error", err, "user_id", user.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save email setting")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/notifications/preferences")
+}
+
+// handleUnsubscribe turns off emails from a link in one. Opening the link
+// only asks for confirmation, since mail scanners follow links; the form, and
+// mail clients' one-click unsubscribe, POST to it.
+func (h *Handlers) handleUnsubscribe(c echo.Context) error {
+	userID, err := strconv.Atoi(c.QueryParam("user"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, services.ErrInvalidUnsubscribeToken.Error())
+	}
+	token := c.QueryParam("token")
+
+	if c.Request().Method == http.MethodGet {
+		return h.renderTemplate(c, templates.Unsubscribe(h.getCurrentUser(c), c.Request().URL.RequestURI(), false))
+	}
+
+	err = h.emailService.Unsubscribe(c.Request().Context(), userID, token)
+	if err == services.ErrInvalidUnsubscribeToken {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error("Failed to unsubscribe", "error", err, "user_id", userID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unsubscribe")
+	}
+
+	c.Logger().Info("User unsubscribed from email", "user_id", userID)
+
+	return h.renderTemplate(c, templates.Unsubscribe(h.getCurrentUser(c), "", true))
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tipjar/internal/invitecode"
+	"tipjar/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/skip2/go-qrcode"
+)
+
+// QR codes of invite links, for admins to print or put on a slide. They're
+// rendered on the server so the link never goes to a third party.
+
+// qrCodePNGSize is the width and height of PNG QR codes in pixels
+const qrCodePNGSize = 512
+
+// inviteLink is the address that opens the join form with the invite's code
+// filled in
+func (h *Handlers) inviteLink(invite *models.Invite) string {
+	return strings.TrimRight(h.cfg.BaseURL, "/") + "/join/" + invitecode.Format(invite.Code)
+}
+
+// inviteQRCode loads the invite from the route and encodes its link
+func (h *Handlers) inviteQRCode(c echo.Context) (*models.Invite, *qrcode.QRCode, error) {
+	user := h.getCurrentUser(c)
+
+	jar, err := h.inviteJar(c, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invite, err := h.jarInvite(c, jar)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qr, err := qrcode.New(h.inviteLink(invite), qrcode.Medium)
+	if err != nil {
+		c.Logger().Error("Failed to encode QR code", "error", err, "invite_id", invite.ID)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to make QR code")
+	}
+
+	return invite, qr, nil
+}
+
+func (h *Handlers) handleInviteQRCodePNG(c echo.Context) error {
+	invite, qr, err := h.inviteQRCode(c)
+	if err != nil {
+		return err
+	}
+
+	png, err := qr.PNG(qrCodePNGSize)
+	if err != nil {
+		c.Logger().Error("Failed to render QR code", "error", err, "invite_id", invite.ID)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to make QR code")
+	}
+
+	setQRCodeHeaders(c, invite, "png")
+	return c.Blob(http.StatusOK, "image/png", png)
+}
+
+func (h *Handlers) handleInviteQRCodeSVG(c echo.Context) error {
+	invite, qr, err := h.inviteQRCode(c)
+	if err != nil {
+		return err
+	}
+
+	setQRCodeHeaders(c, invite, "svg")
+	return c.Blob(http.StatusOK, "image/svg+xml", []byte(qrCodeSVG(qr)))
+}
+
+// setQRCodeHeaders names the file after the code for ?download=1, and stops
+// the image being cached since rotating an invite changes what it should show
+func setQRCodeHeaders(c echo.Context, invite *models.Invite, ext string) {
+	header := c.Response().Header()
+	header.Set("Cache-Control", "private, no-store")
+	if c.QueryParam("download") != "" {
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invite-%s.%s"`, invitecode.Format(invite.Code), ext))
+	}
+}
+
+// qrCodeSVG draws the code one module per unit, with the quiet zone the
+// bitmap already includes, so it scales to any size without blurring
+func qrCodeSVG(qr *qrcode.QRCode) string {
+	bitmap := qr.Bitmap()
+	size := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		size, size, size, size, path.String())
+}
@@ -0,0 +1,80 @@
+// Package invitecode generates and checks the codes people type to join a
+// jar. Codes use Crockford's base 32 alphabet, which leaves out I, L, O and U
+// so they can't be mistaken for 1, 0 or V, and end in a check symbol that
+// catches most typos before the database is asked.
+package invitecode
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Alphabet is the set of symbols a normalized code is made of
+const Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Length is the number of symbols in a code, check symbol included
+const Length = 8
+
+// Generate returns a new random code with its check symbol
+func Generate() (string, error) {
+	b := make([]byte, Length-1)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// 256 is a multiple of 32, so this doesn't favour any symbol
+		b[i] = Alphabet[int(b[i])%len(Alphabet)]
+	}
+	return string(b) + string(checkSymbol(string(b))), nil
+}
+
+// Normalize turns what someone typed into the form codes are stored in. Case,
+// spaces and hyphens are ignored, and the letters Crockford leaves out are
+// read as the digits they look like.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		switch r {
+		case ' ', '\t', '-':
+			continue
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Valid reports whether a normalized code has the right length, only uses the
+// alphabet and ends in the right check symbol
+func Valid(code string) bool {
+	if len(code) != Length {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(Alphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return code[Length-1] == checkSymbol(code[:Length-1])
+}
+
+// Format splits a normalized code in two so it's easier to read out
+func Format(code string) string {
+	if len(code) != Length {
+		return code
+	}
+	return code[:Length/2] + "-" + code[Length/2:]
+}
+
+// checkSymbol weights each symbol by an odd number, so any single wrong
+// symbol changes the sum mod 32, as do most swaps of neighbouring symbols
+func checkSymbol(data string) byte {
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		sum += (2*i + 1) * strings.IndexByte(Alphabet, data[i])
+	}
+	return Alphabet[sum%len(Alphabet)]
+}
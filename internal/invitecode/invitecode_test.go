@@ -0,0 +1,192 @@
+package invitecode
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"tipjar/internal/database/dbtest"
+)
+
+func TestGenerate(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Valid(code) {
+			t.Fatalf("Generate() = %q, which isn't valid", code)
+		}
+		if Normalize(code) != code {
+			t.Errorf("Generate() = %q, which isn't normalized", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 990 {
+		t.Errorf("1000 codes had only %d different ones", len(seen))
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		typed, want string
+	}{
+		{"ABCD1234", "ABCD1234"},
+		{"abcd1234", "ABCD1234"},
+		{"ABCD-1234", "ABCD1234"},
+		{"abcd 1234", "ABCD1234"},
+		{" ab-cd\t12 34 ", "ABCD1234"},
+		{"IL1O0", "11100"},
+		{"il1o0", "11100"},
+		{"ABCD_1234", "ABCD_1234"}, // Anything else is left for Valid to refuse
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.typed); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.typed, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	code := "7K3N9QX" + string(checkSymbol("7K3N9QX"))
+
+	tests := []struct {
+		name  string
+		typed string
+		want  bool
+	}{
+		{"normalized", code, true},
+		{"lower case", strings.ToLower(code), true},
+		{"formatted", Format(code), true},
+		{"with spaces", code[:4] + " " + code[4:], true},
+		{"too short", code[:Length-1], false},
+		{"too long", code + "0", false},
+		{"empty", "", false},
+		{"U isn't in the alphabet", "U" + code[1:], false},
+		{"wrong check symbol", code[:Length-1] + string(Alphabet[(strings.IndexByte(Alphabet, code[Length-1])+1)%len(Alphabet)]), false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(Normalize(tt.typed)); got != tt.want {
+			t.Errorf("%s: Valid(Normalize(%q)) = %v, want %v", tt.name, tt.typed, got, tt.want)
+		}
+	}
+}
+
+func TestLookalikesAreReadAsDigits(t *testing.T) {
+	code := "1100ABC"
+	code += string(checkSymbol(code))
+
+	for _, typed := range []string{
+		"IL00ABC" + code[7:],
+		"1lOoABC" + code[7:],
+		"i1o0-abc" + strings.ToLower(code[7:]),
+	} {
+		if got := Normalize(typed); got != code || !Valid(got) {
+			t.Errorf("Normalize(%q) = %q, want the valid code %q", typed, got, code)
+		}
+	}
+}
+
+func TestSingleTyposAreCaught(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for pos := 0; pos < Length; pos++ {
+			for j := 0; j < len(Alphabet); j++ {
+				if Alphabet[j] == code[pos] {
+					continue
+				}
+				typo := code[:pos] + string(Alphabet[j]) + code[pos+1:]
+				if Valid(typo) {
+					t.Fatalf("%q with symbol %d typed as %c (%q) is still valid", code, pos, Alphabet[j], typo)
+				}
+			}
+		}
+	}
+}
+
+func TestTranspositionsAreCaught(t *testing.T) {
+	// Swapping neighbours changes the sum by twice the difference between
+	// them, which only misses when they're 16 apart in the alphabet
+	for a := 0; a < len(Alphabet); a++ {
+		for b := 0; b < len(Alphabet); b++ {
+			if a == b {
+				continue
+			}
+			for pos := 0; pos < Length-2; pos++ {
+				data := []byte("0000000")
+				data[pos], data[pos+1] = Alphabet[a], Alphabet[b]
+				code := string(data) + string(checkSymbol(string(data)))
+
+				data[pos], data[pos+1] = data[pos+1], data[pos]
+				swapped := string(data) + code[Length-1:]
+
+				wantCaught := a-b != 16 && b-a != 16
+				if caught := !Valid(swapped); caught != wantCaught {
+					t.Errorf("swapping %c%c at %d in %q: caught = %v, want %v", Alphabet[a], Alphabet[b], pos, code, caught, wantCaught)
+				}
+			}
+		}
+	}
+}
+
+// TestMigrationCodes checks the codes 018_invite_codes gave existing invites
+// are ones this package accepts, by running the migration's generator
+func TestMigrationCodes(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	migration, err := os.ReadFile("../database/migrations/018_invite_codes.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := strings.Index(string(migration), "CREATE FUNCTION pg_temp.generate_invite_code()")
+	end := strings.Index(string(migration), "LANGUAGE plpgsql;")
+	if start < 0 || end < start {
+		t.Fatal("generate_invite_code not found in the migration")
+	}
+	function := string(migration[start : end+len("LANGUAGE plpgsql;")])
+
+	// pg_temp belongs to the connection, so keep to one by using a transaction
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, function); err != nil {
+		t.Fatalf("create generate_invite_code: %v", err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT pg_temp.generate_invite_code() FROM generate_series(1, 1000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			t.Fatal(err)
+		}
+		if !Valid(code) {
+			t.Errorf("migration generated %q, which isn't valid", code)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 {
+		t.Errorf("got %d codes, want 1000", n)
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"tipjar/internal/money"
 )
 
 type User struct {
@@ -9,19 +11,52 @@ type User struct {
 	Email     string    `json:"email" db:"email"`
 	Name      string    `json:"name" db:"name"`
 	Avatar    *string   `json:"avatar" db:"avatar"`
-	GoogleID  string    `json:"google_id" db:"google_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type TipJar struct {
+type UserIdentity struct {
 	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description *string   `json:"description" db:"description"`
-	InviteCode  string    `json:"invite_code" db:"invite_code"`
-	CreatedBy   int       `json:"created_by" db:"created_by"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Provider    string    `json:"provider" db:"provider"`
+	Email       *string   `json:"email" db:"email"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+type Session struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	UserAgent  *string   `json:"user_agent" db:"user_agent"`
+	IPAddress  *string   `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type APIToken struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"token_prefix"` // Start of the token, to tell them apart
+	Scope      string     `json:"scope" db:"scope"`         // 'read', 'report' or 'full'
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+type TipJar struct {
+	ID                   int        `json:"id" db:"id"`
+	Name                 string     `json:"name" db:"name"`
+	Description          *string    `json:"description" db:"description"`
+	CreatedBy            int        `json:"created_by" db:"created_by"`
+	DisputeQuorum        int        `json:"dispute_quorum" db:"dispute_quorum"`               // Votes needed to close a dispute early
+	DisputeWindowHours   int        `json:"dispute_window_hours" db:"dispute_window_hours"`   // Disputes close after this long regardless
+	VerificationRequired bool       `json:"verification_required" db:"verification_required"` // Payments must be confirmed by other members
+	VerificationsNeeded  int        `json:"verifications_needed" db:"verifications_needed"`
+	JoinApprovalRequired bool       `json:"join_approval_required" db:"join_approval_required"` // Joining asks an admin first
+	ArchivedAt           *time.Time `json:"archived_at" db:"archived_at"`                       // Read-only while set
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
 }
 type DashboardJar struct {
 	*TipJar
@@ -32,7 +67,7 @@ type JarMembership struct {
 	ID       int       `json:"id" db:"id"`
 	JarID    int       `json:"jar_id" db:"jar_id"`
 	UserID   int       `json:"user_id" db:"user_id"`
-	Role     string    `json:"role" db:"role"` // 'admin' or 'member'
+	Role     string    `json:"role" db:"role"` // One of authz.Roles
 	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
 }
 type JarMemberInfo struct {
@@ -46,92 +81,329 @@ type JarMemberInfo struct {
 }
 
 type OffenseType struct {
-	ID          int       `json:"id" db:"id"`
-	JarID       int       `json:"jar_id" db:"jar_id"`
-	Name        string    `json:"name" db:"name"`
-	Description *string   `json:"description" db:"description"`
-	CostAmount  *float64  `json:"cost_amount" db:"cost_amount"` 
-	CostUnit    *string   `json:"cost_unit" db:"cost_unit"` 
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int           `json:"id" db:"id"`
+	JarID       int           `json:"jar_id" db:"jar_id"`
+	Name        string        `json:"name" db:"name"`
+	Description *string       `json:"description" db:"description"`
+	CostAmount  *money.Amount `json:"cost_amount" db:"cost_amount"`
+	CostUnit    *string       `json:"cost_unit" db:"cost_unit"`
+	IsActive    bool          `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type OffenseTypePrice struct {
+	ID              int           `json:"id" db:"id"`
+	OffenseTypeID   int           `json:"offense_type_id" db:"offense_type_id"`
+	OffenseTypeName string        `json:"offense_type_name" db:"offense_type_name"`
+	CostAmount      *money.Amount `json:"cost_amount" db:"cost_amount"`
+	CostUnit        *string       `json:"cost_unit" db:"cost_unit"`
+	ChangedByName   *string       `json:"changed_by_name" db:"changed_by_name"`
+	EffectiveFrom   time.Time     `json:"effective_from" db:"effective_from"`
 }
 
 type Offense struct {
-	ID            int       `json:"id" db:"id"`
-	JarID         int       `json:"jar_id" db:"jar_id"`
-	OffenseTypeID int       `json:"offense_type_id" db:"offense_type_id"`
-	ReporterID    int       `json:"reporter_id" db:"reporter_id"`
-	OffenderID    int       `json:"offender_id" db:"offender_id"`
-	Notes         *string   `json:"notes" db:"notes"`
-	CostOverride  *float64  `json:"cost_override" db:"cost_override"`
-	Status        string    `json:"status" db:"status"` // 'pending', 'paid', 'disputed', 'forgiven'
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID            int           `json:"id" db:"id"`
+	JarID         int           `json:"jar_id" db:"jar_id"`
+	OffenseTypeID int           `json:"offense_type_id" db:"offense_type_id"`
+	ReporterID    int           `json:"reporter_id" db:"reporter_id"`
+	OffenderID    int           `json:"offender_id" db:"offender_id"`
+	Notes         *string       `json:"notes" db:"notes"`
+	CostOverride  *money.Amount `json:"cost_override" db:"cost_override"`
+	CostAmount    *money.Amount `json:"cost_amount" db:"cost_amount"` // Snapshot of the cost when reported
+	CostUnit      *string       `json:"cost_unit" db:"cost_unit"`
+	Status        string        `json:"status" db:"status"` // 'pending', 'paid', 'disputed', 'forgiven', 'verifying'
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
 }
 
 type Payment struct {
-	ID         int       `json:"id" db:"id"`
-	OffenseID  int       `json:"offense_id" db:"offense_id"`
-	UserID     int       `json:"user_id" db:"user_id"`
-	Amount     *float64  `json:"amount" db:"amount"`
-	ProofType  *string   `json:"proof_type" db:"proof_type"` // 'image', 'receipt', 'video'
-	ProofURL   *string   `json:"proof_url" db:"proof_url"`
-	Verified   bool      `json:"verified" db:"verified"`
-	VerifiedBy *int      `json:"verified_by" db:"verified_by"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID          int                 `json:"id" db:"id"`
+	OffenseID   *int                `json:"offense_id" db:"offense_id"` // Only set when the payment covers a single offense
+	UserID      int                 `json:"user_id" db:"user_id"`
+	Amount      *money.Amount       `json:"amount" db:"amount"`
+	ProofType   *string             `json:"proof_type" db:"proof_type"` // 'image', 'receipt', 'video'
+	ProofURL    *string             `json:"proof_url" db:"proof_url"`
+	Verified    bool                `json:"verified" db:"verified"`
+	VerifiedBy  *int                `json:"verified_by" db:"verified_by"`
+	Rejected    bool                `json:"rejected" db:"rejected"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" db:"updated_at"`
+	Allocations []PaymentAllocation `json:"allocations"`
+}
+
+type PaymentAllocation struct {
+	PaymentID       int           `json:"payment_id" db:"payment_id"`
+	OffenseID       int           `json:"offense_id" db:"offense_id"`
+	OffenseTypeName string        `json:"offense_type_name"`
+	Amount          *money.Amount `json:"amount" db:"amount"`
+	Unit            *string       `json:"unit"`
+}
+
+type Dispute struct {
+	ID              int           `json:"id" db:"id"`
+	OffenseID       int           `json:"offense_id" db:"offense_id"`
+	JarID           int           `json:"jar_id" db:"jar_id"`
+	OpenedBy        int           `json:"opened_by" db:"opened_by"`
+	Reason          string        `json:"reason" db:"reason"`
+	Status          string        `json:"status" db:"status"` // 'open', 'upheld', 'reduced', 'dismissed'
+	ResolvedAmount  *money.Amount `json:"resolved_amount" db:"resolved_amount"`
+	Deadline        time.Time     `json:"deadline" db:"deadline"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	ResolvedAt      *time.Time    `json:"resolved_at" db:"resolved_at"`
+	OffenseTypeName string        `json:"offense_type_name"`
+	OffenderID      int           `json:"offender_id"`
+	OffenderName    string        `json:"offender_name"`
+	CostAmount      *money.Amount `json:"cost_amount"`
+	CostUnit        *string       `json:"cost_unit"`
+	Votes           []DisputeVote `json:"votes"`
+}
+
+type DisputeVote struct {
+	ID            int           `json:"id" db:"id"`
+	DisputeID     int           `json:"dispute_id" db:"dispute_id"`
+	UserID        int           `json:"user_id" db:"user_id"`
+	UserName      string        `json:"user_name"`
+	Vote          string        `json:"vote" db:"vote"` // 'uphold', 'reduce', 'dismiss'
+	ReducedAmount *money.Amount `json:"reduced_amount" db:"reduced_amount"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
+
+type JarEvent struct {
+	ID        int       `json:"id" db:"id"`
+	JarID     int       `json:"jar_id" db:"jar_id"`
+	ActorID   *int      `json:"actor_id" db:"actor_id"`
+	ActorName *string   `json:"actor_name"`
+	OffenseID *int      `json:"offense_id" db:"offense_id"`
+	EventType string    `json:"event_type" db:"event_type"`
+	Message   string    `json:"message" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type PendingVerification struct {
+	PaymentID    int                 `json:"payment_id"`
+	JarID        int                 `json:"jar_id"`
+	JarName      string              `json:"jar_name"`
+	OffenderID   int                 `json:"offender_id"`
+	OffenderName string              `json:"offender_name"`
+	Allocations  []PaymentAllocation `json:"allocations"`
+	ProofURL     *string             `json:"proof_url"`
+	MyVerdict    *bool               `json:"my_verdict"` // nil until the current user has weighed in
+	CreatedAt    time.Time           `json:"created_at"`
 }
+
 type JarActivity struct {
-	ID              int       `json:"id"`
-	OffenseTypeName string    `json:"offense_type_name"`
-	ReporterID      int       `json:"reporter_id"` 
-	ReporterName    string    `json:"reporter_name"`
-	ReporterAvatar  *string   `json:"reporter_avatar"`
-	OffenderID      int       `json:"offender_id"` 
-	OffenderName    string    `json:"offender_name"`
-	OffenderAvatar  *string   `json:"offender_avatar"`
-	Notes           *string   `json:"notes"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID               int           `json:"id"`
+	OffenseTypeName  string        `json:"offense_type_name"`
+	ReporterID       int           `json:"reporter_id"`
+	ReporterName     string        `json:"reporter_name"`
+	ReporterAvatar   *string       `json:"reporter_avatar"`
+	OffenderID       int           `json:"offender_id"`
+	OffenderName     string        `json:"offender_name"`
+	OffenderAvatar   *string       `json:"offender_avatar"`
+	Notes            *string       `json:"notes"`
+	CostAmount       *money.Amount `json:"cost_amount"`
+	CostUnit         *string       `json:"cost_unit"`
+	Status           string        `json:"status"`
+	PeriodID         int           `json:"period_id"`          // Period it counts towards
+	ReportedPeriodID int           `json:"reported_period_id"` // Period it was reported in; differs once carried over
+	CreatedAt        time.Time     `json:"created_at"`
 }
 
 type MemberBalance struct {
-	UserID         int     `json:"user_id"`
-	Name           string  `json:"name"`
-	Avatar         *string `json:"avatar"`
-	TotalOwed      float64 `json:"total_owed"`
-	PendingCount   int     `json:"pending_count"`
+	UserID       int          `json:"user_id"`
+	Name         string       `json:"name"`
+	Avatar       *string      `json:"avatar"`
+	TotalOwed    money.Amount `json:"total_owed"`
+	PendingCount int          `json:"pending_count"`
 }
 
 type MemberBalanceByUnit struct {
-	UserID       int     `json:"user_id"`
-	Name         string  `json:"name"`
-	Avatar       *string `json:"avatar"`
-	Unit         string  `json:"unit"`
-	TotalOwed    float64 `json:"total_owed"`
-	OffenseCount int     `json:"offense_count"`
+	UserID       int          `json:"user_id"`
+	Name         string       `json:"name"`
+	Avatar       *string      `json:"avatar"`
+	Unit         string       `json:"unit"`
+	TotalOwed    money.Amount `json:"total_owed"`
+	OffenseCount int          `json:"offense_count"`
 }
 
 type MemberBalanceSummary struct {
-	UserID        int                    `json:"user_id"`
-	Name          string                 `json:"name"`
-	Avatar        *string                `json:"avatar"`
-	Balances      []MemberBalanceByUnit  `json:"balances"`
-	TotalOffenses int                    `json:"total_offenses"`
+	UserID        int                   `json:"user_id"`
+	Name          string                `json:"name"`
+	Avatar        *string               `json:"avatar"`
+	Balances      []MemberBalanceByUnit `json:"balances"`
+	TotalOffenses int                   `json:"total_offenses"`
+}
+
+// JarPeriod is a stretch of a jar's accounting, e.g. a quarter. New offenses
+// count towards the open period; closed ones are read-only.
+type JarPeriod struct {
+	ID           int        `json:"id" db:"id"`
+	JarID        int        `json:"jar_id" db:"jar_id"`
+	Name         string     `json:"name" db:"name"`
+	StartedAt    time.Time  `json:"started_at" db:"started_at"`
+	ClosedAt     *time.Time `json:"closed_at" db:"closed_at"` // nil while open
+	ClosedBy     *int       `json:"closed_by" db:"closed_by"`
+	ClosedByName *string    `json:"closed_by_name" db:"closed_by_name"`
+	CarriedOver  bool       `json:"carried_over" db:"carried_over"` // Unpaid offenses moved to the next period
+}
+
+// PeriodStanding is what a member owed in one unit when a period closed
+type PeriodStanding struct {
+	UserID       int          `json:"user_id"`
+	Name         string       `json:"name"`
+	Avatar       *string      `json:"avatar"`
+	Unit         string       `json:"unit"`
+	OffenseCount int          `json:"offense_count"`
+	Total        money.Amount `json:"total"`
+	Paid         money.Amount `json:"paid"`
+	Unpaid       money.Amount `json:"unpaid"`
+}
+
+type PeriodStandingSummary struct {
+	UserID        int              `json:"user_id"`
+	Name          string           `json:"name"`
+	Avatar        *string          `json:"avatar"`
+	Standings     []PeriodStanding `json:"standings"`
+	TotalOffenses int              `json:"total_offenses"`
+}
+
+// JarRules limits reporting offenses in a jar. A nil field means that rule is
+// off.
+type JarRules struct {
+	JarID                 int           `json:"jar_id"`
+	MaxOffensesPerDay     *int          `json:"max_offenses_per_day"`    // Per member, in any 24 hours
+	ReportCooldownMinutes *int          `json:"report_cooldown_minutes"` // Before a reporter can report the same member again
+	EscalationWindowDays  *int          `json:"escalation_window_days"`  // How far back repeat offenses count
+	EscalationMultiplier  *money.Amount `json:"escalation_multiplier"`   // Applied once per repeat of the same type
+	UpdatedBy             *int          `json:"updated_by"`
+	UpdatedAt             *time.Time    `json:"updated_at"`
 }
 
 type OffenseDetail struct {
-	ID              int       `json:"id"`
-	JarID           int       `json:"jar_id"` // Add this line
-	OffenseTypeName string    `json:"offense_type_name"`
-	ReporterID      int       `json:"reporter_id"`
-	ReporterName    string    `json:"reporter_name"`
-	OffenderID      int       `json:"offender_id"`
-	OffenderName    string    `json:"offender_name"`
-	Notes           *string   `json:"notes"`
-	Amount          float64   `json:"amount"`
-	Unit            string    `json:"unit"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID              int          `json:"id"`
+	JarID           int          `json:"jar_id"` // Add this line
+	OffenseTypeName string       `json:"offense_type_name"`
+	ReporterID      int          `json:"reporter_id"`
+	ReporterName    string       `json:"reporter_name"`
+	OffenderID      int          `json:"offender_id"`
+	OffenderName    string       `json:"offender_name"`
+	Notes           *string      `json:"notes"`
+	Amount          money.Amount `json:"amount"`
+	Paid            money.Amount `json:"paid"`
+	Remaining       money.Amount `json:"remaining"` // Amount minus payments that weren't rejected
+	Unit            string       `json:"unit"`
+	Status          string       `json:"status"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+type Invite struct {
+	ID          int                `json:"id" db:"id"`
+	JarID       int                `json:"jar_id" db:"jar_id"`
+	Code        string             `json:"code" db:"code"`
+	Label       *string            `json:"label" db:"label"`
+	Role        string             `json:"role" db:"role"`         // Role people joining with it get
+	MaxUses     *int               `json:"max_uses" db:"max_uses"` // nil for unlimited
+	Uses        int                `json:"uses" db:"uses"`
+	ExpiresAt   *time.Time         `json:"expires_at" db:"expires_at"`
+	RevokedAt   *time.Time         `json:"revoked_at" db:"revoked_at"`
+	CreatedBy   int                `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+	Status      string             `json:"status"` // 'active', or 'revoked', 'expired' or 'used up'
+	Redemptions []InviteRedemption `json:"redemptions,omitempty"`
+}
+
+type InviteRedemption struct {
+	InviteID  int       `json:"invite_id" db:"invite_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	UserName  string    `json:"user_name" db:"user_name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type Webhook struct {
+	ID         int       `json:"id" db:"id"`
+	JarID      int       `json:"jar_id" db:"jar_id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"-" db:"secret"` // Signs deliveries so receivers can check they came from us
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedBy  int       `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int        `json:"id" db:"id"`
+	WebhookID      int        `json:"webhook_id" db:"webhook_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Payload        string     `json:"payload" db:"payload"`
+	Status         string     `json:"status" db:"status"` // 'pending', 'delivered', 'failed'
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code" db:"last_status_code"`
+	LastError      *string    `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+}
+
+// ChatAccount links a Slack or Discord user to a tip jar user so they can use
+// slash commands
+type ChatAccount struct {
+	ID             int       `json:"id" db:"id"`
+	Provider       string    `json:"provider" db:"provider"` // 'slack', 'discord'
+	WorkspaceID    string    `json:"workspace_id" db:"workspace_id"`
+	ExternalUserID string    `json:"external_user_id" db:"external_user_id"`
+	ExternalName   string    `json:"external_name" db:"external_name"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+type ChatLinkRequest struct {
+	ID             int       `json:"id" db:"id"`
+	Provider       string    `json:"provider" db:"provider"`
+	WorkspaceID    string    `json:"workspace_id" db:"workspace_id"`
+	ExternalUserID string    `json:"external_user_id" db:"external_user_id"`
+	ExternalName   string    `json:"external_name" db:"external_name"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+type Notification struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	JarID     int        `json:"jar_id" db:"jar_id"`
+	JarName   string     `json:"jar_name" db:"jar_name"`
+	EventType string     `json:"event_type" db:"event_type"`
+	Message   string     `json:"message" db:"message"`
+	Link      string     `json:"link" db:"link"`
+	ReadAt    *time.Time `json:"read_at" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NotificationPreference switches one kind of notification on or off for a
+// jar. Kinds without a preference are on.
+type NotificationPreference struct {
+	JarID     int    `json:"jar_id" db:"jar_id"`
+	EventType string `json:"event_type" db:"event_type"`
+	Enabled   bool   `json:"enabled" db:"enabled"`
+}
+
+// JoinRequest is someone waiting for an admin to let them into a jar that
+// needs approval
+type JoinRequest struct {
+	ID          int        `json:"id" db:"id"`
+	JarID       int        `json:"jar_id" db:"jar_id"`
+	JarName     string     `json:"jar_name" db:"jar_name"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	UserName    string     `json:"user_name" db:"user_name"`
+	UserEmail   string     `json:"user_email" db:"user_email"`
+	UserAvatar  string     `json:"user_avatar" db:"user_avatar"`
+	InviteID    int        `json:"invite_id" db:"invite_id"`
+	InviteCode  string     `json:"invite_code" db:"invite_code"`
+	InviteLabel *string    `json:"invite_label" db:"invite_label"`
+	Role        string     `json:"role" db:"role"`     // Role they'll get if approved
+	Status      string     `json:"status" db:"status"` // 'pending', 'approved' or 'rejected'
+	Message     *string    `json:"message" db:"message"`
+	ReviewedBy  *int       `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt  *time.Time `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,282 @@
+// Package money provides an exact fixed-point decimal used for offense costs,
+// payments and balances. Values are stored as hundredths so they map directly
+// onto the DECIMAL(10,2) columns in the database.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scale is the number of decimal places every Amount is rounded to.
+const Scale = 2
+
+const unit = 100 // 10^Scale
+
+// Max is the largest value that fits in a DECIMAL(10,2) column.
+var Max = Amount{cents: 99999999_99}
+
+var (
+	ErrInvalid    = errors.New("invalid amount")
+	ErrOutOfRange = errors.New("amount out of range")
+)
+
+// Amount is an exact decimal quantity with two fractional digits. The zero
+// value is 0.00.
+type Amount struct {
+	cents int64
+}
+
+// Zero is 0.00.
+var Zero = Amount{}
+
+// FromCents builds an Amount from a number of hundredths.
+func FromCents(cents int64) Amount {
+	return Amount{cents: cents}
+}
+
+// FromInt builds an Amount from a whole number.
+func FromInt(n int64) Amount {
+	return Amount{cents: n * unit}
+}
+
+// Parse reads a decimal string such as "5", "0.29" or "-1.005". Digits beyond
+// the second decimal place are rounded half away from zero.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Zero, ErrInvalid
+	}
+
+	if !isDecimal(s) {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	a, err := fromRat(r)
+	if err != nil {
+		return Zero, err
+	}
+	if abs(a.cents) > Max.cents {
+		return Zero, ErrOutOfRange
+	}
+	return a, nil
+}
+
+// FromNumeric converts a database numeric. The second return value is false
+// when the column was NULL.
+func FromNumeric(n pgtype.Numeric) (Amount, bool, error) {
+	if !n.Valid {
+		return Zero, false, nil
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return Zero, false, fmt.Errorf("%w: non-finite numeric", ErrInvalid)
+	}
+
+	r := new(big.Rat).SetInt(n.Int)
+	if n.Exp > 0 {
+		r.Mul(r, new(big.Rat).SetInt(pow10(int64(n.Exp))))
+	} else if n.Exp < 0 {
+		r.Quo(r, new(big.Rat).SetInt(pow10(int64(-n.Exp))))
+	}
+
+	a, err := fromRat(r)
+	if err != nil {
+		return Zero, false, err
+	}
+	return a, true, nil
+}
+
+// FromNumericPtr converts a nullable database numeric into an optional Amount.
+// Values that cannot be represented are treated as NULL.
+func FromNumericPtr(n pgtype.Numeric) *Amount {
+	a, ok, err := FromNumeric(n)
+	if err != nil || !ok {
+		return nil
+	}
+	return &a
+}
+
+// MustFromNumeric converts a database numeric, returning zero for NULL or
+// unrepresentable values.
+func MustFromNumeric(n pgtype.Numeric) Amount {
+	a, _, _ := FromNumeric(n)
+	return a
+}
+
+// Numeric converts the Amount into a database numeric.
+func (a Amount) Numeric() pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(a.cents), Exp: -Scale, Valid: true}
+}
+
+// NumericPtr converts an optional Amount into a nullable database numeric.
+func NumericPtr(a *Amount) pgtype.Numeric {
+	if a == nil {
+		return pgtype.Numeric{}
+	}
+	return a.Numeric()
+}
+
+// Cents returns the Amount as a number of hundredths.
+func (a Amount) Cents() int64 {
+	return a.cents
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{cents: a.cents + b.cents}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{cents: a.cents - b.cents}
+}
+
+// Mul multiplies two amounts, rounding the product half away from zero.
+func (a Amount) Mul(b Amount) Amount {
+	product := new(big.Int).Mul(big.NewInt(a.cents), big.NewInt(b.cents))
+	r := new(big.Rat).SetFrac(product, big.NewInt(unit*unit))
+	out, _ := fromRat(r)
+	return out
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or
+// greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.cents < b.cents:
+		return -1
+	case a.cents > b.cents:
+		return 1
+	}
+	return 0
+}
+
+func (a Amount) IsZero() bool {
+	return a.cents == 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a.cents < 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a.cents > 0
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b Amount) Amount {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// String formats the Amount with exactly two decimal places, e.g. "5.00".
+func (a Amount) String() string {
+	sign := ""
+	c := a.cents
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/unit, c%unit)
+}
+
+// Display formats the Amount for people: whole numbers drop the decimals
+// ("10 pushups") while fractional values keep both digits ("2.50 dollars").
+func (a Amount) Display() string {
+	if a.cents%unit == 0 {
+		return strconv.FormatInt(a.cents/unit, 10)
+	}
+	return a.String()
+}
+
+// MarshalJSON encodes the Amount as a JSON number with two decimal places.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts either a JSON number or a numeric string.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		s = str
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// fromRat rounds r half away from zero to Scale places.
+func fromRat(r *big.Rat) (Amount, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(unit, 1))
+
+	num := new(big.Int).Set(scaled.Num())
+	den := scaled.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Lsh(m, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+
+	if !q.IsInt64() {
+		return Zero, ErrOutOfRange
+	}
+	return Amount{cents: q.Int64()}, nil
+}
+
+// isDecimal reports whether s is a plain decimal literal: an optional sign,
+// digits and at most one decimal point.
+func isDecimal(s string) bool {
+	if s[0] == '-' || s[0] == '+' {
+		s = s[1:]
+	}
+	digits, dots := 0, 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '.':
+			dots++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && dots <= 1
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,238 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		cents int64
+		err   error
+	}{
+		{"5", 500, nil},
+		{"0.29", 29, nil},
+		{" 2.5 ", 250, nil},
+		{"+3", 300, nil},
+		{"-4.10", -410, nil},
+		{".5", 50, nil},
+		{"7.", 700, nil},
+		{"1.005", 101, nil},
+		{"-1.005", -101, nil},
+		{"1.004", 100, nil},
+		{"0.0049", 0, nil},
+		{"99999999.99", 99999999_99, nil},
+		{"-99999999.99", -99999999_99, nil},
+		{"100000000", 0, ErrOutOfRange},
+		{"-100000000", 0, ErrOutOfRange},
+		{"99999999.995", 0, ErrOutOfRange},
+		{"", 0, ErrInvalid},
+		{"   ", 0, ErrInvalid},
+		{"abc", 0, ErrInvalid},
+		{"-", 0, ErrInvalid},
+		{".", 0, ErrInvalid},
+		{"1.2.3", 0, ErrInvalid},
+		{"1e5", 0, ErrInvalid},
+		{"0x10", 0, ErrInvalid},
+		{"1/3", 0, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			}
+			if err == nil && got.Cents() != tt.cents {
+				t.Errorf("Parse(%q) = %d cents, want %d", tt.in, got.Cents(), tt.cents)
+			}
+		})
+	}
+}
+
+func TestFromNumeric(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    pgtype.Numeric
+		cents int64
+		ok    bool
+		err   error
+	}{
+		{"null", pgtype.Numeric{}, 0, false, nil},
+		{"two places", pgtype.Numeric{Int: big.NewInt(29), Exp: -2, Valid: true}, 29, true, nil},
+		{"no exponent", pgtype.Numeric{Int: big.NewInt(7), Valid: true}, 700, true, nil},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(5), Exp: 2, Valid: true}, 50000, true, nil},
+		{"extra places round up", pgtype.Numeric{Int: big.NewInt(1005), Exp: -3, Valid: true}, 101, true, nil},
+		{"negative rounds away from zero", pgtype.Numeric{Int: big.NewInt(-1005), Exp: -3, Valid: true}, -101, true, nil},
+		{"extra places round down", pgtype.Numeric{Int: big.NewInt(12344), Exp: -4, Valid: true}, 123, true, nil},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, 0, false, ErrInvalid},
+		{"infinity", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, 0, false, ErrInvalid},
+		{"too large", pgtype.Numeric{Int: big.NewInt(1), Exp: 30, Valid: true}, 0, false, ErrOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := FromNumeric(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got.Cents() != tt.cents {
+				t.Errorf("got %d cents, want %d", got.Cents(), tt.cents)
+			}
+		})
+	}
+}
+
+func TestNumericRoundTrip(t *testing.T) {
+	for _, cents := range []int64{0, 1, -1, 29, 250, -410, 99999999_99} {
+		a := FromCents(cents)
+		got, ok, err := FromNumeric(a.Numeric())
+		if err != nil || !ok || got != a {
+			t.Errorf("FromNumeric(%s.Numeric()) = %s, %v, %v", a, got, ok, err)
+		}
+	}
+
+	if NumericPtr(nil).Valid {
+		t.Error("NumericPtr(nil) should be NULL")
+	}
+	if FromNumericPtr(pgtype.Numeric{}) != nil {
+		t.Error("FromNumericPtr(NULL) should be nil")
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"0.29", "3", "0.87"},
+		{"10", "1.5", "15.00"},
+		{"1.50", "1.25", "1.88"},
+		{"-1.50", "1.25", "-1.88"},
+		{"0.01", "0.01", "0.00"},
+		{"0.07", "0.07", "0.00"},
+		{"0.10", "0.05", "0.01"},
+		{"5", "0", "0.00"},
+		{"-2", "-2", "4.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"*"+tt.b, func(t *testing.T) {
+			got := mustParse(t, tt.a).Mul(mustParse(t, tt.b))
+			if got.String() != tt.want {
+				t.Errorf("%s * %s = %s, want %s", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	tests := []struct {
+		cents   int64
+		str     string
+		display string
+	}{
+		{0, "0.00", "0"},
+		{500, "5.00", "5"},
+		{250, "2.50", "2.50"},
+		{5, "0.05", "0.05"},
+		{-5, "-0.05", "-0.05"},
+		{-1000, "-10.00", "-10"},
+	}
+
+	for _, tt := range tests {
+		a := FromCents(tt.cents)
+		if a.String() != tt.str {
+			t.Errorf("String(%d) = %q, want %q", tt.cents, a.String(), tt.str)
+		}
+		if a.Display() != tt.display {
+			t.Errorf("Display(%d) = %q, want %q", tt.cents, a.Display(), tt.display)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type payload struct {
+		Cost Amount  `json:"cost"`
+		Opt  *Amount `json:"opt,omitempty"`
+	}
+
+	tests := []struct {
+		name  string
+		in    string
+		cost  int64
+		opt   *int64
+		out   string
+		error bool
+	}{
+		{name: "number", in: `{"cost":2.5}`, cost: 250, out: `{"cost":2.50}`},
+		{name: "string", in: `{"cost":"0.29"}`, cost: 29, out: `{"cost":0.29}`},
+		{name: "rounds", in: `{"cost":1.005}`, cost: 101, out: `{"cost":1.01}`},
+		{name: "negative", in: `{"cost":-1.005}`, cost: -101, out: `{"cost":-1.01}`},
+		{name: "null", in: `{"cost":null}`, cost: 0, out: `{"cost":0.00}`},
+		{name: "optional", in: `{"cost":1,"opt":"3"}`, cost: 100, opt: ptr(int64(300)), out: `{"cost":1.00,"opt":3.00}`},
+		{name: "out of range", in: `{"cost":100000000}`, error: true},
+		{name: "garbage string", in: `{"cost":"ten"}`, error: true},
+		{name: "exponent", in: `{"cost":1e2}`, error: true},
+		{name: "bool", in: `{"cost":true}`, error: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p payload
+			err := json.Unmarshal([]byte(tt.in), &p)
+			if tt.error {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) succeeded with %s", tt.in, p.Cost)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+			}
+			if p.Cost.Cents() != tt.cost {
+				t.Errorf("cost = %d cents, want %d", p.Cost.Cents(), tt.cost)
+			}
+			if (p.Opt == nil) != (tt.opt == nil) || (p.Opt != nil && p.Opt.Cents() != *tt.opt) {
+				t.Errorf("opt = %v, want %v", p.Opt, tt.opt)
+			}
+
+			out, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("Marshal = %s, want %s", out, tt.out)
+			}
+
+			var back payload
+			if err := json.Unmarshal(out, &back); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", out, err)
+			}
+			if back.Cost != p.Cost {
+				t.Errorf("round trip = %s, want %s", back.Cost, p.Cost)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, s string) Amount {
+	t.Helper()
+	a, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return a
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
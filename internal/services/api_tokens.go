@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// apiTokenPrefix marks tipjar tokens so they're easy to spot in leaked configs
+const apiTokenPrefix = "tj_"
+
+var (
+	ErrInvalidAPIToken  = errors.New("invalid or revoked API token")
+	ErrAPITokenNotFound = errors.New("API token not found")
+	ErrInvalidScope     = errors.New("scope must be read, report or full")
+)
+
+// APITokenScopes lists the scopes a token can have, narrowest first
+var APITokenScopes = []string{"read", "report", "full"}
+
+type APITokenService struct {
+	db     *database.DB
+	secret []byte
+}
+
+func NewAPITokenService(db *database.DB, secret string) *APITokenService {
+	return &APITokenService{
+		db:     db,
+		secret: []byte(secret),
+	}
+}
+
+// CreateToken issues a personal access token and returns it in full. Only a
+// keyed hash is stored, so this is the only time it can be shown.
+func (s *APITokenService) CreateToken(ctx context.Context, userID int, name, scope string) (string, *models.APIToken, error) {
+	if !validScope(scope) {
+		return "", nil, ErrInvalidScope
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	row, err := s.db.CreateApiToken(ctx, sqlc.CreateApiTokenParams{
+		UserID:      int32(userID),
+		Name:        name,
+		TokenPrefix: token[:len(apiTokenPrefix)+6],
+		TokenHash:   s.hash(token),
+		Scope:       scope,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, s.sqlcAPITokenToModel(row), nil
+}
+
+// Authenticate looks up an unrevoked token and records it as used
+func (s *APITokenService) Authenticate(ctx context.Context, token string) (*models.APIToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	row, err := s.db.GetApiTokenByHash(ctx, s.hash(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	if err := s.db.TouchApiToken(ctx, row.ID); err != nil {
+		return nil, err
+	}
+
+	return s.sqlcAPITokenToModel(row), nil
+}
+
+func (s *APITokenService) ListTokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	rows, err := s.db.ListApiTokensForUser(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]models.APIToken, len(rows))
+	for i, row := range rows {
+		tokens[i] = *s.sqlcAPITokenToModel(row)
+	}
+
+	return tokens, nil
+}
+
+// RevokeToken stops one of the user's tokens from working
+func (s *APITokenService) RevokeToken(ctx context.Context, userID, tokenID int) error {
+	revoked, err := s.db.RevokeApiToken(ctx, sqlc.RevokeApiTokenParams{
+		ID:     int32(tokenID),
+		UserID: int32(userID),
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func (s *APITokenService) sqlcAPITokenToModel(token sqlc.ApiToken) *models.APIToken {
+	var lastUsedAt *time.Time
+	if token.LastUsedAt.Valid {
+		lastUsedAt = &token.LastUsedAt.Time
+	}
+
+	return &models.APIToken{
+		ID:         int(token.ID),
+		UserID:     int(token.UserID),
+		Name:       token.Name,
+		Prefix:     token.TokenPrefix,
+		Scope:      token.Scope,
+		CreatedAt:  token.CreatedAt.Time,
+		LastUsedAt: lastUsedAt,
+	}
+}
+
+func (s *APITokenService) hash(token string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(token))
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func validScope(scope string) bool {
+	for _, s := range APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,541 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// chatLinkTTL is how long a "/tipjar link" URL stays valid
+const chatLinkTTL = 15 * time.Minute
+
+var ErrChatLinkNotFound = errors.New("this link has expired or has already been used")
+
+// ChatCommand is a slash command from Slack or Discord, already parsed from
+// the provider's payload
+type ChatCommand struct {
+	Provider    string // 'slack', 'discord'
+	WorkspaceID string // Slack team ID; empty for Discord, whose IDs are global
+	ChannelID   string
+	UserID      string
+	UserName    string
+	Action      string // 'report', 'balance', 'connect', 'link', 'unlink', 'help'
+	Target      string // Chat user ID of the offender for report, or whose balance to show
+	Text        string // Offense type for report, invite code for connect
+	Notes       string
+}
+
+const chatUsage = "Usage:\n" +
+	"• `/tipjar report @someone <offense type> [notes]` reports an offense in this channel's jar\n" +
+	"• `/tipjar balance [@someone]` shows what's owed\n" +
+	"• `/tipjar connect <invite code>` reports this channel's offenses to a jar (jar admins)\n" +
+	"• `/tipjar link` links your chat account to Tip Jar, `/tipjar unlink` undoes it"
+
+type ChatService struct {
+	db       *database.DB
+	offenses *OffenseService
+	tipJars  *TipJarService
+	invites  *InviteService
+	baseURL  string
+}
+
+func NewChatService(db *database.DB, offenses *OffenseService, tipJars *TipJarService, invites *InviteService, baseURL string) *ChatService {
+	return &ChatService{
+		db:       db,
+		offenses: offenses,
+		tipJars:  tipJars,
+		invites:  invites,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Run carries out a command and returns the reply to show the user who sent
+// it. Mistakes on their part are explained in the reply; only unexpected
+// failures are returned as errors.
+func (s *ChatService) Run(ctx context.Context, cmd ChatCommand) (string, error) {
+	switch cmd.Action {
+	case "link":
+		return s.startLink(ctx, cmd)
+	case "unlink":
+		return s.unlink(ctx, cmd)
+	case "report", "balance", "connect":
+	default:
+		return chatUsage, nil
+	}
+
+	userID, err := s.linkedUserID(ctx, cmd.Provider, cmd.WorkspaceID, cmd.UserID)
+	if err != nil {
+		return "", err
+	}
+	if userID == 0 {
+		return "Your chat account isn't linked to Tip Jar yet. Run `/tipjar link` to link it.", nil
+	}
+
+	switch cmd.Action {
+	case "connect":
+		return s.connect(ctx, cmd, userID)
+	case "balance":
+		return s.balance(ctx, cmd, userID)
+	default:
+		return s.report(ctx, cmd, userID)
+	}
+}
+
+func (s *ChatService) report(ctx context.Context, cmd ChatCommand, reporterID int) (string, error) {
+	jar, reply, err := s.channelJar(ctx, cmd, reporterID, authz.ReportOffense)
+	if jar == nil {
+		return reply, err
+	}
+
+	if cmd.Target == "" {
+		return "Say who to report, e.g. `/tipjar report @someone <offense type>`.", nil
+	}
+
+	offenderID, err := s.linkedUserID(ctx, cmd.Provider, cmd.WorkspaceID, cmd.Target)
+	if err != nil {
+		return "", err
+	}
+	if offenderID == 0 {
+		return fmt.Sprintf("<@%s> hasn't linked their chat account to Tip Jar yet. They can run `/tipjar link`.", cmd.Target), nil
+	}
+
+	if offenderID == reporterID {
+		return "You can't report yourself.", nil
+	}
+
+	isMember, err := s.tipJars.IsUserJarMember(ctx, jar.ID, offenderID)
+	if err != nil {
+		return "", err
+	}
+	if !isMember {
+		return fmt.Sprintf("<@%s> isn't a member of %s.", cmd.Target, jar.Name), nil
+	}
+
+	offenseTypes, err := s.offenses.GetOffenseTypesForJar(ctx, jar.ID)
+	if err != nil {
+		return "", err
+	}
+
+	offenseType, notes := matchOffenseType(offenseTypes, cmd.Text)
+	if offenseType == nil {
+		names := make([]string, len(offenseTypes))
+		for i, t := range offenseTypes {
+			names[i] = t.Name
+		}
+		return fmt.Sprintf("I don't know that offense. %s has: %s.", jar.Name, strings.Join(names, ", ")), nil
+	}
+
+	notes = strings.TrimSpace(strings.Join([]string{notes, cmd.Notes}, " "))
+
+	offense, err := s.offenses.CreateOffense(ctx, jar.ID, offenseType.ID, reporterID, offenderID, notes, nil)
+	if errors.Is(err, ErrOffenseLimitReached) || errors.Is(err, ErrReportCooldown) {
+		return fmt.Sprintf("%s won't take that report: %s.", jar.Name, err), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	offender, err := s.db.GetUserByID(ctx, int32(offenderID))
+	if err != nil {
+		return "", err
+	}
+
+	reply = fmt.Sprintf("Reported %s for %s", offender.Name, offenseType.Name)
+	if offense.CostAmount != nil {
+		reply += " (" + formatCost(*offense.CostAmount, textOrNull(offense.CostUnit)) + ")"
+	}
+
+	owed, err := s.owedText(ctx, jar.ID, offenderID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s in %s. %s now owes %s.", reply, jar.Name, offender.Name, owed), nil
+}
+
+func (s *ChatService) balance(ctx context.Context, cmd ChatCommand, userID int) (string, error) {
+	jar, reply, err := s.channelJar(ctx, cmd, userID, authz.ViewJar)
+	if jar == nil {
+		return reply, err
+	}
+
+	memberID, who := userID, "You owe"
+	if cmd.Target != "" && cmd.Target != cmd.UserID {
+		memberID, err = s.linkedUserID(ctx, cmd.Provider, cmd.WorkspaceID, cmd.Target)
+		if err != nil {
+			return "", err
+		}
+		if memberID == 0 {
+			return fmt.Sprintf("<@%s> hasn't linked their chat account to Tip Jar yet.", cmd.Target), nil
+		}
+
+		member, err := s.db.GetUserByID(ctx, int32(memberID))
+		if err != nil {
+			return "", err
+		}
+		who = member.Name + " owes"
+	}
+
+	owed, err := s.owedText(ctx, jar.ID, memberID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s in %s.", who, owed, jar.Name), nil
+}
+
+// connect points the channel at a jar. Only members who can manage the jar
+// can do this, since it lets anyone linked in the channel report offenses to
+// it.
+func (s *ChatService) connect(ctx context.Context, cmd ChatCommand, userID int) (string, error) {
+	inviteCode := strings.TrimSpace(cmd.Text)
+	if inviteCode == "" {
+		return "Give the jar's invite code, e.g. `/tipjar connect 7KQ2-M9XD`.", nil
+	}
+
+	_, jar, err := s.invites.LookupInvite(ctx, inviteCode)
+	switch err {
+	case nil:
+	case ErrInviteNotFound:
+		return "No jar has that invite code.", nil
+	case ErrInviteCodeTypo:
+		return "That isn't a valid invite code. Check it for typos.", nil
+	case ErrInviteRevoked, ErrInviteExpired, ErrInviteUsedUp:
+		return "That invite code no longer works. Ask for a current one.", nil
+	case ErrJarArchived:
+		return "That jar is archived. Restore it before connecting a channel.", nil
+	default:
+		return "", err
+	}
+
+	role, err := s.tipJars.GetMemberRole(ctx, jar.ID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !authz.Can(role, authz.ManageJar) {
+		return fmt.Sprintf("Only admins of %s can connect it to a channel.", jar.Name), nil
+	}
+
+	_, err = s.db.UpsertChatChannel(ctx, sqlc.UpsertChatChannelParams{
+		Provider:    cmd.Provider,
+		WorkspaceID: cmd.WorkspaceID,
+		ChannelID:   cmd.ChannelID,
+		JarID:       int32(jar.ID),
+		ConnectedBy: int32(userID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Offenses reported in this channel now go to %s.", jar.Name), nil
+}
+
+// channelJar returns the jar the command's channel is connected to. If there
+// isn't one, or the user isn't a member of it with the given permission, it
+// returns the reply to send instead.
+func (s *ChatService) channelJar(ctx context.Context, cmd ChatCommand, userID int, permission authz.Permission) (*models.TipJar, string, error) {
+	channel, err := s.db.GetChatChannel(ctx, sqlc.GetChatChannelParams{
+		Provider:    cmd.Provider,
+		WorkspaceID: cmd.WorkspaceID,
+		ChannelID:   cmd.ChannelID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "This channel isn't connected to a jar. A jar admin can run `/tipjar connect <invite code>`.", nil
+		}
+		return nil, "", err
+	}
+
+	jar, err := s.tipJars.GetTipJar(ctx, int(channel.JarID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	role, err := s.tipJars.GetMemberRole(ctx, jar.ID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, fmt.Sprintf("You're not a member of %s.", jar.Name), nil
+	}
+	if !authz.Can(role, permission) {
+		return nil, fmt.Sprintf("You can't do that in %s as a %s.", jar.Name, role), nil
+	}
+	if jar.ArchivedAt != nil && !authz.AllowedWhenArchived(permission) {
+		return nil, fmt.Sprintf("%s is archived, so nothing can be changed in it.", jar.Name), nil
+	}
+
+	return jar, "", nil
+}
+
+// owedText describes what a member still owes in a jar, e.g.
+// "12.50 USD and 3 beers across 4 offenses"
+func (s *ChatService) owedText(ctx context.Context, jarID, userID int) (string, error) {
+	summaries, err := s.tipJars.GetMemberBalancesByUnit(ctx, jarID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, summary := range summaries {
+		if summary.UserID != userID || summary.TotalOffenses == 0 {
+			continue
+		}
+
+		amounts := make([]string, 0, len(summary.Balances))
+		for _, balance := range summary.Balances {
+			amount := balance.TotalOwed.Display()
+			if balance.Unit != "" {
+				amount += " " + balance.Unit
+			}
+			amounts = append(amounts, amount)
+		}
+
+		offenses := "offenses"
+		if summary.TotalOffenses == 1 {
+			offenses = "offense"
+		}
+
+		return fmt.Sprintf("%s across %d %s", strings.Join(amounts, " and "), summary.TotalOffenses, offenses), nil
+	}
+
+	return "nothing", nil
+}
+
+// linkedUserID returns the tip jar user linked to a chat user, or 0 if there
+// isn't one. Discord users who signed in with Discord are linked already.
+func (s *ChatService) linkedUserID(ctx context.Context, provider, workspaceID, externalUserID string) (int, error) {
+	account, err := s.db.GetChatAccount(ctx, sqlc.GetChatAccountParams{
+		Provider:       provider,
+		WorkspaceID:    workspaceID,
+		ExternalUserID: externalUserID,
+	})
+	if err == nil {
+		return int(account.UserID), nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	if provider != "discord" {
+		return 0, nil
+	}
+
+	user, err := s.db.GetUserByIdentity(ctx, sqlc.GetUserByIdentityParams{
+		Provider: "discord",
+		Subject:  externalUserID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return int(user.ID), nil
+}
+
+// startLink hands out a one-time URL that links the chat user to whoever
+// opens it while signed in
+func (s *ChatService) startLink(ctx context.Context, cmd ChatCommand) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	err = qtx.DeleteChatLinkRequests(ctx, sqlc.DeleteChatLinkRequestsParams{
+		Provider:       cmd.Provider,
+		WorkspaceID:    cmd.WorkspaceID,
+		ExternalUserID: cmd.UserID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = qtx.CreateChatLinkRequest(ctx, sqlc.CreateChatLinkRequestParams{
+		Provider:       cmd.Provider,
+		WorkspaceID:    cmd.WorkspaceID,
+		ExternalUserID: cmd.UserID,
+		ExternalName:   cmd.UserName,
+		TokenHash:      hashChatLinkToken(token),
+		ExpiresAt:      pgtype.Timestamp{Time: time.Now().Add(chatLinkTTL), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Open %s/chat/link/%s within %d minutes to link your chat account to Tip Jar. Don't share this link.",
+		s.baseURL, token, int(chatLinkTTL.Minutes())), nil
+}
+
+func (s *ChatService) unlink(ctx context.Context, cmd ChatCommand) (string, error) {
+	deleted, err := s.db.DeleteChatAccount(ctx, sqlc.DeleteChatAccountParams{
+		Provider:       cmd.Provider,
+		WorkspaceID:    cmd.WorkspaceID,
+		ExternalUserID: cmd.UserID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if deleted == 0 {
+		return "Your chat account isn't linked to Tip Jar.", nil
+	}
+	return "Your chat account is no longer linked to Tip Jar.", nil
+}
+
+// GetLinkRequest returns the pending link request for a token from
+// "/tipjar link", or nil if it has expired or been used
+func (s *ChatService) GetLinkRequest(ctx context.Context, token string) (*models.ChatLinkRequest, error) {
+	request, err := s.db.GetChatLinkRequestByHash(ctx, hashChatLinkToken(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.sqlcChatLinkRequestToModel(request), nil
+}
+
+// CompleteLink links the chat user who asked for the token to userID,
+// replacing any earlier link for that chat user
+func (s *ChatService) CompleteLink(ctx context.Context, token string, userID int) (*models.ChatAccount, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	request, err := qtx.GetChatLinkRequestByHash(ctx, hashChatLinkToken(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrChatLinkNotFound
+		}
+		return nil, err
+	}
+
+	account, err := qtx.UpsertChatAccount(ctx, sqlc.UpsertChatAccountParams{
+		Provider:       request.Provider,
+		WorkspaceID:    request.WorkspaceID,
+		ExternalUserID: request.ExternalUserID,
+		ExternalName:   request.ExternalName,
+		UserID:         int32(userID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = qtx.DeleteChatLinkRequests(ctx, sqlc.DeleteChatLinkRequestsParams{
+		Provider:       request.Provider,
+		WorkspaceID:    request.WorkspaceID,
+		ExternalUserID: request.ExternalUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &models.ChatAccount{
+		ID:             int(account.ID),
+		Provider:       account.Provider,
+		WorkspaceID:    account.WorkspaceID,
+		ExternalUserID: account.ExternalUserID,
+		ExternalName:   account.ExternalName,
+		UserID:         int(account.UserID),
+		CreatedAt:      account.CreatedAt.Time,
+	}, nil
+}
+
+// matchOffenseType finds the offense type named at the start of text and
+// returns it with the rest of the text as notes. The name can be quoted,
+// as in `"Late to standup" again`; otherwise the longest matching name wins.
+func matchOffenseType(offenseTypes []models.OffenseType, text string) (*models.OffenseType, string) {
+	text = strings.TrimSpace(text)
+
+	if rest, ok := strings.CutPrefix(text, `"`); ok {
+		if name, notes, ok := strings.Cut(rest, `"`); ok {
+			for i := range offenseTypes {
+				if strings.EqualFold(offenseTypes[i].Name, strings.TrimSpace(name)) {
+					return &offenseTypes[i], strings.TrimSpace(notes)
+				}
+			}
+			return nil, ""
+		}
+	}
+
+	var match *models.OffenseType
+	for i := range offenseTypes {
+		name := offenseTypes[i].Name
+		if len(text) < len(name) || !strings.EqualFold(text[:len(name)], name) {
+			continue
+		}
+		// Don't match "Late" against "Lateness"
+		if len(text) > len(name) && text[len(name)] != ' ' {
+			continue
+		}
+		if match == nil || len(name) > len(match.Name) {
+			match = &offenseTypes[i]
+		}
+	}
+
+	if match == nil {
+		return nil, ""
+	}
+	return match, strings.TrimSpace(text[len(match.Name):])
+}
+
+func hashChatLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func textOrNull(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func (s *ChatService) sqlcChatLinkRequestToModel(request sqlc.ChatLinkRequest) *models.ChatLinkRequest {
+	return &models.ChatLinkRequest{
+		ID:             int(request.ID),
+		Provider:       request.Provider,
+		WorkspaceID:    request.WorkspaceID,
+		ExternalUserID: request.ExternalUserID,
+		ExternalName:   request.ExternalName,
+		ExpiresAt:      request.ExpiresAt.Time,
+		CreatedAt:      request.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrNotOffender       = errors.New("only the offender can dispute an offense")
+	ErrOffenseNotPending = errors.New("only pending offenses can be disputed")
+	ErrAlreadyDisputed   = errors.New("offense has already been disputed")
+	ErrDisputeClosed     = errors.New("dispute is already closed")
+	ErrCannotVote        = errors.New("the offender cannot vote on their own dispute")
+	ErrInvalidVote       = errors.New("vote must be uphold, reduce or dismiss")
+	ErrInvalidReduction  = errors.New("reduced amount must be more than zero and less than the current cost")
+)
+
+type DisputeService struct {
+	db     *database.DB
+	events events.Bus
+}
+
+func NewDisputeService(db *database.DB, bus events.Bus) *DisputeService {
+	return &DisputeService{db: db, events: bus}
+}
+
+// OpenDispute lets the offender challenge a pending offense. The offense is
+// marked disputed until the jar's members vote on it or the window runs out.
+func (s *DisputeService) OpenDispute(ctx context.Context, offenseID, userID int, reason string) (*models.Dispute, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	offense, err := qtx.GetOffense(ctx, int32(offenseID))
+	if err != nil {
+		return nil, err
+	}
+
+	if int(offense.OffenderID) != userID {
+		return nil, ErrNotOffender
+	}
+	if offense.Status != "pending" {
+		return nil, ErrOffenseNotPending
+	}
+
+	// Lock the jar's open period, then lock the offense and look again in
+	// case it was carried over or paid while we waited
+	period, err := openPeriod(ctx, qtx, offense.JarID)
+	if err != nil {
+		return nil, err
+	}
+	offense, err = qtx.GetOffenseForUpdate(ctx, offense.ID)
+	if err != nil {
+		return nil, err
+	}
+	if offense.PeriodID != period.ID {
+		return nil, ErrPeriodClosed
+	}
+	if offense.Status != "pending" {
+		return nil, ErrOffenseNotPending
+	}
+
+	// Each offense gets one dispute; an upheld offense can't be re-litigated
+	_, err = qtx.GetDisputeByOffense(ctx, offense.ID)
+	if err == nil {
+		return nil, ErrAlreadyDisputed
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	jar, err := qtx.GetTipJar(ctx, offense.JarID)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(time.Duration(jar.DisputeWindowHours) * time.Hour)
+
+	dispute, err := qtx.CreateDispute(ctx, sqlc.CreateDisputeParams{
+		OffenseID: offense.ID,
+		JarID:     offense.JarID,
+		OpenedBy:  int32(userID),
+		Reason:    reason,
+		Deadline:  pgtype.Timestamp{Time: deadline, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = qtx.UpdateOffenseStatusInPeriod(ctx, sqlc.UpdateOffenseStatusInPeriodParams{
+		ID:       offense.ID,
+		Status:   "disputed",
+		PeriodID: period.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := describeOffense(ctx, qtx, offense)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordJarEvent(ctx, qtx, offense.JarID, &userID, &offense.ID, "dispute.opened",
+		fmt.Sprintf("disputed %s: %s", subject, reason))
+	if err != nil {
+		return nil, err
+	}
+
+	raised := newJarEvents(qtx)
+	if err := raised.add(ctx, offense.JarID, "offense.disputed", newDisputeEventData(dispute)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	raised.publish(ctx, s.events)
+
+	return s.sqlcDisputeToModel(dispute), nil
+}
+
+// CastVote records a member's vote, replacing any earlier vote of theirs.
+// Once the jar's quorum is reached the dispute is resolved straight away.
+func (s *DisputeService) CastVote(ctx context.Context, disputeID, userID int, vote string, reducedAmount *money.Amount) error {
+	if vote != "uphold" && vote != "reduce" && vote != "dismiss" {
+		return ErrInvalidVote
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	dispute, err := qtx.GetDisputeForUpdate(ctx, int32(disputeID))
+	if err != nil {
+		return err
+	}
+
+	if dispute.Status != "open" || time.Now().After(dispute.Deadline.Time) {
+		return ErrDisputeClosed
+	}
+
+	offense, err := qtx.GetOffense(ctx, dispute.OffenseID)
+	if err != nil {
+		return err
+	}
+
+	if int(offense.OffenderID) == userID {
+		return ErrCannotVote
+	}
+
+	if vote == "reduce" {
+		cost, ok, err := money.FromNumeric(offense.CostAmount)
+		if err != nil {
+			return err
+		}
+		// Reducing to nothing is what dismissing is for
+		if !ok || reducedAmount == nil || !reducedAmount.IsPositive() || reducedAmount.Cmp(cost) >= 0 {
+			return ErrInvalidReduction
+		}
+	} else {
+		reducedAmount = nil
+	}
+
+	_, err = qtx.UpsertDisputeVote(ctx, sqlc.UpsertDisputeVoteParams{
+		DisputeID:     dispute.ID,
+		UserID:        int32(userID),
+		Vote:          vote,
+		ReducedAmount: money.NumericPtr(reducedAmount),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject, err := describeOffense(ctx, qtx, offense)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("voted to %s %s", vote, subject)
+	if reducedAmount != nil {
+		message = fmt.Sprintf("voted to reduce %s to %s", subject, formatCost(*reducedAmount, offense.CostUnit))
+	}
+
+	err = recordJarEvent(ctx, qtx, dispute.JarID, &userID, &offense.ID, "dispute.voted", message)
+	if err != nil {
+		return err
+	}
+
+	jar, err := qtx.GetTipJar(ctx, dispute.JarID)
+	if err != nil {
+		return err
+	}
+
+	votes, err := qtx.ListDisputeVotes(ctx, dispute.ID)
+	if err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	if len(votes) >= int(jar.DisputeQuorum) {
+		if err := s.resolve(ctx, qtx, raised, dispute, offense, votes); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
+}
+
+// ResolveExpiredDisputes closes every open dispute whose deadline has passed
+// and returns how many were closed. Disputes that fail to resolve are logged
+// and retried on the next run.
+func (s *DisputeService) ResolveExpiredDisputes(ctx context.Context) (int, error) {
+	expired, err := s.db.ListExpiredDisputes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	for _, dispute := range expired {
+		// One broken dispute shouldn't hold up the rest
+		if err := s.resolveExpired(ctx, dispute.ID); err != nil {
+			slog.Error("Failed to resolve expired dispute", "dispute_id", dispute.ID, "error", err)
+			continue
+		}
+		resolved++
+	}
+
+	return resolved, nil
+}
+
+func (s *DisputeService) resolveExpired(ctx context.Context, disputeID int32) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	// Re-read under lock in case a vote closed it in the meantime
+	dispute, err := qtx.GetDisputeForUpdate(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	if dispute.Status != "open" {
+		return nil
+	}
+
+	offense, err := qtx.GetOffense(ctx, dispute.OffenseID)
+	if err != nil {
+		return err
+	}
+
+	votes, err := qtx.ListDisputeVotes(ctx, dispute.ID)
+	if err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	if err := s.resolve(ctx, qtx, raised, dispute, offense, votes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
+}
+
+// StartResolver closes expired disputes every interval until ctx is done
+func (s *DisputeService) StartResolver(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resolved, err := s.ResolveExpiredDisputes(ctx)
+			if err != nil {
+				slog.Error("Failed to resolve expired disputes", "error", err)
+				continue
+			}
+			if resolved > 0 {
+				slog.Info("Resolved expired disputes", "count", resolved)
+			}
+		}
+	}
+}
+
+// resolve tallies the votes, closes the dispute and applies the outcome to
+// the offense: upheld goes back to pending, reduced goes back to pending at
+// the lower cost (or paid if that's already covered), dismissed is forgiven.
+func (s *DisputeService) resolve(ctx context.Context, qtx *sqlc.Queries, raised *jarEvents, dispute sqlc.OffenseDispute, offense sqlc.Offense, votes []sqlc.DisputeVote) error {
+	outcome, reducedAmount := tallyVotes(votes)
+
+	// A disputed offense holds its period open, so it's still in the open one
+	period, err := openPeriod(ctx, qtx, offense.JarID)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := qtx.ResolveDispute(ctx, sqlc.ResolveDisputeParams{
+		ID:             dispute.ID,
+		Status:         outcome,
+		ResolvedAmount: money.NumericPtr(reducedAmount),
+	})
+	if err != nil {
+		return err
+	}
+
+	status := "pending"
+	if outcome == "dismissed" {
+		status = "forgiven"
+	}
+
+	if reducedAmount != nil {
+		reduced, err := qtx.UpdateOffenseCost(ctx, sqlc.UpdateOffenseCostParams{
+			ID:         offense.ID,
+			CostAmount: reducedAmount.Numeric(),
+		})
+		if err != nil {
+			return err
+		}
+
+		// Earlier partial payments may already cover the lower cost
+		_, remaining, err := offenseBalance(ctx, qtx, reduced)
+		if err != nil {
+			return err
+		}
+		if remaining.IsZero() {
+			status = "paid"
+		}
+	}
+
+	updated, err := qtx.UpdateOffenseStatusInPeriod(ctx, sqlc.UpdateOffenseStatusInPeriodParams{
+		ID:       offense.ID,
+		Status:   status,
+		PeriodID: period.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := raised.add(ctx, dispute.JarID, "dispute.resolved", newDisputeEventData(resolved)); err != nil {
+		return err
+	}
+	if status == "paid" {
+		if err := raised.add(ctx, dispute.JarID, "offense.paid", newOffenseEventData(updated)); err != nil {
+			return err
+		}
+	}
+
+	subject, err := describeOffense(ctx, qtx, offense)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Dispute over %s was %s", subject, outcome)
+	if reducedAmount != nil {
+		message = fmt.Sprintf("Dispute over %s was settled by reducing it to %s", subject, formatCost(*reducedAmount, offense.CostUnit))
+	}
+	message += " " + voteSummary(votes)
+
+	return recordJarEvent(ctx, qtx, dispute.JarID, nil, &offense.ID, "dispute.resolved", message)
+}
+
+// tallyVotes picks the outcome with the most votes. Ties go to the milder
+// change (uphold, then reduce, then dismiss), and a dispute nobody voted on
+// is upheld. A reduction uses the median of the amounts proposed.
+func tallyVotes(votes []sqlc.DisputeVote) (string, *money.Amount) {
+	counts := make(map[string]int)
+	var proposed []money.Amount
+	for _, vote := range votes {
+		counts[vote.Vote]++
+		if vote.Vote == "reduce" {
+			proposed = append(proposed, money.MustFromNumeric(vote.ReducedAmount))
+		}
+	}
+
+	if counts["dismiss"] > counts["uphold"] && counts["dismiss"] > counts["reduce"] {
+		return "dismissed", nil
+	}
+	if counts["reduce"] > counts["uphold"] {
+		sort.Slice(proposed, func(i, j int) bool {
+			return proposed[i].Cmp(proposed[j]) < 0
+		})
+		median := proposed[(len(proposed)-1)/2]
+		return "reduced", &median
+	}
+	return "upheld", nil
+}
+
+func voteSummary(votes []sqlc.DisputeVote) string {
+	counts := make(map[string]int)
+	for _, vote := range votes {
+		counts[vote.Vote]++
+	}
+	return fmt.Sprintf("(%d uphold, %d reduce, %d dismiss)", counts["uphold"], counts["reduce"], counts["dismiss"])
+}
+
+// describeOffense names an offense for the jar history, e.g.
+// "Alice's Late to standup offense"
+func describeOffense(ctx context.Context, qtx *sqlc.Queries, offense sqlc.Offense) (string, error) {
+	offender, err := qtx.GetUserByID(ctx, offense.OffenderID)
+	if err != nil {
+		return "", err
+	}
+
+	offenseType, err := qtx.GetOffenseType(ctx, offense.OffenseTypeID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s's %s offense", offender.Name, offenseType.Name), nil
+}
+
+// describeOffenses names the offenses covered by a payment, e.g.
+// "3 of Alice's offenses". They all belong to the same offender.
+func describeOffenses(ctx context.Context, qtx *sqlc.Queries, offenses []sqlc.Offense) (string, error) {
+	if len(offenses) == 1 {
+		return describeOffense(ctx, qtx, offenses[0])
+	}
+
+	offender, err := qtx.GetUserByID(ctx, offenses[0].OffenderID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d of %s's offenses", len(offenses), offender.Name), nil
+}
+
+func formatCost(amount money.Amount, unit pgtype.Text) string {
+	if unit.Valid && unit.String != "" {
+		return amount.Display() + " " + unit.String
+	}
+	return amount.Display()
+}
+
+// recordJarEvent adds an entry to the jar's history. Messages for events
+// with an actor are shown after their name, e.g. "Alice voted to dismiss ..."
+func recordJarEvent(ctx context.Context, qtx *sqlc.Queries, jarID int32, actorID *int, offenseID *int32, eventType, message string) error {
+	var actor pgtype.Int4
+	if actorID != nil {
+		actor = pgtype.Int4{Int32: int32(*actorID), Valid: true}
+	}
+
+	var offense pgtype.Int4
+	if offenseID != nil {
+		offense = pgtype.Int4{Int32: *offenseID, Valid: true}
+	}
+
+	_, err := qtx.CreateJarEvent(ctx, sqlc.CreateJarEventParams{
+		JarID:     jarID,
+		ActorID:   actor,
+		OffenseID: offense,
+		EventType: eventType,
+		Message:   message,
+	})
+	return err
+}
+
+// GetDispute returns a dispute without its votes, or nil if it doesn't exist
+func (s *DisputeService) GetDispute(ctx context.Context, disputeID int) (*models.Dispute, error) {
+	dispute, err := s.db.GetDispute(ctx, int32(disputeID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.sqlcDisputeToModel(dispute), nil
+}
+
+// GetDisputesForJar returns the jar's disputes, open ones first, along with
+// the votes cast on each
+func (s *DisputeService) GetDisputesForJar(ctx context.Context, jarID int, limit int) ([]models.Dispute, error) {
+	rows, err := s.db.ListDisputesForJar(ctx, sqlc.ListDisputesForJarParams{
+		JarID: int32(jarID),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	voteRows, err := s.db.ListDisputeVotesForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make(map[int][]models.DisputeVote)
+	for _, row := range voteRows {
+		votes[int(row.DisputeID)] = append(votes[int(row.DisputeID)], models.DisputeVote{
+			ID:            int(row.ID),
+			DisputeID:     int(row.DisputeID),
+			UserID:        int(row.UserID),
+			UserName:      row.UserName,
+			Vote:          row.Vote,
+			ReducedAmount: money.FromNumericPtr(row.ReducedAmount),
+			CreatedAt:     row.CreatedAt.Time,
+		})
+	}
+
+	disputes := make([]models.Dispute, len(rows))
+	for i, row := range rows {
+		dispute := s.sqlcDisputeToModel(sqlc.OffenseDispute{
+			ID:             row.ID,
+			OffenseID:      row.OffenseID,
+			JarID:          row.JarID,
+			OpenedBy:       row.OpenedBy,
+			Reason:         row.Reason,
+			Status:         row.Status,
+			ResolvedAmount: row.ResolvedAmount,
+			Deadline:       row.Deadline,
+			CreatedAt:      row.CreatedAt,
+			ResolvedAt:     row.ResolvedAt,
+		})
+
+		var costUnit *string
+		if row.CostUnit.Valid {
+			costUnit = &row.CostUnit.String
+		}
+
+		dispute.OffenseTypeName = row.OffenseTypeName
+		dispute.OffenderID = int(row.OffenderID)
+		dispute.OffenderName = row.OffenderName
+		dispute.CostAmount = money.FromNumericPtr(row.CostAmount)
+		dispute.CostUnit = costUnit
+		dispute.Votes = votes[dispute.ID]
+
+		disputes[i] = *dispute
+	}
+
+	return disputes, nil
+}
+
+func (s *DisputeService) sqlcDisputeToModel(dispute sqlc.OffenseDispute) *models.Dispute {
+	var resolvedAt *time.Time
+	if dispute.ResolvedAt.Valid {
+		resolvedAt = &dispute.ResolvedAt.Time
+	}
+
+	return &models.Dispute{
+		ID:             int(dispute.ID),
+		OffenseID:      int(dispute.OffenseID),
+		JarID:          int(dispute.JarID),
+		OpenedBy:       int(dispute.OpenedBy),
+		Reason:         dispute.Reason,
+		Status:         dispute.Status,
+		ResolvedAmount: money.FromNumericPtr(dispute.ResolvedAmount),
+		Deadline:       dispute.Deadline.Time,
+		CreatedAt:      dispute.CreatedAt.Time,
+		ResolvedAt:     resolvedAt,
+	}
+}
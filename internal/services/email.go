@@ -0,0 +1,429 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/email"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxEmailAttempts = 5
+	emailBaseDelay   = time.Minute
+	emailBatchSize   = 20
+	digestBatchSize  = 20
+
+	// emailMaxAge is how long a queued email is worth sending. Anything older,
+	// e.g. queued while SMTP wasn't configured, is dropped.
+	emailMaxAge = 24 * time.Hour
+
+	defaultEmailMode = "immediate"
+)
+
+var (
+	ErrInvalidEmailMode        = errors.New("invalid email setting")
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+)
+
+// EmailMode is how often someone wants to be emailed
+type EmailMode struct {
+	Name        string
+	Description string
+}
+
+// EmailModes lists the email settings users can pick from
+var EmailModes = []EmailMode{
+	{"immediate", "Email me as soon as someone reports an offense against me"},
+	{"daily", "Send me a daily digest of what's outstanding in my jars"},
+	{"weekly", "Send me a weekly digest of what's outstanding in my jars"},
+	{"off", "Don't email me"},
+}
+
+// enqueueOffenseEmail queues an email to the offender about an offense
+// reported against them, if they get emails as things happen. Like
+// notifications, it runs inside the transaction reporting the offense.
+func enqueueOffenseEmail(ctx context.Context, qtx *sqlc.Queries, jarID int32, data offenseEventData, reporterName, offenseTypeName string) error {
+	offender, err := qtx.GetUserByID(ctx, data.OffenderID)
+	if err != nil {
+		return err
+	}
+	jar, err := qtx.GetTipJar(ctx, jarID)
+	if err != nil {
+		return err
+	}
+
+	amount := "nothing"
+	if data.Amount != nil {
+		var unit pgtype.Text
+		if data.Unit != nil {
+			unit = pgtype.Text{String: *data.Unit, Valid: true}
+		}
+		amount = formatCost(*data.Amount, unit)
+	}
+
+	payload := email.OffenseReported{
+		RecipientName: offender.Name,
+		ReporterName:  reporterName,
+		OffenseType:   offenseTypeName,
+		JarID:         int(jarID),
+		JarName:       jar.Name,
+		Amount:        amount,
+	}
+	if data.Notes != nil {
+		payload.Notes = *data.Notes
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return qtx.EnqueueImmediateEmail(ctx, sqlc.EnqueueImmediateEmailParams{
+		UserID:    data.OffenderID,
+		Template:  "offense_reported",
+		Subject:   fmt.Sprintf("%s reported you for %s in %s", reporterName, offenseTypeName, jar.Name),
+		Payload:   string(encoded),
+		JarID:     jarID,
+		EventType: "offense.reported",
+	})
+}
+
+// EmailService sends queued emails and digests, and manages how each user
+// wants to be emailed. The mailer is nil when SMTP isn't configured, in
+// which case nothing is sent.
+type EmailService struct {
+	db      *database.DB
+	mailer  *email.Mailer
+	baseURL string
+	secret  []byte
+}
+
+func NewEmailService(db *database.DB, mailer *email.Mailer, baseURL, secret string) *EmailService {
+	return &EmailService{
+		db:      db,
+		mailer:  mailer,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
+
+// Enabled reports whether SMTP is configured
+func (s *EmailService) Enabled() bool {
+	return s.mailer != nil
+}
+
+// GetEmailMode returns the user's email setting
+func (s *EmailService) GetEmailMode(ctx context.Context, userID int) (string, error) {
+	settings, err := s.db.GetEmailSettings(ctx, int32(userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return defaultEmailMode, nil
+		}
+		return "", err
+	}
+	return settings.Mode, nil
+}
+
+func (s *EmailService) SetEmailMode(ctx context.Context, userID int, mode string) error {
+	if !isEmailMode(mode) {
+		return ErrInvalidEmailMode
+	}
+
+	return s.db.UpsertEmailMode(ctx, sqlc.UpsertEmailModeParams{
+		UserID: int32(userID),
+		Mode:   mode,
+	})
+}
+
+// UnsubscribeURL is a link that turns the user's emails off without logging
+// in. It's signed so it can't be forged for someone else.
+func (s *EmailService) UnsubscribeURL(userID int) string {
+	query := url.Values{
+		"user":  {strconv.Itoa(userID)},
+		"token": {s.unsubscribeToken(userID)},
+	}
+	return s.baseURL + "/email/unsubscribe?" + query.Encode()
+}
+
+// Unsubscribe turns the user's emails off if the token is theirs
+func (s *EmailService) Unsubscribe(ctx context.Context, userID int, token string) error {
+	if !hmac.Equal([]byte(token), []byte(s.unsubscribeToken(userID))) {
+		return ErrInvalidUnsubscribeToken
+	}
+	return s.SetEmailMode(ctx, userID, "off")
+}
+
+func (s *EmailService) unsubscribeToken(userID int) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte("unsubscribe:" + strconv.Itoa(userID)))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// StartDispatcher sends queued emails every interval until ctx is done
+func (s *EmailService) StartDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.DispatchDue(ctx)
+			if err != nil {
+				slog.Error("Failed to send emails", "error", err)
+				continue
+			}
+			if sent > 0 {
+				slog.Info("Sent emails", "count", sent)
+			}
+		}
+	}
+}
+
+// DispatchDue attempts every email whose next attempt is due and returns how
+// many were attempted
+func (s *EmailService) DispatchDue(ctx context.Context) (int, error) {
+	if !s.Enabled() {
+		return 0, nil
+	}
+
+	expired, err := s.db.ExpireStaleEmails(ctx, pgtype.Timestamp{Time: time.Now().Add(-emailMaxAge), Valid: true})
+	if err != nil {
+		return 0, err
+	}
+	if expired > 0 {
+		slog.Warn("Dropped emails that waited too long to send", "count", expired)
+	}
+
+	attempted := 0
+	for {
+		emails, err := s.db.ClaimDueEmails(ctx, emailBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		if len(emails) == 0 {
+			return attempted, nil
+		}
+
+		for _, queued := range emails {
+			if err := s.attempt(ctx, queued); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+	}
+}
+
+// attempt sends one email and records the outcome, retrying with backoff if
+// the SMTP server couldn't take it
+func (s *EmailService) attempt(ctx context.Context, queued sqlc.EmailOutbox) error {
+	sendErr := s.send(ctx, queued)
+	if sendErr == nil {
+		return s.db.MarkEmailSent(ctx, queued.ID)
+	}
+
+	attempts := int(queued.Attempts) + 1
+	status := "pending"
+	if attempts >= maxEmailAttempts {
+		status = "failed"
+	}
+
+	return s.db.RecordEmailFailure(ctx, sqlc.RecordEmailFailureParams{
+		ID:            queued.ID,
+		Status:        status,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now().Add(emailBaseDelay << (attempts - 1)), Valid: true},
+		LastError:     pgtype.Text{String: sendErr.Error(), Valid: true},
+	})
+}
+
+func (s *EmailService) send(ctx context.Context, queued sqlc.EmailOutbox) error {
+	user, err := s.db.GetUserByID(ctx, queued.UserID)
+	if err != nil {
+		return err
+	}
+
+	var data any
+	switch queued.Template {
+	case "offense_reported":
+		data = &email.OffenseReported{}
+	case "digest":
+		data = &email.Digest{}
+	default:
+		return fmt.Errorf("unknown email template %q", queued.Template)
+	}
+	if err := json.Unmarshal([]byte(queued.Payload), data); err != nil {
+		return err
+	}
+
+	unsubscribeURL := s.UnsubscribeURL(int(user.ID))
+	html, text, err := email.Render(queued.Template, email.Page{
+		BaseURL:        s.baseURL,
+		SettingsURL:    s.baseURL + "/notifications/preferences",
+		UnsubscribeURL: unsubscribeURL,
+		Data:           data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(email.Message{
+		To:             user.Email,
+		Subject:        queued.Subject,
+		HTML:           html,
+		Text:           text,
+		UnsubscribeURL: unsubscribeURL,
+	})
+}
+
+// StartDigests queues digests for users who are due one every interval
+// until ctx is done
+func (s *EmailService) StartDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			queued, err := s.QueueDueDigests(ctx)
+			if err != nil {
+				slog.Error("Failed to queue digests", "error", err)
+				continue
+			}
+			if queued > 0 {
+				slog.Info("Queued digests", "count", queued)
+			}
+		}
+	}
+}
+
+// QueueDueDigests queues a digest for each user whose daily or weekly digest
+// is due and returns how many were queued. Users with nothing outstanding in
+// any jar are skipped until their next one.
+func (s *EmailService) QueueDueDigests(ctx context.Context) (int, error) {
+	if !s.Enabled() {
+		return 0, nil
+	}
+
+	queued := 0
+	for {
+		due, err := s.db.ClaimDueDigests(ctx, digestBatchSize)
+		if err != nil {
+			return queued, err
+		}
+		if len(due) == 0 {
+			return queued, nil
+		}
+
+		for _, row := range due {
+			sent, err := s.queueDigest(ctx, row.UserID, row.Mode)
+			if err != nil {
+				return queued, err
+			}
+			if sent {
+				queued++
+			}
+		}
+	}
+}
+
+func (s *EmailService) queueDigest(ctx context.Context, userID int32, period string) (bool, error) {
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	jars, err := s.db.ListTipJarsForUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	digest := email.Digest{RecipientName: user.Name, Period: period}
+
+	for _, jar := range jars {
+		rows, err := s.db.GetJarBalancesByUnit(ctx, jar.ID)
+		if err != nil {
+			return false, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		digestJar := email.DigestJar{ID: int(jar.ID), Name: jar.Name}
+
+		// Rows arrive grouped by member, one per unit they owe in
+		var owed []string
+		offenses := 0
+		for i, row := range rows {
+			owed = append(owed, formatCost(money.MustFromNumeric(row.TotalOwed), pgtype.Text{String: row.Unit, Valid: true}))
+			offenses += int(row.OffenseCount)
+
+			if i == len(rows)-1 || rows[i+1].UserID != row.UserID {
+				if row.UserID == userID {
+					digestJar.YouOwe = strings.Join(owed, " and ")
+				}
+				digestJar.Members = append(digestJar.Members, email.DigestMember{
+					Name:     row.UserName,
+					Owed:     strings.Join(owed, " and "),
+					Offenses: offenses,
+				})
+				owed, offenses = nil, 0
+			}
+		}
+
+		digest.Jars = append(digest.Jars, digestJar)
+	}
+
+	if len(digest.Jars) == 0 {
+		return false, nil
+	}
+
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return false, err
+	}
+
+	subject := "Your daily Tip Jar digest"
+	if period == "weekly" {
+		subject = "Your weekly Tip Jar digest"
+	}
+
+	err = s.db.CreateEmail(ctx, sqlc.CreateEmailParams{
+		UserID:   userID,
+		Template: "digest",
+		Subject:  subject,
+		Payload:  string(payload),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func isEmailMode(name string) bool {
+	for _, mode := range EmailModes {
+		if mode.Name == name {
+			return true
+		}
+	}
+	return false
+}
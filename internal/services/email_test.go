@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/email"
+	"tipjar/internal/email/emailtest"
+	"tipjar/internal/events"
+	"tipjar/internal/money"
+)
+
+// newTestEmailService sends through an in-process SMTP server
+func newTestEmailService(t *testing.T, db *database.DB) (*EmailService, *emailtest.Server) {
+	t.Helper()
+	server := emailtest.NewServer(t)
+	mailer, err := email.NewMailer(server.Host, server.Port, "", "", "Tip Jar <tipjar@example.test>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEmailService(db, mailer, "https://tipjar.example.test/", "test-secret"), server
+}
+
+// newEmailJar creates a jar with an offense type and the given members
+func newEmailJar(t *testing.T, db *database.DB, owner sqlc.User, name string, members ...sqlc.User) (jarID, offenseTypeID int) {
+	t.Helper()
+	ctx := context.Background()
+
+	jar, err := NewTipJarService(db, events.NewMemoryBus()).CreateTipJar(ctx, name, "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jar.ID), UserID: member.ID, Role: authz.Member}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cost, unit := money.FromInt(5), "dollars"
+	offenseType, err := NewOffenseService(db, events.NewMemoryBus()).CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jar.ID, offenseType.ID
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	ctx := context.Background()
+	s := NewEmailService(nil, nil, "https://tipjar.example.test/", "test-secret")
+
+	link, err := url.Parse(s.UnsubscribeURL(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Scheme != "https" || link.Host != "tipjar.example.test" || link.Path != "/email/unsubscribe" {
+		t.Errorf("unsubscribe URL = %s", link)
+	}
+	if got := link.Query().Get("user"); got != "42" {
+		t.Errorf("user = %q, want 42", got)
+	}
+	token := link.Query().Get("token")
+	if token != s.unsubscribeToken(42) {
+		t.Errorf("token = %q, want %q", token, s.unsubscribeToken(42))
+	}
+
+	other := NewEmailService(nil, nil, "https://tipjar.example.test", "other-secret")
+	last := byte('A')
+	if token[len(token)-1] == 'A' {
+		last = 'B'
+	}
+	tampered := token[:len(token)-1] + string(last)
+
+	// Bad tokens are refused before the database is touched
+	for name, bad := range map[string]string{
+		"empty":                "",
+		"tampered":             tampered,
+		"another user's":       s.unsubscribeToken(43),
+		"another secret's":     other.unsubscribeToken(42),
+		"with padding":         token + "=",
+		"from the wrong field": "unsubscribe:42",
+	} {
+		if err := s.Unsubscribe(ctx, 42, bad); err != ErrInvalidUnsubscribeToken {
+			t.Errorf("%s token: Unsubscribe = %v, want ErrInvalidUnsubscribeToken", name, err)
+		}
+	}
+}
+
+func TestImmediateEmails(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	s, server := newTestEmailService(t, db)
+	offenses := NewOffenseService(db, events.NewMemoryBus())
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+	jarID, offenseTypeID := newEmailJar(t, db, owner, "Standup", member)
+
+	report := func() {
+		t.Helper()
+		if _, err := offenses.CreateOffense(ctx, jarID, offenseTypeID, int(owner.ID), int(member.ID), "Ten minutes", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DispatchDue(ctx); err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+	}
+
+	report()
+	sent := server.To(member.Email)
+	if len(sent) != 1 {
+		t.Fatalf("member got %d emails, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.Subject != "owner reported you for Late in Standup" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	for _, want := range []string{"Hi member", "Ten minutes", "https://tipjar.example.test/jars/" + strconv.Itoa(jarID)} {
+		if !strings.Contains(msg.Text, want) || !strings.Contains(msg.HTML, want) {
+			t.Errorf("email is missing %q:\n%s", want, msg.Text)
+		}
+	}
+	if got := server.To(owner.Email); len(got) != 0 {
+		t.Errorf("reporter got %d emails, want none", len(got))
+	}
+
+	// The List-Unsubscribe link turns emails off, but only with its own token
+	header := msg.Header.Get("List-Unsubscribe")
+	link, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(header, "<"), ">"))
+	if err != nil || !strings.Contains(msg.Text, link.String()) {
+		t.Fatalf("List-Unsubscribe = %q (%v), want the link in the email", header, err)
+	}
+	userID, err := strconv.Atoi(link.Query().Get("user"))
+	if err != nil || userID != int(member.ID) {
+		t.Fatalf("unsubscribe link is for user %q, want %d", link.Query().Get("user"), member.ID)
+	}
+	token := link.Query().Get("token")
+
+	if err := s.Unsubscribe(ctx, int(owner.ID), token); err != ErrInvalidUnsubscribeToken {
+		t.Errorf("another user's token: Unsubscribe = %v, want ErrInvalidUnsubscribeToken", err)
+	}
+	if err := s.Unsubscribe(ctx, userID, strings.ToUpper(token)); err != ErrInvalidUnsubscribeToken {
+		t.Errorf("tampered token: Unsubscribe = %v, want ErrInvalidUnsubscribeToken", err)
+	}
+	if mode, err := s.GetEmailMode(ctx, userID); err != nil || mode != "immediate" {
+		t.Errorf("mode after bad tokens = %q, %v; want immediate", mode, err)
+	}
+
+	if err := s.Unsubscribe(ctx, userID, token); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if mode, err := s.GetEmailMode(ctx, userID); err != nil || mode != "off" {
+		t.Errorf("mode after unsubscribing = %q, %v; want off", mode, err)
+	}
+
+	report()
+	if got := server.To(member.Email); len(got) != 1 {
+		t.Errorf("member got %d emails after unsubscribing, want 1", len(got))
+	}
+}
+
+func TestDigestEmails(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	s, server := newTestEmailService(t, db)
+
+	// More members than either batch holds, so both are worked through in
+	// several goes
+	owner := dbtest.User(t, db, "owner")
+	members := make([]sqlc.User, digestBatchSize+emailBatchSize)
+	for i := range members {
+		members[i] = dbtest.User(t, db, "member"+strconv.Itoa(i))
+		mode := "daily"
+		if i == 0 {
+			mode = "weekly"
+		}
+		if err := s.SetEmailMode(ctx, int(members[i].ID), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jarID, offenseTypeID := newEmailJar(t, db, owner, "Digested", members...)
+
+	// Someone whose jars have nothing outstanding gets no digest
+	idle := dbtest.User(t, db, "idle")
+	if err := s.SetEmailMode(ctx, int(idle.ID), "daily"); err != nil {
+		t.Fatal(err)
+	}
+	newEmailJar(t, db, owner, "Quiet", idle)
+
+	debtor := members[1]
+	_, err := NewOffenseService(db, events.NewMemoryBus()).CreateOffense(ctx, jarID, offenseTypeID, int(owner.ID), int(debtor.ID), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := s.QueueDueDigests(ctx)
+	if err != nil {
+		t.Fatalf("QueueDueDigests: %v", err)
+	}
+	if queued < len(members) {
+		t.Errorf("queued %d digests, want at least %d", queued, len(members))
+	}
+
+	// Digests that were just queued aren't due again
+	if _, err := s.QueueDueDigests(ctx); err != nil {
+		t.Fatalf("QueueDueDigests: %v", err)
+	}
+	if _, err := s.DispatchDue(ctx); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+
+	for i, member := range members {
+		sent := server.To(member.Email)
+		if len(sent) != 1 {
+			t.Errorf("%s got %d emails, want one digest", member.Name, len(sent))
+			continue
+		}
+
+		wantSubject := "Your daily Tip Jar digest"
+		if i == 0 {
+			wantSubject = "Your weekly Tip Jar digest"
+		}
+		wantOwed := "You're all paid up"
+		if member.ID == debtor.ID {
+			wantOwed = "You owe"
+		}
+		msg := sent[0]
+		if msg.Subject != wantSubject {
+			t.Errorf("%s's digest subject = %q, want %q", member.Name, msg.Subject, wantSubject)
+		}
+		for _, want := range []string{"Hi " + member.Name, "Digested", debtor.Name, wantOwed} {
+			if !strings.Contains(msg.Text, want) {
+				t.Errorf("%s's digest is missing %q:\n%s", member.Name, want, msg.Text)
+			}
+		}
+		if strings.Contains(msg.Text, "Quiet") {
+			t.Errorf("%s's digest lists a jar they aren't in", member.Name)
+		}
+	}
+
+	if got := server.To(idle.Email); len(got) != 0 {
+		t.Errorf("idle user got %d emails, want none", len(got))
+	}
+	if got := server.To(owner.Email); len(got) != 0 {
+		t.Errorf("owner, who gets emails as things happen, got %d, want none", len(got))
+	}
+}
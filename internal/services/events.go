@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+)
+
+// jarEvents collects the events raised while changing a jar. Webhook
+// deliveries and notifications are created as each event is added, in the
+// same transaction as the change; the events go out on the bus once that
+// transaction commits, so anyone refreshing in response sees the change.
+type jarEvents struct {
+	qtx    *sqlc.Queries
+	raised []events.Event
+}
+
+func newJarEvents(qtx *sqlc.Queries) *jarEvents {
+	return &jarEvents{qtx: qtx}
+}
+
+func (e *jarEvents) add(ctx context.Context, jarID int32, eventType string, data any) error {
+	if err := enqueueWebhooks(ctx, e.qtx, jarID, eventType, data); err != nil {
+		return err
+	}
+	if err := notify(ctx, e.qtx, jarID, eventType, data); err != nil {
+		return err
+	}
+
+	e.raised = append(e.raised, events.Event{
+		Type:      eventType,
+		JarID:     int(jarID),
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	return nil
+}
+
+// publish sends the collected events. Call it after the transaction commits.
+func (e *jarEvents) publish(ctx context.Context, bus events.Bus) {
+	for _, event := range e.raised {
+		bus.Publish(ctx, event)
+	}
+}
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/invitecode"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteCodeTypo = errors.New("that isn't a valid invite code")
+	ErrInviteRevoked  = errors.New("this invite has been revoked")
+	ErrInviteExpired  = errors.New("this invite has expired")
+	ErrInviteUsedUp   = errors.New("this invite has been used as many times as it allows")
+	ErrInvalidInvite  = errors.New("max uses must be at least 1 and expiry must be in the future")
+	ErrAlreadyMember  = errors.New("you are already a member of this jar")
+	ErrAlreadyAsked   = errors.New("you have already asked to join this jar")
+)
+
+// InviteOptions limits who an invite lets in and for how long. The zero
+// value is an unlabelled invite for members that never runs out.
+type InviteOptions struct {
+	Label     string
+	Role      string
+	MaxUses   *int
+	ExpiresAt *time.Time
+}
+
+// InviteService manages a jar's invites and joining with them
+type InviteService struct {
+	db     *database.DB
+	events events.Bus
+}
+
+func NewInviteService(db *database.DB, bus events.Bus) *InviteService {
+	return &InviteService{db: db, events: bus}
+}
+
+// CreateInvite adds an invite with a fresh code to the jar
+func (s *InviteService) CreateInvite(ctx context.Context, jarID, createdBy int, opts InviteOptions) (*models.Invite, error) {
+	code, err := invitecode.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := createInvite(ctx, s.db.Queries, int32(jarID), int32(createdBy), code, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlcInviteToModel(invite, time.Now()), nil
+}
+
+func createInvite(ctx context.Context, q *sqlc.Queries, jarID, createdBy int32, code string, opts InviteOptions) (sqlc.Invite, error) {
+	role := opts.Role
+	if role == "" {
+		role = authz.Member
+	}
+	if !authz.IsRole(role) || role == authz.Owner {
+		return sqlc.Invite{}, ErrInvalidRole
+	}
+	if opts.MaxUses != nil && *opts.MaxUses < 1 {
+		return sqlc.Invite{}, ErrInvalidInvite
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return sqlc.Invite{}, ErrInvalidInvite
+	}
+
+	params := sqlc.CreateInviteParams{
+		JarID:     jarID,
+		Code:      code,
+		Role:      role,
+		CreatedBy: createdBy,
+	}
+	if opts.Label != "" {
+		params.Label = pgtype.Text{String: opts.Label, Valid: true}
+	}
+	if opts.MaxUses != nil {
+		params.MaxUses = pgtype.Int4{Int32: int32(*opts.MaxUses), Valid: true}
+	}
+	if opts.ExpiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamp{Time: *opts.ExpiresAt, Valid: true}
+	}
+
+	return q.CreateInvite(ctx, params)
+}
+
+// GetInvite returns an invite whatever its status, or nil if there isn't one
+func (s *InviteService) GetInvite(ctx context.Context, inviteID int) (*models.Invite, error) {
+	invite, err := s.db.GetInvite(ctx, int32(inviteID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return sqlcInviteToModel(invite, time.Now()), nil
+}
+
+// GetInviteByCode returns the invite with the code whatever its status, or nil
+// if there isn't one
+func (s *InviteService) GetInviteByCode(ctx context.Context, code string) (*models.Invite, error) {
+	invite, err := findInvite(ctx, s.db.Queries, code)
+	if err != nil {
+		if err == ErrInviteNotFound || err == ErrInviteCodeTypo {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return sqlcInviteToModel(invite, time.Now()), nil
+}
+
+// ListInvites returns the jar's invites, newest first with revoked ones last,
+// and who joined using each
+func (s *InviteService) ListInvites(ctx context.Context, jarID int) ([]models.Invite, error) {
+	rows, err := s.db.ListInvitesForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, err := s.db.ListInviteRedemptionsForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	byInvite := make(map[int32][]models.InviteRedemption)
+	for _, row := range redemptions {
+		byInvite[row.InviteID] = append(byInvite[row.InviteID], models.InviteRedemption{
+			InviteID:  int(row.InviteID),
+			UserID:    int(row.UserID),
+			UserName:  row.UserName,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	now := time.Now()
+	invites := make([]models.Invite, len(rows))
+	for i, row := range rows {
+		invites[i] = *sqlcInviteToModel(row, now)
+		invites[i].Redemptions = byInvite[row.ID]
+	}
+
+	return invites, nil
+}
+
+// RevokeInvite stops an invite working. Revoking one that's already revoked
+// does nothing.
+func (s *InviteService) RevokeInvite(ctx context.Context, inviteID int) error {
+	_, err := s.db.RevokeInvite(ctx, int32(inviteID))
+	return err
+}
+
+// RotateInvite revokes an invite and replaces it with one that has a new code
+// but the same label, role and limits. Uses start again, and an expiring
+// invite gets as long as the original was given.
+func (s *InviteService) RotateInvite(ctx context.Context, inviteID, rotatedBy int) (*models.Invite, error) {
+	code, err := invitecode.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	old, err := qtx.GetInvite(ctx, int32(inviteID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+	if old.RevokedAt.Valid {
+		return nil, ErrInviteRevoked
+	}
+
+	if _, err := qtx.RevokeInvite(ctx, old.ID); err != nil {
+		return nil, err
+	}
+
+	opts := InviteOptions{Label: old.Label.String, Role: old.Role}
+	if old.MaxUses.Valid {
+		maxUses := int(old.MaxUses.Int32)
+		opts.MaxUses = &maxUses
+	}
+	if old.ExpiresAt.Valid {
+		expiresAt := time.Now().Add(old.ExpiresAt.Time.Sub(old.CreatedAt.Time))
+		opts.ExpiresAt = &expiresAt
+	}
+
+	invite, err := createInvite(ctx, qtx, old.JarID, int32(rotatedBy), code, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return sqlcInviteToModel(invite, time.Now()), nil
+}
+
+// LookupInvite returns a working invite and its jar, or why the code can't be
+// used
+func (s *InviteService) LookupInvite(ctx context.Context, code string) (*models.Invite, *models.TipJar, error) {
+	invite, err := findInvite(ctx, s.db.Queries, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := inviteUsable(invite, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	jar, err := s.db.GetTipJar(ctx, invite.JarID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if jar.ArchivedAt.Valid {
+		return nil, nil, ErrJarArchived
+	}
+
+	return sqlcInviteToModel(invite, time.Now()), sqlcTipJarToModel(jar), nil
+}
+
+// Join adds the user to the invite's jar with the invite's role, and records
+// that they joined with it. If the jar needs approval, it files a join request
+// for an admin to review instead and returns it.
+func (s *InviteService) Join(ctx context.Context, code string, userID int) (*models.TipJar, *models.JoinRequest, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	invite, err := findInvite(ctx, qtx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkNotArchived(ctx, qtx, invite.JarID); err != nil {
+		return nil, nil, err
+	}
+
+	role, err := memberRole(ctx, qtx, invite.JarID, int32(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if role != "" {
+		return nil, nil, ErrAlreadyMember
+	}
+
+	_, err = qtx.GetPendingJoinRequest(ctx, sqlc.GetPendingJoinRequestParams{
+		JarID:  invite.JarID,
+		UserID: int32(userID),
+	})
+	if err == nil {
+		return nil, nil, ErrAlreadyAsked
+	}
+	if err != pgx.ErrNoRows {
+		return nil, nil, err
+	}
+
+	// Taking a use re-checks the invite under a row lock, in case it ran out
+	// since it was read
+	used, err := qtx.UseInvite(ctx, invite.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			current, getErr := qtx.GetInvite(ctx, invite.ID)
+			if getErr != nil {
+				return nil, nil, getErr
+			}
+			if usableErr := inviteUsable(current, time.Now()); usableErr != nil {
+				return nil, nil, usableErr
+			}
+			return nil, nil, ErrInviteUsedUp
+		}
+		return nil, nil, err
+	}
+	invite = used
+
+	jar, err := qtx.GetTipJar(ctx, invite.JarID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raised := newJarEvents(qtx)
+	var request *models.JoinRequest
+
+	if jar.JoinApprovalRequired {
+		created, err := qtx.CreateJoinRequest(ctx, sqlc.CreateJoinRequestParams{
+			JarID:    jar.ID,
+			UserID:   int32(userID),
+			InviteID: invite.ID,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = raised.add(ctx, jar.ID, "join_request.created", newJoinRequestEventData(created, invite.Role))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		request = sqlcJoinRequestToModel(created, invite.Role)
+		request.JarName = jar.Name
+	} else {
+		message := fmt.Sprintf("joined the jar with the %s invite", inviteName(invite))
+		if err := addInvitedMember(ctx, qtx, raised, invite, int32(userID), userID, message); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	raised.publish(ctx, s.events)
+
+	return sqlcTipJarToModel(jar), request, nil
+}
+
+// addInvitedMember makes the user a member with the invite's role and records
+// the invite they used. actorID is whoever let them in, for the history.
+func addInvitedMember(ctx context.Context, qtx *sqlc.Queries, raised *jarEvents, invite sqlc.Invite, userID int32, actorID int, message string) error {
+	membership, err := qtx.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{
+		JarID:  invite.JarID,
+		UserID: userID,
+		Role:   invite.Role,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = qtx.CreateInviteRedemption(ctx, sqlc.CreateInviteRedemptionParams{
+		InviteID: invite.ID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := recordJarEvent(ctx, qtx, invite.JarID, &actorID, nil, "member.joined", message); err != nil {
+		return err
+	}
+
+	return raised.add(ctx, membership.JarID, "member.joined", memberEventData{
+		UserID: membership.UserID,
+		Role:   membership.Role,
+	})
+}
+
+// inviteName is how an invite is referred to in the jar's history
+func inviteName(invite sqlc.Invite) string {
+	if invite.Label.Valid {
+		return invite.Label.String
+	}
+	return invitecode.Format(invite.Code)
+}
+
+// inviteUsable returns why an invite can't be used at now, if it can't
+func inviteUsable(invite sqlc.Invite, now time.Time) error {
+	switch inviteStatus(invite, now) {
+	case "revoked":
+		return ErrInviteRevoked
+	case "expired":
+		return ErrInviteExpired
+	case "used up":
+		return ErrInviteUsedUp
+	}
+	return nil
+}
+
+func inviteStatus(invite sqlc.Invite, now time.Time) string {
+	switch {
+	case invite.RevokedAt.Valid:
+		return "revoked"
+	case invite.ExpiresAt.Valid && !invite.ExpiresAt.Time.After(now):
+		return "expired"
+	case invite.MaxUses.Valid && invite.Uses >= invite.MaxUses.Int32:
+		return "used up"
+	default:
+		return "active"
+	}
+}
+
+// findInvite looks up an invite by a code as someone typed it. Codes handed
+// out before the move to Crockford's alphabet still work. A code that isn't
+// found and fails its check symbol is reported as a typo rather than as
+// missing.
+func findInvite(ctx context.Context, q *sqlc.Queries, input string) (sqlc.Invite, error) {
+	code := invitecode.Normalize(input)
+	if code == "" {
+		return sqlc.Invite{}, ErrInviteNotFound
+	}
+
+	invite, err := q.GetInviteByCode(ctx, code)
+	if err != pgx.ErrNoRows {
+		return invite, err
+	}
+
+	legacy := strings.TrimSpace(input)
+	invite, err = q.GetInviteByLegacyCode(ctx, pgtype.Text{String: legacy, Valid: true})
+	if err != pgx.ErrNoRows {
+		return invite, err
+	}
+
+	if !invitecode.Valid(code) {
+		return sqlc.Invite{}, ErrInviteCodeTypo
+	}
+	return sqlc.Invite{}, ErrInviteNotFound
+}
+
+func sqlcInviteToModel(invite sqlc.Invite, now time.Time) *models.Invite {
+	model := &models.Invite{
+		ID:        int(invite.ID),
+		JarID:     int(invite.JarID),
+		Code:      invite.Code,
+		Role:      invite.Role,
+		Uses:      int(invite.Uses),
+		CreatedBy: int(invite.CreatedBy),
+		CreatedAt: invite.CreatedAt.Time,
+		Status:    inviteStatus(invite, now),
+	}
+	if invite.Label.Valid {
+		model.Label = &invite.Label.String
+	}
+	if invite.MaxUses.Valid {
+		maxUses := int(invite.MaxUses.Int32)
+		model.MaxUses = &maxUses
+	}
+	if invite.ExpiresAt.Valid {
+		model.ExpiresAt = &invite.ExpiresAt.Time
+	}
+	if invite.RevokedAt.Valid {
+		model.RevokedAt = &invite.RevokedAt.Time
+	}
+	return model
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestReviewed = errors.New("this request has already been answered")
+)
+
+// JoinRequestService handles the queue of people waiting to be let into jars
+// that need approval. Requests are filed by InviteService.Join.
+type JoinRequestService struct {
+	db     *database.DB
+	events events.Bus
+}
+
+func NewJoinRequestService(db *database.DB, bus events.Bus) *JoinRequestService {
+	return &JoinRequestService{db: db, events: bus}
+}
+
+// GetJoinRequest returns a request whatever its status, or nil if there isn't
+// one
+func (s *JoinRequestService) GetJoinRequest(ctx context.Context, requestID int) (*models.JoinRequest, error) {
+	request, err := s.db.GetJoinRequest(ctx, int32(requestID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return sqlcJoinRequestToModel(request, ""), nil
+}
+
+// ListPendingForJar returns the requests waiting for the jar's admins, oldest
+// first
+func (s *JoinRequestService) ListPendingForJar(ctx context.Context, jarID int) ([]models.JoinRequest, error) {
+	rows, err := s.db.ListPendingJoinRequestsForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]models.JoinRequest, len(rows))
+	for i, row := range rows {
+		requests[i] = models.JoinRequest{
+			ID:         int(row.ID),
+			JarID:      int(row.JarID),
+			UserID:     int(row.UserID),
+			UserName:   row.UserName,
+			UserEmail:  row.UserEmail,
+			UserAvatar: row.UserAvatar.String,
+			InviteID:   int(row.InviteID),
+			InviteCode: row.InviteCode,
+			Role:       row.InviteRole,
+			Status:     "pending",
+			CreatedAt:  row.CreatedAt.Time,
+		}
+		if row.InviteLabel.Valid {
+			requests[i].InviteLabel = &row.InviteLabel.String
+		}
+	}
+
+	return requests, nil
+}
+
+// ListPendingForUser returns the jars the user is waiting to be let into
+func (s *JoinRequestService) ListPendingForUser(ctx context.Context, userID int) ([]models.JoinRequest, error) {
+	rows, err := s.db.ListPendingJoinRequestsForUser(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]models.JoinRequest, len(rows))
+	for i, row := range rows {
+		requests[i] = models.JoinRequest{
+			ID:        int(row.ID),
+			JarID:     int(row.JarID),
+			JarName:   row.JarName,
+			UserID:    userID,
+			Status:    "pending",
+			CreatedAt: row.CreatedAt.Time,
+		}
+	}
+
+	return requests, nil
+}
+
+// Approve lets the requester into the jar with the role of the invite they
+// used. The message, if any, is passed on to them.
+func (s *JoinRequestService) Approve(ctx context.Context, requestID, reviewerID int, message string) error {
+	return s.review(ctx, requestID, reviewerID, "approved", message)
+}
+
+// Reject turns the request down and gives the invite back the use it took.
+// The message, if any, is passed on to the requester.
+func (s *JoinRequestService) Reject(ctx context.Context, requestID, reviewerID int, message string) error {
+	return s.review(ctx, requestID, reviewerID, "rejected", message)
+}
+
+func (s *JoinRequestService) review(ctx context.Context, requestID, reviewerID int, status, message string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	var messageText pgtype.Text
+	if message != "" {
+		messageText = pgtype.Text{String: message, Valid: true}
+	}
+
+	request, err := qtx.ReviewJoinRequest(ctx, sqlc.ReviewJoinRequestParams{
+		ID:         int32(requestID),
+		Status:     status,
+		Message:    messageText,
+		ReviewedBy: pgtype.Int4{Int32: int32(reviewerID), Valid: true},
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			if _, getErr := qtx.GetJoinRequest(ctx, int32(requestID)); getErr == pgx.ErrNoRows {
+				return ErrJoinRequestNotFound
+			}
+			return ErrJoinRequestReviewed
+		}
+		return err
+	}
+
+	invite, err := qtx.GetInvite(ctx, request.InviteID)
+	if err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+
+	if status == "approved" {
+		requester, err := qtx.GetUserByID(ctx, request.UserID)
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("let %s join with the %s invite", requester.Name, inviteName(invite))
+		if err := addInvitedMember(ctx, qtx, raised, invite, request.UserID, reviewerID, message); err != nil {
+			return err
+		}
+	} else {
+		if err := qtx.ReleaseInviteUse(ctx, invite.ID); err != nil {
+			return err
+		}
+	}
+
+	err = raised.add(ctx, request.JarID, "join_request."+status, newJoinRequestEventData(request, invite.Role))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
+}
+
+func sqlcJoinRequestToModel(request sqlc.JoinRequest, role string) *models.JoinRequest {
+	model := &models.JoinRequest{
+		ID:        int(request.ID),
+		JarID:     int(request.JarID),
+		UserID:    int(request.UserID),
+		InviteID:  int(request.InviteID),
+		Role:      role,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt.Time,
+	}
+	if request.Message.Valid {
+		model.Message = &request.Message.String
+	}
+	if request.ReviewedBy.Valid {
+		reviewedBy := int(request.ReviewedBy.Int32)
+		model.ReviewedBy = &reviewedBy
+	}
+	if request.ReviewedAt.Valid {
+		model.ReviewedAt = &request.ReviewedAt.Time
+	}
+	return model
+}
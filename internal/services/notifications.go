@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// notificationsShown is how many notifications the notifications page lists
+const notificationsShown = 50
+
+// NotificationType is a kind of notification users can switch on or off
+type NotificationType struct {
+	Name        string
+	Description string
+}
+
+// NotificationTypes lists the events that notify someone, named after the
+// event that triggers them
+var NotificationTypes = []NotificationType{
+	{"offense.reported", "Someone reports an offense against you"},
+	{"payment.recorded", "Someone pays an offense you reported"},
+	{"offense.disputed", "Someone disputes an offense you reported"},
+	{"dispute.resolved", "A dispute over your offense or report is settled"},
+	{"payment.verified", "Your payment is verified"},
+	{"payment.rejected", "Your payment is rejected"},
+	{"member.joined", "Someone joins a jar you're an admin of"},
+	{"join_request.created", "Someone asks to join a jar you're an admin of"},
+	{"join_request.approved", "Your request to join a jar is approved"},
+	{"join_request.rejected", "Your request to join a jar is turned down"},
+	{"jar.archived", "A jar you're in is archived"},
+	{"jar.restored", "A jar you're in is restored from the archive"},
+	{"period.closed", "A period closes in a jar you're in"},
+}
+
+// notify creates notifications for the people an event concerns. Like
+// webhooks, it runs inside the transaction making the change.
+func notify(ctx context.Context, qtx *sqlc.Queries, jarID int32, eventType string, data any) error {
+	link := fmt.Sprintf("/jars/%d", jarID)
+	send := func(userID int32, message string) error {
+		return qtx.CreateNotification(ctx, sqlc.CreateNotificationParams{
+			UserID:    userID,
+			JarID:     jarID,
+			EventType: eventType,
+			Message:   message,
+			Link:      link,
+		})
+	}
+
+	switch data := data.(type) {
+	case offenseEventData:
+		if eventType != "offense.reported" {
+			return nil
+		}
+
+		reporter, err := qtx.GetUserByID(ctx, data.ReporterID)
+		if err != nil {
+			return err
+		}
+		offenseType, err := qtx.GetOffenseType(ctx, data.OffenseTypeID)
+		if err != nil {
+			return err
+		}
+
+		if err := send(data.OffenderID, fmt.Sprintf("%s reported you for %s", reporter.Name, offenseType.Name)); err != nil {
+			return err
+		}
+		return enqueueOffenseEmail(ctx, qtx, jarID, data, reporter.Name, offenseType.Name)
+
+	case paymentEventData:
+		offenses := make([]sqlc.Offense, len(data.Allocations))
+		for i, allocation := range data.Allocations {
+			offense, err := qtx.GetOffense(ctx, allocation.OffenseID)
+			if err != nil {
+				return err
+			}
+			offenses[i] = offense
+		}
+		if len(offenses) == 0 {
+			return nil
+		}
+
+		subject, err := describeOffenses(ctx, qtx, offenses)
+		if err != nil {
+			return err
+		}
+
+		switch eventType {
+		case "payment.recorded":
+			payer, err := qtx.GetUserByID(ctx, data.UserID)
+			if err != nil {
+				return err
+			}
+
+			// Each reporter hears once, however many of their reports it covers
+			notified := make(map[int32]bool)
+			for _, offense := range offenses {
+				if offense.ReporterID == data.UserID || notified[offense.ReporterID] {
+					continue
+				}
+				notified[offense.ReporterID] = true
+
+				if err := send(offense.ReporterID, fmt.Sprintf("%s paid for %s", payer.Name, subject)); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "payment.verified":
+			return send(data.UserID, fmt.Sprintf("Your payment for %s was verified", subject))
+		case "payment.rejected":
+			return send(data.UserID, fmt.Sprintf("Your payment for %s was rejected and is owed again", subject))
+		}
+
+	case disputeEventData:
+		offense, err := qtx.GetOffense(ctx, data.OffenseID)
+		if err != nil {
+			return err
+		}
+
+		subject, err := describeOffense(ctx, qtx, offense)
+		if err != nil {
+			return err
+		}
+
+		switch eventType {
+		case "offense.disputed":
+			return send(offense.ReporterID, fmt.Sprintf("%s was disputed: %s", subject, data.Reason))
+		case "dispute.resolved":
+			message := fmt.Sprintf("The dispute over %s was %s", subject, data.Status)
+			if err := send(offense.OffenderID, message); err != nil {
+				return err
+			}
+			return send(offense.ReporterID, message)
+		}
+
+	case memberEventData:
+		if eventType != "member.joined" {
+			return nil
+		}
+
+		joined, err := qtx.GetUserByID(ctx, data.UserID)
+		if err != nil {
+			return err
+		}
+
+		members, err := qtx.ListJarMembers(ctx, jarID)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			if !authz.Can(member.Role, authz.ManageMembers) || member.UserID == data.UserID {
+				continue
+			}
+			if err := send(member.UserID, fmt.Sprintf("%s joined the jar", joined.Name)); err != nil {
+				return err
+			}
+		}
+
+	case joinRequestEventData:
+		switch eventType {
+		case "join_request.created":
+			requester, err := qtx.GetUserByID(ctx, data.UserID)
+			if err != nil {
+				return err
+			}
+
+			members, err := qtx.ListJarMembers(ctx, jarID)
+			if err != nil {
+				return err
+			}
+
+			link = fmt.Sprintf("/jars/%d/settings#members", jarID)
+			for _, member := range members {
+				if !authz.Can(member.Role, authz.ManageMembers) {
+					continue
+				}
+				if err := send(member.UserID, fmt.Sprintf("%s asked to join the jar", requester.Name)); err != nil {
+					return err
+				}
+			}
+		case "join_request.approved", "join_request.rejected":
+			message := "Your request to join was approved"
+			if eventType == "join_request.rejected" {
+				// They can't open the jar, so point them somewhere they can
+				message = "Your request to join was turned down"
+				link = "/dashboard"
+			}
+			if data.Message != nil {
+				message += ": " + *data.Message
+			}
+			return send(data.UserID, message)
+		}
+
+	case jarEventData:
+		actor, err := qtx.GetUserByID(ctx, data.ActorID)
+		if err != nil {
+			return err
+		}
+
+		members, err := qtx.ListJarMembers(ctx, jarID)
+		if err != nil {
+			return err
+		}
+
+		verb := "archived"
+		if eventType == "jar.restored" {
+			verb = "restored"
+		}
+		for _, member := range members {
+			if member.UserID == data.ActorID {
+				continue
+			}
+			if err := send(member.UserID, fmt.Sprintf("%s %s the jar", actor.Name, verb)); err != nil {
+				return err
+			}
+		}
+
+	case periodEventData:
+		actor, err := qtx.GetUserByID(ctx, data.ActorID)
+		if err != nil {
+			return err
+		}
+
+		members, err := qtx.ListJarMembers(ctx, jarID)
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("%s closed %s; %s has started", actor.Name, data.Name, data.NextName)
+		for _, member := range members {
+			if member.UserID == data.ActorID {
+				continue
+			}
+			if err := send(member.UserID, message); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type NotificationService struct {
+	db *database.DB
+}
+
+func NewNotificationService(db *database.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// ListNotifications returns the user's most recent notifications, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, userID int) ([]models.Notification, error) {
+	rows, err := s.db.ListNotificationsForUser(ctx, sqlc.ListNotificationsForUserParams{
+		UserID: int32(userID),
+		Limit:  notificationsShown,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]models.Notification, len(rows))
+	for i, row := range rows {
+		var readAt *time.Time
+		if row.ReadAt.Valid {
+			readAt = &row.ReadAt.Time
+		}
+
+		notifications[i] = models.Notification{
+			ID:        int(row.ID),
+			UserID:    int(row.UserID),
+			JarID:     int(row.JarID),
+			JarName:   row.JarName,
+			EventType: row.EventType,
+			Message:   row.Message,
+			Link:      row.Link,
+			ReadAt:    readAt,
+			CreatedAt: row.CreatedAt.Time,
+		}
+	}
+
+	return notifications, nil
+}
+
+func (s *NotificationService) CountUnread(ctx context.Context, userID int) (int, error) {
+	count, err := s.db.CountUnreadNotifications(ctx, int32(userID))
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// MarkRead marks one of the user's notifications read and returns where it
+// links to, or "" if the user has no such notification
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID int) (string, error) {
+	notification, err := s.db.MarkNotificationRead(ctx, sqlc.MarkNotificationReadParams{
+		ID:     int32(notificationID),
+		UserID: int32(userID),
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return notification.Link, nil
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int) error {
+	return s.db.MarkAllNotificationsRead(ctx, int32(userID))
+}
+
+// GetPreferences returns the preferences the user has set. Anything missing
+// is switched on.
+func (s *NotificationService) GetPreferences(ctx context.Context, userID int) ([]models.NotificationPreference, error) {
+	rows, err := s.db.ListNotificationPreferences(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]models.NotificationPreference, len(rows))
+	for i, row := range rows {
+		preferences[i] = models.NotificationPreference{
+			JarID:     int(row.JarID),
+			EventType: row.EventType,
+			Enabled:   row.Enabled,
+		}
+	}
+
+	return preferences, nil
+}
+
+// UpdatePreferences saves the given preferences, leaving any others as they were
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int, preferences []models.NotificationPreference) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	for _, preference := range preferences {
+		err := qtx.UpsertNotificationPreference(ctx, sqlc.UpsertNotificationPreferenceParams{
+			UserID:    int32(userID),
+			JarID:     int32(preference.JarID),
+			EventType: preference.EventType,
+			Enabled:   preference.Enabled,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -2,38 +2,88 @@ package services
 
 import (
 	"context"
-	"math/big"
+	"errors"
+	"fmt"
+	"slices"
 
 	"tipjar/internal/database"
 	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
 	"tipjar/internal/models"
+	"tipjar/internal/money"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	ErrOffenseNotFound      = errors.New("offense not found")
+	ErrOffenseSettled       = errors.New("offense has already been settled")
+	ErrNoOffensesSelected   = errors.New("select at least one offense to pay")
+	ErrMixedOffenses        = errors.New("a payment can only cover one member's offenses in one jar")
+	ErrPartialBatch         = errors.New("a partial payment can only cover one offense")
+	ErrInvalidPaymentAmount = errors.New("payment amount must be greater than zero")
+	ErrOverpayment          = errors.New("payment is more than what's left on the offense")
+	ErrOffenseTypeNotInJar  = errors.New("offense type is not available in this jar")
+)
+
 type OffenseService struct {
-	db *database.DB
+	db     *database.DB
+	events events.Bus
 }
 
-func NewOffenseService(db *database.DB) *OffenseService {
-	return &OffenseService{db: db}
+func NewOffenseService(db *database.DB, bus events.Bus) *OffenseService {
+	return &OffenseService{db: db, events: bus}
 }
 
-func (s *OffenseService) CreateOffense(ctx context.Context, jarID, offenseTypeID, reporterID, offenderID int, notes string, costOverride *float64) (*models.Offense, error) {
+func (s *OffenseService) CreateOffense(ctx context.Context, jarID, offenseTypeID, reporterID, offenderID int, notes string, costOverride *money.Amount) (*models.Offense, error) {
 	var notesText pgtype.Text
 	if notes != "" {
 		notesText = pgtype.Text{String: notes, Valid: true}
 	}
 
-	var costOverrideNumeric pgtype.Numeric
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	period, err := openPeriod(ctx, qtx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold the offender's membership until the offense exists, so they can't
+	// leave in between, and so concurrent reports against them queue up for
+	// the jar's rules
+	_, err = qtx.LockJarMembership(ctx, sqlc.LockJarMembershipParams{
+		JarID:  int32(jarID),
+		UserID: int32(offenderID),
+	})
+	if err == pgx.ErrNoRows {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	offenseType, err := qtx.GetOffenseType(ctx, int32(offenseTypeID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrOffenseTypeNotInJar
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int(offenseType.JarID) != jarID || !offenseType.IsActive {
+		return nil, ErrOffenseTypeNotInJar
+	}
+
+	// Freeze the cost so later edits to the offense type don't change it
+	costAmount := offenseType.CostAmount
 	if costOverride != nil {
-		cents := int64(*costOverride * 100)
-		costOverrideNumeric = pgtype.Numeric{
-			Int:   big.NewInt(cents),
-			Exp:   -2,
-			Valid: true,
-		}
+		costAmount = costOverride.Numeric()
 	}
 
 	params := sqlc.CreateOffenseParams{
@@ -42,14 +92,31 @@ func (s *OffenseService) CreateOffense(ctx context.Context, jarID, offenseTypeID
 		ReporterID:    int32(reporterID),
 		OffenderID:    int32(offenderID),
 		Notes:         notesText,
-		CostOverride:  costOverrideNumeric,
+		CostOverride:  money.NumericPtr(costOverride),
+		CostAmount:    costAmount,
+		CostUnit:      offenseType.CostUnit,
+		PeriodID:      period.ID,
+	}
+
+	if err := applyJarRules(ctx, qtx, &params); err != nil {
+		return nil, err
 	}
 
-	offense, err := s.db.CreateOffense(ctx, params)
+	offense, err := qtx.CreateOffense(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
+	raised := newJarEvents(qtx)
+	if err := raised.add(ctx, offense.JarID, "offense.reported", newOffenseEventData(offense)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	raised.publish(ctx, s.events)
+
 	return s.sqlcOffenseToModel(offense), nil
 }
 
@@ -104,22 +171,12 @@ func (s *OffenseService) GetOffenseType(ctx context.Context, offenseTypeID int)
 	return &model, nil
 }
 
-func (s *OffenseService) CreateOffenseType(ctx context.Context, jarID int, name, description string, costAmount *float64, costUnit *string) (*models.OffenseType, error) {
+func (s *OffenseService) CreateOffenseType(ctx context.Context, jarID int, name, description string, costAmount *money.Amount, costUnit *string, createdBy int) (*models.OffenseType, error) {
 	var descText pgtype.Text
 	if description != "" {
 		descText = pgtype.Text{String: description, Valid: true}
 	}
 
-	var costAmountNumeric pgtype.Numeric
-	if costAmount != nil {
-		cents := int64(*costAmount * 100)
-		costAmountNumeric = pgtype.Numeric{
-			Int:   big.NewInt(cents),
-			Exp:   -2,
-			Valid: true,
-		}
-	}
-
 	var costUnitText pgtype.Text
 	if costUnit != nil && *costUnit != "" {
 		costUnitText = pgtype.Text{String: *costUnit, Valid: true}
@@ -129,15 +186,37 @@ func (s *OffenseService) CreateOffenseType(ctx context.Context, jarID int, name,
 		JarID:       int32(jarID),
 		Name:        name,
 		Description: descText,
-		CostAmount:  costAmountNumeric,
+		CostAmount:  money.NumericPtr(costAmount),
 		CostUnit:    costUnitText,
 	}
 
-	offenseType, err := s.db.CreateOffenseType(ctx, params)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	offenseType, err := qtx.CreateOffenseType(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
+	_, err = qtx.CreateOffenseTypePrice(ctx, sqlc.CreateOffenseTypePriceParams{
+		OffenseTypeID: offenseType.ID,
+		CostAmount:    offenseType.CostAmount,
+		CostUnit:      offenseType.CostUnit,
+		ChangedBy:     pgtype.Int4{Int32: int32(createdBy), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	model := s.rowToOffenseTypeModel(
 		offenseType.ID,
 		offenseType.JarID,
@@ -152,22 +231,14 @@ func (s *OffenseService) CreateOffenseType(ctx context.Context, jarID int, name,
 	return &model, nil
 }
 
-func (s *OffenseService) UpdateOffenseType(ctx context.Context, offenseTypeID int, name, description string, costAmount *float64, costUnit *string) (*models.OffenseType, error) {
+// UpdateOffenseType edits an offense type and records a price history entry
+// when its cost changes. Existing offenses keep the cost they were reported at.
+func (s *OffenseService) UpdateOffenseType(ctx context.Context, offenseTypeID int, name, description string, costAmount *money.Amount, costUnit *string, changedBy int) (*models.OffenseType, error) {
 	var descText pgtype.Text
 	if description != "" {
 		descText = pgtype.Text{String: description, Valid: true}
 	}
 
-	var costAmountNumeric pgtype.Numeric
-	if costAmount != nil {
-		cents := int64(*costAmount * 100)
-		costAmountNumeric = pgtype.Numeric{
-			Int:   big.NewInt(cents),
-			Exp:   -2,
-			Valid: true,
-		}
-	}
-
 	var costUnitText pgtype.Text
 	if costUnit != nil && *costUnit != "" {
 		costUnitText = pgtype.Text{String: *costUnit, Valid: true}
@@ -177,14 +248,43 @@ func (s *OffenseService) UpdateOffenseType(ctx context.Context, offenseTypeID in
 		ID:          int32(offenseTypeID),
 		Name:        name,
 		Description: descText,
-		CostAmount:  costAmountNumeric,
+		CostAmount:  money.NumericPtr(costAmount),
 		CostUnit:    costUnitText,
 	}
 
-	offenseType, err := s.db.UpdateOffenseType(ctx, params)
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	previous, err := qtx.GetOffenseType(ctx, int32(offenseTypeID))
+	if err != nil {
+		return nil, err
+	}
+
+	offenseType, err := qtx.UpdateOffenseType(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !samePrice(previous.CostAmount, previous.CostUnit, offenseType.CostAmount, offenseType.CostUnit) {
+		_, err = qtx.CreateOffenseTypePrice(ctx, sqlc.CreateOffenseTypePriceParams{
+			OffenseTypeID: offenseType.ID,
+			CostAmount:    offenseType.CostAmount,
+			CostUnit:      offenseType.CostUnit,
+			ChangedBy:     pgtype.Int4{Int32: int32(changedBy), Valid: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
 	model := s.rowToOffenseTypeModel(
 		offenseType.ID,
@@ -200,6 +300,54 @@ func (s *OffenseService) UpdateOffenseType(ctx context.Context, offenseTypeID in
 	return &model, nil
 }
 
+// GetPriceHistoryForJar returns every recorded price change for the jar's
+// offense types, newest first.
+func (s *OffenseService) GetPriceHistoryForJar(ctx context.Context, jarID int) ([]models.OffenseTypePrice, error) {
+	rows, err := s.db.ListOffenseTypePricesForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.OffenseTypePrice, len(rows))
+	for i, row := range rows {
+		var unit *string
+		if row.CostUnit.Valid {
+			unit = &row.CostUnit.String
+		}
+
+		var changedByName *string
+		if row.ChangedByName.Valid {
+			changedByName = &row.ChangedByName.String
+		}
+
+		result[i] = models.OffenseTypePrice{
+			ID:              int(row.ID),
+			OffenseTypeID:   int(row.OffenseTypeID),
+			OffenseTypeName: row.OffenseTypeName,
+			CostAmount:      money.FromNumericPtr(row.CostAmount),
+			CostUnit:        unit,
+			ChangedByName:   changedByName,
+			EffectiveFrom:   row.EffectiveFrom.Time,
+		}
+	}
+
+	return result, nil
+}
+
+// samePrice reports whether two amount/unit pairs describe the same cost
+func samePrice(amountA pgtype.Numeric, unitA pgtype.Text, amountB pgtype.Numeric, unitB pgtype.Text) bool {
+	if unitA != unitB {
+		return false
+	}
+
+	a := money.FromNumericPtr(amountA)
+	b := money.FromNumericPtr(amountB)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Cmp(*b) == 0
+}
+
 func (s *OffenseService) SetOffenseTypeActiveStatus(ctx context.Context, offenseTypeID int, isActive bool) error {
 	_, err := s.db.SetOffenseTypeActiveStatus(ctx, sqlc.SetOffenseTypeActiveStatusParams{
 		ID:       int32(offenseTypeID),
@@ -225,14 +373,6 @@ func (s *OffenseService) rowToOffenseTypeModel(
 		desc = &description.String
 	}
 
-	var amount *float64
-	if costAmount.Valid {
-		floatVal, _ := costAmount.Float64Value()
-		if floatVal.Valid {
-			amount = &floatVal.Float64
-		}
-	}
-
 	var unit *string
 	if costUnit.Valid {
 		unit = &costUnit.String
@@ -243,7 +383,7 @@ func (s *OffenseService) rowToOffenseTypeModel(
 		JarID:       int(jarID),
 		Name:        name,
 		Description: desc,
-		CostAmount:  amount,
+		CostAmount:  money.FromNumericPtr(costAmount),
 		CostUnit:    unit,
 		IsActive:    isActive,
 		CreatedAt:   createdAt.Time,
@@ -257,12 +397,9 @@ func (s *OffenseService) sqlcOffenseToModel(offense sqlc.Offense) *models.Offens
 		notes = &offense.Notes.String
 	}
 
-	var costOverride *float64
-	if offense.CostOverride.Valid {
-		floatVal, _ := offense.CostOverride.Float64Value()
-		if floatVal.Valid {
-			costOverride = &floatVal.Float64
-		}
+	var costUnit *string
+	if offense.CostUnit.Valid {
+		costUnit = &offense.CostUnit.String
 	}
 
 	return &models.Offense{
@@ -272,14 +409,16 @@ func (s *OffenseService) sqlcOffenseToModel(offense sqlc.Offense) *models.Offens
 		ReporterID:    int(offense.ReporterID),
 		OffenderID:    int(offense.OffenderID),
 		Notes:         notes,
-		CostOverride:  costOverride,
+		CostOverride:  money.FromNumericPtr(offense.CostOverride),
+		CostAmount:    money.FromNumericPtr(offense.CostAmount),
+		CostUnit:      costUnit,
 		Status:        offense.Status,
 		CreatedAt:     offense.CreatedAt.Time,
 		UpdatedAt:     offense.UpdatedAt.Time,
 	}
 }
 func (s *OffenseService) GetOffenseDetail(ctx context.Context, offenseID int) (*models.OffenseDetail, error) {
-	
+
 	offense, err := s.db.GetOffense(ctx, int32(offenseID))
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -303,26 +442,17 @@ func (s *OffenseService) GetOffenseDetail(ctx context.Context, offenseID int) (*
 		return nil, err
 	}
 
-	// Calculate amount and unit
-	var amount float64
-	var unit string
+	// Use the cost frozen onto the offense when it was reported
+	amount := money.MustFromNumeric(offense.CostAmount)
 
-	if offense.CostOverride.Valid {
-		floatVal, _ := offense.CostOverride.Float64Value()
-		if floatVal.Valid {
-			amount = floatVal.Float64
-		}
-	} else if offenseType.CostAmount.Valid {
-		floatVal, _ := offenseType.CostAmount.Float64Value()
-		if floatVal.Valid {
-			amount = floatVal.Float64
-		}
+	paid, remaining, err := offenseBalance(ctx, s.db.Queries, offense)
+	if err != nil {
+		return nil, err
 	}
 
-	if offenseType.CostUnit.Valid {
-		unit = offenseType.CostUnit.String
-	} else {
-		unit = "items"
+	unit := "items"
+	if offense.CostUnit.Valid {
+		unit = offense.CostUnit.String
 	}
 
 	var notes *string
@@ -340,66 +470,385 @@ func (s *OffenseService) GetOffenseDetail(ctx context.Context, offenseID int) (*
 		OffenderName:    offender.Name,
 		Notes:           notes,
 		Amount:          amount,
+		Paid:            paid,
+		Remaining:       remaining,
 		Unit:            unit,
 		Status:          offense.Status,
 		CreatedAt:       offense.CreatedAt.Time,
 	}, nil
 }
 
-func (s *OffenseService) CreatePayment(ctx context.Context, offenseID, userID int, amount *float64, proofURL *string, notes string) (*models.Payment, error) {
-	var amountNumeric pgtype.Numeric
+// PayOffenses records a single payment from the offender towards pending
+// offenses. With a nil amount it covers what's left of each offense; an
+// amount can only be given for one offense and pays it off in part. The
+// payment, its allocations and the offense statuses are written in one
+// transaction, so either every offense is covered or none are. An offense is
+// paid once nothing remains, and in jars that require verification it waits
+// in 'verifying' until members confirm the payment. proofURL is the storage
+// key of the uploaded proof and proofType its kind (image, receipt or video).
+func (s *OffenseService) PayOffenses(ctx context.Context, markedBy int, offenseIDs []int, amount *money.Amount, proofURL, proofType *string) (*models.Payment, error) {
+	ids := slices.Clone(offenseIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) == 0 {
+		return nil, ErrNoOffensesSelected
+	}
+	if amount != nil && len(ids) > 1 {
+		return nil, ErrPartialBatch
+	}
+	if amount != nil && !amount.IsPositive() {
+		return nil, ErrInvalidPaymentAmount
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	// Lock the jar's open period before the offenses, as closing it does
+	first, err := qtx.GetOffense(ctx, int32(ids[0]))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrOffenseNotFound
+		}
+		return nil, err
+	}
+	period, err := openPeriod(ctx, qtx, first.JarID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the offenses in ID order so concurrent payments can't deadlock
+	offenses := make([]sqlc.Offense, len(ids))
+	remaining := make([]money.Amount, len(ids))
+	for i, id := range ids {
+		offense, err := qtx.GetOffenseForUpdate(ctx, int32(id))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, ErrOffenseNotFound
+			}
+			return nil, err
+		}
+
+		if offense.Status != "pending" {
+			return nil, ErrOffenseSettled
+		}
+		if i > 0 && (offense.JarID != offenses[0].JarID || offense.OffenderID != offenses[0].OffenderID) {
+			return nil, ErrMixedOffenses
+		}
+		if offense.PeriodID != period.ID {
+			return nil, ErrPeriodClosed
+		}
+
+		offenses[i] = offense
+		_, remaining[i], err = offenseBalance(ctx, qtx, offense)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Amount paid towards each offense
+	amounts := remaining
 	if amount != nil {
-		cents := int64(*amount * 100)
-		amountNumeric = pgtype.Numeric{
-			Int:   big.NewInt(cents),
-			Exp:   -2,
-			Valid: true,
+		if amount.Cmp(remaining[0]) > 0 {
+			return nil, ErrOverpayment
 		}
+		amounts = []money.Amount{*amount}
 	}
 
 	var proofURLText pgtype.Text
+	var proofTypeText pgtype.Text
 	if proofURL != nil && *proofURL != "" {
 		proofURLText = pgtype.Text{String: *proofURL, Valid: true}
+		if proofType != nil {
+			proofTypeText = pgtype.Text{String: *proofType, Valid: true}
+		}
+	}
+
+	// The payer is always the offender, whoever records the payment
+	var payment sqlc.Payment
+	if len(offenses) == 1 {
+		payment, err = qtx.CreatePayment(ctx, sqlc.CreatePaymentParams{
+			OffenseID: pgtype.Int4{Int32: offenses[0].ID, Valid: true},
+			UserID:    offenses[0].OffenderID,
+			Amount:    amounts[0].Numeric(),
+			ProofType: proofTypeText,
+			ProofUrl:  proofURLText,
+		})
+	} else {
+		payment, err = qtx.CreateBatchPayment(ctx, sqlc.CreateBatchPaymentParams{
+			UserID:    offenses[0].OffenderID,
+			ProofType: proofTypeText,
+			ProofUrl:  proofURLText,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jar, err := qtx.GetTipJar(ctx, offenses[0].JarID)
+	if err != nil {
+		return nil, err
+	}
+
+	raised := newJarEvents(qtx)
+	for i, offense := range offenses {
+		_, err = qtx.CreatePaymentAllocation(ctx, sqlc.CreatePaymentAllocationParams{
+			PaymentID: payment.ID,
+			OffenseID: offense.ID,
+			Amount:    amounts[i].Numeric(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		status := "pending"
+		if jar.VerificationRequired {
+			status = "verifying"
+		} else if amounts[i].Cmp(remaining[i]) == 0 {
+			status = "paid"
+		}
+
+		updated, err := qtx.UpdateOffenseStatusInPeriod(ctx, sqlc.UpdateOffenseStatusInPeriodParams{
+			ID:       offense.ID,
+			Status:   status,
+			PeriodID: period.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if status == "paid" {
+			if err := raised.add(ctx, jar.ID, "offense.paid", newOffenseEventData(updated)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	allocations := make([]allocationEventData, len(offenses))
+	for i, offense := range offenses {
+		allocations[i] = allocationEventData{OffenseID: offense.ID, Amount: &amounts[i]}
+		if offense.CostUnit.Valid {
+			allocations[i].Unit = &offense.CostUnit.String
+		}
+	}
+
+	err = raised.add(ctx, jar.ID, "payment.recorded", paymentEventData{
+		PaymentID:   payment.ID,
+		UserID:      payment.UserID,
+		Amount:      money.FromNumericPtr(payment.Amount),
+		Allocations: allocations,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := describeOffenses(ctx, qtx, offenses)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventOffense *int32
+	if len(offenses) == 1 {
+		eventOffense = &offenses[0].ID
+	}
+
+	err = recordJarEvent(ctx, qtx, jar.ID, &markedBy, eventOffense, "payment.recorded",
+		fmt.Sprintf("recorded a payment for %s", subject))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
+	raised.publish(ctx, s.events)
 
-	var proofType pgtype.Text
-	if proofURL != nil {
-		proofType = pgtype.Text{String: "image", Valid: true}
+	return s.GetPayment(ctx, int(payment.ID))
+}
+
+// offenseBalance returns how much has been paid towards an offense by
+// payments that weren't rejected, and how much is still owed
+func offenseBalance(ctx context.Context, qtx *sqlc.Queries, offense sqlc.Offense) (money.Amount, money.Amount, error) {
+	paidAmount, err := qtx.GetOffensePaidAmount(ctx, offense.ID)
+	if err != nil {
+		return money.Zero, money.Zero, err
+	}
+
+	paid := money.MustFromNumeric(paidAmount)
+	return paid, owedAfter(money.MustFromNumeric(offense.CostAmount), paid), nil
+}
+
+// owedAfter is what's left of cost once paid has been paid, never below zero
+func owedAfter(cost, paid money.Amount) money.Amount {
+	remaining := cost.Sub(paid)
+	if remaining.IsNegative() {
+		return money.Zero
 	}
+	return remaining
+}
 
-	params := sqlc.CreatePaymentParams{
-		OffenseID: int32(offenseID),
-		UserID:    int32(userID),
-		Amount:    amountNumeric,
-		ProofType: proofType,
-		ProofUrl:  proofURLText,
+// GetPayment returns a payment with the offenses it covered, or nil if it
+// doesn't exist
+func (s *OffenseService) GetPayment(ctx context.Context, paymentID int) (*models.Payment, error) {
+	payment, err := s.db.GetPayment(ctx, int32(paymentID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	payment, err := s.db.CreatePayment(ctx, params)
+	rows, err := s.db.ListAllocationsForPayment(ctx, payment.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.sqlcPaymentToModel(payment), nil
+	result := s.sqlcPaymentToModel(payment)
+	result.Allocations = make([]models.PaymentAllocation, len(rows))
+	for i, row := range rows {
+		var unit *string
+		if row.CostUnit.Valid {
+			unit = &row.CostUnit.String
+		}
+
+		result.Allocations[i] = models.PaymentAllocation{
+			PaymentID:       int(row.PaymentID),
+			OffenseID:       int(row.OffenseID),
+			OffenseTypeName: row.OffenseTypeName,
+			Amount:          money.FromNumericPtr(row.Amount),
+			Unit:            unit,
+		}
+	}
+
+	return result, nil
 }
 
-func (s *OffenseService) UpdateOffenseStatus(ctx context.Context, offenseID int, status string) error {
-	_, err := s.db.UpdateOffenseStatus(ctx, sqlc.UpdateOffenseStatusParams{
-		ID:     int32(offenseID),
-		Status: status,
+// GetPendingOffensesForUserInJar lists what a member still owes in a jar,
+// oldest first
+func (s *OffenseService) GetPendingOffensesForUserInJar(ctx context.Context, jarID, userID int) ([]models.OffenseDetail, error) {
+	rows, err := s.db.ListPendingOffensesForUserInJar(ctx, sqlc.ListPendingOffensesForUserInJarParams{
+		JarID:      int32(jarID),
+		OffenderID: int32(userID),
 	})
-	return err
+	if err != nil {
+		return nil, err
+	}
+
+	offenses := make([]models.OffenseDetail, len(rows))
+	for i, row := range rows {
+		unit := "items"
+		if row.CostUnit.Valid {
+			unit = row.CostUnit.String
+		}
+
+		var notes *string
+		if row.Notes.Valid {
+			notes = &row.Notes.String
+		}
+
+		amount := money.MustFromNumeric(row.CostAmount)
+		paid := money.MustFromNumeric(row.PaidAmount)
+
+		offenses[i] = models.OffenseDetail{
+			ID:              int(row.ID),
+			JarID:           int(row.JarID),
+			OffenseTypeName: row.OffenseTypeName,
+			ReporterID:      int(row.ReporterID),
+			OffenderID:      int(row.OffenderID),
+			Notes:           notes,
+			Amount:          amount,
+			Paid:            paid,
+			Remaining:       owedAfter(amount, paid),
+			Unit:            unit,
+			Status:          row.Status,
+			CreatedAt:       row.CreatedAt.Time,
+		}
+	}
+
+	return offenses, nil
 }
 
-func (s *OffenseService) sqlcPaymentToModel(payment sqlc.Payment) *models.Payment {
-	var amount *float64
-	if payment.Amount.Valid {
-		floatVal, _ := payment.Amount.Float64Value()
-		if floatVal.Valid {
-			amount = &floatVal.Float64
+// ListOffensesForJar returns a page of the jar's offenses, newest first
+func (s *OffenseService) ListOffensesForJar(ctx context.Context, jarID, limit, offset int) ([]models.OffenseDetail, error) {
+	rows, err := s.db.ListOffensesForJar(ctx, sqlc.ListOffensesForJarParams{
+		JarID:  int32(jarID),
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offenses := make([]models.OffenseDetail, len(rows))
+	for i, row := range rows {
+		unit := "items"
+		if row.CostUnit.Valid {
+			unit = row.CostUnit.String
+		}
+
+		var notes *string
+		if row.Notes.Valid {
+			notes = &row.Notes.String
+		}
+
+		amount := money.MustFromNumeric(row.CostAmount)
+		paid := money.MustFromNumeric(row.PaidAmount)
+
+		offenses[i] = models.OffenseDetail{
+			ID:              int(row.ID),
+			JarID:           int(row.JarID),
+			OffenseTypeName: row.OffenseTypeName,
+			ReporterID:      int(row.ReporterID),
+			ReporterName:    row.ReporterName,
+			OffenderID:      int(row.OffenderID),
+			OffenderName:    row.OffenderName,
+			Notes:           notes,
+			Amount:          amount,
+			Paid:            paid,
+			Remaining:       owedAfter(amount, paid),
+			Unit:            unit,
+			Status:          row.Status,
+			CreatedAt:       row.CreatedAt.Time,
 		}
 	}
 
+	return offenses, nil
+}
+
+// ListPaymentsForOffense returns the payments that went towards an offense,
+// newest first. Amount is the share of each payment allocated to the offense.
+func (s *OffenseService) ListPaymentsForOffense(ctx context.Context, offenseID int) ([]models.Payment, error) {
+	rows, err := s.db.ListPaymentsForOffense(ctx, int32(offenseID))
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]models.Payment, len(rows))
+	for i, row := range rows {
+		payment := s.sqlcPaymentToModel(sqlc.Payment{
+			ID:         row.ID,
+			OffenseID:  row.OffenseID,
+			UserID:     row.UserID,
+			Amount:     row.AllocatedAmount,
+			ProofType:  row.ProofType,
+			ProofUrl:   row.ProofUrl,
+			Verified:   row.Verified,
+			VerifiedBy: row.VerifiedBy,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Rejected:   row.Rejected,
+		})
+		payments[i] = *payment
+	}
+
+	return payments, nil
+}
+
+func (s *OffenseService) sqlcPaymentToModel(payment sqlc.Payment) *models.Payment {
 	var proofType *string
 	if payment.ProofType.Valid {
 		proofType = &payment.ProofType.String
@@ -410,6 +859,12 @@ func (s *OffenseService) sqlcPaymentToModel(payment sqlc.Payment) *models.Paymen
 		proofURL = &payment.ProofUrl.String
 	}
 
+	var offenseID *int
+	if payment.OffenseID.Valid {
+		offenseIDInt := int(payment.OffenseID.Int32)
+		offenseID = &offenseIDInt
+	}
+
 	var verifiedBy *int
 	if payment.VerifiedBy.Valid {
 		verifiedByInt := int(payment.VerifiedBy.Int32)
@@ -418,14 +873,15 @@ func (s *OffenseService) sqlcPaymentToModel(payment sqlc.Payment) *models.Paymen
 
 	return &models.Payment{
 		ID:         int(payment.ID),
-		OffenseID:  int(payment.OffenseID),
+		OffenseID:  offenseID,
 		UserID:     int(payment.UserID),
-		Amount:     amount,
+		Amount:     money.FromNumericPtr(payment.Amount),
 		ProofType:  proofType,
 		ProofURL:   proofURL,
 		Verified:   payment.Verified,
 		VerifiedBy: verifiedBy,
+		Rejected:   payment.Rejected,
 		CreatedAt:  payment.CreatedAt.Time,
 		UpdatedAt:  payment.UpdatedAt.Time,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/money"
+)
+
+// TestCreateOffenseChecksJar reports into a jar without rules, which every
+// report path reaches, with an offender and offense type from elsewhere
+func TestCreateOffenseChecksJar(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	bus := events.NewMemoryBus()
+	offenseService := NewOffenseService(db, bus)
+	tipJarService := NewTipJarService(db, bus)
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+	outsider := dbtest.User(t, db, "outsider")
+
+	jar, err := tipJarService.CreateTipJar(ctx, "Plain", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := tipJarService.CreateTipJar(ctx, "Other", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, jarID := range []int{jar.ID, other.ID} {
+		if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jarID), UserID: member.ID, Role: authz.Member}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cost, unit := money.FromInt(5), "dollars"
+	offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherType, err := offenseService.CreateOffenseType(ctx, other.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := offenseService.CreateOffenseType(ctx, jar.ID, "Retired", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := offenseService.SetOffenseTypeActiveStatus(ctx, retired.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		offenseTypeID int
+		offenderID    int32
+		want          error
+	}{
+		{"member", offenseType.ID, member.ID, nil},
+		{"non-member", offenseType.ID, outsider.ID, ErrMemberNotFound},
+		{"another jar's offense type", otherType.ID, member.ID, ErrOffenseTypeNotInJar},
+		{"inactive offense type", retired.ID, member.ID, ErrOffenseTypeNotInJar},
+		{"missing offense type", -1, member.ID, ErrOffenseTypeNotInJar},
+	}
+
+	for _, tt := range tests {
+		_, err := offenseService.CreateOffense(ctx, jar.ID, tt.offenseTypeID, int(owner.ID), int(tt.offenderID), "", nil)
+		if err != tt.want {
+			t.Errorf("%s: CreateOffense = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	offenses, err := offenseService.ListOffensesForJar(ctx, jar.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offenses) != 1 {
+		t.Errorf("jar has %d offenses, want only the member's", len(offenses))
+	}
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrPeriodClosed      = errors.New("this offense's period is closed, so it can't be changed")
+	ErrPeriodUnsettled   = errors.New("settle open disputes and payments awaiting verification before closing the period")
+	ErrInvalidPeriodName = errors.New("period names must be 1 to 100 characters")
+)
+
+// FirstPeriodName is what a new jar's first period is called until it's
+// closed under another name
+const FirstPeriodName = "First period"
+
+// maxPeriodNameLength matches the jar_periods.name column
+const maxPeriodNameLength = 100
+
+// PeriodService handles a jar's accounting periods. Offenses count towards
+// the jar's one open period; closing it records everyone's final standings,
+// locks its offenses and starts the next.
+type PeriodService struct {
+	db     *database.DB
+	events events.Bus
+}
+
+func NewPeriodService(db *database.DB, bus events.Bus) *PeriodService {
+	return &PeriodService{db: db, events: bus}
+}
+
+// ListPeriods returns the jar's periods, newest first, so the open one leads
+func (s *PeriodService) ListPeriods(ctx context.Context, jarID int) ([]models.JarPeriod, error) {
+	rows, err := s.db.ListJarPeriods(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	periods := make([]models.JarPeriod, len(rows))
+	for i, row := range rows {
+		periods[i] = *sqlcPeriodToModel(sqlc.JarPeriod{
+			ID:          row.ID,
+			JarID:       row.JarID,
+			Name:        row.Name,
+			StartedAt:   row.StartedAt,
+			ClosedAt:    row.ClosedAt,
+			ClosedBy:    row.ClosedBy,
+			CarriedOver: row.CarriedOver,
+		})
+		if row.ClosedByName.Valid {
+			periods[i].ClosedByName = &row.ClosedByName.String
+		}
+	}
+
+	return periods, nil
+}
+
+// GetPeriod returns a period, or nil if it doesn't exist
+func (s *PeriodService) GetPeriod(ctx context.Context, periodID int) (*models.JarPeriod, error) {
+	period, err := s.db.GetJarPeriod(ctx, int32(periodID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return sqlcPeriodToModel(period), nil
+}
+
+// GetOpenPeriod returns the period the jar's offenses count towards now
+func (s *PeriodService) GetOpenPeriod(ctx context.Context, jarID int) (*models.JarPeriod, error) {
+	period, err := s.db.GetOpenJarPeriod(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlcPeriodToModel(period), nil
+}
+
+// GetLedger returns a page of the period's offenses, newest first. Besides
+// those reported in it, that includes offenses carried in from earlier
+// periods and, once closed, those it carried on to the next.
+func (s *PeriodService) GetLedger(ctx context.Context, periodID, limit, offset int) ([]models.JarActivity, error) {
+	rows, err := s.db.ListOffensesForPeriod(ctx, sqlc.ListOffensesForPeriodParams{
+		PeriodID: int32(periodID),
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]models.JarActivity, len(rows))
+	for i, row := range rows {
+		var notes *string
+		if row.Notes.Valid {
+			notes = &row.Notes.String
+		}
+
+		var unit *string
+		if row.CostUnit.Valid {
+			unit = &row.CostUnit.String
+		}
+
+		activities[i] = models.JarActivity{
+			ID:               int(row.ID),
+			OffenseTypeName:  row.OffenseTypeName,
+			ReporterID:       int(row.ReporterID),
+			ReporterName:     row.ReporterName,
+			OffenderID:       int(row.OffenderID),
+			OffenderName:     row.OffenderName,
+			Notes:            notes,
+			CostAmount:       money.FromNumericPtr(row.CostAmount),
+			CostUnit:         unit,
+			Status:           row.Status,
+			PeriodID:         int(row.PeriodID),
+			ReportedPeriodID: int(row.ReportedPeriodID),
+			CreatedAt:        row.CreatedAt.Time,
+		}
+	}
+
+	return activities, nil
+}
+
+// GetStandings returns the balances recorded when the period closed, grouped
+// by member. Open periods have none yet.
+func (s *PeriodService) GetStandings(ctx context.Context, periodID int) ([]models.PeriodStandingSummary, error) {
+	rows, err := s.db.ListJarPeriodBalances(ctx, int32(periodID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows arrive grouped by member, so consecutive rows share a summary
+	var summaries []models.PeriodStandingSummary
+
+	for _, row := range rows {
+		var avatar *string
+		if row.Avatar.Valid {
+			avatar = &row.Avatar.String
+		}
+
+		if len(summaries) == 0 || summaries[len(summaries)-1].UserID != int(row.UserID) {
+			summaries = append(summaries, models.PeriodStandingSummary{
+				UserID: int(row.UserID),
+				Name:   row.UserName,
+				Avatar: avatar,
+			})
+		}
+
+		summary := &summaries[len(summaries)-1]
+		summary.Standings = append(summary.Standings, models.PeriodStanding{
+			UserID:       int(row.UserID),
+			Name:         row.UserName,
+			Avatar:       avatar,
+			Unit:         row.Unit,
+			OffenseCount: int(row.OffenseCount),
+			Total:        money.MustFromNumeric(row.TotalAmount),
+			Paid:         money.MustFromNumeric(row.PaidAmount),
+			Unpaid:       money.MustFromNumeric(row.UnpaidAmount),
+		})
+		summary.TotalOffenses += int(row.OffenseCount)
+	}
+
+	return summaries, nil
+}
+
+// ClosePeriod closes the jar's open period and starts the next one, called
+// nextName. The closing period is renamed to name unless it's empty. Each
+// member's final standings are recorded and the period's offenses are locked;
+// with carryOver, offenses still owed move to the new period instead and can
+// be paid there. Open disputes and payments awaiting verification have to be
+// settled first. It returns the new period.
+func (s *PeriodService) ClosePeriod(ctx context.Context, jarID, closedBy int, name, nextName string, carryOver bool) (*models.JarPeriod, error) {
+	name = strings.TrimSpace(name)
+	nextName = strings.TrimSpace(nextName)
+	if nextName == "" || len(nextName) > maxPeriodNameLength || len(name) > maxPeriodNameLength {
+		return nil, ErrInvalidPeriodName
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	// Offenses being reported or paid hold the period, so this waits for them
+	period, err := qtx.GetOpenJarPeriodForUpdate(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = period.Name
+	}
+
+	unsettled, err := qtx.CountUnsettledOffensesInPeriod(ctx, period.ID)
+	if err != nil {
+		return nil, err
+	}
+	if unsettled > 0 {
+		return nil, ErrPeriodUnsettled
+	}
+
+	// Record the standings before carrying over takes offenses out
+	if err := qtx.SnapshotJarPeriodBalances(ctx, period.ID); err != nil {
+		return nil, err
+	}
+
+	closed, err := qtx.CloseJarPeriod(ctx, sqlc.CloseJarPeriodParams{
+		ID:          period.ID,
+		Name:        name,
+		ClosedBy:    pgtype.Int4{Int32: int32(closedBy), Valid: true},
+		CarriedOver: carryOver,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := qtx.CreateJarPeriod(ctx, sqlc.CreateJarPeriodParams{
+		JarID: closed.JarID,
+		Name:  nextName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var carried int64
+	if carryOver {
+		carried, err = qtx.CarryOverOffenses(ctx, sqlc.CarryOverOffensesParams{
+			ToPeriodID:   next.ID,
+			FromPeriodID: closed.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message := fmt.Sprintf("closed %s and started %s", closed.Name, next.Name)
+	switch {
+	case carried == 1:
+		message += ", carrying over 1 unpaid offense"
+	case carried > 1:
+		message += fmt.Sprintf(", carrying over %d unpaid offenses", carried)
+	}
+	if err := recordJarEvent(ctx, qtx, closed.JarID, &closedBy, nil, "period.closed", message); err != nil {
+		return nil, err
+	}
+
+	raised := newJarEvents(qtx)
+	err = raised.add(ctx, closed.JarID, "period.closed", periodEventData{
+		ActorID:         int32(closedBy),
+		PeriodID:        closed.ID,
+		Name:            closed.Name,
+		NextPeriodID:    next.ID,
+		NextName:        next.Name,
+		CarriedOffenses: carried,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	raised.publish(ctx, s.events)
+
+	return sqlcPeriodToModel(next), nil
+}
+
+// openPeriod returns the jar's open period, locked so it can't close until the
+// caller's transaction is done. Lock it before any of its offenses, in the
+// same order as closing does.
+func openPeriod(ctx context.Context, q *sqlc.Queries, jarID int32) (sqlc.JarPeriod, error) {
+	period, err := q.GetOpenJarPeriodForShare(ctx, jarID)
+	if err == pgx.ErrNoRows {
+		// It closed while we waited for the lock, and its successor is open
+		period, err = q.GetOpenJarPeriodForShare(ctx, jarID)
+	}
+	return period, err
+}
+
+func sqlcPeriodToModel(period sqlc.JarPeriod) *models.JarPeriod {
+	var closedAt *time.Time
+	if period.ClosedAt.Valid {
+		closedAt = &period.ClosedAt.Time
+	}
+
+	var closedBy *int
+	if period.ClosedBy.Valid {
+		closedByInt := int(period.ClosedBy.Int32)
+		closedBy = &closedByInt
+	}
+
+	return &models.JarPeriod{
+		ID:          int(period.ID),
+		JarID:       int(period.JarID),
+		Name:        period.Name,
+		StartedAt:   period.StartedAt.Time,
+		ClosedAt:    closedAt,
+		ClosedBy:    closedBy,
+		CarriedOver: period.CarriedOver,
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/money"
+)
+
+func TestClosePeriodCountsPaymentsOnce(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	bus := events.NewMemoryBus()
+	offenseService := NewOffenseService(db, bus)
+	periodService := NewPeriodService(db, bus)
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+	jar, err := NewTipJarService(db, bus).CreateTipJar(ctx, "Carried", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jar.ID), UserID: member.ID, Role: authz.Member}); err != nil {
+		t.Fatal(err)
+	}
+
+	cost, unit := money.FromInt(5), "dollars"
+	offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	offense, err := offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, int(owner.ID), int(member.ID), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pay := func(amount int64) {
+		t.Helper()
+		paid := money.FromInt(amount)
+		if _, err := offenseService.PayOffenses(ctx, int(member.ID), []int{offense.ID}, &paid, nil, nil); err != nil {
+			t.Fatalf("PayOffenses: %v", err)
+		}
+	}
+	closePeriod := func(name string, total, paid, unpaid int64) {
+		t.Helper()
+		open, err := periodService.GetOpenPeriod(ctx, jar.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := periodService.ClosePeriod(ctx, jar.ID, int(owner.ID), name, "Next", true); err != nil {
+			t.Fatalf("ClosePeriod: %v", err)
+		}
+
+		summaries, err := periodService.GetStandings(ctx, open.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summaries) != 1 || len(summaries[0].Standings) != 1 {
+			t.Fatalf("%s standings = %+v, want one for the member", name, summaries)
+		}
+		got := summaries[0].Standings[0]
+		if got.Total != money.FromInt(total) || got.Paid != money.FromInt(paid) || got.Unpaid != money.FromInt(unpaid) {
+			t.Errorf("%s: total %s, paid %s, unpaid %s; want %d, %d, %d", name, got.Total, got.Paid, got.Unpaid, total, paid, unpaid)
+		}
+	}
+
+	pay(2)
+	closePeriod("First", 5, 2, 3)
+
+	// The offense is carried over still owing 3; only what's paid towards it
+	// now counts as paid in the second period
+	pay(1)
+	closePeriod("Second", 5, 1, 2)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"tipjar/internal/storage"
+)
+
+var (
+	ErrProofTooLarge        = errors.New("proof file is too large")
+	ErrUnsupportedProofType = errors.New("proof must be an image, PDF receipt or video")
+)
+
+// proofKinds maps sniffed content types to the payments.proof_type value and
+// the extension the file is stored under
+var proofKinds = map[string]struct {
+	proofType string
+	ext       string
+}{
+	"image/jpeg":      {"image", ".jpg"},
+	"image/png":       {"image", ".png"},
+	"image/gif":       {"image", ".gif"},
+	"image/webp":      {"image", ".webp"},
+	"application/pdf": {"receipt", ".pdf"},
+	"video/mp4":       {"video", ".mp4"},
+	"video/webm":      {"video", ".webm"},
+}
+
+type ProofService struct {
+	store    storage.Storage
+	maxBytes int64
+}
+
+func NewProofService(store storage.Storage, maxBytes int64) *ProofService {
+	return &ProofService{store: store, maxBytes: maxBytes}
+}
+
+// MaxBytes is the largest proof file accepted
+func (s *ProofService) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Upload checks the file's size and sniffed content type, then stores it
+// under the jar's prefix. It returns the storage key and the proof type.
+func (s *ProofService) Upload(ctx context.Context, jarID int, r io.Reader, size int64) (string, string, error) {
+	if size > s.maxBytes {
+		return "", "", ErrProofTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", "", err
+	}
+	head = head[:n]
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	kind, ok := proofKinds[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedProofType
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", "", err
+	}
+	key := fmt.Sprintf("proofs/%d/%s%s", jarID, hex.EncodeToString(name), kind.ext)
+
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), r), size)
+	if err := s.store.Put(ctx, key, body, size, contentType); err != nil {
+		return "", "", err
+	}
+
+	return key, kind.proofType, nil
+}
+
+// Open returns the stored proof along with the content type to serve it as
+func (s *ProofService) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	rc, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := "application/octet-stream"
+	for ct, kind := range proofKinds {
+		if kind.ext == path.Ext(key) {
+			contentType = ct
+			break
+		}
+	}
+
+	return rc, contentType, nil
+}
+
+// Delete removes a stored proof, used to clean up when recording a payment fails
+func (s *ProofService) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
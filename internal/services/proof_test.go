@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"tipjar/internal/storage"
+)
+
+var (
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpegHeader = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	pdfHeader  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	webmHeader = []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\xf7\x81\x01\x42\xf2\x81\x04\x42\xf3\x81\x08\x42\x82\x84webm")
+)
+
+func newTestProofService(t *testing.T, maxBytes int64) *ProofService {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProofService(store, maxBytes)
+}
+
+func TestProofUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		proofType   string
+		ext         string
+		contentType string
+	}{
+		{"png", pngHeader, "image", ".png", "image/png"},
+		{"jpeg", jpegHeader, "image", ".jpg", "image/jpeg"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image", ".gif", "image/gif"},
+		{"pdf", pdfHeader, "receipt", ".pdf", "application/pdf"},
+		{"webm", webmHeader, "video", ".webm", "video/webm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestProofService(t, 1024)
+			data := append(append([]byte{}, tt.data...), bytes.Repeat([]byte{0}, 600)...)
+
+			key, proofType, err := s.Upload(ctx, 7, bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("Upload: %v", err)
+			}
+			if proofType != tt.proofType {
+				t.Errorf("proof type = %q, want %q", proofType, tt.proofType)
+			}
+			if !strings.HasPrefix(key, "proofs/7/") || !strings.HasSuffix(key, tt.ext) {
+				t.Errorf("key = %q, want proofs/7/*%s", key, tt.ext)
+			}
+
+			rc, contentType, err := s.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			stored, _ := io.ReadAll(rc)
+			rc.Close()
+			if !bytes.Equal(stored, data) {
+				t.Errorf("stored %d bytes, want the %d uploaded", len(stored), len(data))
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.contentType)
+			}
+
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, _, err := s.Open(ctx, key); err != storage.ErrNotFound {
+				t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestProofUploadRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		size int64
+		want error
+	}{
+		{"plain text", []byte("paid you in cash, promise"), -1, ErrUnsupportedProofType},
+		{"html", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), -1, ErrUnsupportedProofType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), -1, ErrUnsupportedProofType},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00"), -1, ErrUnsupportedProofType},
+		{"empty", nil, -1, ErrUnsupportedProofType},
+		{"oversized", pngHeader, 1025, ErrProofTooLarge},
+		{"far too large", pdfHeader, 1 << 30, ErrProofTooLarge},
+		{"exactly the limit", pngHeader, 1024, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestProofService(t, 1024)
+			size := tt.size
+			if size < 0 {
+				size = int64(len(tt.data))
+			}
+
+			key, _, err := s.Upload(context.Background(), 1, bytes.NewReader(tt.data), size)
+			if err != tt.want {
+				t.Fatalf("Upload = %q, %v; want %v", key, err, tt.want)
+			}
+			if err != nil && key != "" {
+				t.Errorf("rejected upload returned key %q", key)
+			}
+		})
+	}
+}
+
+func TestProofUploadStopsAtDeclaredSize(t *testing.T) {
+	ctx := context.Background()
+	s := newTestProofService(t, 1024)
+
+	// A client that declares a small file but keeps sending must not get
+	// more than it declared into storage
+	data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 4096)...)
+	key, _, err := s.Upload(ctx, 1, bytes.NewReader(data), 100)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	rc, _, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	stored, _ := io.ReadAll(rc)
+	if len(stored) != 100 {
+		t.Errorf("stored %d bytes, want 100", len(stored))
+	}
+}
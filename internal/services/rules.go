@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrOffenseLimitReached = errors.New("this member has been reported as many times as the jar allows for now")
+	ErrReportCooldown      = errors.New("you reported this member too recently")
+	ErrInvalidJarRules     = errors.New("jar rules are out of range")
+)
+
+// Bounds on what admins can set, so a typo can't make a jar unusable
+const (
+	maxOffensesPerDayLimit   = 100
+	maxReportCooldownMinutes = 7 * 24 * 60
+	maxEscalationWindowDays  = 365
+)
+
+// maxEscalationMultiplier is the steepest escalation admins can set. The
+// column would take up to 99.99, but a handful of repeats at that rate is
+// already as much as a cost can be.
+var maxEscalationMultiplier = money.FromInt(10)
+
+// RuleService manages the rules a jar puts on reporting offenses: how often a
+// member can be reported, how soon a reporter can report them again, and how
+// repeat offenses cost more. OffenseService applies them.
+type RuleService struct {
+	db *database.DB
+}
+
+func NewRuleService(db *database.DB) *RuleService {
+	return &RuleService{db: db}
+}
+
+// GetRules returns the jar's rules, all off if it has never set any
+func (s *RuleService) GetRules(ctx context.Context, jarID int) (*models.JarRules, error) {
+	rules, err := s.db.GetJarRules(ctx, int32(jarID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &models.JarRules{JarID: jarID}, nil
+		}
+		return nil, err
+	}
+
+	return sqlcJarRulesToModel(rules), nil
+}
+
+// UpdateRules replaces the jar's rules. Leave a field nil to turn that rule
+// off; the escalation window and multiplier go together.
+func (s *RuleService) UpdateRules(ctx context.Context, jarID, updatedBy int, maxPerDay, cooldownMinutes, windowDays *int, multiplier *money.Amount) (*models.JarRules, error) {
+	if !inRange(maxPerDay, maxOffensesPerDayLimit) ||
+		!inRange(cooldownMinutes, maxReportCooldownMinutes) ||
+		!inRange(windowDays, maxEscalationWindowDays) ||
+		(windowDays == nil) != (multiplier == nil) {
+		return nil, ErrInvalidJarRules
+	}
+	if multiplier != nil && (multiplier.Cmp(money.FromInt(1)) <= 0 || multiplier.Cmp(maxEscalationMultiplier) > 0) {
+		return nil, ErrInvalidJarRules
+	}
+
+	rules, err := s.db.UpsertJarRules(ctx, sqlc.UpsertJarRulesParams{
+		JarID:                 int32(jarID),
+		MaxOffensesPerDay:     int4Ptr(maxPerDay),
+		ReportCooldownMinutes: int4Ptr(cooldownMinutes),
+		EscalationWindowDays:  int4Ptr(windowDays),
+		EscalationMultiplier:  money.NumericPtr(multiplier),
+		UpdatedBy:             pgtype.Int4{Int32: int32(updatedBy), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlcJarRulesToModel(rules), nil
+}
+
+// applyJarRules checks a new offense against its jar's rules, rejecting it if
+// the offender has hit the daily limit or the reporter is still cooling down,
+// and escalates its cost for repeat offenses. A cost the reporter set by hand
+// is kept as it is. Run it in the transaction that creates the offense, with
+// the offender's membership locked so concurrent reports against them can't
+// all pass the limit together.
+func applyJarRules(ctx context.Context, q *sqlc.Queries, params *sqlc.CreateOffenseParams) error {
+	rules, err := q.GetJarRules(ctx, params.JarID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if rules.MaxOffensesPerDay.Valid {
+		count, err := q.CountOffensesAgainstSince(ctx, sqlc.CountOffensesAgainstSinceParams{
+			JarID:      params.JarID,
+			OffenderID: params.OffenderID,
+			Minutes:    int32((24 * time.Hour).Minutes()),
+		})
+		if err != nil {
+			return err
+		}
+		if count >= int64(rules.MaxOffensesPerDay.Int32) {
+			return fmt.Errorf("%w: the limit is %d in 24 hours", ErrOffenseLimitReached, rules.MaxOffensesPerDay.Int32)
+		}
+	}
+
+	if rules.ReportCooldownMinutes.Valid {
+		left, err := q.GetReportCooldownRemaining(ctx, sqlc.GetReportCooldownRemainingParams{
+			CooldownMinutes: rules.ReportCooldownMinutes.Int32,
+			JarID:           params.JarID,
+			ReporterID:      params.ReporterID,
+			OffenderID:      params.OffenderID,
+		})
+		if err != nil {
+			return err
+		}
+		if left > 0 {
+			return fmt.Errorf("%w; you can report them again in %s", ErrReportCooldown, minutesText(left))
+		}
+	}
+
+	if rules.EscalationWindowDays.Valid && rules.EscalationMultiplier.Valid && params.CostAmount.Valid && !params.CostOverride.Valid {
+		repeats, err := q.CountRepeatOffensesSince(ctx, sqlc.CountRepeatOffensesSinceParams{
+			JarID:         params.JarID,
+			OffenderID:    params.OffenderID,
+			OffenseTypeID: params.OffenseTypeID,
+			Days:          rules.EscalationWindowDays.Int32,
+		})
+		if err != nil {
+			return err
+		}
+		cost := money.MustFromNumeric(params.CostAmount)
+		params.CostAmount = escalate(cost, money.MustFromNumeric(rules.EscalationMultiplier), repeats).Numeric()
+	}
+
+	return nil
+}
+
+// escalate multiplies cost by multiplier once per repeat, rounding each time
+// and stopping at the most a cost can be
+func escalate(cost, multiplier money.Amount, repeats int64) money.Amount {
+	for i := int64(0); i < repeats; i++ {
+		cost = cost.Mul(multiplier)
+		if cost.Cmp(money.Max) >= 0 {
+			return money.Max
+		}
+	}
+	return cost
+}
+
+func minutesText(minutes int32) string {
+	if minutes == 1 {
+		return "1 minute"
+	}
+	if minutes < 120 {
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return fmt.Sprintf("%d hours", (minutes+59)/60)
+}
+
+func inRange(n *int, max int) bool {
+	return n == nil || (*n > 0 && *n <= max)
+}
+
+func int4Ptr(n *int) pgtype.Int4 {
+	if n == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(*n), Valid: true}
+}
+
+func sqlcJarRulesToModel(rules sqlc.JarRule) *models.JarRules {
+	model := &models.JarRules{
+		JarID:                int(rules.JarID),
+		EscalationMultiplier: money.FromNumericPtr(rules.EscalationMultiplier),
+	}
+	if rules.MaxOffensesPerDay.Valid {
+		n := int(rules.MaxOffensesPerDay.Int32)
+		model.MaxOffensesPerDay = &n
+	}
+	if rules.ReportCooldownMinutes.Valid {
+		n := int(rules.ReportCooldownMinutes.Int32)
+		model.ReportCooldownMinutes = &n
+	}
+	if rules.EscalationWindowDays.Valid {
+		n := int(rules.EscalationWindowDays.Int32)
+		model.EscalationWindowDays = &n
+	}
+	if rules.UpdatedBy.Valid {
+		n := int(rules.UpdatedBy.Int32)
+		model.UpdatedBy = &n
+	}
+	if rules.UpdatedAt.Valid {
+		model.UpdatedAt = &rules.UpdatedAt.Time
+	}
+	return model
+}
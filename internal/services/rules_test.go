@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/money"
+)
+
+func TestEscalate(t *testing.T) {
+	tests := []struct {
+		cost, multiplier string
+		repeats          int64
+		want             string
+	}{
+		{"5.00", "2.00", 0, "5.00"},
+		{"5.00", "2.00", 1, "10.00"},
+		{"5.00", "2.00", 3, "40.00"},
+		{"3.33", "1.50", 2, "7.50"}, // 4.995 rounds to 5.00 before the second step
+		{"10.00", "10.00", 100, money.Max.String()},
+	}
+
+	for _, tt := range tests {
+		cost, _ := money.Parse(tt.cost)
+		multiplier, _ := money.Parse(tt.multiplier)
+		if got := escalate(cost, multiplier, tt.repeats); got.String() != tt.want {
+			t.Errorf("escalate(%s, %s, %d) = %s, want %s", tt.cost, tt.multiplier, tt.repeats, got, tt.want)
+		}
+	}
+}
+
+func TestCreateOffenseEscalation(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	bus := events.NewMemoryBus()
+	offenseService := NewOffenseService(db, bus)
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+	jar, err := NewTipJarService(db, bus).CreateTipJar(ctx, "Strict", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jar.ID), UserID: member.ID, Role: authz.Member}); err != nil {
+		t.Fatal(err)
+	}
+
+	cost, unit := money.FromInt(5), "dollars"
+	offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window, multiplier := 7, money.FromInt(2)
+	if _, err := NewRuleService(db).UpdateRules(ctx, jar.ID, int(owner.ID), nil, nil, &window, &multiplier); err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(override *money.Amount) *money.Amount {
+		t.Helper()
+		offense, err := offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, int(owner.ID), int(member.ID), "", override)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if override != nil && (offense.CostOverride == nil || *offense.CostOverride != *offense.CostAmount) {
+			t.Errorf("override stored as %v but charged %v", offense.CostOverride, offense.CostAmount)
+		}
+		return offense.CostAmount
+	}
+
+	if got := report(nil); *got != money.FromInt(5) {
+		t.Errorf("first offense costs %s, want 5.00", got)
+	}
+	if got := report(nil); *got != money.FromInt(10) {
+		t.Errorf("second offense costs %s, want 10.00", got)
+	}
+
+	// A cost set by hand is charged as it is, whatever the repeats
+	override := money.FromInt(3)
+	if got := report(&override); *got != override {
+		t.Errorf("overridden offense costs %s, want 3.00", got)
+	}
+
+	// Overridden offenses still count as repeats
+	if got := report(nil); *got != money.FromInt(40) {
+		t.Errorf("fourth offense costs %s, want 40.00", got)
+	}
+
+	// Someone who isn't in the jar can't be reported, even if the handler's
+	// own check was raced
+	outsider := dbtest.User(t, db, "outsider")
+	_, err = offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, int(owner.ID), int(outsider.ID), "", nil)
+	if err != ErrMemberNotFound {
+		t.Errorf("reporting a non-member = %v, want ErrMemberNotFound", err)
+	}
+}
@@ -1,83 +1,145 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
 	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const sessionDuration = 24 * time.Hour * 7 // 7 days
+
 type SessionService struct {
+	db     *database.DB
 	secret []byte
 }
 
-type SessionData struct {
-	UserID    int       `json:"user_id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	ExpiresAt time.Time `json:"expires_at"`
-}
-
-func NewSessionService(secret string) *SessionService {
+func NewSessionService(db *database.DB, secret string) *SessionService {
 	return &SessionService{
+		db:     db,
 		secret: []byte(secret),
 	}
 }
 
-func (s *SessionService) CreateSession(userID int, email, name string) (string, error) {
-	sessionData := SessionData{
-		UserID:    userID,
-		Email:     email,
-		Name:      name,
-		ExpiresAt: time.Now().Add(24 * time.Hour * 7), // 7 days
+// CreateSession stores a new session for the user and returns the token to
+// put in the cookie. Only a keyed hash of the token is kept in the database.
+func (s *SessionService) CreateSession(ctx context.Context, userID int, userAgent, ipAddress string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	var userAgentText pgtype.Text
+	if userAgent != "" {
+		userAgentText = pgtype.Text{String: userAgent, Valid: true}
 	}
 
-	jsonData, err := json.Marshal(sessionData)
+	var ipAddressText pgtype.Text
+	if ipAddress != "" {
+		ipAddressText = pgtype.Text{String: ipAddress, Valid: true}
+	}
+
+	_, err := s.db.CreateSession(ctx, sqlc.CreateSessionParams{
+		UserID:    int32(userID),
+		TokenHash: s.sign(token),
+		UserAgent: userAgentText,
+		IpAddress: ipAddressText,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(sessionDuration), Valid: true},
+	})
 	if err != nil {
 		return "", err
 	}
 
-	encoded := base64.URLEncoding.EncodeToString(jsonData)
-	signature := s.sign(encoded)
-
-	return fmt.Sprintf("%s.%s", encoded, signature), nil
+	return token, nil
 }
 
-func (s *SessionService) ValidateSession(sessionToken string) (*SessionData, error) {
-	parts := strings.Split(sessionToken, ".")
-	if len(parts) != 2 {
+// ValidateSession looks the token up in the session store and records the
+// session as recently seen.
+func (s *SessionService) ValidateSession(ctx context.Context, sessionToken string) (*models.Session, error) {
+	if sessionToken == "" {
 		return nil, fmt.Errorf("invalid session format")
 	}
 
-	encoded, signature := parts[0], parts[1]
+	session, err := s.db.GetSessionByTokenHash(ctx, s.sign(sessionToken))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("session not found or expired")
+		}
+		return nil, err
+	}
 
-	// Verify signature
-	expectedSignature := s.sign(encoded)
-	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
-		return nil, fmt.Errorf("invalid session signature")
+	if err := s.db.TouchSession(ctx, session.ID); err != nil {
+		return nil, err
 	}
 
-	// Decode session data
-	jsonData, err := base64.URLEncoding.DecodeString(encoded)
+	return s.sqlcSessionToModel(session), nil
+}
+
+func (s *SessionService) ListSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	sessions, err := s.db.ListSessionsForUser(ctx, int32(userID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode session: %w", err)
+		return nil, err
 	}
 
-	var sessionData SessionData
-	if err := json.Unmarshal(jsonData, &sessionData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	result := make([]models.Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = *s.sqlcSessionToModel(session)
 	}
 
-	// Check expiration
-	if time.Now().After(sessionData.ExpiresAt) {
-		return nil, fmt.Errorf("session expired")
-	}
+	return result, nil
+}
+
+// RevokeSession deletes one of the user's sessions
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	return s.db.DeleteSession(ctx, sqlc.DeleteSessionParams{
+		ID:     int32(sessionID),
+		UserID: int32(userID),
+	})
+}
+
+// RevokeAllSessions logs the user out on every device
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	return s.db.DeleteSessionsForUser(ctx, int32(userID))
+}
+
+// DeleteSession removes the session belonging to a token, used on logout
+func (s *SessionService) DeleteSession(ctx context.Context, sessionToken string) error {
+	return s.db.DeleteSessionByTokenHash(ctx, s.sign(sessionToken))
+}
 
-	return &sessionData, nil
+// StartCleanup removes expired sessions every interval until ctx is done
+func (s *SessionService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.db.DeleteExpiredSessions(ctx)
+			if err != nil {
+				slog.Error("Failed to clean up expired sessions", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Cleaned up expired sessions", "count", deleted)
+			}
+		}
+	}
 }
 
 func (s *SessionService) SetSessionCookie(w http.ResponseWriter, sessionToken string) {
@@ -85,7 +147,7 @@ func (s *SessionService) SetSessionCookie(w http.ResponseWriter, sessionToken st
 		Name:     "session",
 		Value:    sessionToken,
 		Path:     "/",
-		MaxAge:   int((24 * time.Hour * 7).Seconds()), // 7 days
+		MaxAge:   int(sessionDuration.Seconds()),
 		HttpOnly: true,
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
@@ -104,8 +166,30 @@ func (s *SessionService) ClearSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, cookie)
 }
 
+func (s *SessionService) sqlcSessionToModel(session sqlc.Session) *models.Session {
+	var userAgent *string
+	if session.UserAgent.Valid {
+		userAgent = &session.UserAgent.String
+	}
+
+	var ipAddress *string
+	if session.IpAddress.Valid {
+		ipAddress = &session.IpAddress.String
+	}
+
+	return &models.Session{
+		ID:         int(session.ID),
+		UserID:     int(session.UserID),
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  session.CreatedAt.Time,
+		LastSeenAt: session.LastSeenAt.Time,
+		ExpiresAt:  session.ExpiresAt.Time,
+	}
+}
+
 func (s *SessionService) sign(data string) string {
 	h := hmac.New(sha256.New, s.secret)
 	h.Write([]byte(data))
 	return base64.URLEncoding.EncodeToString(h.Sum(nil))
-}
\ No newline at end of file
+}
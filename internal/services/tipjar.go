@@ -2,61 +2,50 @@ package services
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
-	"math/big"
-	"strconv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"tipjar/internal/authz"
 	"tipjar/internal/database"
 	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/invitecode"
 	"tipjar/internal/models"
+	"tipjar/internal/money"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	ErrMemberNotFound  = errors.New("user is not a member of this jar")
+	ErrOwnerRole       = errors.New("the owner can't leave or change role; transfer ownership first")
+	ErrInvalidRole     = errors.New("role must be admin, moderator, member or viewer")
+	ErrNotAllowed      = errors.New("you don't have permission to do that")
+	ErrJarArchived     = errors.New("this jar is archived; restore it to make changes")
+	ErrJarNotArchived  = errors.New("this jar isn't archived")
+	ErrJarNameMismatch = errors.New("type the jar's name exactly to delete it")
+)
+
 type TipJarService struct {
-	db *database.DB
+	db     *database.DB
+	events events.Bus
 }
 
-func NewTipJarService(db *database.DB) *TipJarService {
-	return &TipJarService{db: db}
+func NewTipJarService(db *database.DB, bus events.Bus) *TipJarService {
+	return &TipJarService{db: db, events: bus}
 }
 
+// CreateTipJar creates a jar with a generated invite code
 func (s *TipJarService) CreateTipJar(ctx context.Context, name, description string, createdBy int) (*models.TipJar, error) {
-	inviteCode, err := s.generateInviteCode()
+	inviteCode, err := invitecode.Generate()
 	if err != nil {
 		return nil, err
 	}
 
-	var descText pgtype.Text
-	if description != "" {
-		descText = pgtype.Text{String: description, Valid: true}
-	}
-
-	params := sqlc.CreateTipJarParams{
-		Name:        name,
-		Description: descText,
-		InviteCode:  inviteCode,
-		CreatedBy:   int32(createdBy),
-	}
-
-	jar, err := s.db.CreateTipJar(ctx, params)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create admin membership for creator
-	_, err = s.db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{
-		JarID:  jar.ID,
-		UserID: int32(createdBy),
-		Role:   "admin",
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return s.sqlcTipJarToModel(jar), nil
+	return s.CreateTipJarWithInviteCode(ctx, name, description, inviteCode, createdBy)
 }
 
 func (s *TipJarService) GetTipJar(ctx context.Context, jarID int) (*models.TipJar, error) {
@@ -68,19 +57,7 @@ func (s *TipJarService) GetTipJar(ctx context.Context, jarID int) (*models.TipJa
 		return nil, err
 	}
 
-	return s.sqlcTipJarToModel(jar), nil
-}
-
-func (s *TipJarService) GetTipJarByInviteCode(ctx context.Context, inviteCode string) (*models.TipJar, error) {
-	jar, err := s.db.GetTipJarByInviteCode(ctx, inviteCode)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return s.sqlcTipJarToModel(jar), nil
+	return sqlcTipJarToModel(jar), nil
 }
 
 func (s *TipJarService) ListTipJarsForUser(ctx context.Context, userID int) ([]*models.TipJar, error) {
@@ -91,68 +68,411 @@ func (s *TipJarService) ListTipJarsForUser(ctx context.Context, userID int) ([]*
 
 	result := make([]*models.TipJar, len(jars))
 	for i, jar := range jars {
-		result[i] = s.sqlcTipJarToModel(sqlc.TipJar{
-			ID:          jar.ID,
-			Name:        jar.Name,
-			Description: jar.Description,
-			InviteCode:  jar.InviteCode,
-			CreatedBy:   jar.CreatedBy,
-			CreatedAt:   jar.CreatedAt,
-			UpdatedAt:   jar.UpdatedAt,
-		})
+		result[i] = sqlcTipJarToModel(jar)
 	}
 
 	return result, nil
 }
 
-func (s *TipJarService) JoinTipJar(ctx context.Context, jarID, userID int) error {
-	params := sqlc.CreateJarMembershipParams{
+func (s *TipJarService) IsUserJarMember(ctx context.Context, jarID, userID int) (bool, error) {
+	return s.db.IsUserJarMember(ctx, sqlc.IsUserJarMemberParams{
 		JarID:  int32(jarID),
 		UserID: int32(userID),
-		Role:   "member",
-	}
+	})
+}
 
-	_, err := s.db.CreateJarMembership(ctx, params)
-	return err
+// GetMemberRole returns the user's role in the jar, or "" if they aren't a
+// member
+func (s *TipJarService) GetMemberRole(ctx context.Context, jarID, userID int) (string, error) {
+	return memberRole(ctx, s.db.Queries, int32(jarID), int32(userID))
 }
 
-func (s *TipJarService) IsUserJarMember(ctx context.Context, jarID, userID int) (bool, error) {
-	return s.db.IsUserJarMember(ctx, sqlc.IsUserJarMemberParams{
+func memberRole(ctx context.Context, q *sqlc.Queries, jarID, userID int32) (string, error) {
+	membership, err := q.GetJarMembership(ctx, sqlc.GetJarMembershipParams{
+		JarID:  jarID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return membership.Role, nil
+}
+
+// UpdateMemberRole gives a member a new role on behalf of changedBy, who
+// needs to be allowed to manage members. The owner's role can't be changed
+// this way; see TransferOwnership.
+func (s *TipJarService) UpdateMemberRole(ctx context.Context, jarID, userID int, role string, changedBy int) error {
+	if !authz.IsRole(role) || role == authz.Owner {
+		return ErrInvalidRole
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	current, err := memberRole(ctx, qtx, int32(jarID), int32(userID))
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return ErrMemberNotFound
+	}
+	if current == authz.Owner {
+		return ErrOwnerRole
+	}
+
+	actorRole, err := memberRole(ctx, qtx, int32(jarID), int32(changedBy))
+	if err != nil {
+		return err
+	}
+	if !authz.CanAssign(actorRole, current, role) {
+		return ErrNotAllowed
+	}
+
+	if current == role {
+		return nil
+	}
+
+	_, err = qtx.UpdateMemberRole(ctx, sqlc.UpdateMemberRoleParams{
 		JarID:  int32(jarID),
 		UserID: int32(userID),
+		Role:   role,
 	})
+	if err != nil {
+		return err
+	}
+
+	member, err := qtx.GetUserByID(ctx, int32(userID))
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("made %s %s", member.Name, roleWithArticle(role))
+	if userID == changedBy {
+		message = fmt.Sprintf("stepped down to %s", role)
+	}
+	if err := recordJarEvent(ctx, qtx, int32(jarID), &changedBy, nil, "member.role_changed", message); err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	err = raised.add(ctx, int32(jarID), "member.role_changed", memberEventData{
+		UserID: int32(userID),
+		Role:   role,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
 }
 
-func (s *TipJarService) IsUserJarAdmin(ctx context.Context, jarID, userID int) (bool, error) {
-	return s.db.IsUserJarAdmin(ctx, sqlc.IsUserJarAdminParams{
+// TransferOwnership makes another member the jar's owner. The old owner
+// becomes an admin.
+func (s *TipJarService) TransferOwnership(ctx context.Context, jarID, ownerID, newOwnerID int) error {
+	if ownerID == newOwnerID {
+		return nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	ownerRole, err := memberRole(ctx, qtx, int32(jarID), int32(ownerID))
+	if err != nil {
+		return err
+	}
+	if !authz.Can(ownerRole, authz.TransferOwnership) {
+		return ErrNotAllowed
+	}
+
+	newOwnerRole, err := memberRole(ctx, qtx, int32(jarID), int32(newOwnerID))
+	if err != nil {
+		return err
+	}
+	if newOwnerRole == "" {
+		return ErrMemberNotFound
+	}
+
+	// Step the old owner down first; a jar can only have one owner at a time
+	for _, change := range []struct {
+		userID int
+		role   string
+	}{
+		{ownerID, authz.Admin},
+		{newOwnerID, authz.Owner},
+	} {
+		_, err = qtx.UpdateMemberRole(ctx, sqlc.UpdateMemberRoleParams{
+			JarID:  int32(jarID),
+			UserID: int32(change.userID),
+			Role:   change.role,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	newOwner, err := qtx.GetUserByID(ctx, int32(newOwnerID))
+	if err != nil {
+		return err
+	}
+
+	err = recordJarEvent(ctx, qtx, int32(jarID), &ownerID, nil, "member.role_changed",
+		fmt.Sprintf("handed ownership of the jar to %s", newOwner.Name))
+	if err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	for _, data := range []memberEventData{
+		{UserID: int32(ownerID), Role: authz.Admin},
+		{UserID: int32(newOwnerID), Role: authz.Owner},
+	} {
+		if err := raised.add(ctx, int32(jarID), "member.role_changed", data); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
+}
+
+// RemoveMember takes a user out of a jar, whether they leave (removedBy is
+// themselves) or someone allowed to manage members removes them. The owner
+// can't leave or be removed. Their offenses and payments stay in the jar's
+// history.
+func (s *TipJarService) RemoveMember(ctx context.Context, jarID, userID, removedBy int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	role, err := memberRole(ctx, qtx, int32(jarID), int32(userID))
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrMemberNotFound
+	}
+	if role == authz.Owner {
+		return ErrOwnerRole
+	}
+
+	if userID != removedBy {
+		actorRole, err := memberRole(ctx, qtx, int32(jarID), int32(removedBy))
+		if err != nil {
+			return err
+		}
+		if !authz.CanRemove(actorRole, role) {
+			return ErrNotAllowed
+		}
+	}
+
+	err = qtx.DeleteJarMembership(ctx, sqlc.DeleteJarMembershipParams{
 		JarID:  int32(jarID),
 		UserID: int32(userID),
 	})
+	if err != nil {
+		return err
+	}
+
+	message := "left the jar"
+	if userID != removedBy {
+		member, err := qtx.GetUserByID(ctx, int32(userID))
+		if err != nil {
+			return err
+		}
+		message = fmt.Sprintf("removed %s from the jar", member.Name)
+	}
+	if err := recordJarEvent(ctx, qtx, int32(jarID), &removedBy, nil, "member.left", message); err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	err = raised.add(ctx, int32(jarID), "member.left", memberEventData{
+		UserID: int32(userID),
+		Role:   role,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
 }
 
-func (s *TipJarService) generateInviteCode() (string, error) {
-	b := make([]byte, 6)
-	_, err := rand.Read(b)
+// roleWithArticle reads naturally after "made Alice", e.g. "an admin"
+func roleWithArticle(role string) string {
+	if role == authz.Admin || role == authz.Owner {
+		return "an " + role
+	}
+	return "a " + role
+}
+
+// ArchiveTipJar makes the jar read-only and moves it off the dashboard's main
+// list. Nothing is deleted, and it can be restored at any time.
+func (s *TipJarService) ArchiveTipJar(ctx context.Context, jarID, archivedBy int) error {
+	return s.setArchived(ctx, jarID, archivedBy, true)
+}
+
+// RestoreTipJar takes the jar out of the archive so it can be used again
+func (s *TipJarService) RestoreTipJar(ctx context.Context, jarID, restoredBy int) error {
+	return s.setArchived(ctx, jarID, restoredBy, false)
+}
+
+func (s *TipJarService) setArchived(ctx context.Context, jarID, actorID int, archived bool) error {
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return base64.URLEncoding.EncodeToString(b)[:8], nil
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	var jar sqlc.TipJar
+	eventType, message := "jar.archived", "archived the jar"
+	if archived {
+		jar, err = qtx.ArchiveTipJar(ctx, int32(jarID))
+	} else {
+		eventType, message = "jar.restored", "restored the jar"
+		jar, err = qtx.RestoreTipJar(ctx, int32(jarID))
+	}
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			if archived {
+				return ErrJarArchived
+			}
+			return ErrJarNotArchived
+		}
+		return err
+	}
+
+	if err := recordJarEvent(ctx, qtx, jar.ID, &actorID, nil, eventType, message); err != nil {
+		return err
+	}
+
+	raised := newJarEvents(qtx)
+	if err := raised.add(ctx, jar.ID, eventType, jarEventData{ActorID: int32(actorID), Name: jar.Name}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
 }
 
-func (s *TipJarService) sqlcTipJarToModel(jar sqlc.TipJar) *models.TipJar {
+// DeleteTipJar permanently removes a jar along with its memberships,
+// offenses, payments and everything else that belongs to it. confirmName must
+// match the jar's name, so a jar can't be deleted by a stray click. It returns
+// the storage keys of the deleted payments' proofs for the caller to remove.
+func (s *TipJarService) DeleteTipJar(ctx context.Context, jarID int, confirmName string) ([]string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	jar, err := qtx.GetTipJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(confirmName) != jar.Name {
+		return nil, ErrJarNameMismatch
+	}
+
+	// Payments covering several offenses aren't tied to one by a foreign key,
+	// so they don't cascade with the jar
+	proofs, err := qtx.DeletePaymentsForJar(ctx, jar.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := qtx.DeleteTipJar(ctx, jar.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, proof := range proofs {
+		if proof.Valid {
+			keys = append(keys, proof.String)
+		}
+	}
+	return keys, nil
+}
+
+// checkNotArchived returns ErrJarArchived if the jar is archived, for changes
+// made outside the handlers' permission checks
+func checkNotArchived(ctx context.Context, q *sqlc.Queries, jarID int32) error {
+	jar, err := q.GetTipJar(ctx, jarID)
+	if err != nil {
+		return err
+	}
+	if jar.ArchivedAt.Valid {
+		return ErrJarArchived
+	}
+	return nil
+}
+
+func sqlcTipJarToModel(jar sqlc.TipJar) *models.TipJar {
 	var description *string
 	if jar.Description.Valid {
 		description = &jar.Description.String
 	}
 
+	var archivedAt *time.Time
+	if jar.ArchivedAt.Valid {
+		archivedAt = &jar.ArchivedAt.Time
+	}
+
 	return &models.TipJar{
-		ID:          int(jar.ID),
-		Name:        jar.Name,
-		Description: description,
-		InviteCode:  jar.InviteCode,
-		CreatedBy:   int(jar.CreatedBy),
-		CreatedAt:   jar.CreatedAt.Time,
-		UpdatedAt:   jar.UpdatedAt.Time,
+		ID:                   int(jar.ID),
+		Name:                 jar.Name,
+		Description:          description,
+		CreatedBy:            int(jar.CreatedBy),
+		DisputeQuorum:        int(jar.DisputeQuorum),
+		DisputeWindowHours:   int(jar.DisputeWindowHours),
+		VerificationRequired: jar.VerificationRequired,
+		VerificationsNeeded:  int(jar.VerificationsNeeded),
+		JoinApprovalRequired: jar.JoinApprovalRequired,
+		ArchivedAt:           archivedAt,
+		CreatedAt:            jar.CreatedAt.Time,
+		UpdatedAt:            jar.UpdatedAt.Time,
 	}
 }
 func (s *TipJarService) GetJarMembers(ctx context.Context, jarID int) ([]models.JarMemberInfo, error) {
@@ -215,169 +535,82 @@ func (s *TipJarService) GetJarActivity(ctx context.Context, jarID int, limit int
 	return activities, nil
 }
 
+// GetMemberBalancesByUnit returns what each member still owes grouped by
+// unit, counting pending offenses and whatever payments awaiting verification
+// leave unpaid. Members who owe nothing are left out.
 func (s *TipJarService) GetMemberBalancesByUnit(ctx context.Context, jarID int) ([]models.MemberBalanceSummary, error) {
-	// For now, let's use a simpler approach that works with existing schema
-	// Get all jar members
-	members, err := s.db.ListJarMembers(ctx, int32(jarID))
+	rows, err := s.db.GetJarBalancesByUnit(ctx, int32(jarID))
 	if err != nil {
 		return nil, err
 	}
 
+	// Rows arrive grouped by member, so consecutive rows share a summary
 	var summaries []models.MemberBalanceSummary
 
-	for _, member := range members {
-		// Get all pending offenses for this user in this jar
-		allOffenses, err := s.db.ListOffensesForJar(ctx, sqlc.ListOffensesForJarParams{
-			JarID:  int32(jarID),
-			Limit:  1000, // Get all
-			Offset: 0,
-		})
-		if err != nil {
-			continue
-		}
-
-		// Filter for this user's pending offenses
-		userOffenses := make([]sqlc.ListOffensesForJarRow, 0)
-		for _, offense := range allOffenses {
-			if offense.OffenderID == member.UserID && offense.Status == "pending" {
-				userOffenses = append(userOffenses, offense)
-			}
-		}
-
-		if len(userOffenses) == 0 {
-			continue // Skip users with no pending offenses
-		}
-
-		// Group by unit
-		unitBalances := make(map[string]*models.MemberBalanceByUnit)
-		totalOffenses := 0
-
-		for _, offense := range userOffenses {
-			totalOffenses++
-			
-			var unit string
-			if offense.CostUnit.Valid {
-				unit = offense.CostUnit.String
-			} else {
-				unit = "items"
-			}
-
-			var amount float64
-			if offense.CostOverride.Valid {
-				if val, err := offense.CostOverride.Float64Value(); err == nil && val.Valid {
-					amount = val.Float64
-				}
-			} else if offense.CostAmount.Valid {
-				if val, err := offense.CostAmount.Float64Value(); err == nil && val.Valid {
-					amount = val.Float64
-				}
-			}
-
-			if _, exists := unitBalances[unit]; !exists {
-				var avatar *string
-				if member.Avatar.Valid {
-					avatar = &member.Avatar.String
-				}
-
-				unitBalances[unit] = &models.MemberBalanceByUnit{
-					UserID:       int(member.UserID),
-					Name:         member.Name,
-					Avatar:       avatar,
-					Unit:         unit,
-					TotalOwed:    0,
-					OffenseCount: 0,
-				}
-			}
-
-			unitBalances[unit].TotalOwed += amount
-			unitBalances[unit].OffenseCount++
-		}
-
-		// Convert map to slice
-		balances := make([]models.MemberBalanceByUnit, 0, len(unitBalances))
-		for _, balance := range unitBalances {
-			balances = append(balances, *balance)
+	for _, row := range rows {
+		var avatar *string
+		if row.Avatar.Valid {
+			avatar = &row.Avatar.String
 		}
 
-		if len(balances) > 0 {
-			var avatar *string
-			if member.Avatar.Valid {
-				avatar = &member.Avatar.String
-			}
-
+		if len(summaries) == 0 || summaries[len(summaries)-1].UserID != int(row.UserID) {
 			summaries = append(summaries, models.MemberBalanceSummary{
-				UserID:        int(member.UserID),
-				Name:          member.Name,
-				Avatar:        avatar,
-				Balances:      balances,
-				TotalOffenses: totalOffenses,
+				UserID: int(row.UserID),
+				Name:   row.UserName,
+				Avatar: avatar,
 			})
 		}
+
+		summary := &summaries[len(summaries)-1]
+		summary.Balances = append(summary.Balances, models.MemberBalanceByUnit{
+			UserID:       int(row.UserID),
+			Name:         row.UserName,
+			Avatar:       avatar,
+			Unit:         row.Unit,
+			TotalOwed:    money.MustFromNumeric(row.TotalOwed),
+			OffenseCount: int(row.OffenseCount),
+		})
+		summary.TotalOffenses += int(row.OffenseCount)
 	}
 
 	return summaries, nil
 }
 
-// Keep the old method for backward compatibility where simple totals are needed
+// GetMemberBalances returns a single total still owed per member across all
+// units, including what payments awaiting verification leave unpaid
 func (s *TipJarService) GetMemberBalances(ctx context.Context, jarID int) ([]models.MemberBalance, error) {
-	// Get all jar members
-	members, err := s.db.ListJarMembers(ctx, int32(jarID))
+	rows, err := s.db.GetJarMemberBalances(ctx, int32(jarID))
 	if err != nil {
 		return nil, err
 	}
 
-	balances := make([]models.MemberBalance, len(members))
-
-	for i, member := range members {
-		// Get user's balance in this jar (only monetary for backward compatibility)
-		balance, err := s.db.GetUserBalanceInJar(ctx, sqlc.GetUserBalanceInJarParams{
-			JarID:      int32(jarID),
-			OffenderID: member.UserID,
-		})
-		if err != nil {
-			balance = 0
-		}
-
-		var totalOwed float64
-		if balance != nil {
-			if balanceStr, ok := balance.(string); ok {
-				if parsed, err := strconv.ParseFloat(balanceStr, 64); err == nil {
-					totalOwed = parsed
-				}
-			} else if balanceFloat, ok := balance.(float64); ok {
-				totalOwed = balanceFloat
-			}
-		}
-
-		// Get pending offense count
-		pendingOffenses, err := s.db.ListPendingOffensesForUser(ctx, member.UserID)
-		pendingCount := 0
-		if err == nil {
-			for _, offense := range pendingOffenses {
-				if offense.JarID == int32(jarID) {
-					pendingCount++
-				}
-			}
-		}
-
+	balances := make([]models.MemberBalance, len(rows))
+	for i, row := range rows {
 		var avatar *string
-		if member.Avatar.Valid {
-			avatar = &member.Avatar.String
+		if row.Avatar.Valid {
+			avatar = &row.Avatar.String
 		}
 
 		balances[i] = models.MemberBalance{
-			UserID:       int(member.UserID),
-			Name:         member.Name,
+			UserID:       int(row.UserID),
+			Name:         row.UserName,
 			Avatar:       avatar,
-			TotalOwed:    totalOwed,
-			PendingCount: pendingCount,
+			TotalOwed:    money.MustFromNumeric(row.TotalOwed),
+			PendingCount: int(row.PendingCount),
 		}
 	}
 
 	return balances, nil
 }
 
+// CreateTipJarWithInviteCode creates a jar whose first invite uses the code
+// chosen on the create form, which must be a valid invite code
 func (s *TipJarService) CreateTipJarWithInviteCode(ctx context.Context, name, description, inviteCode string, createdBy int) (*models.TipJar, error) {
+	inviteCode = invitecode.Normalize(inviteCode)
+	if !invitecode.Valid(inviteCode) {
+		return nil, ErrInviteCodeTypo
+	}
+
 	var descText pgtype.Text
 	if description != "" {
 		descText = pgtype.Text{String: description, Valid: true}
@@ -386,39 +619,66 @@ func (s *TipJarService) CreateTipJarWithInviteCode(ctx context.Context, name, de
 	params := sqlc.CreateTipJarParams{
 		Name:        name,
 		Description: descText,
-		InviteCode:  inviteCode,
 		CreatedBy:   int32(createdBy),
 	}
 
-	jar, err := s.db.CreateTipJar(ctx, params)
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
 
-	// Create admin membership for creator
-	_, err = s.db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{
+	jar, err := qtx.CreateTipJar(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// The creator owns the jar
+	_, err = qtx.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{
 		JarID:  jar.ID,
 		UserID: int32(createdBy),
-		Role:   "admin",
+		Role:   authz.Owner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = qtx.CreateJarPeriod(ctx, sqlc.CreateJarPeriodParams{
+		JarID: jar.ID,
+		Name:  FirstPeriodName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The code chosen on the create form becomes the jar's first invite
+	_, err = createInvite(ctx, qtx, jar.ID, jar.CreatedBy, inviteCode, InviteOptions{
+		Label: "Original invite",
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Create default offense type for new jar
-	err = s.createDefaultOffenseType(ctx, jar.ID)
+	err = createDefaultOffenseType(ctx, qtx, jar.ID, jar.CreatedBy)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.sqlcTipJarToModel(jar), nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return sqlcTipJarToModel(jar), nil
 }
 
 // createDefaultOffenseType creates a default "General Offense" type for a new jar
-func (s *TipJarService) createDefaultOffenseType(ctx context.Context, jarID int32) error {
+func createDefaultOffenseType(ctx context.Context, q *sqlc.Queries, jarID, createdBy int32) error {
 	descText := pgtype.Text{String: "A general offense for any rule breaking", Valid: true}
 
-	costAmount := pgtype.Numeric{Int: big.NewInt(500), Exp: -2, Valid: true} // 5.00
+	costAmount := money.FromInt(5).Numeric()
 
 	costUnit := pgtype.Text{String: "dollars", Valid: true}
 
@@ -430,7 +690,17 @@ func (s *TipJarService) createDefaultOffenseType(ctx context.Context, jarID int3
 		CostUnit:    costUnit,
 	}
 
-	_, err := s.db.CreateOffenseType(ctx, params)
+	offenseType, err := q.CreateOffenseType(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOffenseTypePrice(ctx, sqlc.CreateOffenseTypePriceParams{
+		OffenseTypeID: offenseType.ID,
+		CostAmount:    offenseType.CostAmount,
+		CostUnit:      offenseType.CostUnit,
+		ChangedBy:     pgtype.Int4{Int32: createdBy, Valid: true},
+	})
 	return err
 }
 
@@ -450,6 +720,82 @@ func (s *TipJarService) UpdateTipJar(ctx context.Context, jarID int, name, descr
 	return err
 }
 
+// UpdateDisputeSettings changes how many votes close a dispute and how long
+// disputes stay open
+func (s *TipJarService) UpdateDisputeSettings(ctx context.Context, jarID, quorum, windowHours int) error {
+	_, err := s.db.UpdateTipJarDisputeSettings(ctx, sqlc.UpdateTipJarDisputeSettingsParams{
+		ID:                 int32(jarID),
+		DisputeQuorum:      int32(quorum),
+		DisputeWindowHours: int32(windowHours),
+	})
+	return err
+}
+
+// UpdateVerificationSettings turns witness verification of payments on or off
+// and sets how many members must confirm each payment
+func (s *TipJarService) UpdateVerificationSettings(ctx context.Context, jarID int, required bool, needed int) error {
+	_, err := s.db.UpdateTipJarVerificationSettings(ctx, sqlc.UpdateTipJarVerificationSettingsParams{
+		ID:                   int32(jarID),
+		VerificationRequired: required,
+		VerificationsNeeded:  int32(needed),
+	})
+	return err
+}
+
+// UpdateJoinApproval sets whether joining the jar waits for an admin to
+// approve it. Requests already waiting stay in the queue either way.
+func (s *TipJarService) UpdateJoinApproval(ctx context.Context, jarID int, required bool) error {
+	_, err := s.db.UpdateTipJarJoinApproval(ctx, sqlc.UpdateTipJarJoinApprovalParams{
+		ID:                   int32(jarID),
+		JoinApprovalRequired: required,
+	})
+	return err
+}
+
+// GetJarHistory returns the most recent entries in the jar's history
+func (s *TipJarService) GetJarHistory(ctx context.Context, jarID int, limit int) ([]models.JarEvent, error) {
+	rows, err := s.db.ListJarEvents(ctx, sqlc.ListJarEventsParams{
+		JarID: int32(jarID),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.JarEvent, len(rows))
+	for i, row := range rows {
+		var actorID *int
+		if row.ActorID.Valid {
+			id := int(row.ActorID.Int32)
+			actorID = &id
+		}
+
+		var actorName *string
+		if row.ActorName.Valid {
+			actorName = &row.ActorName.String
+		}
+
+		var offenseID *int
+		if row.OffenseID.Valid {
+			id := int(row.OffenseID.Int32)
+			offenseID = &id
+		}
+
+		events[i] = models.JarEvent{
+			ID:        int(row.ID),
+			JarID:     int(row.JarID),
+			ActorID:   actorID,
+			ActorName: actorName,
+			OffenseID: offenseID,
+			EventType: row.EventType,
+			Message:   row.Message,
+			CreatedAt: row.CreatedAt.Time,
+		}
+	}
+
+	return events, nil
+}
+
 func (s *TipJarService) ListTipJarsForUserWithMemberCount(ctx context.Context, userID int) ([]*models.DashboardJar, error) {
 	jarsWithCounts, err := s.db.ListTipJarsForUserWithMemberCount(ctx, int32(userID))
 	if err != nil {
@@ -463,14 +809,24 @@ func (s *TipJarService) ListTipJarsForUserWithMemberCount(ctx context.Context, u
 			description = &jarWithCount.Description.String
 		}
 
+		var archivedAt *time.Time
+		if jarWithCount.ArchivedAt.Valid {
+			archivedAt = &jarWithCount.ArchivedAt.Time
+		}
+
 		tipJar := &models.TipJar{
-			ID:          int(jarWithCount.ID),
-			Name:        jarWithCount.Name,
-			Description: description,
-			InviteCode:  jarWithCount.InviteCode,
-			CreatedBy:   int(jarWithCount.CreatedBy),
-			CreatedAt:   jarWithCount.CreatedAt.Time,
-			UpdatedAt:   jarWithCount.UpdatedAt.Time,
+			ID:                   int(jarWithCount.ID),
+			Name:                 jarWithCount.Name,
+			Description:          description,
+			CreatedBy:            int(jarWithCount.CreatedBy),
+			DisputeQuorum:        int(jarWithCount.DisputeQuorum),
+			DisputeWindowHours:   int(jarWithCount.DisputeWindowHours),
+			VerificationRequired: jarWithCount.VerificationRequired,
+			VerificationsNeeded:  int(jarWithCount.VerificationsNeeded),
+			JoinApprovalRequired: jarWithCount.JoinApprovalRequired,
+			ArchivedAt:           archivedAt,
+			CreatedAt:            jarWithCount.CreatedAt.Time,
+			UpdatedAt:            jarWithCount.UpdatedAt.Time,
 		}
 
 		result[i] = &models.DashboardJar{
@@ -480,4 +836,4 @@ func (s *TipJarService) ListTipJarsForUserWithMemberCount(ctx context.Context, u
 	}
 
 	return result, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/invitecode"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+)
+
+// seedBusyJar creates a jar with the given number of members and offenses
+// spread over three units. Every fourth offense is already paid.
+func seedBusyJar(tb testing.TB, db *database.DB, members, offenses int) int {
+	tb.Helper()
+	ctx := context.Background()
+
+	owner := dbtest.User(tb, db, "owner")
+	jar, err := NewTipJarService(db, events.NewMemoryBus()).CreateTipJar(ctx, "Busy jar", "", int(owner.ID))
+	if err != nil {
+		tb.Fatalf("create jar: %v", err)
+	}
+
+	memberIDs := []int32{owner.ID}
+	for i := 1; i < members; i++ {
+		user := dbtest.User(tb, db, fmt.Sprintf("member%d", i))
+		_, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{
+			JarID:  int32(jar.ID),
+			UserID: user.ID,
+			Role:   authz.Member,
+		})
+		if err != nil {
+			tb.Fatalf("add member: %v", err)
+		}
+		memberIDs = append(memberIDs, user.ID)
+	}
+
+	offenseService := NewOffenseService(db, events.NewMemoryBus())
+	var typeIDs []int32
+	for _, unit := range []string{"dollars", "pushups", "coffees"} {
+		cost := money.FromCents(250)
+		offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late "+unit, "", &cost, &unit, int(owner.ID))
+		if err != nil {
+			tb.Fatalf("create offense type: %v", err)
+		}
+		typeIDs = append(typeIDs, int32(offenseType.ID))
+	}
+
+	period, err := db.GetOpenJarPeriod(ctx, int32(jar.ID))
+	if err != nil {
+		tb.Fatalf("open period: %v", err)
+	}
+
+	_, err = db.Exec(ctx, `
+		WITH seed AS (SELECT $2::int[] AS members, $3::int[] AS types)
+		INSERT INTO offenses (jar_id, offense_type_id, reporter_id, offender_id, cost_amount, cost_unit, status, period_id, reported_period_id)
+		SELECT $1, ot.id,
+		       seed.members[1 + (g.i + 1) % cardinality(seed.members)],
+		       seed.members[1 + g.i % cardinality(seed.members)],
+		       ot.cost_amount, ot.cost_unit,
+		       CASE WHEN g.i % 4 = 0 THEN 'paid' ELSE 'pending' END,
+		       $4, $4
+		FROM seed
+		CROSS JOIN generate_series(0, $5 - 1) AS g(i)
+		JOIN offense_types ot ON ot.id = seed.types[1 + g.i % cardinality(seed.types)]`,
+		jar.ID, memberIDs, typeIDs, period.ID, offenses)
+	if err != nil {
+		tb.Fatalf("seed offenses: %v", err)
+	}
+
+	return jar.ID
+}
+
+func BenchmarkGetMemberBalancesByUnit(b *testing.B) {
+	db := dbtest.Open(b)
+	jarID := seedBusyJar(b, db, 25, 5000)
+	s := NewTipJarService(db, events.NewMemoryBus())
+	ctx := context.Background()
+
+	summaries, err := s.GetMemberBalancesByUnit(ctx, jarID)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pending := 0
+	for _, summary := range summaries {
+		pending += summary.TotalOffenses
+	}
+	if pending != 3750 {
+		b.Fatalf("got %d pending offenses across %d members, want 3750", pending, len(summaries))
+	}
+
+	for b.Loop() {
+		if _, err := s.GetMemberBalancesByUnit(ctx, jarID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMemberBalancesIncludeVerifyingRemainder(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	bus := events.NewMemoryBus()
+	tipJars := NewTipJarService(db, bus)
+	offenseService := NewOffenseService(db, bus)
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+	jar, err := tipJars.CreateTipJar(ctx, "Verified jar", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jar.ID), UserID: member.ID, Role: authz.Member}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tipJars.UpdateVerificationSettings(ctx, jar.ID, true, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cost, unit := money.FromInt(10), "dollars"
+	offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var offenseIDs []int
+	for range 2 {
+		offense, err := offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, int(owner.ID), int(member.ID), "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offenseIDs = append(offenseIDs, offense.ID)
+	}
+
+	// Pay 4 of the first offense and all of the second; both now wait for
+	// verification, but only the first still owes anything
+	partial := money.FromInt(4)
+	if _, err := offenseService.PayOffenses(ctx, int(member.ID), offenseIDs[:1], &partial, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := offenseService.PayOffenses(ctx, int(member.ID), offenseIDs[1:], nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	summaries, err := tipJars.GetMemberBalancesByUnit(ctx, jar.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || summaries[0].UserID != int(member.ID) {
+		t.Fatalf("got %+v, want only the member", summaries)
+	}
+	balances := summaries[0].Balances
+	if len(balances) != 1 || balances[0].Unit != "dollars" || balances[0].TotalOwed != money.FromInt(6) || balances[0].OffenseCount != 1 {
+		t.Errorf("balances = %+v, want 6 dollars across 1 offense", balances)
+	}
+
+	totals, err := tipJars.GetMemberBalances(ctx, jar.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, total := range totals {
+		want, count := money.Zero, 0
+		if total.UserID == int(member.ID) {
+			want, count = money.FromInt(6), 1
+		}
+		if total.TotalOwed != want || total.PendingCount != count {
+			t.Errorf("user %d owes %s across %d offenses, want %s across %d", total.UserID, total.TotalOwed, total.PendingCount, want, count)
+		}
+	}
+}
+
+func TestCreateTipJarIsAllOrNothing(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	s := NewTipJarService(db, events.NewMemoryBus())
+
+	code, err := invitecode.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := dbtest.User(t, db, "first")
+	jar, err := s.CreateTipJarWithInviteCode(ctx, "First", "", code, int(first.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role, err := s.GetMemberRole(ctx, jar.ID, int(first.ID)); err != nil || role != authz.Owner {
+		t.Errorf("creator's role = %q, %v; want owner", role, err)
+	}
+	if types, err := db.ListOffenseTypesForJar(ctx, int32(jar.ID)); err != nil || len(types) != 1 {
+		t.Errorf("got %d offense types, %v; want the default one", len(types), err)
+	}
+	if period, err := db.GetOpenJarPeriod(ctx, int32(jar.ID)); err != nil || period.Name != FirstPeriodName {
+		t.Errorf("open period = %q, %v; want %q", period.Name, err, FirstPeriodName)
+	}
+
+	// The invite code is taken, so the second jar fails after it has been
+	// inserted and must not be left behind
+	second := dbtest.User(t, db, "second")
+	if _, err := s.CreateTipJarWithInviteCode(ctx, "Second", "", code, int(second.ID)); err == nil {
+		t.Fatal("created a second jar with the same invite code")
+	}
+	jars, err := s.ListTipJarsForUser(ctx, int(second.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jars) != 0 {
+		t.Errorf("second user is in %d jars after the failed create, want none", len(jars))
+	}
+
+	var orphans int
+	err = db.QueryRow(ctx, "SELECT COUNT(*) FROM tip_jars WHERE name = 'Second' AND created_by = $1", second.ID).Scan(&orphans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orphans != 0 {
+		t.Errorf("left %d half-created jars behind", orphans)
+	}
+}
+
+func TestDeleteTipJarRemovesEverythingInIt(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	bus := events.NewMemoryBus()
+	tipJars := NewTipJarService(db, bus)
+	offenseService := NewOffenseService(db, bus)
+
+	owner := dbtest.User(t, db, "owner")
+	member := dbtest.User(t, db, "member")
+
+	// fill gives a jar a member, two offenses, a payment with proof, a second
+	// invite and a closed period
+	fill := func(name string) (int, *models.Payment) {
+		t.Helper()
+		jar, err := tipJars.CreateTipJar(ctx, name, "", int(owner.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateJarMembership(ctx, sqlc.CreateJarMembershipParams{JarID: int32(jar.ID), UserID: member.ID, Role: authz.Member}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewInviteService(db, bus).CreateInvite(ctx, jar.ID, int(owner.ID), InviteOptions{Role: authz.Viewer}); err != nil {
+			t.Fatal(err)
+		}
+
+		cost, unit := money.FromInt(3), "dollars"
+		offenseType, err := offenseService.CreateOffenseType(ctx, jar.ID, "Late", "", &cost, &unit, int(owner.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var offenseIDs []int
+		for range 2 {
+			offense, err := offenseService.CreateOffense(ctx, jar.ID, offenseType.ID, int(owner.ID), int(member.ID), "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offenseIDs = append(offenseIDs, offense.ID)
+		}
+
+		proof, proofType := fmt.Sprintf("proofs/%d/receipt.png", member.ID), "image"
+		payment, err := offenseService.PayOffenses(ctx, int(member.ID), offenseIDs, nil, &proof, &proofType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewPeriodService(db, bus).ClosePeriod(ctx, jar.ID, int(owner.ID), "", "Next", true); err != nil {
+			t.Fatal(err)
+		}
+		return jar.ID, payment
+	}
+
+	jarID, payment := fill("Doomed")
+	keptID, keptPayment := fill("Kept")
+
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(ctx, query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	tables := []string{"jar_memberships", "offense_types", "offenses", "invites", "jar_periods", "jar_events"}
+	rows := func(jarID, paymentID int) map[string]int {
+		t.Helper()
+		counts := make(map[string]int)
+		for _, table := range tables {
+			counts[table] = count("SELECT COUNT(*) FROM "+table+" WHERE jar_id = $1", jarID)
+		}
+		counts["payments"] = count("SELECT COUNT(*) FROM payments WHERE id = $1", paymentID)
+		counts["payment_allocations"] = count("SELECT COUNT(*) FROM payment_allocations WHERE payment_id = $1", paymentID)
+		return counts
+	}
+
+	before := rows(jarID, payment.ID)
+	for table, n := range before {
+		if n == 0 {
+			t.Fatalf("the jar has no %s to delete", table)
+		}
+	}
+	if before["offenses"] != 2 || before["invites"] != 2 || before["jar_periods"] != 2 {
+		t.Fatalf("seeded %+v", before)
+	}
+
+	if _, err := tipJars.DeleteTipJar(ctx, jarID, "doomed"); err != ErrJarNameMismatch {
+		t.Fatalf("DeleteTipJar with the wrong name = %v, want ErrJarNameMismatch", err)
+	}
+	if got := rows(jarID, payment.ID); fmt.Sprint(got) != fmt.Sprint(before) {
+		t.Errorf("a refused delete changed %+v to %+v", before, got)
+	}
+
+	keys, err := tipJars.DeleteTipJar(ctx, jarID, " Doomed ")
+	if err != nil {
+		t.Fatalf("DeleteTipJar: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != fmt.Sprintf("proofs/%d/receipt.png", member.ID) {
+		t.Errorf("proof keys = %v, want the payment's proof", keys)
+	}
+
+	for table, n := range rows(jarID, payment.ID) {
+		if n != 0 {
+			t.Errorf("%d %s left behind", n, table)
+		}
+	}
+	if jar, err := tipJars.GetTipJar(ctx, jarID); err != nil || jar != nil {
+		t.Errorf("GetTipJar after delete = %+v, %v", jar, err)
+	}
+
+	// Only the deleted jar is affected
+	if got := rows(keptID, keptPayment.ID); fmt.Sprint(got) != fmt.Sprint(before) {
+		t.Errorf("the other jar went from %+v to %+v", before, got)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 
 	"tipjar/internal/database"
 	"tipjar/internal/database/sqlc"
@@ -11,6 +12,8 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var ErrLastIdentity = errors.New("cannot unlink the only sign-in method")
+
 type UserService struct {
 	db *database.DB
 }
@@ -19,8 +22,12 @@ func NewUserService(db *database.DB) *UserService {
 	return &UserService{db: db}
 }
 
-func (s *UserService) GetUserByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
-	user, err := s.db.GetUserByGoogleID(ctx, googleID)
+// GetUserByIdentity finds the user linked to a provider account
+func (s *UserService) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	user, err := s.db.GetUserByIdentity(ctx, sqlc.GetUserByIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			// User not found - this is expected for new users
@@ -29,31 +36,135 @@ func (s *UserService) GetUserByGoogleID(ctx context.Context, googleID string) (*
 		// Actual database error
 		return nil, err
 	}
-	
+
 	return s.sqlcUserToModel(user), nil
 }
 
-func (s *UserService) CreateUser(ctx context.Context, email, name, avatar, googleID string) (*models.User, error) {
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.sqlcUserToModel(user), nil
+}
+
+// CreateUserWithIdentity creates a user along with the provider account they
+// signed up with
+func (s *UserService) CreateUserWithIdentity(ctx context.Context, email, name, avatar, provider, subject string) (*models.User, error) {
 	var avatarText pgtype.Text
 	if avatar != "" {
 		avatarText = pgtype.Text{String: avatar, Valid: true}
 	}
 
-	params := sqlc.CreateUserParams{
-		Email:    email,
-		Name:     name,
-		Avatar:   avatarText,
-		GoogleID: googleID,
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	user, err := qtx.CreateUser(ctx, sqlc.CreateUserParams{
+		Email:  email,
+		Name:   name,
+		Avatar: avatarText,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.db.CreateUser(ctx, params)
+	_, err = qtx.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    pgtype.Text{String: email, Valid: email != ""},
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return s.sqlcUserToModel(user), nil
 }
 
+// LinkIdentity attaches another provider account to an existing user
+func (s *UserService) LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error {
+	_, err := s.db.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:   int32(userID),
+		Provider: provider,
+		Subject:  subject,
+		Email:    pgtype.Text{String: email, Valid: email != ""},
+	})
+	return err
+}
+
+// RecordIdentityLogin updates when a provider account was last used to sign in
+func (s *UserService) RecordIdentityLogin(ctx context.Context, provider, subject, email string) error {
+	identity, err := s.db.GetUserIdentity(ctx, sqlc.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.db.TouchUserIdentity(ctx, sqlc.TouchUserIdentityParams{
+		ID:    identity.ID,
+		Email: pgtype.Text{String: email, Valid: email != ""},
+	})
+}
+
+func (s *UserService) ListIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	identities, err := s.db.ListUserIdentities(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.UserIdentity, len(identities))
+	for i, identity := range identities {
+		var email *string
+		if identity.Email.Valid {
+			email = &identity.Email.String
+		}
+
+		result[i] = models.UserIdentity{
+			ID:          int(identity.ID),
+			UserID:      int(identity.UserID),
+			Provider:    identity.Provider,
+			Email:       email,
+			CreatedAt:   identity.CreatedAt.Time,
+			LastLoginAt: identity.LastLoginAt.Time,
+		}
+	}
+
+	return result, nil
+}
+
+// UnlinkIdentity removes a provider account from the user. The last remaining
+// identity can't be removed or the user would be locked out.
+func (s *UserService) UnlinkIdentity(ctx context.Context, userID, identityID int) error {
+	count, err := s.db.CountUserIdentities(ctx, int32(userID))
+	if err != nil {
+		return err
+	}
+
+	if count <= 1 {
+		return ErrLastIdentity
+	}
+
+	return s.db.DeleteUserIdentity(ctx, sqlc.DeleteUserIdentityParams{
+		ID:     int32(identityID),
+		UserID: int32(userID),
+	})
+}
+
 func (s *UserService) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
 	user, err := s.db.GetUserByID(ctx, int32(userID))
 	if err != nil {
@@ -98,8 +209,7 @@ func (s *UserService) sqlcUserToModel(user sqlc.User) *models.User {
 		Email:     user.Email,
 		Name:      user.Name,
 		Avatar:    avatar,
-		GoogleID:  user.GoogleID,
 		CreatedAt: user.CreatedAt.Time,
 		UpdatedAt: user.UpdatedAt.Time,
 	}
-}
\ No newline at end of file
+}
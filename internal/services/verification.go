@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tipjar/internal/authz"
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrPaymentSettled  = errors.New("payment has already been verified or rejected")
+	ErrOwnPayment      = errors.New("you can't verify your own payment")
+	ErrNotJarMember    = errors.New("you are not a member of this jar")
+)
+
+type VerificationService struct {
+	db     *database.DB
+	events events.Bus
+}
+
+func NewVerificationService(db *database.DB, bus events.Bus) *VerificationService {
+	return &VerificationService{db: db, events: bus}
+}
+
+// RecordVerdict records a member's verdict on a payment that is awaiting
+// verification. The verdict of anyone who can overrule payments is final;
+// otherwise the payment is settled once the jar's required number of members
+// agree either way. A verified payment marks the offenses it covers paid, or
+// back to pending if something is still owed; a rejected one sends them back
+// to pending.
+func (s *VerificationService) RecordVerdict(ctx context.Context, paymentID, userID int, approved bool) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+
+	payment, err := qtx.GetPaymentForUpdate(ctx, int32(paymentID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+
+	allocations, err := qtx.ListAllocationsForPayment(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if len(allocations) == 0 {
+		return ErrPaymentNotFound
+	}
+
+	offenses := make([]sqlc.Offense, len(allocations))
+	for i, allocation := range allocations {
+		offenses[i], err = qtx.GetOffense(ctx, allocation.OffenseID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if payment.Verified || payment.Rejected {
+		return ErrPaymentSettled
+	}
+	for _, offense := range offenses {
+		if offense.Status != "verifying" {
+			return ErrPaymentSettled
+		}
+	}
+
+	// Every offense a payment covers is in the same jar and has the same offender
+	offense := offenses[0]
+
+	role, err := memberRole(ctx, qtx, offense.JarID, int32(userID))
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrNotJarMember
+	}
+	if !authz.Can(role, authz.VerifyPayments) {
+		return ErrNotAllowed
+	}
+	if err := checkNotArchived(ctx, qtx, offense.JarID); err != nil {
+		return err
+	}
+
+	if int(payment.UserID) == userID || int(offense.OffenderID) == userID {
+		return ErrOwnPayment
+	}
+
+	canOverrule := authz.Can(role, authz.OverrulePayments)
+
+	_, err = qtx.UpsertPaymentVerification(ctx, sqlc.UpsertPaymentVerificationParams{
+		PaymentID: payment.ID,
+		UserID:    int32(userID),
+		Approved:  approved,
+	})
+	if err != nil {
+		return err
+	}
+
+	subject, err := describeOffenses(ctx, qtx, offenses)
+	if err != nil {
+		return err
+	}
+
+	var eventOffense *int32
+	if len(offenses) == 1 {
+		eventOffense = &offense.ID
+	}
+
+	verdict := "rejected"
+	if approved {
+		verdict = "approved"
+	}
+
+	err = recordJarEvent(ctx, qtx, offense.JarID, &userID, eventOffense, "payment.verdict",
+		fmt.Sprintf("%s the payment for %s", verdict, subject))
+	if err != nil {
+		return err
+	}
+
+	counts, err := qtx.CountPaymentVerifications(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	jar, err := qtx.GetTipJar(ctx, offense.JarID)
+	if err != nil {
+		return err
+	}
+
+	needed := int64(jar.VerificationsNeeded)
+	raised := newJarEvents(qtx)
+
+	// Offenses awaiting verification hold their period open, so they're
+	// still in the open one
+	period, err := openPeriod(ctx, qtx, offense.JarID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case approved && (canOverrule || counts.Approvals >= needed):
+		_, err = qtx.VerifyPayment(ctx, sqlc.VerifyPaymentParams{
+			ID:         payment.ID,
+			VerifiedBy: pgtype.Int4{Int32: int32(userID), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		// Offenses with something still owed after a partial payment reopen
+		for _, offense := range offenses {
+			_, remaining, err := offenseBalance(ctx, qtx, offense)
+			if err != nil {
+				return err
+			}
+
+			status := "pending"
+			if remaining.IsZero() {
+				status = "paid"
+			}
+
+			updated, err := qtx.UpdateOffenseStatusInPeriod(ctx, sqlc.UpdateOffenseStatusInPeriodParams{
+				ID:       offense.ID,
+				Status:   status,
+				PeriodID: period.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			if status == "paid" {
+				if err := raised.add(ctx, offense.JarID, "offense.paid", newOffenseEventData(updated)); err != nil {
+					return err
+				}
+			}
+		}
+
+		err = recordJarEvent(ctx, qtx, offense.JarID, nil, eventOffense, "payment.verified",
+			fmt.Sprintf("Payment for %s was verified", subject))
+		if err != nil {
+			return err
+		}
+
+		err = raised.add(ctx, offense.JarID, "payment.verified", newPaymentEventData(payment, allocations))
+		if err != nil {
+			return err
+		}
+
+	case !approved && (canOverrule || counts.Rejections >= needed):
+		_, err = qtx.RejectPayment(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, offense := range offenses {
+			_, err = qtx.UpdateOffenseStatusInPeriod(ctx, sqlc.UpdateOffenseStatusInPeriodParams{
+				ID:       offense.ID,
+				Status:   "pending",
+				PeriodID: period.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = recordJarEvent(ctx, qtx, offense.JarID, nil, eventOffense, "payment.rejected",
+			fmt.Sprintf("Payment for %s was rejected and is owed again", subject))
+		if err != nil {
+			return err
+		}
+
+		err = raised.add(ctx, offense.JarID, "payment.rejected", newPaymentEventData(payment, allocations))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	raised.publish(ctx, s.events)
+
+	return nil
+}
+
+// ListAwaitingVerification returns payments in the user's jars that are
+// waiting on witnesses, leaving out the user's own and those in jars where
+// their role can't verify
+func (s *VerificationService) ListAwaitingVerification(ctx context.Context, userID int) ([]models.PendingVerification, error) {
+	rows, err := s.db.ListPaymentsAwaitingVerification(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows come one per covered offense, grouped by payment
+	var pending []models.PendingVerification
+	for _, row := range rows {
+		if !authz.Can(row.MyRole, authz.VerifyPayments) {
+			continue
+		}
+
+		var unit *string
+		if row.CostUnit.Valid {
+			unit = &row.CostUnit.String
+		}
+
+		allocation := models.PaymentAllocation{
+			PaymentID:       int(row.ID),
+			OffenseID:       int(row.AllocatedOffenseID),
+			OffenseTypeName: row.OffenseTypeName,
+			Amount:          money.FromNumericPtr(row.AllocatedAmount),
+			Unit:            unit,
+		}
+
+		if n := len(pending); n > 0 && pending[n-1].PaymentID == int(row.ID) {
+			pending[n-1].Allocations = append(pending[n-1].Allocations, allocation)
+			continue
+		}
+
+		var proofURL *string
+		if row.ProofUrl.Valid {
+			proofURL = &row.ProofUrl.String
+		}
+
+		var myVerdict *bool
+		if row.MyVerdict.Valid {
+			myVerdict = &row.MyVerdict.Bool
+		}
+
+		pending = append(pending, models.PendingVerification{
+			PaymentID:    int(row.ID),
+			JarID:        int(row.JarID),
+			JarName:      row.JarName,
+			OffenderID:   int(row.OffenderID),
+			OffenderName: row.OffenderName,
+			Allocations:  []models.PaymentAllocation{allocation},
+			ProofURL:     proofURL,
+			MyVerdict:    myVerdict,
+			CreatedAt:    row.CreatedAt.Time,
+		})
+	}
+
+	return pending, nil
+}
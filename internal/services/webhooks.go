@@ -0,0 +1,563 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"tipjar/internal/database"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/models"
+	"tipjar/internal/money"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxWebhookAttempts   = 8
+	webhookBaseDelay     = 30 * time.Second
+	webhookMaxDelay      = 6 * time.Hour
+	webhookBatchSize     = 20
+	webhookTimeout       = 10 * time.Second
+	webhookDeliveriesLog = 50
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+	ErrNoWebhookEvents   = errors.New("choose at least one event for the webhook")
+	ErrUnknownEventType  = errors.New("unknown webhook event type")
+
+	errWebhookPaused = errors.New("webhook is paused")
+)
+
+// WebhookEventType is an event webhooks can subscribe to
+type WebhookEventType struct {
+	Name        string
+	Description string
+}
+
+// WebhookEventTypes lists every event sent to webhooks
+var WebhookEventTypes = []WebhookEventType{
+	{"offense.reported", "An offense is reported"},
+	{"offense.paid", "An offense is paid off"},
+	{"offense.disputed", "An offense is disputed"},
+	{"dispute.resolved", "A dispute is settled"},
+	{"payment.recorded", "A payment is recorded"},
+	{"payment.verified", "A payment is verified by members"},
+	{"payment.rejected", "A payment is rejected by members"},
+	{"member.joined", "Someone joins the jar"},
+	{"member.left", "Someone leaves or is removed from the jar"},
+	{"member.role_changed", "A member is promoted or demoted"},
+	{"join_request.created", "Someone asks to join the jar"},
+	{"join_request.approved", "A request to join is approved"},
+	{"join_request.rejected", "A request to join is turned down"},
+	{"jar.archived", "The jar is archived"},
+	{"jar.restored", "The jar is restored from the archive"},
+	{"period.closed", "A period is closed and the next one starts"},
+}
+
+// webhookEnvelope is the JSON body of every delivery
+type webhookEnvelope struct {
+	Type      string    `json:"type"`
+	JarID     int32     `json:"jar_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// offenseEventData describes an offense in webhook payloads
+type offenseEventData struct {
+	OffenseID     int32         `json:"offense_id"`
+	OffenseTypeID int32         `json:"offense_type_id"`
+	ReporterID    int32         `json:"reporter_id"`
+	OffenderID    int32         `json:"offender_id"`
+	Amount        *money.Amount `json:"amount"`
+	Unit          *string       `json:"unit"`
+	Status        string        `json:"status"`
+	Notes         *string       `json:"notes"`
+}
+
+func newOffenseEventData(offense sqlc.Offense) offenseEventData {
+	data := offenseEventData{
+		OffenseID:     offense.ID,
+		OffenseTypeID: offense.OffenseTypeID,
+		ReporterID:    offense.ReporterID,
+		OffenderID:    offense.OffenderID,
+		Amount:        money.FromNumericPtr(offense.CostAmount),
+		Status:        offense.Status,
+	}
+	if offense.CostUnit.Valid {
+		data.Unit = &offense.CostUnit.String
+	}
+	if offense.Notes.Valid {
+		data.Notes = &offense.Notes.String
+	}
+	return data
+}
+
+// paymentEventData describes a payment in webhook payloads. Amount is only
+// set for payments covering a single offense.
+type paymentEventData struct {
+	PaymentID   int32                 `json:"payment_id"`
+	UserID      int32                 `json:"user_id"`
+	Amount      *money.Amount         `json:"amount"`
+	Allocations []allocationEventData `json:"allocations"`
+}
+
+type allocationEventData struct {
+	OffenseID int32         `json:"offense_id"`
+	Amount    *money.Amount `json:"amount"`
+	Unit      *string       `json:"unit"`
+}
+
+func newPaymentEventData(payment sqlc.Payment, allocations []sqlc.ListAllocationsForPaymentRow) paymentEventData {
+	data := paymentEventData{
+		PaymentID:   payment.ID,
+		UserID:      payment.UserID,
+		Amount:      money.FromNumericPtr(payment.Amount),
+		Allocations: make([]allocationEventData, len(allocations)),
+	}
+	for i, allocation := range allocations {
+		data.Allocations[i] = allocationEventData{
+			OffenseID: allocation.OffenseID,
+			Amount:    money.FromNumericPtr(allocation.Amount),
+		}
+		if allocation.CostUnit.Valid {
+			data.Allocations[i].Unit = &allocation.CostUnit.String
+		}
+	}
+	return data
+}
+
+// disputeEventData describes a dispute in webhook payloads
+type disputeEventData struct {
+	DisputeID      int32         `json:"dispute_id"`
+	OffenseID      int32         `json:"offense_id"`
+	OpenedBy       int32         `json:"opened_by"`
+	Reason         string        `json:"reason"`
+	Status         string        `json:"status"`
+	ResolvedAmount *money.Amount `json:"resolved_amount"`
+	Deadline       time.Time     `json:"deadline"`
+}
+
+func newDisputeEventData(dispute sqlc.OffenseDispute) disputeEventData {
+	return disputeEventData{
+		DisputeID:      dispute.ID,
+		OffenseID:      dispute.OffenseID,
+		OpenedBy:       dispute.OpenedBy,
+		Reason:         dispute.Reason,
+		Status:         dispute.Status,
+		ResolvedAmount: money.FromNumericPtr(dispute.ResolvedAmount),
+		Deadline:       dispute.Deadline.Time,
+	}
+}
+
+// memberEventData describes a membership change in webhook payloads
+type memberEventData struct {
+	UserID int32  `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+}
+
+// jarEventData describes a change to the jar itself in webhook payloads
+type jarEventData struct {
+	ActorID int32  `json:"actor_id"`
+	Name    string `json:"name"`
+}
+
+// periodEventData describes a closed period and the one that replaced it in
+// webhook payloads
+type periodEventData struct {
+	ActorID         int32  `json:"actor_id"`
+	PeriodID        int32  `json:"period_id"`
+	Name            string `json:"name"`
+	NextPeriodID    int32  `json:"next_period_id"`
+	NextName        string `json:"next_name"`
+	CarriedOffenses int64  `json:"carried_offenses"` // Unpaid offenses moved to the next period
+}
+
+// joinRequestEventData describes a request to join in webhook payloads
+type joinRequestEventData struct {
+	RequestID int32   `json:"request_id"`
+	UserID    int32   `json:"user_id"`
+	Role      string  `json:"role"`
+	Status    string  `json:"status"`
+	Message   *string `json:"message,omitempty"`
+}
+
+func newJoinRequestEventData(request sqlc.JoinRequest, role string) joinRequestEventData {
+	data := joinRequestEventData{
+		RequestID: request.ID,
+		UserID:    request.UserID,
+		Role:      role,
+		Status:    request.Status,
+	}
+	if request.Message.Valid {
+		data.Message = &request.Message.String
+	}
+	return data
+}
+
+// enqueueWebhooks queues a delivery of the event to each of the jar's active
+// webhooks subscribed to it. Called inside the transaction making the change,
+// so an event is only sent if the change commits.
+func enqueueWebhooks(ctx context.Context, qtx *sqlc.Queries, jarID int32, eventType string, data any) error {
+	payload, err := json.Marshal(webhookEnvelope{
+		Type:      eventType,
+		JarID:     jarID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.EnqueueWebhookDeliveries(ctx, sqlc.EnqueueWebhookDeliveriesParams{
+		EventType: eventType,
+		Payload:   string(payload),
+		JarID:     jarID,
+	})
+	return err
+}
+
+type WebhookService struct {
+	db     *database.DB
+	client *http.Client
+}
+
+func NewWebhookService(db *database.DB) *WebhookService {
+	return &WebhookService{
+		db:     db,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// CreateWebhook registers a URL to receive the chosen events from a jar. A
+// signing secret is generated for it.
+func (s *WebhookService) CreateWebhook(ctx context.Context, jarID int, rawURL string, eventTypes []string, createdBy int) (*models.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, ErrNoWebhookEvents
+	}
+	for _, eventType := range eventTypes {
+		if !isWebhookEventType(eventType) {
+			return nil, ErrUnknownEventType
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.db.CreateWebhook(ctx, sqlc.CreateWebhookParams{
+		JarID:      int32(jarID),
+		Url:        u.String(),
+		Secret:     "whsec_" + hex.EncodeToString(b),
+		EventTypes: eventTypes,
+		CreatedBy:  int32(createdBy),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sqlcWebhookToModel(webhook), nil
+}
+
+// GetWebhook returns a webhook, or nil if it doesn't exist
+func (s *WebhookService) GetWebhook(ctx context.Context, webhookID int) (*models.Webhook, error) {
+	webhook, err := s.db.GetWebhook(ctx, int32(webhookID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.sqlcWebhookToModel(webhook), nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, jarID int) ([]models.Webhook, error) {
+	rows, err := s.db.ListWebhooksForJar(ctx, int32(jarID))
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]models.Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = *s.sqlcWebhookToModel(row)
+	}
+
+	return webhooks, nil
+}
+
+// SetWebhookActive pauses or resumes a webhook. Paused webhooks don't queue
+// new deliveries, and ones already queued wait until it's resumed.
+func (s *WebhookService) SetWebhookActive(ctx context.Context, webhookID int, isActive bool) error {
+	return s.db.SetWebhookActive(ctx, sqlc.SetWebhookActiveParams{
+		ID:       int32(webhookID),
+		IsActive: isActive,
+	})
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID int) error {
+	return s.db.DeleteWebhook(ctx, int32(webhookID))
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.ListWebhookDeliveries(ctx, sqlc.ListWebhookDeliveriesParams{
+		WebhookID: int32(webhookID),
+		Limit:     webhookDeliveriesLog,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = *s.sqlcDeliveryToModel(row)
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery returns a delivery, or nil if it doesn't exist
+func (s *WebhookService) GetDelivery(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error) {
+	delivery, err := s.db.GetWebhookDelivery(ctx, int32(deliveryID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.sqlcDeliveryToModel(delivery), nil
+}
+
+// Redeliver queues the payload of an earlier delivery to be sent again as a
+// new delivery, leaving the original in the log
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error) {
+	original, err := s.db.GetWebhookDelivery(ctx, int32(deliveryID))
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.db.CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+		WebhookID: original.WebhookID,
+		EventType: original.EventType,
+		Payload:   original.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sqlcDeliveryToModel(delivery), nil
+}
+
+// StartDispatcher sends due deliveries every interval until ctx is done
+func (s *WebhookService) StartDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.DispatchDue(ctx)
+			if err != nil {
+				slog.Error("Failed to dispatch webhooks", "error", err)
+				continue
+			}
+			if sent > 0 {
+				slog.Info("Dispatched webhooks", "count", sent)
+			}
+		}
+	}
+}
+
+// DispatchDue attempts every delivery whose next attempt is due and returns
+// how many were attempted
+func (s *WebhookService) DispatchDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := s.db.ClaimDueWebhookDeliveries(ctx, webhookBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		if len(deliveries) == 0 {
+			return attempted, nil
+		}
+
+		for _, delivery := range deliveries {
+			err := s.attempt(ctx, delivery)
+			if err == errWebhookPaused {
+				continue
+			}
+			if err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome, scheduling a retry
+// with exponential backoff if it failed. A delivery whose webhook was paused
+// after it was claimed is handed back unsent and errWebhookPaused returned.
+func (s *WebhookService) attempt(ctx context.Context, delivery sqlc.WebhookDelivery) error {
+	webhook, err := s.db.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if !webhook.IsActive {
+		if err := s.db.ReleaseWebhookDelivery(ctx, delivery.ID); err != nil {
+			return err
+		}
+		return errWebhookPaused
+	}
+
+	statusCode, sendErr := s.send(ctx, webhook, delivery)
+	if sendErr == nil {
+		return s.db.MarkWebhookDelivered(ctx, sqlc.MarkWebhookDeliveredParams{
+			ID:             delivery.ID,
+			LastStatusCode: pgtype.Int4{Int32: int32(statusCode), Valid: true},
+		})
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	status := "pending"
+	if attempts >= maxWebhookAttempts {
+		status = "failed"
+	}
+
+	var code pgtype.Int4
+	if statusCode != 0 {
+		code = pgtype.Int4{Int32: int32(statusCode), Valid: true}
+	}
+
+	return s.db.RecordWebhookFailure(ctx, sqlc.RecordWebhookFailureParams{
+		ID:             delivery.ID,
+		Status:         status,
+		NextAttemptAt:  pgtype.Timestamp{Time: time.Now().Add(webhookRetryDelay(attempts)), Valid: true},
+		LastStatusCode: code,
+		LastError:      pgtype.Text{String: sendErr.Error(), Valid: true},
+	})
+}
+
+// send POSTs the payload, returning the response status and an error unless
+// the receiver answered with a 2xx
+func (s *WebhookService) send(ctx context.Context, webhook sqlc.Webhook, delivery sqlc.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TipJar-Webhooks/1.0")
+	req.Header.Set("X-TipJar-Event", delivery.EventType)
+	req.Header.Set("X-TipJar-Delivery", strconv.Itoa(int(delivery.ID)))
+	req.Header.Set("X-TipJar-Timestamp", timestamp)
+	req.Header.Set("X-TipJar-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload is the hex HMAC-SHA256 of "timestamp.payload" keyed with
+// the webhook's secret. Receivers recompute it to check a delivery.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay doubles the wait after each failed attempt, up to a cap
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxDelay)
+}
+
+func isWebhookEventType(name string) bool {
+	for _, eventType := range WebhookEventTypes {
+		if eventType.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WebhookService) sqlcWebhookToModel(webhook sqlc.Webhook) *models.Webhook {
+	return &models.Webhook{
+		ID:         int(webhook.ID),
+		JarID:      int(webhook.JarID),
+		URL:        webhook.Url,
+		Secret:     webhook.Secret,
+		EventTypes: webhook.EventTypes,
+		IsActive:   webhook.IsActive,
+		CreatedBy:  int(webhook.CreatedBy),
+		CreatedAt:  webhook.CreatedAt.Time,
+	}
+}
+
+func (s *WebhookService) sqlcDeliveryToModel(delivery sqlc.WebhookDelivery) *models.WebhookDelivery {
+	var lastStatusCode *int
+	if delivery.LastStatusCode.Valid {
+		code := int(delivery.LastStatusCode.Int32)
+		lastStatusCode = &code
+	}
+
+	var lastError *string
+	if delivery.LastError.Valid {
+		lastError = &delivery.LastError.String
+	}
+
+	var deliveredAt *time.Time
+	if delivery.DeliveredAt.Valid {
+		deliveredAt = &delivery.DeliveredAt.Time
+	}
+
+	return &models.WebhookDelivery{
+		ID:             int(delivery.ID),
+		WebhookID:      int(delivery.WebhookID),
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       int(delivery.Attempts),
+		NextAttemptAt:  delivery.NextAttemptAt.Time,
+		LastStatusCode: lastStatusCode,
+		LastError:      lastError,
+		CreatedAt:      delivery.CreatedAt.Time,
+		DeliveredAt:    deliveredAt,
+	}
+}
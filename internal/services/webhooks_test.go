@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tipjar/internal/database/dbtest"
+	"tipjar/internal/database/sqlc"
+	"tipjar/internal/events"
+	"tipjar/internal/models"
+)
+
+// webhookReceiver is an endpoint that checks each delivery's signature and
+// answers with whatever status the test sets
+type webhookReceiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	received []receivedWebhook
+}
+
+type receivedWebhook struct {
+	event    string
+	delivery string
+	body     string
+}
+
+func newWebhookReceiver(t *testing.T, secret string) (*webhookReceiver, *httptest.Server) {
+	r := &webhookReceiver{t: t, secret: secret, status: http.StatusOK}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		r.t.Errorf("got %s with content type %q, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+	}
+
+	timestamp := req.Header.Get("X-TipJar-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)).Abs() > time.Minute {
+		r.t.Errorf("timestamp %q isn't now", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte(r.secret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get("X-TipJar-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+		r.t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, receivedWebhook{
+		event:    req.Header.Get("X-TipJar-Event"),
+		delivery: req.Header.Get("X-TipJar-Delivery"),
+		body:     string(body),
+	})
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) respondWith(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	got := SignWebhookPayload("whsec_test", "1700000000", []byte(`{"type":"offense.reported"}`))
+	want := "600a2e22625698fc6b7151547db322432a456e5ddc2b9c992ec5edf28b49d080"
+	if got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+
+	if SignWebhookPayload("whsec_other", "1700000000", []byte(`{"type":"offense.reported"}`)) == want {
+		t.Error("signature doesn't depend on the secret")
+	}
+	if SignWebhookPayload("whsec_test", "1700000001", []byte(`{"type":"offense.reported"}`)) == want {
+		t.Error("signature doesn't depend on the timestamp")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{8, 64 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	ctx := context.Background()
+	receiver, server := newWebhookReceiver(t, "whsec_test")
+	s := &WebhookService{client: server.Client()}
+
+	webhook := sqlc.Webhook{Url: server.URL + "/hooks", Secret: "whsec_test"}
+	delivery := sqlc.WebhookDelivery{ID: 42, EventType: "offense.reported", Payload: `{"type":"offense.reported","jar_id":1}`}
+
+	status, err := s.send(ctx, webhook, delivery)
+	if status != http.StatusOK || err != nil {
+		t.Fatalf("send = %d, %v; want 200", status, err)
+	}
+	got := receiver.requests()
+	if len(got) != 1 || got[0].event != "offense.reported" || got[0].delivery != "42" || got[0].body != delivery.Payload {
+		t.Errorf("received %+v", got)
+	}
+
+	receiver.respondWith(http.StatusGone)
+	status, err = s.send(ctx, webhook, delivery)
+	if status != http.StatusGone || err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("send to a failing receiver = %d, %v; want 410 and an error", status, err)
+	}
+
+	server.Close()
+	if status, err := s.send(ctx, webhook, delivery); status != 0 || err == nil {
+		t.Errorf("send to a closed server = %d, %v; want 0 and an error", status, err)
+	}
+}
+
+func TestWebhookDispatchRetriesAndRedelivers(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+
+	owner := dbtest.User(t, db, "owner")
+	jar, err := NewTipJarService(db, events.NewMemoryBus()).CreateTipJar(ctx, "Hooked", "", int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewWebhookService(db)
+	// The secret isn't known until the webhook exists, so point it at a
+	// receiver created afterwards
+	webhook, err := s.CreateWebhook(ctx, jar.ID, "http://placeholder.invalid", []string{"offense.reported"}, int(owner.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, server := newWebhookReceiver(t, webhook.Secret)
+	if _, err := db.Exec(ctx, "UPDATE webhooks SET url = $1 WHERE id = $2", server.URL, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	enqueue := func(eventType string) {
+		t.Helper()
+		if err := enqueueWebhooks(ctx, db.Queries, int32(jar.ID), eventType, map[string]int{"offense_id": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dispatch := func(want int) {
+		t.Helper()
+		attempted, err := s.DispatchDue(ctx)
+		if err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+		if attempted != want {
+			t.Fatalf("DispatchDue attempted %d deliveries, want %d", attempted, want)
+		}
+	}
+	makeDue := func() {
+		t.Helper()
+		if _, err := db.Exec(ctx, "UPDATE webhook_deliveries SET next_attempt_at = NOW() WHERE webhook_id = $1", webhook.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest := func() models.WebhookDelivery {
+		t.Helper()
+		deliveries, err := s.ListDeliveries(ctx, webhook.ID)
+		if err != nil || len(deliveries) == 0 {
+			t.Fatalf("ListDeliveries = %d, %v", len(deliveries), err)
+		}
+		return deliveries[0]
+	}
+
+	// Events the webhook isn't subscribed to aren't queued
+	enqueue("member.joined")
+	dispatch(0)
+
+	enqueue("offense.reported")
+	receiver.respondWith(http.StatusServiceUnavailable)
+
+	for attempt, wantDelay := range []time.Duration{30 * time.Second, time.Minute} {
+		start := time.Now()
+		dispatch(1)
+
+		d := latest()
+		if d.Status != "pending" || d.Attempts != attempt+1 {
+			t.Errorf("after failed attempt %d: status %s, attempts %d", attempt+1, d.Status, d.Attempts)
+		}
+		if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusServiceUnavailable {
+			t.Errorf("last status = %v, want 503", d.LastStatusCode)
+		}
+		if delay := d.NextAttemptAt.Sub(start); delay < wantDelay-5*time.Second || delay > wantDelay+5*time.Second {
+			t.Errorf("retry %d scheduled in %s, want about %s", attempt+1, delay, wantDelay)
+		}
+
+		// Nothing is sent again before the retry is due
+		dispatch(0)
+		makeDue()
+	}
+
+	receiver.respondWith(http.StatusNoContent)
+	dispatch(1)
+
+	original := latest()
+	if original.Status != "delivered" || original.Attempts != 3 ||
+		original.LastStatusCode == nil || *original.LastStatusCode != http.StatusNoContent {
+		t.Errorf("after delivering: %+v", original)
+	}
+	makeDue()
+	dispatch(0)
+
+	sent := receiver.requests()
+	if len(sent) != 3 {
+		t.Fatalf("receiver got %d requests, want 3", len(sent))
+	}
+	for _, r := range sent {
+		if r.event != "offense.reported" || r.delivery != strconv.Itoa(original.ID) || r.body != sent[0].body {
+			t.Errorf("retry %+v differs from the first attempt %+v", r, sent[0])
+		}
+	}
+
+	// Redelivering sends the same payload as a new delivery
+	redelivery, err := s.Redeliver(ctx, original.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivery.ID == original.ID || redelivery.Status != "pending" || redelivery.Payload != sent[0].body {
+		t.Errorf("redelivery = %+v", redelivery)
+	}
+	dispatch(1)
+
+	sent = receiver.requests()
+	if len(sent) != 4 || sent[3].delivery != strconv.Itoa(redelivery.ID) || sent[3].body != sent[0].body {
+		t.Errorf("redelivery sent as %+v", sent[len(sent)-1])
+	}
+	if d, err := s.GetDelivery(ctx, original.ID); err != nil || d.Status != "delivered" || d.Attempts != 3 {
+		t.Errorf("original after redelivery = %+v, %v", d, err)
+	}
+
+	// A delivery gives up after its last attempt
+	enqueue("offense.reported")
+	receiver.respondWith(http.StatusInternalServerError)
+	if _, err := db.Exec(ctx, "UPDATE webhook_deliveries SET attempts = $1 WHERE status = 'pending' AND webhook_id = $2", maxWebhookAttempts-1, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(1)
+	if d := latest(); d.Status != "failed" || d.Attempts != maxWebhookAttempts {
+		t.Errorf("after the last attempt: status %s, attempts %d", d.Status, d.Attempts)
+	}
+	makeDue()
+	dispatch(0)
+
+	// Deliveries queued before a webhook is paused wait until it's resumed
+	receiver.respondWith(http.StatusOK)
+	enqueue("offense.reported")
+	if err := s.SetWebhookActive(ctx, webhook.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(0)
+
+	// Paused webhooks don't queue anything
+	enqueue("offense.reported")
+	dispatch(0)
+
+	// One claimed just before the pause is handed back unsent
+	held := latest()
+	if err := s.attempt(ctx, sqlc.WebhookDelivery{ID: int32(held.ID), WebhookID: int32(webhook.ID)}); err != errWebhookPaused {
+		t.Errorf("attempt on a paused webhook = %v, want errWebhookPaused", err)
+	}
+	if d := latest(); d.Status != "pending" || d.Attempts != 0 || d.NextAttemptAt.After(time.Now()) {
+		t.Errorf("held delivery = %+v, want pending and due", d)
+	}
+	if sent := receiver.requests(); len(sent) != 5 {
+		t.Errorf("receiver got %d requests while paused, want 5", len(sent))
+	}
+
+	if err := s.SetWebhookActive(ctx, webhook.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(1)
+	if d := latest(); d.ID != held.ID || d.Status != "delivered" || d.Attempts != 1 {
+		t.Errorf("after resuming: %+v", d)
+	}
+}
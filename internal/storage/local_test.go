@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "uploads")
+	s, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "proofs/7/receipt.pdf"
+	if err := s.Put(ctx, key, strings.NewReader("%PDF-1.4 receipt"), 16, "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "proofs", "7", "receipt.pdf")); err != nil {
+		t.Errorf("object not stored under root: %v", err)
+	}
+
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "%PDF-1.4 receipt" {
+		t.Errorf("Get = %q", body)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, key); err != ErrNotFound {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(root, "proofs", "7"))
+	if len(entries) != 0 {
+		t.Errorf("left %d files behind, want none", len(entries))
+	}
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "uploads")
+	s, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outside := filepath.Join(dir, "outside")
+	if err := os.WriteFile(outside, []byte("keep me"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{
+		"",
+		"/",
+		"..",
+		"../outside",
+		"../../etc/passwd",
+		"proofs/../../outside",
+		"proofs/7/../../../outside",
+		"/../outside",
+		"proofs/..",
+	}
+
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			if err := s.Put(ctx, key, strings.NewReader("evil"), 4, "text/plain"); err == nil {
+				t.Error("Put accepted the key")
+			}
+			if rc, err := s.Get(ctx, key); err == nil {
+				rc.Close()
+				t.Error("Get accepted the key")
+			}
+			if err := s.Delete(ctx, key); err == nil {
+				t.Error("Delete accepted the key")
+			}
+		})
+	}
+
+	data, err := os.ReadFile(outside)
+	if err != nil || string(data) != "keep me" {
+		t.Errorf("file outside root was touched: %q, %v", data, err)
+	}
+}
+
+func TestLocalStorageKeepsAbsoluteKeysUnderRoot(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "/proofs/1/a.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "proofs", "1", "a.png")); err != nil {
+		t.Errorf("absolute key not stored under root: %v", err)
+	}
+}
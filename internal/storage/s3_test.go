@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-central-1"
+)
+
+type s3Object struct {
+	body        []byte
+	contentType string
+}
+
+// fakeS3 is an in-memory, path-style S3 endpoint that checks every request's
+// SigV4 signature the way the real service does
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]s3Object
+	fail    bool
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: make(map[string]s3Object)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySigV4(r, testAccessKey, testSecretKey, testRegion); err != nil {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+err.Error()+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail {
+		http.Error(w, "<Error><Code>InternalError</Code></Error>", http.StatusInternalServerError)
+		return
+	}
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) != r.ContentLength {
+			http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = s3Object{body: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Write(obj.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySigV4 rebuilds the canonical request from what arrived on the wire
+// and checks the Authorization header against it
+func verifySigV4(r *http.Request, accessKey, secretKey, region string) error {
+	auth := r.Header.Get("Authorization")
+	rest, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return fmt.Errorf("unexpected authorization %q", auth)
+	}
+
+	fields := make(map[string]string)
+	for _, part := range strings.Split(rest, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return fmt.Errorf("bad X-Amz-Date %q", amzDate)
+	}
+	if d := time.Since(signedAt); d < -15*time.Minute || d > 15*time.Minute {
+		return fmt.Errorf("request signed at %s", signedAt)
+	}
+
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+	if fields["Credential"] != accessKey+"/"+scope {
+		return fmt.Errorf("credential %q", fields["Credential"])
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return fmt.Errorf("signed headers not sorted: %v", signed)
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !contains(signed, required) {
+			return fmt.Errorf("%s is not signed", required)
+		}
+	}
+	if r.Header.Get("Content-Type") != "" && !contains(signed, "content-type") {
+		return fmt.Errorf("content-type is not signed")
+	}
+
+	var headers strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		headers.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{amzDate[:8], region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+
+	if want := hex.EncodeToString(key); !hmac.Equal([]byte(fields["Signature"]), []byte(want)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestS3StoragePutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeS3(t)
+
+	s, err := NewS3Storage(server.URL+"/storage/", testRegion, "tipjar", testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"proofs/7/abc.png", "/proofs/7/with space.pdf"}
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			body := "contents of " + key
+			if err := s.Put(ctx, key, strings.NewReader(body), int64(len(body)), "image/png"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			stored, ok := fake.objects["/storage/tipjar/"+strings.TrimPrefix(key, "/")]
+			if !ok {
+				t.Fatalf("object not stored under the bucket path, have %v", fake.objects)
+			}
+			if stored.contentType != "image/png" {
+				t.Errorf("content type = %q", stored.contentType)
+			}
+
+			rc, err := s.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			got, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(got) != body {
+				t.Errorf("Get = %q, want %q", got, body)
+			}
+
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := s.Get(ctx, key); err != ErrNotFound {
+				t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeS3(t)
+
+	wrongSecret, err := NewS3Storage(server.URL, testRegion, "tipjar", testAccessKey, "not-the-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wrongSecret.Put(ctx, "proofs/1/a.png", strings.NewReader("png"), 3, "image/png")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Put with the wrong secret = %v, want a signature error", err)
+	}
+
+	wrongRegion, err := NewS3Storage(server.URL, "", "tipjar", testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongRegion.Get(ctx, "proofs/1/a.png"); err == nil || err == ErrNotFound {
+		t.Errorf("Get signed for us-east-1 = %v, want a signature error", err)
+	}
+
+	s, err := NewS3Storage(server.URL, testRegion, "tipjar", testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "proofs/1/missing.png"); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+
+	fake.fail = true
+	if _, err := s.Get(ctx, "proofs/1/a.png"); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Get from a failing server = %v, want the status in the error", err)
+	}
+}
+
+func TestNewS3Storage(t *testing.T) {
+	tests := []struct {
+		endpoint, bucket string
+		ok               bool
+	}{
+		{"https://s3.example.com", "tipjar", true},
+		{"http://localhost:9000/", "tipjar", true},
+		{"", "tipjar", false},
+		{"https://s3.example.com", "", false},
+		{"s3.example.com", "tipjar", false},
+		{"://bad", "tipjar", false},
+	}
+
+	for _, tt := range tests {
+		s, err := NewS3Storage(tt.endpoint, "", tt.bucket, testAccessKey, testSecretKey)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewS3Storage(%q, %q) error = %v, want ok %v", tt.endpoint, tt.bucket, err, tt.ok)
+		}
+		if err == nil && s.region != "us-east-1" {
+			t.Errorf("default region = %q, want us-east-1", s.region)
+		}
+	}
+}
@@ -21,8 +21,43 @@ document.addEventListener("DOMContentLoaded", function () {
     },
   };
   utils.formatTimestamps();
+  document.querySelectorAll("[data-jar-events]").forEach(liveJar.connect);
 });
 
+// Live jar pages: the jar's event stream says when something changed, and
+// the parts of the page marked data-live are swapped for freshly rendered
+// ones. Parts holding focus are left alone so nobody loses what they're typing.
+const liveJar = {
+  connect: (el) => {
+    if (typeof EventSource === "undefined") return;
+
+    const source = new EventSource(el.getAttribute("data-jar-events"));
+    // Several events often arrive together, e.g. a payment and its offenses
+    source.onmessage = utils.debounce(liveJar.refresh, 300);
+  },
+
+  refresh: async () => {
+    try {
+      const response = await fetch(window.location.href, {
+        headers: { Accept: "text/html" },
+      });
+      if (!response.ok) return;
+
+      const html = await response.text();
+      const fresh = new DOMParser().parseFromString(html, "text/html");
+
+      document.querySelectorAll("[data-live]").forEach((el) => {
+        const replacement = fresh.getElementById(el.id);
+        if (!replacement || el.contains(document.activeElement)) return;
+        el.innerHTML = replacement.innerHTML;
+      });
+      utils.formatTimestamps();
+    } catch (err) {
+      console.error("Failed to refresh jar:", err);
+    }
+  },
+};
+
 // Utility functions
 const utils = {
   formatCurrency: (amount) => {
@@ -98,13 +133,20 @@ const utils = {
     }
   },
 
+  // Must match internal/invitecode: seven random symbols from Crockford's
+  // base 32 and a check symbol, shown as two groups of four
   generateInviteCode: () => {
-    const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789";
-    let result = "";
-    for (let i = 0; i < 8; i++) {
-      result += chars.charAt(Math.floor(Math.random() * chars.length));
-    }
-    return result;
+    const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ";
+    const values = crypto.getRandomValues(new Uint8Array(7));
+    let code = "";
+    let sum = 0;
+    values.forEach((value, i) => {
+      const symbol = value % alphabet.length;
+      code += alphabet[symbol];
+      sum += (2 * i + 1) * symbol;
+    });
+    code += alphabet[sum % alphabet.length];
+    return `${code.slice(0, 4)}-${code.slice(4)}`;
   },
 
   validateEmail: (email) => {